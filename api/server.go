@@ -0,0 +1,211 @@
+// Package api serves the game being played over HTTP, for tools such as
+// stream overlays and dashboards that follow it as it is played:
+//
+//	GET /state   the board, score, current, next and held pieces as JSON
+//	GET /stats   the statistics of the game so far as JSON
+//	POST /reset  starts a new game, like pressing R
+//
+// The game publishes its state to the server after every frame, so requests
+// are answered from the last frame without holding up the game.
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// State is the answer to GET /state
+type State struct {
+	Board      game.Board `json:"board"` // Every row, hidden ones included, bottom row first
+	Score      int        `json:"score"`
+	Level      int        `json:"level"`
+	Lines      int        `json:"lines"`
+	Piece      string     `json:"piece"`
+	NextPieces []string   `json:"nextPieces"`
+	Hold       string     `json:"hold"` // Empty when nothing is held
+	GameOver   bool       `json:"gameOver"`
+}
+
+// Stats is the answer to GET /stats
+type Stats struct {
+	Time             float64        `json:"time"` // Seconds played
+	PiecesPlaced     map[string]int `json:"piecesPlaced"`
+	Rotations        int            `json:"rotations"`
+	Holds            int            `json:"holds"`
+	LineClears       [5]int         `json:"lineClears"` // Indexed by the number of lines cleared
+	TSpins           int            `json:"tSpins"`
+	TSpinMinis       int            `json:"tSpinMinis"`
+	Combos           int            `json:"combos"`
+	MaxCombo         int            `json:"maxCombo"`
+	HardDrops        int            `json:"hardDrops"`
+	MaxHeight        int            `json:"maxHeight"`
+	FinesseErrors    int            `json:"finesseErrors"`
+	FinesseErrorRate float64        `json:"finesseErrorRate"` // Percentage of pieces placed
+}
+
+// Server answers requests about the game being played. A nil Server
+// publishes nothing and never asks for a reset.
+type Server struct {
+	listener net.Listener
+	http     *http.Server
+
+	mu    sync.RWMutex
+	state []byte // The last state published, encoded
+	stats []byte // The last stats published, encoded
+	reset bool   // Whether a reset was asked for since the last TakeReset
+}
+
+// NewServer starts a server listening on the TCP address addr, such as
+// "localhost:8080". Until the first Publish, /state and /stats answer
+// 503 Service Unavailable.
+func NewServer(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: l}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", s.getOnly(func() []byte { return s.state }))
+	mux.HandleFunc("/stats", s.getOnly(func() []byte { return s.stats }))
+	mux.HandleFunc("/reset", s.handleReset)
+	s.http = &http.Server{Handler: mux}
+	go s.http.Serve(l)
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr { return s.listener.Addr() }
+
+// Close stops the server, dropping the requests in progress
+func (s *Server) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.http.Close()
+}
+
+// Publish makes the state of gs what the server answers with until the
+// next Publish. It is called from the game's goroutine after each frame.
+func (s *Server) Publish(gs *game.GameState) error {
+	if s == nil {
+		return nil
+	}
+	state, err := json.Marshal(newState(gs))
+	if err != nil {
+		return err
+	}
+	stats, err := json.Marshal(newStats(gs))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state, s.stats = state, stats
+	return nil
+}
+
+// TakeReset returns whether a reset was asked for since it was last called
+func (s *Server) TakeReset() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset := s.reset
+	s.reset = false
+	return reset
+}
+
+// newState returns the answer to GET /state for gs
+func newState(gs *game.GameState) State {
+	var next []string
+	for _, p := range gs.NextPieces() {
+		next = append(next, game.PieceName(p))
+	}
+	return State{
+		Board:      gs.Board(),
+		Score:      gs.Score(),
+		Level:      gs.Level(),
+		Lines:      gs.LinesCleared(),
+		Piece:      game.PieceName(gs.CurrentPiece()),
+		NextPieces: next,
+		Hold:       game.PieceName(gs.HoldPiece()),
+		GameOver:   gs.GameOver(),
+	}
+}
+
+// newStats returns the answer to GET /stats for gs
+func newStats(gs *game.GameState) Stats {
+	s := gs.Stats()
+	placed := make(map[string]int)
+	for p, n := range s.PiecesPlaced {
+		placed[game.PieceName(game.Piece(p))] = n
+	}
+	return Stats{
+		Time:             gs.ElapsedTime(),
+		PiecesPlaced:     placed,
+		Rotations:        s.Rotations,
+		Holds:            s.Holds,
+		LineClears:       s.LineClears,
+		TSpins:           s.TSpins,
+		TSpinMinis:       s.TSpinMinis,
+		Combos:           s.Combos,
+		MaxCombo:         s.MaxCombo,
+		HardDrops:        s.HardDrops,
+		MaxHeight:        s.MaxHeight,
+		FinesseErrors:    s.FinesseErrors,
+		FinesseErrorRate: s.FinesseErrorRate(),
+	}
+}
+
+// getOnly returns a handler answering GET requests with the JSON returned
+// by body, read under the lock
+func (s *Server) getOnly(body func() []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed)
+			return
+		}
+		s.mu.RLock()
+		b := body()
+		s.mu.RUnlock()
+		if b == nil {
+			writeError(w, http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
+
+// handleReset asks the game to start over. The game restarts on its next
+// frame, so the answer is 202 Accepted.
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	s.reset = true
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"reset":true}` + "\n"))
+}
+
+// writeError answers with status and its text as a JSON error
+func writeError(w http.ResponseWriter, status int) {
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{http.StatusText(status)})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
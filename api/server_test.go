@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+)
+
+// startServer starts a server on a random port, closed at the end of the
+// test
+func startServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// request makes a request with method for path of s, failing the test
+// unless it is answered with status and JSON. Returns the body.
+func request(t *testing.T, s *Server, method, path string, status int) []byte {
+	t.Helper()
+	req, err := http.NewRequest(method, "http://"+s.Addr().String()+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != status {
+		t.Errorf("%s %s answered %d %s, want %d", method, path, resp.StatusCode, body, status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s answered with Content-Type %q, want application/json", method, path, ct)
+	}
+	if !json.Valid(body) {
+		t.Errorf("%s %s answered %q, want JSON", method, path, body)
+	}
+	return body
+}
+
+func TestGetState(t *testing.T) {
+	s := startServer(t)
+	request(t, s, http.MethodGet, "/state", http.StatusServiceUnavailable)
+
+	gs := game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), 1)
+	if err := s.Publish(gs); err != nil {
+		t.Fatal(err)
+	}
+	var got State
+	if err := json.Unmarshal(request(t, s, http.MethodGet, "/state", http.StatusOK), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := newState(gs); !reflect.DeepEqual(got, want) {
+		t.Errorf("GET /state = %+v, want %+v", got, want)
+	}
+	if len(got.NextPieces) == 0 || got.Piece == "" || got.Hold != "" || got.Level != 1 {
+		t.Errorf("GET /state = %+v, want a new game", got)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	s := startServer(t)
+	request(t, s, http.MethodGet, "/stats", http.StatusServiceUnavailable)
+
+	gs := game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), 1)
+	if err := s.Publish(gs); err != nil {
+		t.Fatal(err)
+	}
+	var got Stats
+	if err := json.Unmarshal(request(t, s, http.MethodGet, "/stats", http.StatusOK), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := newStats(gs); !reflect.DeepEqual(got, want) {
+		t.Errorf("GET /stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestReset(t *testing.T) {
+	s := startServer(t)
+	if s.TakeReset() {
+		t.Error("TakeReset() = true before a reset was asked for")
+	}
+	request(t, s, http.MethodPost, "/reset", http.StatusAccepted)
+	if !s.TakeReset() {
+		t.Error("TakeReset() = false after POST /reset")
+	}
+	if s.TakeReset() {
+		t.Error("TakeReset() = true a second time")
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s := startServer(t)
+	if err := s.Publish(game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), 1)); err != nil {
+		t.Fatal(err)
+	}
+	request(t, s, http.MethodPost, "/state", http.StatusMethodNotAllowed)
+	request(t, s, http.MethodDelete, "/stats", http.StatusMethodNotAllowed)
+	request(t, s, http.MethodGet, "/reset", http.StatusMethodNotAllowed)
+	if s.TakeReset() {
+		t.Error("GET /reset asked for a reset")
+	}
+}
+
+func TestNilServer(t *testing.T) {
+	var s *Server
+	if err := s.Publish(game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), 1)); err != nil {
+		t.Errorf("Publish() = %v", err)
+	}
+	if s.TakeReset() {
+		t.Error("TakeReset() = true on a nil Server")
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
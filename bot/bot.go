@@ -0,0 +1,66 @@
+// Package bot plays the game without a player, for demonstrations and to
+// exercise the game logic. Bots only see what the game shows and press the
+// same actions a player would.
+package bot
+
+import (
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// InputEvent is a tap of an action: pressed for one step of the game and
+// released the next
+type InputEvent struct {
+	Action input.Action
+}
+
+// Bot chooses where each piece goes
+type Bot interface {
+	// MakeMove returns the taps that put the active piece of gs where the
+	// bot wants it, ending with a hard drop
+	MakeMove(gs *game.GameState) []InputEvent
+}
+
+// Driver presses the actions a bot chooses, one step of the game at a time
+type Driver struct {
+	bot     Bot
+	placed  int // Pieces placed when the bot last moved
+	taps    []InputEvent
+	pressed bool // Whether the first of taps is held down
+}
+
+// NewDriver returns a driver for b, which is asked for its move each time a
+// new piece comes into play
+func NewDriver(b Bot) *Driver {
+	return &Driver{bot: b, placed: -1}
+}
+
+// Next returns the actions held down for the next step of gs
+func (d *Driver) Next(gs *game.GameState) [input.NumActions]bool {
+	if n := gs.Stats().TotalPlaced(); n != d.placed && !d.pressed {
+		d.placed = n
+		d.taps = d.bot.MakeMove(gs)
+	}
+
+	var down [input.NumActions]bool
+	if d.pressed {
+		d.taps = d.taps[1:]
+		d.pressed = false
+	} else if len(d.taps) > 0 {
+		down[d.taps[0].Action] = true
+		d.pressed = true
+	}
+	return down
+}
+
+// Play runs gs in fixed steps with b at the controls until the game is over
+// or maxFrames steps have run, when maxFrames is above 0
+func Play(gs *game.GameState, b Bot, maxFrames int) {
+	var in input.PlayerInput
+	handler := input.NewInputHandler(gs.Settings().DAS, gs.Settings().ARR)
+	d := NewDriver(b)
+	for step := 0; !gs.GameOver() && (maxFrames <= 0 || step < maxFrames); step++ {
+		in.Next(d.Next(gs))
+		gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+	}
+}
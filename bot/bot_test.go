@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// holeBoard is an empty board but for a bottom row with one cell missing
+var holeBoard = strings.Repeat("..........\n", 21) + "XXXX.XXXXX"
+
+// holeGame returns a marathon on holeBoard dealt from the first seed from 1
+// up whose first piece is p
+func holeGame(t testing.TB, p game.Piece) *game.GameState {
+	t.Helper()
+	b, err := game.BoardFromString(holeBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seed := int64(1); seed <= 100; seed++ {
+		gs := game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), seed)
+		if err := gs.SetStartBoard(b); err != nil {
+			t.Fatal(err)
+		}
+		if gs.CurrentPiece() == p {
+			return gs
+		}
+	}
+	t.Fatalf("no seed up to 100 starts with piece %d", p)
+	return nil
+}
+
+// playPieces runs gs as Play does until b has placed n pieces
+func playPieces(gs *game.GameState, b Bot, n int) {
+	var in input.PlayerInput
+	handler := input.NewInputHandler(gs.Settings().DAS, gs.Settings().ARR)
+	d := NewDriver(b)
+	placed := gs.Stats().TotalPlaced()
+	for !gs.GameOver() && gs.Stats().TotalPlaced() < placed+n {
+		in.Next(d.Next(gs))
+		gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+	}
+}
+
+// blocks returns the number of cells of b that aren't empty
+func blocks(b game.Board) int {
+	n := 0
+	for r := 0; r < b.Rows(); r++ {
+		for c := 0; c < b.Cols(); c++ {
+			if b.At(r, c) != game.Empty {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestGreedyBotFillsHole(t *testing.T) {
+	// Each of the pieces that can reach down into the hole does, clearing
+	// the row and leaving nothing but the next piece
+	for _, p := range []game.Piece{game.IPiece, game.JPiece, game.LPiece, game.TPiece} {
+		gs := holeGame(t, p)
+		playPieces(gs, NewGreedyBot(DefaultWeights), 1)
+		if gs.LinesCleared() != 1 {
+			t.Errorf("piece %d cleared %d lines, want 1:\n%s", p, gs.LinesCleared(), gs.Board())
+			continue
+		}
+		if n := blocks(gs.Board()); n != 4+3 {
+			t.Errorf("piece %d left %d blocks, want the 3 left over and the next piece:\n%s", p, n, gs.Board())
+		}
+	}
+}
+
+func TestGreedyBotClearsHoleRow(t *testing.T) {
+	// Pieces that can't reach the hole are stacked so a later one can
+	for p := game.IPiece; p <= game.ZPiece; p++ {
+		gs := holeGame(t, p)
+		playPieces(gs, NewGreedyBot(DefaultWeights), 6)
+		if gs.GameOver() || gs.LinesCleared() < 1 {
+			t.Errorf("starting with piece %d: %d lines cleared in 6 pieces, game over %t:\n%s", p, gs.LinesCleared(), gs.GameOver(), gs.Board())
+		}
+	}
+}
+
+func TestPlacementTaps(t *testing.T) {
+	tests := []struct {
+		p    game.Placement
+		want []input.Action
+	}{
+		{game.Placement{}, []input.Action{input.ActionHardDrop}},
+		{game.Placement{Rotation: 1, Shift: 2}, []input.Action{input.ActionRotateCW, input.ActionMoveRight, input.ActionMoveRight, input.ActionHardDrop}},
+		{game.Placement{Rotation: 2, Shift: -1}, []input.Action{input.ActionRotate180, input.ActionMoveLeft, input.ActionHardDrop}},
+		{game.Placement{Rotation: 3}, []input.Action{input.ActionRotateCCW, input.ActionHardDrop}},
+	}
+	for _, tt := range tests {
+		taps := placementTaps(tt.p)
+		var got []input.Action
+		for _, tap := range taps {
+			got = append(got, tap.Action)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("placementTaps(rotation %d, shift %d) = %v, want %v", tt.p.Rotation, tt.p.Shift, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("placementTaps(rotation %d, shift %d) = %v, want %v", tt.p.Rotation, tt.p.Shift, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkGreedyMakeMove(b *testing.B) {
+	gs := holeGame(b, game.TPiece)
+	bot := NewGreedyBot(DefaultWeights)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bot.MakeMove(gs)
+	}
+}
+
+func BenchmarkLookaheadMakeMove(b *testing.B) {
+	gs := holeGame(b, game.TPiece)
+	bot := NewLookaheadBot(DefaultWeights)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bot.MakeMove(gs)
+	}
+}
+
+func TestSprintComplete(t *testing.T) {
+	// A whole sprint played by the bot through the same input path as a
+	// player. It is here and not in package main, which needs a display,
+	// as the game package can't import the bot.
+	gs := game.NewSeededGameState(game.ModeSprint, config.DefaultSettings(), 1)
+	var in input.PlayerInput
+	handler := input.NewInputHandler(gs.Settings().DAS, gs.Settings().ARR)
+	d := NewDriver(NewGreedyBot(DefaultWeights))
+	const maxSteps = int(10 * 60 / game.StepLength) // 10 minutes
+	step := 0
+	for ; !gs.GameOver() && step < maxSteps; step++ {
+		if gs.ModeComplete() || gs.SprintLinesLeft() == 0 {
+			t.Fatalf("step %d: sprint complete with the game going on", step)
+		}
+		in.Next(d.Next(gs))
+		gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+	}
+	if !gs.GameOver() {
+		t.Fatalf("%d lines cleared in %d steps, want the sprint over:\n%s", gs.LinesCleared(), step, gs.Board())
+	}
+	if !gs.ModeComplete() {
+		t.Fatalf("topped out with %d lines cleared, want a complete sprint:\n%s", gs.LinesCleared(), gs.Board())
+	}
+	if gs.LinesCleared() < 40 || gs.SprintLinesLeft() != 0 {
+		t.Errorf("sprint complete with %d lines cleared and %d left, want at least 40 and none left", gs.LinesCleared(), gs.SprintLinesLeft())
+	}
+	if gs.ElapsedTime() <= 0 {
+		t.Errorf("sprint took %v seconds", gs.ElapsedTime())
+	}
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+)
+
+// BotDifficulty is how well a bot made by NewBot plays
+type BotDifficulty int
+
+// Difficulties from weakest to strongest
+const (
+	DiffRandom    BotDifficulty = iota // RandomBot
+	DiffGreedy                         // GreedyBot with DefaultWeights
+	DiffLookahead                      // LookaheadBot with DefaultWeights
+)
+
+// ParseBotDifficulty returns the difficulty named name: random, greedy or
+// lookahead
+func ParseBotDifficulty(name string) (BotDifficulty, error) {
+	switch name {
+	case "random":
+		return DiffRandom, nil
+	case "greedy":
+		return DiffGreedy, nil
+	case "lookahead":
+		return DiffLookahead, nil
+	}
+	return DiffGreedy, fmt.Errorf("unknown bot difficulty %q", name)
+}
+
+// NewBot returns a bot that plays at difficulty d
+func NewBot(d BotDifficulty) Bot {
+	switch d {
+	case DiffRandom:
+		return NewRandomBot(time.Now().UnixNano())
+	case DiffLookahead:
+		return NewLookaheadBot(DefaultWeights)
+	default:
+		return NewGreedyBot(DefaultWeights)
+	}
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"math"
+
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// Indexes of the weights of a GreedyBot
+const (
+	WeightHoles        = iota // Empty cells under the top of their column
+	WeightMaxHeight           // Height of the highest column
+	WeightBumpiness           // Sum of the height differences of neighbouring columns
+	WeightLinesCleared        // Rows cleared by the piece, which lower the cost
+)
+
+// DefaultWeights play a steady game, keeping the stack low and free of holes
+var DefaultWeights = [4]float64{
+	WeightHoles:        8,
+	WeightMaxHeight:    1,
+	WeightBumpiness:    0.4,
+	WeightLinesCleared: 3,
+}
+
+// GreedyBot drops each piece where the board it leaves costs the least,
+// without looking at the pieces to come. The cost is
+//
+//	weights[0]*holes + weights[1]*maxHeight + weights[2]*bumpiness - weights[3]*linesCleared
+//
+// in the style of Dellacherie's heuristic.
+type GreedyBot struct {
+	weights [4]float64
+}
+
+// NewGreedyBot returns a bot that weighs the boards it could leave with
+// weights, indexed by WeightHoles and the others
+func NewGreedyBot(weights [4]float64) *GreedyBot {
+	return &GreedyBot{weights: weights}
+}
+
+// MakeMove rotates and moves the active piece to the placement that costs
+// the least and hard drops it. Returns nil when the piece can't be placed.
+func (b *GreedyBot) MakeMove(gs *game.GameState) []InputEvent {
+	best := math.Inf(1)
+	var move *game.Placement
+	placements := gs.Placements()
+	for i := range placements {
+		if cost := b.cost(placements[i]); cost < best {
+			best = cost
+			move = &placements[i]
+		}
+	}
+	if move == nil {
+		return nil
+	}
+	return placementTaps(*move)
+}
+
+// cost weighs the board p leaves
+func (b *GreedyBot) cost(p game.Placement) float64 {
+	heights := game.ColumnHeights(p.Board)
+	maxHeight, bumpiness := 0, 0
+	for c, h := range heights {
+		if h > maxHeight {
+			maxHeight = h
+		}
+		if c > 0 {
+			bumpiness += absInt(h - heights[c-1])
+		}
+	}
+	holes := len(game.FindHoles(p.Board))
+	return b.weights[WeightHoles]*float64(holes) +
+		b.weights[WeightMaxHeight]*float64(maxHeight) +
+		b.weights[WeightBumpiness]*float64(bumpiness) -
+		b.weights[WeightLinesCleared]*float64(p.Lines)
+}
+
+// placementTaps returns the taps that rotate, move and hard drop the active
+// piece to p
+func placementTaps(p game.Placement) []InputEvent {
+	var taps []InputEvent
+	switch p.Rotation {
+	case 1:
+		taps = append(taps, InputEvent{input.ActionRotateCW})
+	case 2:
+		taps = append(taps, InputEvent{input.ActionRotate180})
+	case 3:
+		taps = append(taps, InputEvent{input.ActionRotateCCW})
+	}
+	for i := 0; i < p.Shift; i++ {
+		taps = append(taps, InputEvent{input.ActionMoveRight})
+	}
+	for i := 0; i > p.Shift; i-- {
+		taps = append(taps, InputEvent{input.ActionMoveLeft})
+	}
+	return append(taps, InputEvent{input.ActionHardDrop})
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
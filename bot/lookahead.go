@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"math"
+	"sort"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// LookaheadBeamWidth is how many of the best placements of the active piece
+// a LookaheadBot tries the next piece after
+const LookaheadBeamWidth = 5
+
+// LookaheadBot drops each piece where, with the next piece placed as well as
+// it can be, the board left costs the least. Only the placements of the
+// active piece a GreedyBot likes best are searched further.
+type LookaheadBot struct {
+	greedy GreedyBot
+}
+
+// NewLookaheadBot returns a bot that weighs the boards it could leave with
+// weights, as NewGreedyBot does
+func NewLookaheadBot(weights [4]float64) *LookaheadBot {
+	return &LookaheadBot{greedy: GreedyBot{weights: weights}}
+}
+
+// MakeMove rotates and moves the active piece to the placement that costs
+// the least after the next piece and hard drops it. Returns nil when the
+// piece can't be placed.
+func (b *LookaheadBot) MakeMove(gs *game.GameState) []InputEvent {
+	placements := gs.Placements()
+	if len(placements) == 0 {
+		return nil
+	}
+	costs := make([]float64, len(placements))
+	for i := range placements {
+		costs[i] = b.greedy.cost(placements[i])
+	}
+	order := make([]int, len(placements))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return costs[order[i]] < costs[order[j]] })
+	if len(order) > LookaheadBeamWidth {
+		order = order[:LookaheadBeamWidth]
+	}
+
+	best := math.Inf(1)
+	move := order[0]
+	for _, i := range order {
+		cost := b.afterNext(gs, placements[i])
+		if cost < best {
+			best = cost
+			move = i
+		}
+	}
+	return placementTaps(placements[move])
+}
+
+// afterNext returns the cost of the best board the next piece can leave once
+// the active piece has been dropped to p, counting the rows both clear. A
+// placement the next piece can't follow costs +Inf.
+func (b *LookaheadBot) afterNext(gs *game.GameState, p game.Placement) float64 {
+	best := math.Inf(1)
+	for _, next := range gs.PlacementsAfter(p) {
+		next.Lines += p.Lines
+		if cost := b.greedy.cost(next); cost < best {
+			best = cost
+		}
+	}
+	return best
+}
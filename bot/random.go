@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"math/rand"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// RandomBot drops each piece at one of its placements picked at random
+type RandomBot struct {
+	rng *rand.Rand
+}
+
+// NewRandomBot returns a bot that picks its placements with a random
+// source seeded with seed
+func NewRandomBot(seed int64) *RandomBot {
+	return &RandomBot{rng: rand.New(rand.NewSource(seed))}
+}
+
+// MakeMove rotates and moves the active piece to a placement picked
+// uniformly at random and hard drops it. Returns nil when the piece can't be
+// placed.
+func (b *RandomBot) MakeMove(gs *game.GameState) []InputEvent {
+	placements := gs.Placements()
+	if len(placements) == 0 {
+		return nil
+	}
+	return placementTaps(placements[b.rng.Intn(len(placements))])
+}
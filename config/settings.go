@@ -0,0 +1,192 @@
+// Package config loads the user editable settings of the game from the
+// resources directory. It doesn't depend on pixel so the game logic can use
+// it without a display, the key bindings are loaded by the controls package.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+// Smallest values accepted for the timing settings
+const (
+	MinSoftDropSpeed = 0.01 // Seconds per row, keeps soft drop from running every frame
+	MinLockDelay     = 0.05 // Seconds, leaves at least a few frames to slide a piece
+)
+
+// Sizes of the visible part of the board that are allowed
+const (
+	MinRows = 6
+	MaxRows = 40
+	MinCols = 4
+	MaxCols = 20
+)
+
+// Sizes of the window in pixels. The layout is designed at the default size
+// and scaled to the window.
+const (
+	DefaultWindowWidth  = 765.0
+	DefaultWindowHeight = 450.0
+	MinWindowWidth      = 640.0 // Keeps the UI elements usable
+	MinWindowHeight     = 400.0
+)
+
+// WallKickMode chooses the wall kicks tried when a piece is rotated
+type WallKickMode string
+
+// Wall kick modes that can be set in the settings file
+const (
+	WKMGenerous WallKickMode = "generous" // Many extra kicks so rotations almost never fail
+	WKMStandard WallKickMode = "standard" // Only the SRS kicks of the Tetris guideline
+)
+
+// Theme chooses the colors the game is drawn in
+type Theme string
+
+// Themes that can be set in the settings file
+const (
+	ThemeDefault      Theme = "default"      // Block sprites on the mountain background
+	ThemeHighContrast Theme = "highContrast" // Primary colors on jet black
+)
+
+// Settings holds the handling of the game. Times are in seconds.
+type Settings struct {
+	DAS              float64      `json:"das"`              // Delay before a held move key repeats
+	ARR              float64      `json:"arr"`              // Delay between repeated moves
+	SoftDropSpeed    float64      `json:"softDropSpeed"`    // Delay between rows while soft dropping
+	SoftDropFriction float64      `json:"softDropFriction"` // Pause after a soft drop lands on the stack
+	LockDelay        float64      `json:"lockDelay"`        // Time a piece can rest on the stack before locking
+	MaxLockResets    int          `json:"maxLockResets"`    // Moves that can restart the lock delay
+	ShowGhost        bool         `json:"showGhost"`        // Whether to show where the piece will land
+	GhostOpacity     float64      `json:"ghostOpacity"`     // Opacity of the ghost piece from 0 to 1
+	ShowGrid         bool         `json:"showGrid"`         // Whether to draw grid lines over the board
+	Rows             int          `json:"rows"`             // Visible height of the board in blocks
+	Cols             int          `json:"cols"`             // Width of the board in blocks
+	WallKickMode     WallKickMode `json:"wallKickMode"`     // Wall kicks tried when rotating
+	ColorblindMode   bool         `json:"colorblindMode"`   // Whether each piece's blocks have a pattern as well as a color
+	AnimatedBlock    bool         `json:"animatedBlock"`    // Whether the blocks of the pieces pulse with a glow
+	Theme            Theme        `json:"theme"`            // Colors the game is drawn in
+	SFXVolume        float64      `json:"sfxVolume"`        // Volume of the sound effects from 0 to 1
+	MusicVolume      float64      `json:"musicVolume"`      // Volume of the music from 0 to 1
+	MusicTrack       string       `json:"musicTrack"`       // Name of the music track played, empty for none
+	HapticsEnabled   bool         `json:"hapticsEnabled"`   // Whether a connected gamepad rumbles on locks and clears
+	Fullscreen       bool         `json:"fullscreen"`       // Whether the window fills the primary monitor
+	WindowX          float64      `json:"windowX"`          // Left edge of the window on the screen when last closed
+	WindowY          float64      `json:"windowY"`          // Top edge of the window on the screen when last closed
+	WindowWidth      float64      `json:"windowWidth"`      // Width of the window when last closed, 0 for the default
+	WindowHeight     float64      `json:"windowHeight"`     // Height of the window when last closed, 0 for the default
+}
+
+// DefaultSettings returns the settings used when settings.json is missing.
+func DefaultSettings() Settings {
+	return Settings{
+		DAS:              0.033,
+		ARR:              0.033,
+		SoftDropSpeed:    0.05,
+		SoftDropFriction: 0.1,
+		LockDelay:        0.25,
+		MaxLockResets:    30,
+		ShowGhost:        true,
+		GhostOpacity:     0.4,
+		Rows:             20,
+		Cols:             10,
+		WallKickMode:     WKMGenerous,
+		Theme:            ThemeDefault,
+		SFXVolume:        1,
+		MusicVolume:      0.5,
+		MusicTrack:       "korobeiniki",
+		HapticsEnabled:   true,
+	}
+}
+
+// Validate reports the first setting that is out of range.
+func (s Settings) Validate() error {
+	switch {
+	case s.DAS < 0:
+		return errors.New("das must not be negative")
+	case s.ARR < 0:
+		return errors.New("arr must not be negative")
+	case s.SoftDropSpeed < MinSoftDropSpeed:
+		return fmt.Errorf("softDropSpeed must be at least %g", MinSoftDropSpeed)
+	case s.SoftDropFriction < 0:
+		return errors.New("softDropFriction must not be negative")
+	case s.LockDelay < MinLockDelay:
+		return fmt.Errorf("lockDelay must be at least %g", MinLockDelay)
+	case s.MaxLockResets < 0:
+		return errors.New("maxLockResets must not be negative")
+	case s.GhostOpacity < 0 || s.GhostOpacity > 1:
+		return errors.New("ghostOpacity must be between 0 and 1")
+	case s.Rows < MinRows || s.Rows > MaxRows:
+		return fmt.Errorf("rows must be between %d and %d", MinRows, MaxRows)
+	case s.Cols < MinCols || s.Cols > MaxCols:
+		return fmt.Errorf("cols must be between %d and %d", MinCols, MaxCols)
+	case s.WallKickMode != WKMGenerous && s.WallKickMode != WKMStandard:
+		return fmt.Errorf("wallKickMode must be %q or %q", WKMGenerous, WKMStandard)
+	case s.Theme != ThemeDefault && s.Theme != ThemeHighContrast:
+		return fmt.Errorf("theme must be %q or %q", ThemeDefault, ThemeHighContrast)
+	case s.SFXVolume < 0 || s.SFXVolume > 1:
+		return errors.New("sfxVolume must be between 0 and 1")
+	case s.MusicVolume < 0 || s.MusicVolume > 1:
+		return errors.New("musicVolume must be between 0 and 1")
+	case s.WindowWidth != 0 && s.WindowWidth < MinWindowWidth:
+		return fmt.Errorf("windowWidth must be at least %g", MinWindowWidth)
+	case s.WindowHeight != 0 && s.WindowHeight < MinWindowHeight:
+		return fmt.Errorf("windowHeight must be at least %g", MinWindowHeight)
+	}
+	return nil
+}
+
+// LoadSettings reads and validates the settings file at path. Settings left
+// out of the file keep their default value and the default settings are used
+// when the file does not exist. An out of range ghost opacity is clamped with
+// a warning rather than rejected.
+func LoadSettings(path string) (Settings, error) {
+	s := DefaultSettings()
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &s)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err == nil {
+		if clamped := math.Max(0, math.Min(1, s.GhostOpacity)); clamped != s.GhostOpacity {
+			fmt.Fprintf(os.Stderr, "warning: %s: ghostOpacity %g is outside 0 to 1, using %g\n", path, s.GhostOpacity, clamped)
+			s.GhostOpacity = clamped
+		}
+		err = s.Validate()
+	}
+	if err != nil {
+		return Settings{}, fmt.Errorf("loading %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// WindowPlacement returns where to open the window on a screen of the
+// given size: where it was last closed, or the default size centered on the
+// screen when it was never saved or would now be entirely off the screen,
+// such as after a monitor was unplugged.
+func (s Settings) WindowPlacement(screenWidth, screenHeight float64) (x, y, width, height float64) {
+	if s.WindowWidth == 0 || s.WindowHeight == 0 ||
+		s.WindowX+s.WindowWidth <= 0 || s.WindowX >= screenWidth ||
+		s.WindowY+s.WindowHeight <= 0 || s.WindowY >= screenHeight {
+		width, height = DefaultWindowWidth, DefaultWindowHeight
+		return (screenWidth - width) / 2, (screenHeight - height) / 2, width, height
+	}
+	return s.WindowX, s.WindowY, s.WindowWidth, s.WindowHeight
+}
+
+// Save validates the settings and writes them to path.
+func (s Settings) Save(path string) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSettings writes data to a settings.json in a new temporary directory,
+// which is removed at the end of the test
+func writeSettings(t *testing.T, data string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "settings.json")
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSettingsClampsGhostOpacity(t *testing.T) {
+	tests := []struct {
+		data string
+		want float64
+	}{
+		{`{"ghostOpacity": 0.7}`, 0.7},
+		{`{"ghostOpacity": 1.5}`, 1},
+		{`{"ghostOpacity": -0.2}`, 0},
+		{`{}`, DefaultSettings().GhostOpacity},
+	}
+	for _, tt := range tests {
+		s, err := LoadSettings(writeSettings(t, tt.data))
+		if err != nil {
+			t.Errorf("LoadSettings(%s) returned error: %v", tt.data, err)
+			continue
+		}
+		if s.GhostOpacity != tt.want {
+			t.Errorf("LoadSettings(%s) ghost opacity = %v, want %v", tt.data, s.GhostOpacity, tt.want)
+		}
+	}
+}
+
+func TestValidateGhostOpacity(t *testing.T) {
+	s := DefaultSettings()
+	s.GhostOpacity = 1.5
+	if err := s.Validate(); err == nil {
+		t.Error("Validate() accepted a ghost opacity of 1.5")
+	}
+}
+
+func TestFullscreenSaved(t *testing.T) {
+	path := writeSettings(t, `{}`)
+	if s, err := LoadSettings(path); err != nil || s.Fullscreen {
+		t.Fatalf("LoadSettings({}) fullscreen = %t, %v, want windowed", s.Fullscreen, err)
+	}
+	s := DefaultSettings()
+	s.Fullscreen = true
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := LoadSettings(path); err != nil || !s.Fullscreen {
+		t.Errorf("LoadSettings() fullscreen = %t, %v after saving it, want true", s.Fullscreen, err)
+	}
+}
+
+func TestWindowPlacement(t *testing.T) {
+	// A 1920x1080 screen, on which the default window is centered at
+	// (577.5, 315)
+	const centerX, centerY = (1920 - DefaultWindowWidth) / 2, (1080 - DefaultWindowHeight) / 2
+	tests := []struct {
+		name                string
+		x, y, width, height float64 // Saved
+		want                [4]float64
+	}{
+		{"never saved", 0, 0, 0, 0, [4]float64{centerX, centerY, DefaultWindowWidth, DefaultWindowHeight}},
+		{"on screen", 100, 50, 800, 600, [4]float64{100, 50, 800, 600}},
+		{"partly off the left", -700, 50, 800, 600, [4]float64{-700, 50, 800, 600}},
+		{"partly off the bottom", 100, 1000, 800, 600, [4]float64{100, 1000, 800, 600}},
+		{"off the left", -800, 50, 800, 600, [4]float64{centerX, centerY, DefaultWindowWidth, DefaultWindowHeight}},
+		{"off the right", 1920, 50, 800, 600, [4]float64{centerX, centerY, DefaultWindowWidth, DefaultWindowHeight}},
+		{"off the top", 100, -600, 800, 600, [4]float64{centerX, centerY, DefaultWindowWidth, DefaultWindowHeight}},
+		{"off the bottom", 100, 1080, 800, 600, [4]float64{centerX, centerY, DefaultWindowWidth, DefaultWindowHeight}},
+		{"on a second monitor", 2500, 200, 1024, 768, [4]float64{centerX, centerY, DefaultWindowWidth, DefaultWindowHeight}},
+	}
+	for _, tt := range tests {
+		s := DefaultSettings()
+		s.WindowX, s.WindowY, s.WindowWidth, s.WindowHeight = tt.x, tt.y, tt.width, tt.height
+		x, y, width, height := s.WindowPlacement(1920, 1080)
+		if got := [4]float64{x, y, width, height}; got != tt.want {
+			t.Errorf("%s: WindowPlacement() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWindowSize(t *testing.T) {
+	tests := []struct {
+		width, height float64
+		ok            bool
+	}{
+		{0, 0, true},
+		{MinWindowWidth, MinWindowHeight, true},
+		{1920, 1080, true},
+		{MinWindowWidth - 1, MinWindowHeight, false},
+		{MinWindowWidth, MinWindowHeight - 1, false},
+		{320, 0, false},
+	}
+	for _, tt := range tests {
+		s := DefaultSettings()
+		s.WindowWidth, s.WindowHeight = tt.width, tt.height
+		if err := s.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate() of a %gx%g window = %v", tt.width, tt.height, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+//go:build !wasm
+// +build !wasm
+
+// Package controls loads the key bindings of the game from controls.json in
+// the resources directory.
+package controls
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Controls holds the name of the key bound to each action as it is written
+// in controls.json. Names are matched against keyNames, ignoring case.
+type Controls struct {
+	MoveLeft  string `json:"moveLeft"`
+	MoveRight string `json:"moveRight"`
+	RotateCW  string `json:"rotateCW"`
+	RotateCCW string `json:"rotateCCW"`
+	Rotate180 string `json:"rotate180"`
+	SoftDrop  string `json:"softDrop"`
+	HardDrop  string `json:"hardDrop"`
+	Hold      string `json:"hold"`
+}
+
+// Keys holds the button bound to each action, as resolved from Controls.
+type Keys struct {
+	MoveLeft  pixelgl.Button
+	MoveRight pixelgl.Button
+	RotateCW  pixelgl.Button
+	RotateCCW pixelgl.Button
+	Rotate180 pixelgl.Button
+	SoftDrop  pixelgl.Button
+	HardDrop  pixelgl.Button
+	Hold      pixelgl.Button
+}
+
+// DefaultControls returns the bindings used when controls.json is missing.
+func DefaultControls() Controls {
+	return Controls{
+		MoveLeft:  "Left",
+		MoveRight: "Right",
+		RotateCW:  "Up",
+		RotateCCW: "Z",
+		Rotate180: "A",
+		SoftDrop:  "Down",
+		HardDrop:  "Space",
+		Hold:      "C",
+	}
+}
+
+// TwoPlayerControls returns the bindings of the left and right players of a
+// two player game, the left around WASD and the right around the arrow
+// keys, with no key shared between them.
+func TwoPlayerControls() [2]Controls {
+	return [2]Controls{
+		{
+			MoveLeft:  "A",
+			MoveRight: "D",
+			RotateCW:  "W",
+			RotateCCW: "Q",
+			Rotate180: "E",
+			SoftDrop:  "S",
+			HardDrop:  "Space",
+			Hold:      "LeftShift",
+		},
+		{
+			MoveLeft:  "Left",
+			MoveRight: "Right",
+			RotateCW:  "Up",
+			RotateCCW: "Slash",
+			Rotate180: "Period",
+			SoftDrop:  "Down",
+			HardDrop:  "Enter",
+			Hold:      "RightShift",
+		},
+	}
+}
+
+// keyNames maps the lower case name of a key to its button.
+var keyNames = buildKeyNames()
+
+func buildKeyNames() map[string]pixelgl.Button {
+	names := map[string]pixelgl.Button{
+		"space":        pixelgl.KeySpace,
+		"apostrophe":   pixelgl.KeyApostrophe,
+		"comma":        pixelgl.KeyComma,
+		"minus":        pixelgl.KeyMinus,
+		"period":       pixelgl.KeyPeriod,
+		"slash":        pixelgl.KeySlash,
+		"semicolon":    pixelgl.KeySemicolon,
+		"equal":        pixelgl.KeyEqual,
+		"leftbracket":  pixelgl.KeyLeftBracket,
+		"backslash":    pixelgl.KeyBackslash,
+		"rightbracket": pixelgl.KeyRightBracket,
+		"graveaccent":  pixelgl.KeyGraveAccent,
+		"escape":       pixelgl.KeyEscape,
+		"enter":        pixelgl.KeyEnter,
+		"tab":          pixelgl.KeyTab,
+		"backspace":    pixelgl.KeyBackspace,
+		"insert":       pixelgl.KeyInsert,
+		"delete":       pixelgl.KeyDelete,
+		"right":        pixelgl.KeyRight,
+		"left":         pixelgl.KeyLeft,
+		"down":         pixelgl.KeyDown,
+		"up":           pixelgl.KeyUp,
+		"pageup":       pixelgl.KeyPageUp,
+		"pagedown":     pixelgl.KeyPageDown,
+		"home":         pixelgl.KeyHome,
+		"end":          pixelgl.KeyEnd,
+		"leftshift":    pixelgl.KeyLeftShift,
+		"leftcontrol":  pixelgl.KeyLeftControl,
+		"leftalt":      pixelgl.KeyLeftAlt,
+		"rightshift":   pixelgl.KeyRightShift,
+		"rightcontrol": pixelgl.KeyRightControl,
+		"rightalt":     pixelgl.KeyRightAlt,
+	}
+
+	// Letters, digits, function keys and the keypad digits are numbered
+	// consecutively
+	for i := 0; i < 26; i++ {
+		names[string(rune('a'+i))] = pixelgl.KeyA + pixelgl.Button(i)
+	}
+	for i := 0; i < 10; i++ {
+		names[fmt.Sprint(i)] = pixelgl.Key0 + pixelgl.Button(i)
+		names[fmt.Sprintf("kp%d", i)] = pixelgl.KeyKP0 + pixelgl.Button(i)
+	}
+	for i := 1; i <= 12; i++ {
+		names[fmt.Sprintf("f%d", i)] = pixelgl.KeyF1 + pixelgl.Button(i-1)
+	}
+	return names
+}
+
+// LookupKey returns the button with the given name, ignoring case.
+func LookupKey(name string) (pixelgl.Button, error) {
+	b, ok := keyNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return pixelgl.KeyUnknown, fmt.Errorf("unknown key %q", name)
+	}
+	return b, nil
+}
+
+// Keys resolves the name of every binding to its button. The error names the
+// action whose key could not be found.
+func (c Controls) Keys() (Keys, error) {
+	var k Keys
+	bindings := []struct {
+		action string
+		name   string
+		button *pixelgl.Button
+	}{
+		{"moveLeft", c.MoveLeft, &k.MoveLeft},
+		{"moveRight", c.MoveRight, &k.MoveRight},
+		{"rotateCW", c.RotateCW, &k.RotateCW},
+		{"rotateCCW", c.RotateCCW, &k.RotateCCW},
+		{"rotate180", c.Rotate180, &k.Rotate180},
+		{"softDrop", c.SoftDrop, &k.SoftDrop},
+		{"hardDrop", c.HardDrop, &k.HardDrop},
+		{"hold", c.Hold, &k.Hold},
+	}
+	for _, b := range bindings {
+		button, err := LookupKey(b.name)
+		if err != nil {
+			return Keys{}, fmt.Errorf("%s: %v", b.action, err)
+		}
+		*b.button = button
+	}
+	return k, nil
+}
+
+// ParseControls reads controls from JSON. Actions left out of data keep
+// their default binding.
+func ParseControls(data []byte) (Controls, error) {
+	c := DefaultControls()
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Controls{}, err
+	}
+	return c, nil
+}
+
+// LoadControls reads the controls file at path and resolves its bindings.
+// The default controls are used when the file does not exist.
+func LoadControls(path string) (Keys, error) {
+	c := DefaultControls()
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		c, err = ParseControls(data)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return Keys{}, fmt.Errorf("loading %s: %v", path, err)
+	}
+
+	k, err := c.Keys()
+	if err != nil {
+		return Keys{}, fmt.Errorf("loading %s: %v", path, err)
+	}
+	return k, nil
+}
@@ -0,0 +1,124 @@
+//go:build !wasm
+// +build !wasm
+
+package controls
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want pixelgl.Button
+	}{
+		{"Space", pixelgl.KeySpace},
+		{"left", pixelgl.KeyLeft},
+		{"UP", pixelgl.KeyUp},
+		{" Down ", pixelgl.KeyDown},
+		{"a", pixelgl.KeyA},
+		{"Z", pixelgl.KeyZ},
+		{"0", pixelgl.Key0},
+		{"9", pixelgl.Key9},
+		{"KP5", pixelgl.KeyKP5},
+		{"F1", pixelgl.KeyF1},
+		{"f12", pixelgl.KeyF12},
+		{"LeftShift", pixelgl.KeyLeftShift},
+		{"Slash", pixelgl.KeySlash},
+	}
+	for _, tt := range tests {
+		got, err := LookupKey(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("LookupKey(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, name := range []string{"", "F13", "LeftWindows", "kp10"} {
+		if _, err := LookupKey(name); err == nil {
+			t.Errorf("LookupKey(%q) returned no error", name)
+		}
+	}
+}
+
+func TestParseControls(t *testing.T) {
+	c, err := ParseControls([]byte(`{"moveLeft": "J", "hardDrop": "Enter"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultControls()
+	want.MoveLeft = "J"
+	want.HardDrop = "Enter"
+	if c != want {
+		t.Errorf("ParseControls() = %+v, want %+v", c, want)
+	}
+
+	if _, err := ParseControls([]byte(`{"moveLeft": `)); err == nil {
+		t.Error("ParseControls(malformed JSON) returned no error")
+	}
+}
+
+func TestKeysNamesAction(t *testing.T) {
+	c := DefaultControls()
+	c.Hold = "NoSuchKey"
+	_, err := c.Keys()
+	if err == nil || !strings.Contains(err.Error(), "hold") || !strings.Contains(err.Error(), "NoSuchKey") {
+		t.Errorf("Keys() error = %v, want one naming hold and NoSuchKey", err)
+	}
+}
+
+func TestDefaultAndTwoPlayerControls(t *testing.T) {
+	if _, err := DefaultControls().Keys(); err != nil {
+		t.Errorf("default controls: %v", err)
+	}
+
+	// The two players can't share any key
+	used := make(map[pixelgl.Button]bool)
+	for i, c := range TwoPlayerControls() {
+		k, err := c.Keys()
+		if err != nil {
+			t.Fatalf("player %d: %v", i+1, err)
+		}
+		for _, b := range []pixelgl.Button{k.MoveLeft, k.MoveRight, k.RotateCW, k.RotateCCW, k.Rotate180, k.SoftDrop, k.HardDrop, k.Hold} {
+			if used[b] {
+				t.Errorf("player %d: %v is bound twice", i+1, b)
+			}
+			used[b] = true
+		}
+	}
+}
+
+func TestLoadControls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "controls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A missing file falls back to the defaults
+	k, err := LoadControls(filepath.Join(dir, "missing.json"))
+	if err != nil || k.HardDrop != pixelgl.KeySpace {
+		t.Errorf("LoadControls(missing) = %+v, %v, want the defaults", k, err)
+	}
+
+	path := filepath.Join(dir, "controls.json")
+	if err := ioutil.WriteFile(path, []byte(`{"rotateCW": "X"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	k, err = LoadControls(path)
+	if err != nil || k.RotateCW != pixelgl.KeyX || k.MoveLeft != pixelgl.KeyLeft {
+		t.Errorf("LoadControls() = %+v, %v, want X to rotate", k, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"rotateCW": "Nope"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadControls(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadControls(unknown key) error = %v, want one naming the file", err)
+	}
+}
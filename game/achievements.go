@@ -0,0 +1,119 @@
+package game
+
+import "time"
+
+// achievementToastTime is how many seconds an unlocked achievement is shown
+const achievementToastTime = 3.0
+
+// Achievement is a goal that stays unlocked across games once reached
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+	Unlocked    bool
+	UnlockedAt  time.Time
+
+	reached func(gs *GameState) bool // Whether the game has reached the goal
+}
+
+// Unlock marks the achievement as unlocked now
+func (a *Achievement) Unlock() {
+	a.Unlocked = true
+	a.UnlockedAt = time.Now()
+}
+
+// NewAchievements returns every achievement, all locked
+func NewAchievements() []Achievement {
+	return []Achievement{
+		{ID: "first-line", Name: "First Line", Description: "Clear a line",
+			reached: func(gs *GameState) bool { return gs.linesCleared >= 1 }},
+		{ID: "first-tetris", Name: "First Tetris", Description: "Clear 4 lines at once",
+			reached: func(gs *GameState) bool { return gs.stats.LineClears[4] >= 1 }},
+		{ID: "tetris-ten", Name: "Tetris Machine", Description: "Clear 10 Tetrises in a game",
+			reached: func(gs *GameState) bool { return gs.stats.LineClears[4] >= 10 }},
+		{ID: "first-tspin", Name: "Spin Doctor", Description: "Perform a T-spin",
+			reached: func(gs *GameState) bool { return gs.stats.TSpins >= 1 }},
+		{ID: "tspin-mini", Name: "Mini Me", Description: "Perform a mini T-spin",
+			reached: func(gs *GameState) bool { return gs.stats.TSpinMinis >= 1 }},
+		{ID: "tspin-master", Name: "T-Spin Master", Description: "Perform 10 T-spins in a game",
+			reached: func(gs *GameState) bool { return gs.stats.TSpins >= 10 }},
+		{ID: "back-to-back", Name: "Back to Back", Description: "Chain 3 back-to-back bonuses",
+			reached: func(gs *GameState) bool { return gs.btbCount >= 3 }},
+		{ID: "combo-five", Name: "Combo Breaker", Description: "Reach a 5 combo",
+			reached: func(gs *GameState) bool { return gs.stats.MaxCombo >= 5 }},
+		{ID: "combo-ten", Name: "Unbreakable", Description: "Reach a 10 combo",
+			reached: func(gs *GameState) bool { return gs.stats.MaxCombo >= 10 }},
+		{ID: "perfect", Name: "Perfect", Description: "Achieve a perfect clear",
+			reached: func(gs *GameState) bool { return gs.allClearStreak >= 1 }},
+		{ID: "perfect-twice", Name: "Spotless", Description: "Achieve 2 perfect clears in a row",
+			reached: func(gs *GameState) bool { return gs.allClearStreak >= 2 }},
+		{ID: "speed-demon", Name: "Speed Demon", Description: "Reach level 10",
+			reached: func(gs *GameState) bool { return gs.level >= 10 }},
+		{ID: "terminal-velocity", Name: "Terminal Velocity", Description: "Reach level 20",
+			reached: func(gs *GameState) bool { return gs.level >= 20 }},
+		{ID: "century", Name: "Century", Description: "Clear 100 lines in a game",
+			reached: func(gs *GameState) bool { return gs.linesCleared >= 100 }},
+		{ID: "getting-started", Name: "Getting Started", Description: "Score 10,000 points",
+			reached: func(gs *GameState) bool { return gs.score >= 10000 }},
+		{ID: "marathon", Name: "Marathon", Description: "Score 100,000 points",
+			reached: func(gs *GameState) bool { return gs.score >= 100000 }},
+		{ID: "sprinter", Name: "Sprinter", Description: "Finish a sprint",
+			reached: func(gs *GameState) bool { return IsSprint(gs.mode) && gs.modeComplete }},
+		{ID: "sprint-two-minutes", Name: "Photo Finish", Description: "Finish a sprint in under 2 minutes",
+			reached: func(gs *GameState) bool { return IsSprint(gs.mode) && gs.modeComplete && gs.elapsedTime < 120 }},
+		{ID: "survivor", Name: "Survivor", Description: "Last 5 minutes in survival",
+			reached: func(gs *GameState) bool { return gs.mode == ModeSurvival && gs.elapsedTime >= 300 }},
+		{ID: "clean-hands", Name: "Clean Hands", Description: "Place 100 pieces without a finesse error",
+			reached: func(gs *GameState) bool { return gs.stats.TotalPlaced() >= 100 && gs.stats.FinesseErrors == 0 }},
+	}
+}
+
+// SetAchievements has the game unlock the achievements as their goals are
+// reached. The slice is changed in place, so the caller sees the unlocks.
+// Games without achievements, such as replays, unlock nothing.
+func (gs *GameState) SetAchievements(achievements []Achievement) {
+	gs.achievements = achievements
+}
+
+// checkAchievements unlocks the achievements whose goal the game has just
+// reached and queues their toasts
+func (gs *GameState) checkAchievements() {
+	for i := range gs.achievements {
+		a := &gs.achievements[i]
+		if a.Unlocked || !a.reached(gs) {
+			continue
+		}
+		a.Unlock()
+		gs.newUnlocks = append(gs.newUnlocks, *a)
+		gs.achievementToasts = append(gs.achievementToasts, a.Name)
+	}
+}
+
+// TakeUnlocks returns the achievements unlocked since it was last called
+func (gs *GameState) TakeUnlocks() []Achievement {
+	unlocks := gs.newUnlocks
+	gs.newUnlocks = nil
+	return unlocks
+}
+
+// AchievementToast returns the name of the achievement being shown as
+// unlocked, ok being false when there is none
+func (gs *GameState) AchievementToast() (name string, ok bool) {
+	if len(gs.achievementToasts) == 0 {
+		return "", false
+	}
+	return gs.achievementToasts[0], true
+}
+
+// updateAchievementToast shows each unlocked achievement in turn for
+// achievementToastTime seconds
+func (gs *GameState) updateAchievementToast(dt float64) {
+	if len(gs.achievementToasts) == 0 {
+		return
+	}
+	gs.achievementToastTimer += dt
+	if gs.achievementToastTimer >= achievementToastTime {
+		gs.achievementToasts = gs.achievementToasts[1:]
+		gs.achievementToastTimer = 0
+	}
+}
@@ -0,0 +1,128 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+)
+
+// achievement returns the achievement with id from NewAchievements
+func achievement(t *testing.T, id string) Achievement {
+	t.Helper()
+	for _, a := range NewAchievements() {
+		if a.ID == id {
+			return a
+		}
+	}
+	t.Fatalf("no achievement %q", id)
+	return Achievement{}
+}
+
+func TestAchievementConditions(t *testing.T) {
+	tests := []struct {
+		id   string
+		mode GameMode
+		set  func(gs *GameState)
+		want bool
+	}{
+		{"first-line", ModeMarathon, func(gs *GameState) {}, false},
+		{"first-line", ModeMarathon, func(gs *GameState) { gs.linesCleared = 1 }, true},
+		{"first-tetris", ModeMarathon, func(gs *GameState) { gs.stats.LineClears[3] = 5 }, false},
+		{"first-tetris", ModeMarathon, func(gs *GameState) { gs.stats.LineClears[4] = 1 }, true},
+		{"tetris-ten", ModeMarathon, func(gs *GameState) { gs.stats.LineClears[4] = 9 }, false},
+		{"tetris-ten", ModeMarathon, func(gs *GameState) { gs.stats.LineClears[4] = 10 }, true},
+		{"first-tspin", ModeMarathon, func(gs *GameState) {}, false},
+		{"first-tspin", ModeMarathon, func(gs *GameState) { gs.stats.TSpins = 1 }, true},
+		{"tspin-mini", ModeMarathon, func(gs *GameState) { gs.stats.TSpins = 1 }, false},
+		{"tspin-mini", ModeMarathon, func(gs *GameState) { gs.stats.TSpins, gs.stats.TSpinMinis = 1, 1 }, true},
+		{"tspin-master", ModeMarathon, func(gs *GameState) { gs.stats.TSpins = 9 }, false},
+		{"tspin-master", ModeMarathon, func(gs *GameState) { gs.stats.TSpins = 10 }, true},
+		{"back-to-back", ModeMarathon, func(gs *GameState) { gs.btbActive, gs.btbCount = true, 2 }, false},
+		{"back-to-back", ModeMarathon, func(gs *GameState) { gs.btbActive, gs.btbCount = true, 3 }, true},
+		{"combo-five", ModeMarathon, func(gs *GameState) { gs.combo, gs.stats.MaxCombo = 5, 4 }, false},
+		{"combo-five", ModeMarathon, func(gs *GameState) { gs.stats.MaxCombo = 5 }, true},
+		{"combo-ten", ModeMarathon, func(gs *GameState) { gs.stats.MaxCombo = 9 }, false},
+		{"combo-ten", ModeMarathon, func(gs *GameState) { gs.stats.MaxCombo = 10 }, true},
+		{"perfect", ModeMarathon, func(gs *GameState) {}, false},
+		{"perfect", ModeMarathon, func(gs *GameState) { gs.allClearStreak = 1 }, true},
+		{"perfect-twice", ModeMarathon, func(gs *GameState) { gs.allClearStreak = 1 }, false},
+		{"perfect-twice", ModeMarathon, func(gs *GameState) { gs.allClearStreak = 2 }, true},
+		{"speed-demon", ModeMarathon, func(gs *GameState) { gs.level = 9 }, false},
+		{"speed-demon", ModeMarathon, func(gs *GameState) { gs.level = 10 }, true},
+		{"terminal-velocity", ModeMarathon, func(gs *GameState) { gs.level = 19 }, false},
+		{"terminal-velocity", ModeMarathon, func(gs *GameState) { gs.level = 20 }, true},
+		{"century", ModeMarathon, func(gs *GameState) { gs.linesCleared = 99 }, false},
+		{"century", ModeMarathon, func(gs *GameState) { gs.linesCleared = 100 }, true},
+		{"getting-started", ModeMarathon, func(gs *GameState) { gs.score = 9999 }, false},
+		{"getting-started", ModeMarathon, func(gs *GameState) { gs.score = 10000 }, true},
+		{"marathon", ModeMarathon, func(gs *GameState) { gs.score = 99999 }, false},
+		{"marathon", ModeMarathon, func(gs *GameState) { gs.score = 100000 }, true},
+		{"sprinter", ModeSprint, func(gs *GameState) { gs.sprintLinesLeft = 1 }, false},
+		{"sprinter", ModeSprint, func(gs *GameState) { gs.modeComplete = true }, true},
+		{"sprinter", ModeDailyChallenge, func(gs *GameState) { gs.modeComplete = true }, true},
+		{"sprinter", ModeUltra, func(gs *GameState) { gs.modeComplete = true }, false},
+		{"sprint-two-minutes", ModeSprint, func(gs *GameState) { gs.modeComplete, gs.elapsedTime = true, 120 }, false},
+		{"sprint-two-minutes", ModeSprint, func(gs *GameState) { gs.elapsedTime = 60 }, false},
+		{"sprint-two-minutes", ModeSprint, func(gs *GameState) { gs.modeComplete, gs.elapsedTime = true, 119.9 }, true},
+		{"survivor", ModeSurvival, func(gs *GameState) { gs.elapsedTime = 299.9 }, false},
+		{"survivor", ModeSurvival, func(gs *GameState) { gs.elapsedTime = 300 }, true},
+		{"survivor", ModeMarathon, func(gs *GameState) { gs.elapsedTime = 300 }, false},
+		{"clean-hands", ModeMarathon, func(gs *GameState) { gs.stats.PiecesPlaced = [7]int{99} }, false},
+		{"clean-hands", ModeMarathon, func(gs *GameState) { gs.stats.PiecesPlaced, gs.stats.FinesseErrors = [7]int{100}, 1 }, false},
+		{"clean-hands", ModeMarathon, func(gs *GameState) { gs.stats.PiecesPlaced = [7]int{100} }, true},
+	}
+	for _, tt := range tests {
+		gs := NewSeededGameState(tt.mode, config.DefaultSettings(), 1)
+		gs.stats = Stats{}
+		tt.set(gs)
+		if got := achievement(t, tt.id).reached(gs); got != tt.want {
+			t.Errorf("%s in %s: reached = %t, want %t", tt.id, tt.mode.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestAchievementsDistinct(t *testing.T) {
+	achievements := NewAchievements()
+	if len(achievements) != 20 {
+		t.Errorf("%d achievements, want 20", len(achievements))
+	}
+	ids := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, a := range achievements {
+		if ids[a.ID] || names[a.Name] {
+			t.Errorf("achievement %q (%s) isn't unique", a.ID, a.Name)
+		}
+		ids[a.ID], names[a.Name] = true, true
+		if a.Unlocked || a.Description == "" || a.reached == nil {
+			t.Errorf("achievement %q: unlocked %t, description %q", a.ID, a.Unlocked, a.Description)
+		}
+	}
+}
+
+func TestAchievementUnlock(t *testing.T) {
+	gs := newTestGame(t)
+	achievements := NewAchievements()
+	gs.SetAchievements(achievements)
+	lockClear(t, gs, 1)
+
+	unlocks := gs.TakeUnlocks()
+	if len(unlocks) != 1 || unlocks[0].ID != "first-line" || !unlocks[0].Unlocked {
+		t.Fatalf("TakeUnlocks() = %+v, want first-line", unlocks)
+	}
+	if !achievements[0].Unlocked || achievements[0].UnlockedAt.IsZero() {
+		t.Errorf("first-line in the caller's slice: unlocked %t at %v", achievements[0].Unlocked, achievements[0].UnlockedAt)
+	}
+	if name, ok := gs.AchievementToast(); !ok || name != "First Line" {
+		t.Errorf("AchievementToast() = %q, %t, want First Line", name, ok)
+	}
+
+	// Unlocked once only, and the toast goes after achievementToastTime
+	lockClear(t, gs, 1)
+	if unlocks := gs.TakeUnlocks(); len(unlocks) != 0 {
+		t.Errorf("TakeUnlocks() = %+v after a second line, want none", unlocks)
+	}
+	gs.updateAchievementToast(achievementToastTime)
+	if name, ok := gs.AchievementToast(); ok {
+		t.Errorf("AchievementToast() = %q after %v seconds, want none", name, achievementToastTime)
+	}
+}
@@ -0,0 +1,215 @@
+package game
+
+import "testing"
+
+// The hot paths of a step are benchmarked here, to be run with
+//
+//	go test -bench=. -benchmem ./game
+//
+// checkCollision runs for every move, rotation and gravity step and must
+// not allocate. rotateShape runs for every rotation and kick tried and may
+// allocate at most 2 times when it misses the cache, and not at all when it
+// hits it. TestHotPathAllocs holds them to that.
+
+// halfFullBoard returns a full size board whose bottom half is filled but
+// for a scattering of holes
+func halfFullBoard() Board {
+	b := newBoard(20, 10)
+	for r := 0; r < 10; r++ {
+		for c := range b[r] {
+			if (r+c)%3 != 0 {
+				b[r][c] = Gray
+			}
+		}
+	}
+	return b
+}
+
+// collisionShapes returns every position of every piece in its spawn state
+// on a board of rows by cols, some in the way and some not
+func collisionShapes(rows, cols int) []Shape {
+	var shapes []Shape
+	for p := IPiece; p <= ZPiece; p++ {
+		for r := -1; r < rows; r++ {
+			for c := -1; c < cols; c++ {
+				shapes = append(shapes, moveShape(r, c, PieceShape(p)))
+			}
+		}
+	}
+	return shapes
+}
+
+func TestHotPathAllocs(t *testing.T) {
+	b := halfFullBoard()
+	shapes := collisionShapes(b.Rows(), b.Cols())
+	if n := testing.AllocsPerRun(10, func() {
+		for _, s := range shapes {
+			b.checkCollision(s)
+		}
+	}); n != 0 {
+		t.Errorf("checkCollision allocates %v times, want 0", n)
+	}
+
+	// Missing the cache and hitting it, every piece in every state
+	keepRotationCache(t)
+	for p := IPiece; p <= ZPiece; p++ {
+		s := moveShape(10, 4, PieceShape(p))
+		for state := 0; state < 4; state++ {
+			rotateShape(s, p, state)
+			n := testing.AllocsPerRun(10, func() {
+				forgetRotation(p, state)
+				rotateShape(s, p, state)
+			})
+			if n > 2 {
+				t.Errorf("uncached rotateShape(piece %d, state %d) allocates %v times, want at most 2", p, state, n)
+			}
+			if n := testing.AllocsPerRun(10, func() { rotateShape(s, p, state) }); n != 0 {
+				t.Errorf("cached rotateShape(piece %d, state %d) allocates %v times, want 0", p, state, n)
+			}
+			s = rotateShape(s, p, state)
+		}
+	}
+}
+
+// keepRotationCache has the rotation cache restored at the end of the test
+// to what it holds now, so a test that forgets rotations leaves the cache
+// as it found it
+func keepRotationCache(tb testing.TB) {
+	rotationCacheMutex.Lock()
+	saved := copyRotationCache(rotationCache)
+	rotationCacheMutex.Unlock()
+	tb.Cleanup(func() {
+		rotationCacheMutex.Lock()
+		rotationCache = saved
+		rotationCacheMutex.Unlock()
+	})
+}
+
+// copyRotationCache returns a copy of cache that can be changed without
+// changing it
+func copyRotationCache(cache map[Piece]map[int]map[int]Shape) map[Piece]map[int]map[int]Shape {
+	c := make(map[Piece]map[int]map[int]Shape, len(cache))
+	for p, states := range cache {
+		c[p] = make(map[int]map[int]Shape, len(states))
+		for state, dirs := range states {
+			c[p][state] = make(map[int]Shape, len(dirs))
+			for dir, s := range dirs {
+				c[p][state][dir] = s
+			}
+		}
+	}
+	return c
+}
+
+// forgetRotation drops the cached clockwise rotation of p from state, but
+// not the cache of the piece
+func forgetRotation(p Piece, state int) {
+	rotationCacheMutex.Lock()
+	delete(rotationCache[p], state)
+	rotationCacheMutex.Unlock()
+}
+
+func BenchmarkCheckCollision(b *testing.B) {
+	board := halfFullBoard()
+	shapes := collisionShapes(board.Rows(), board.Cols())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.checkCollision(shapes[i%len(shapes)])
+	}
+}
+
+func BenchmarkApplyGravity(b *testing.B) {
+	// A T falling from the top of an empty board until it lands
+	gs := newTestGame(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		spawnPiece(gs, TPiece)
+		for gs.applyGravity() > 0 {
+		}
+	}
+}
+
+func BenchmarkRotateShape(b *testing.B) {
+	// Every piece turned clockwise through its 4 states
+	var shapes [ZPiece + 1]Shape
+	for p := range shapes {
+		shapes[p] = moveShape(10, 4, PieceShape(Piece(p)))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for p := range shapes {
+			shapes[p] = rotateShape(shapes[p], Piece(p), i%4)
+		}
+	}
+}
+
+func BenchmarkRotateCached(b *testing.B) {
+	s := moveShape(10, 4, PieceShape(TPiece))
+	rotateShape(s, TPiece, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rotateShape(s, TPiece, 0)
+	}
+}
+
+func BenchmarkRotateUncached(b *testing.B) {
+	// The T's rotation is forgotten before every one, as at its first
+	// rotation from that state
+	keepRotationCache(b)
+	s := moveShape(10, 4, PieceShape(TPiece))
+	rotateShape(s, TPiece, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		forgetRotation(TPiece, 0)
+		rotateShape(s, TPiece, 0)
+	}
+}
+
+func BenchmarkInstafall(b *testing.B) {
+	// An I hard dropped from the top of an empty board to the floor
+	gs := newTestGame(b)
+	empty := newBoard(gs.rows, gs.cols)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copyBoard(gs.board, empty)
+		spawnPiece(gs, IPiece)
+		b.StartTimer()
+		gs.instafall()
+	}
+}
+
+func BenchmarkCheckRowCompletion(b *testing.B) {
+	// A vertical I completing 4 rows, which are then deleted
+	gs := newTestGame(b)
+	col := gs.cols - 1
+	shape := Shape{{row: 0, col: col}, {row: 1, col: col}, {row: 2, col: col}, {row: 3, col: col}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		gs.board = newBoard(gs.rows, gs.cols)
+		for r := 0; r < 4; r++ {
+			for c := 0; c < col; c++ {
+				gs.board[r][c] = Gray
+			}
+		}
+		placePiece(gs, IPiece, shape, 1)
+		b.StartTimer()
+		gs.checkRowCompletion(shape)
+		gs.finishLineClear()
+	}
+}
+
+// copyBoard copies the blocks of src onto dst, the same size
+func copyBoard(dst, src Board) {
+	for r := range src {
+		copy(dst[r], src[r])
+	}
+}
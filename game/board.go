@@ -0,0 +1,1144 @@
+package game
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/zkry/golang-tetris/config"
+)
+
+// isTouchingFloor checks if the piece that the user is controlling has a piece
+// directly below it. Used to give the user more time when placing block on
+// floor
+func (gs *GameState) isTouchingFloor() bool {
+	blockType := gs.board[gs.activeShape[0].row][gs.activeShape[0].col]
+	gs.board.drawPiece(gs.activeShape, Empty)
+	isTouching := gs.board.checkCollision(moveShapeDown(gs.activeShape))
+	gs.board.drawPiece(gs.activeShape, blockType)
+	return isTouching
+}
+
+// rotatePiece rotates the piece that the user is currently moving.
+// direction 1 for clockwise, -1 for counter-clockwise.
+// Implements an ultra-responsive rotation system with generous wall kicks,
+// or only the guideline SRS kicks when the WallKickMode setting is standard.
+// Returns true if rotation succeeded, false otherwise.
+func (gs *GameState) rotatePiece(direction int) bool {
+	// The O piece should not be rotated
+	if gs.currentPiece == OPiece {
+		return false
+	}
+	blockType := gs.board[gs.activeShape[0].row][gs.activeShape[0].col]
+	// Erase Piece
+	gs.board.drawPiece(gs.activeShape, Empty)
+
+	// Save the shape before rotation for T-spin detection
+	gs.lastRotationPoint = gs.activeShape
+
+	// Get the new shape based on rotation direction
+	var newShape Shape
+	if direction == 1 {
+		newShape = rotateShape(gs.activeShape, gs.currentPiece, gs.rotationState)
+	} else {
+		newShape = rotateShapeCounterClockwise(gs.activeShape, gs.currentPiece, gs.rotationState)
+	}
+
+	// Try to place with standard wall kicks first
+	standard := gs.settings.WallKickMode == config.WKMStandard
+	kicks := wallKickData(gs.currentPiece, gs.rotationState, direction)
+	if standard {
+		kicks = standardWallKickData(gs.currentPiece, gs.rotationState, direction)
+	}
+	rotated := false
+
+	// Try standard kicks for all pieces
+	for _, kick := range kicks {
+		kickedShape := moveShape(kick[1], kick[0], newShape) // x, y offset
+		if !gs.board.checkCollision(kickedShape) {
+			// Wall kick succeeded
+			gs.activeShape = kickedShape
+			rotated = true
+
+			// Update rotation state
+			gs.rotationState = (gs.rotationState + direction) % 4
+			if gs.rotationState < 0 {
+				gs.rotationState += 4
+			}
+
+			// Set flag for T-spin detection
+			gs.lastMovementWasRotation = true
+			break
+		}
+	}
+
+	// If standard kicks failed, try extra kicks for ALL pieces, not just I
+	if !rotated && !standard {
+		// Get extra aggressive kicks
+		extraKicks := getExtraIKicks(gs.rotationState, direction)
+		for _, kick := range extraKicks {
+			kickedShape := moveShape(kick[1], kick[0], newShape)
+			if !gs.board.checkCollision(kickedShape) {
+				// Extra kick succeeded
+				gs.activeShape = kickedShape
+				rotated = true
+
+				// Update rotation state
+				gs.rotationState = (gs.rotationState + direction) % 4
+				if gs.rotationState < 0 {
+					gs.rotationState += 4
+				}
+
+				gs.lastMovementWasRotation = true
+				break
+			}
+		}
+	}
+
+	// If still not rotated, try one last set of aggressive kicks as a last resort
+	if !rotated && !standard {
+		// Extremely aggressive last resort kicks - will almost always find a spot
+		lastResortKicks := [][2]int{
+			{0, 4}, {4, 0}, {0, -4}, {-4, 0}, // Far kicks
+			{3, 3}, {-3, 3}, {3, -3}, {-3, -3}, // Corner kicks
+			{5, 0}, {0, 5}, {-5, 0}, {0, -5}, // Very far kicks
+		}
+
+		for _, kick := range lastResortKicks {
+			kickedShape := moveShape(kick[1], kick[0], newShape)
+			if !gs.board.checkCollision(kickedShape) {
+				// Last resort kick succeeded
+				gs.activeShape = kickedShape
+				rotated = true
+
+				// Update rotation state
+				gs.rotationState = (gs.rotationState + direction) % 4
+				if gs.rotationState < 0 {
+					gs.rotationState += 4
+				}
+
+				gs.lastMovementWasRotation = true
+				break
+			}
+		}
+	}
+
+	if !rotated {
+		// Failed to rotate with any wall kick
+		gs.board.drawPiece(gs.activeShape, blockType)
+		return false
+	}
+
+	gs.stats.Rotations++
+	gs.board.drawPiece(gs.activeShape, blockType)
+	return true
+}
+
+// rotatePiece180 rotates the piece that the user is currently moving by 180
+// degrees, trying the kicks from wallKick180Data. Returns true if rotation
+// succeeded, false otherwise.
+func (gs *GameState) rotatePiece180() bool {
+	// The O piece should not be rotated
+	if gs.currentPiece == OPiece {
+		return false
+	}
+	blockType := gs.board[gs.activeShape[0].row][gs.activeShape[0].col]
+	// Erase Piece
+	gs.board.drawPiece(gs.activeShape, Empty)
+
+	// Save the shape before rotation for T-spin detection
+	gs.lastRotationPoint = gs.activeShape
+
+	newShape := rotateShape180(gs.activeShape, gs.currentPiece, gs.rotationState)
+	for _, kick := range wallKick180Data(gs.currentPiece, gs.rotationState, gs.settings.WallKickMode) {
+		kickedShape := moveShape(kick[1], kick[0], newShape) // x, y offset
+		if !gs.board.checkCollision(kickedShape) {
+			gs.activeShape = kickedShape
+			gs.rotationState = (gs.rotationState + 2) % 4
+			gs.lastMovementWasRotation = true
+			gs.stats.Rotations++
+			gs.board.drawPiece(gs.activeShape, blockType)
+			return true
+		}
+	}
+
+	// Failed to rotate with any wall kick
+	gs.board.drawPiece(gs.activeShape, blockType)
+	return false
+}
+
+// holdCurrentPiece allows the player to hold the current piece and retrieve a previously held piece
+func (gs *GameState) holdCurrentPiece() {
+	if !gs.canHold {
+		return
+	}
+
+	gs.stats.Holds++
+
+	// Erase current piece
+	gs.board.drawPiece(gs.activeShape, Empty)
+
+	if gs.holdPiece == NoPiece {
+		// First hold - store current piece and get next piece
+		gs.holdPiece = gs.currentPiece
+		gs.addPiece()
+	} else {
+		// Swap current piece with held piece
+		tempPiece := gs.holdPiece
+		gs.holdPiece = gs.currentPiece
+
+		// Create the held piece
+		baseShape := PieceShape(tempPiece)
+		baseShape = moveShape(gs.rows, spawnCol(tempPiece, gs.cols), baseShape)
+		gs.board.fillShape(baseShape, PieceBlock(tempPiece))
+		gs.currentPiece = tempPiece
+		gs.activeShape = baseShape
+		gs.rotationState = 0 // Reset rotation state for new piece
+		gs.moveCount = 0
+	}
+
+	gs.canHold = false // Prevent multiple holds until next piece
+}
+
+// lockPiece finalizes the current piece position and adds a new piece
+func (gs *GameState) lockPiece() {
+	if isGameOver(gs.activeShape, gs.rows) {
+		gs.gameOver = true
+		return
+	}
+	gs.lockFlash = lockFlashEffect{
+		active: true,
+		timer:  lockFlashTime,
+		shape:  gs.activeShape,
+		block:  gs.board[gs.activeShape[0].row][gs.activeShape[0].col],
+	}
+	if gs.cols == finesseCols {
+		if optimal := finesse[gs.currentPiece][gs.rotationState][shapeLeftCol(gs.activeShape)]; optimal >= 0 && gs.moveCount > optimal {
+			gs.stats.FinesseErrors++
+		}
+	}
+	gs.mode.OnPieceLock(gs)
+	if gs.haptics != nil {
+		gs.haptics.OnPieceLock()
+	}
+	gs.placements.add(PlacedPiece{
+		Piece:         gs.currentPiece,
+		RotationState: gs.rotationState,
+		Col:           shapeLeftCol(gs.activeShape),
+		Row:           shapeBottomRow(gs.activeShape),
+		Timestamp:     gs.elapsedTime,
+	})
+	scoreBefore, linesBefore := gs.score, gs.linesCleared
+	gs.checkRowCompletion(gs.activeShape)
+	gs.placements.setLastResult(gs.linesCleared-linesBefore, gs.score-scoreBefore)
+	if gs.clearAnim.active() {
+		// The next piece spawns once the cleared rows are deleted
+		return
+	}
+	gs.injectPendingGarbage()
+	if gs.gameOver {
+		return
+	}
+	gs.addPiece()     // Replace with random piece
+	gs.canHold = true // Enable hold for the next piece
+}
+
+// finishLineClear deletes the rows of the line clear animation once it is
+// over and spawns the next piece.
+func (gs *GameState) finishLineClear() {
+	for _, r := range gs.clearAnim.rows {
+		gs.board.deleteRow(r)
+	}
+	gs.clearAnim = lineClearAnimation{}
+
+	gs.injectPendingGarbage()
+	if gs.gameOver {
+		return
+	}
+	gs.addPiece()
+	gs.canHold = true
+}
+
+// injectPendingGarbage pushes the queued garbage lines up from the bottom
+// of the board, all with the hole in the same random column. Ends the game
+// if the stack is pushed out of the top.
+func (gs *GameState) injectPendingGarbage() {
+	if gs.pendingGarbage == 0 {
+		return
+	}
+	if gs.board.InjectGarbage(gs.pendingGarbage, []int{gs.rng.Intn(gs.cols)}) {
+		gs.gameOver = true
+	}
+	gs.pendingGarbage = 0
+}
+
+// movePiece attemps to move the piece that the user is controlling either
+// right or left. +1 signifies a right move while -1 signifies a left move
+func (gs *GameState) movePiece(dir int) bool {
+	blockType := gs.board[gs.activeShape[0].row][gs.activeShape[0].col]
+
+	// Erase old piece for accurate collision detection
+	gs.board.drawPiece(gs.activeShape, Empty)
+
+	// Get the proposed new shape
+	newShape := moveShape(0, dir, gs.activeShape)
+
+	// Check collision with optimized algorithm
+	didCollide := gs.board.checkCollision(newShape)
+
+	if !didCollide {
+		// Update to new position
+		gs.activeShape = newShape
+		gs.lastMovementWasRotation = false // Reset T-spin detection
+
+		// Draw the piece at new position
+		gs.board.drawPiece(gs.activeShape, blockType)
+		return true // Successfully moved
+	} else {
+		// Movement failed due to collision - restore original position
+		gs.board.drawPiece(gs.activeShape, blockType)
+		return false
+	}
+}
+
+// newBoard returns an empty board with rows visible rows and cols columns
+func newBoard(rows, cols int) Board {
+	b := make(Board, rows+HiddenRows)
+	for r := range b {
+		b[r] = make([]Block, cols)
+	}
+	return b
+}
+
+// Rows returns the number of rows of the board, including the hidden ones
+func (b Board) Rows() int {
+	return len(b)
+}
+
+// Cols returns the number of columns of the board
+func (b Board) Cols() int {
+	if len(b) == 0 {
+		return 0
+	}
+	return len(b[0])
+}
+
+// Hash returns the FNV-1a hash of every block of the board, row by row from
+// the bottom, so boards that differ in any block almost always hash
+// differently
+func (b Board) Hash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for r := range b {
+		for c := range b[r] {
+			binary.LittleEndian.PutUint64(buf[:], uint64(b[r][c]))
+			h.Write(buf[:])
+		}
+	}
+	return h.Sum64()
+}
+
+// At returns the block at row r and column c, counted from the bottom left
+func (b Board) At(r, c int) Block {
+	return b[r][c]
+}
+
+// clone returns a copy of the board that can be changed without changing b
+func (b Board) clone() Board {
+	c := make(Board, len(b))
+	for r := range b {
+		c[r] = append([]Block(nil), b[r]...)
+	}
+	return c
+}
+
+// drawPiece sets the values of a board, b, to a specific block type, t
+// according to shape, s.
+func (b Board) drawPiece(s Shape, t Block) {
+	for i := 0; i < 4; i++ {
+		b[s[i].row][s[i].col] = t
+	}
+}
+
+// checkCollision checks if at the 4 points of a shape, s, there is
+// nothing but Empty value under it and the position of the shape
+// is inside the playing board (top HiddenRows rows invisiable).
+func (b Board) checkCollision(s Shape) bool {
+	for i := 0; i < 4; i++ {
+		r := s[i].row
+		c := s[i].col
+		if r < 0 || r >= b.Rows() || c < 0 || c >= b.Cols() || b[r][c] != Empty {
+			return true
+		}
+	}
+	return false
+}
+
+// applyGravity is the function that moves a piece down. Returns the number
+// of rows the piece moved, which is 0 when a collision was made.
+func (gs *GameState) applyGravity() int {
+	blockType := gs.board[gs.activeShape[0].row][gs.activeShape[0].col]
+	// Erase old piece
+	gs.board.drawPiece(gs.activeShape, Empty)
+
+	// Does the block collide if it moves down?
+	didCollide := gs.board.checkCollision(moveShapeDown(gs.activeShape))
+
+	if !didCollide {
+		gs.activeShape = moveShapeDown(gs.activeShape)
+		gs.lastMovementWasRotation = false // Reset T-spin detection
+	}
+
+	gs.board.drawPiece(gs.activeShape, blockType)
+
+	if didCollide {
+		return 0
+	}
+	return 1
+}
+
+// instafall calls the applyGravity function until a collision is detected
+// and locks the piece. Returns the number of rows the piece dropped. The
+// cells the piece fell through are left as a trail that is brightest just
+// above where the piece landed.
+func (gs *GameState) instafall() int {
+	var passed []Shape
+	for shape := gs.activeShape; gs.applyGravity() > 0; shape = gs.activeShape {
+		passed = append(passed, shape)
+	}
+	rows := len(passed)
+	gs.hardDropTrail = gs.hardDropTrail[:0]
+	for i, shape := range passed {
+		for _, p := range shape {
+			gs.hardDropTrail = append(gs.hardDropTrail, TrailSegment{Row: p.row, Col: p.col, Alpha: 0.8 * float64(i+1) / float64(rows)})
+		}
+	}
+	gs.shakeTimer = shakeTime
+	gs.shakeAmplitude = shakeAmplitude
+
+	// Lock the piece immediately
+	gs.stats.HardDrops++
+	gs.lockPiece()
+	return rows
+}
+
+// checkRowCompletion checks if the rows in a given shape are filled (ie should
+// be deleted) and scores the clear. Full rows are not deleted straight away
+// but handed to the line clear animation, see finishLineClear.
+func (gs *GameState) checkRowCompletion(s Shape) {
+	scoreBefore := gs.score
+
+	// Check for T-spin before any rows are deleted
+	tSpinType := gs.classifyTSpin(gs.board)
+	tSpin := tSpinType != TSpinNone
+	allSpin := gs.lastMovementWasRotation && classifyAllSpin(gs.board, gs.currentPiece, s, gs.rotationState)
+
+	// Ony the rows of the shape can be filled
+	var fullRows []int
+	for i := 0; i < 4; i++ {
+		r := s[i].row
+		if containsInt(fullRows, r) {
+			continue
+		}
+		emptyFound := false
+		// Look for empty row
+		for c := 0; c < gs.cols; c++ {
+			if gs.board[r][c] == Empty {
+				emptyFound = true
+				break
+			}
+		}
+		if !emptyFound {
+			fullRows = append(fullRows, r)
+		}
+	}
+	deleteRowCt := len(fullRows)
+	gs.stats.LineClears[deleteRowCt]++
+	if tSpin {
+		gs.stats.TSpins++
+	}
+	if tSpinType == TSpinMini {
+		gs.stats.TSpinMinis++
+	}
+	if deleteRowCt > 0 {
+		gs.clearAnim = lineClearAnimation{rows: fullRows, timer: lineClearTime}
+		if gs.haptics != nil {
+			gs.haptics.OnLineClear(deleteRowCt)
+		}
+	}
+
+	// The board as it will be once the rows are deleted. Deleting from the
+	// top down keeps the lower row numbers valid.
+	cleared := gs.board.clone()
+	sort.Sort(sort.Reverse(sort.IntSlice(fullRows)))
+	for _, r := range fullRows {
+		cleared.deleteRow(r)
+	}
+
+	// Clearing every block off the board is a perfect clear, and any lock
+	// that doesn't breaks a streak of them. Consecutive clears build a
+	// combo, any lock without a clear breaks it.
+	gs.lastClearWasPC = deleteRowCt > 0 && isBoardEmpty(cleared)
+	if gs.lastClearWasPC {
+		gs.allClearStreak++
+	} else {
+		gs.allClearStreak = 0
+	}
+	if deleteRowCt > 0 {
+		gs.combo++
+	} else {
+		gs.combo = -1
+	}
+	gs.score += gs.scorer.Award(deleteRowCt, tSpinType, allSpin, gs.combo, gs.btbActive, gs.allClearStreak)
+	gs.lastClear = lineClear{
+		lines:        deleteRowCt,
+		tSpinType:    tSpinType,
+		combo:        gs.combo,
+		btbActive:    gs.btbActive,
+		perfectClear: gs.lastClearWasPC,
+	}
+
+	// Tetrises and T-spins are special clears that can be chained back to
+	// back, while any other clear breaks the chain
+	if deleteRowCt > 0 {
+		if deleteRowCt == 4 || tSpin {
+			if gs.btbActive {
+				gs.btbCount++
+			}
+			gs.btbActive = true
+		} else {
+			gs.btbActive = false
+			gs.btbCount = 0
+		}
+	}
+
+	gs.linesCleared += deleteRowCt
+
+	// Every linesPerLevel lines advance a level. The mode decides whether
+	// gravity speeds up with it.
+	if level := 1 + gs.linesCleared/linesPerLevel; level != gs.level {
+		gs.level = level
+		gs.levelFlash = levelFlashEffect{
+			active: true,
+			timer:  levelFlashTime,
+			level:  level,
+		}
+	}
+	if deleteRowCt > 0 {
+		gs.mode.OnLinesClear(gs, deleteRowCt)
+		gs.checkModeOver()
+	}
+
+	if gs.lastClearWasPC {
+		gs.perfectClearTimer = perfectClearDisplayTime
+	}
+	if gs.combo >= 1 {
+		gs.stats.Combos++
+		if gs.combo > gs.stats.MaxCombo {
+			gs.stats.MaxCombo = gs.combo
+		}
+		if gs.combo >= 2 {
+			gs.comboShown = gs.combo
+			gs.comboTimer = comboFadeTime
+		}
+	}
+
+	gs.checkAchievements()
+
+	// Show what the clear was worth just above the highest cleared row
+	if deleteRowCt > 0 && gs.score > scoreBefore {
+		gs.scorePopups = append(gs.scorePopups, ScorePopup{
+			Text:  fmt.Sprintf("+%d", gs.score-scoreBefore),
+			X:     float64(gs.cols) / 2,
+			Y:     float64(fullRows[0] + 1),
+			Alpha: 1,
+		})
+	}
+
+	// Reset T-spin detection
+	gs.lastMovementWasRotation = false
+}
+
+// classifyAllSpin checks if piece, which was just rotated into shape in
+// rotation state rotState, is an all-spin. Like a T-spin, at least 3 of the
+// 4 corners around the center of the piece must be blocked by the board or
+// the walls. The center of J, L, S and Z is their pivot. The center of the
+// I piece lies between cells so its corners are the cells on either side of
+// both of its ends. T pieces have their own rules in classifyTSpin and O
+// pieces can't spin.
+func classifyAllSpin(b Board, piece Piece, shape Shape, rotState int) bool {
+	var corners [4]Point
+	switch piece {
+	case TPiece, OPiece, NoPiece:
+		return false
+	case IPiece:
+		first, last := shape[0], shape[0]
+		for _, p := range shape {
+			if p.row < first.row || p.col < first.col {
+				first = p
+			}
+			if p.row > last.row || p.col > last.col {
+				last = p
+			}
+		}
+		if rotState%2 == 0 {
+			// Lying flat, so above and below the ends
+			corners = [4]Point{{first.row + 1, first.col}, {first.row - 1, first.col}, {last.row + 1, last.col}, {last.row - 1, last.col}}
+		} else {
+			corners = [4]Point{{first.row, first.col - 1}, {first.row, first.col + 1}, {last.row, last.col - 1}, {last.row, last.col + 1}}
+		}
+	default:
+		center := shape[1]
+		corners = [4]Point{
+			{center.row + 1, center.col + 1},
+			{center.row + 1, center.col - 1},
+			{center.row - 1, center.col + 1},
+			{center.row - 1, center.col - 1},
+		}
+	}
+
+	blockedCorners := 0
+	for _, corner := range corners {
+		r, c := corner.row, corner.col
+		// A corner covered by the piece itself doesn't count
+		if shapeContains(shape, corner) {
+			continue
+		}
+		if r < 0 || r >= b.Rows() || c < 0 || c >= b.Cols() || b[r][c] != Empty {
+			blockedCorners++
+		}
+	}
+	return blockedCorners >= 3
+}
+
+// shapeContains checks if the point p is one of the points of shape s
+func shapeContains(s Shape, p Point) bool {
+	for i := 0; i < 4; i++ {
+		if s[i] == p {
+			return true
+		}
+	}
+	return false
+}
+
+// ColHeight returns the height of column col of the board, which is one
+// more than the row of its topmost block in the visible rows, or 0 if the
+// column is empty
+func (b Board) ColHeight(col int) int {
+	for r := b.Rows() - HiddenRows - 1; r >= 0; r-- {
+		if b[r][col] != Empty {
+			return r + 1
+		}
+	}
+	return 0
+}
+
+// ColumnHeights returns the height of every column of b, as ColHeight does
+func ColumnHeights(b Board) []int {
+	heights := make([]int, b.Cols())
+	for c := range heights {
+		heights[c] = b.ColHeight(c)
+	}
+	return heights
+}
+
+// FindOverhangs returns the row and column of every filled cell of b that
+// has an empty cell directly below it
+func FindOverhangs(b Board) [][2]int {
+	var overhangs [][2]int
+	for r := 1; r < b.Rows(); r++ {
+		for c := 0; c < b.Cols(); c++ {
+			if b[r][c] != Empty && b[r-1][c] == Empty {
+				overhangs = append(overhangs, [2]int{r, c})
+			}
+		}
+	}
+	return overhangs
+}
+
+// FindHoles returns the row and column of every empty cell of b below the
+// topmost filled cell of its column
+func FindHoles(b Board) [][2]int {
+	var holes [][2]int
+	for c := 0; c < b.Cols(); c++ {
+		covered := false
+		for r := b.Rows() - 1; r >= 0; r-- {
+			if b[r][c] != Empty {
+				covered = true
+			} else if covered {
+				holes = append(holes, [2]int{r, c})
+			}
+		}
+	}
+	return holes
+}
+
+// DetectTSDSlot looks for the lowest T-spin double setup on b: a notch
+// three cells wide in one row over a one cell hole in the row below, with a
+// roof over one end of the notch, where a T fills both rows. It returns
+// where the center of the T goes and the rotation state it has to be in,
+// which is the spawn state with the point of the T down into the hole.
+func DetectTSDSlot(b Board) (row, col int, rotState int, found bool) {
+	filled := func(r, c int) bool {
+		return c < 0 || c >= b.Cols() || r < 0 || b[r][c] != Empty
+	}
+	// The rest of a row the T doesn't fill has to be full for it to clear
+	fullExcept := func(r, from, to int) bool {
+		for c := 0; c < b.Cols(); c++ {
+			if (c < from || c > to) && b[r][c] == Empty {
+				return false
+			}
+		}
+		return true
+	}
+
+	for r := 0; r+2 < b.Rows()-HiddenRows; r++ {
+		for c := 1; c+1 < b.Cols(); c++ {
+			// The hole under the middle of the notch
+			if filled(r, c) || !fullExcept(r, c, c) {
+				continue
+			}
+			// The notch, which has to be open above its middle
+			if filled(r+1, c-1) || filled(r+1, c) || filled(r+1, c+1) || !fullExcept(r+1, c-1, c+1) || filled(r+2, c) {
+				continue
+			}
+			// A roof over exactly one end so the T has to spin in
+			if filled(r+2, c-1) != filled(r+2, c+1) {
+				return r + 1, c, 0, true
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// maxColumnHeight returns the height of the highest column of b
+func maxColumnHeight(b Board) int {
+	height := 0
+	for c := 0; c < b.Cols(); c++ {
+		height = maxInt(height, b.ColHeight(c))
+	}
+	return height
+}
+
+// updateMaxHeight measures the height of the stack and keeps the highest it
+// has been in the stats. The active piece isn't part of the stack until it
+// locks.
+func (gs *GameState) updateMaxHeight() {
+	if gs.clearAnim.active() {
+		// The piece has locked, the next one isn't out yet
+		gs.maxHeight = maxColumnHeight(gs.board)
+	} else {
+		blockType := gs.board[gs.activeShape[0].row][gs.activeShape[0].col]
+		gs.board.drawPiece(gs.activeShape, Empty)
+		gs.maxHeight = maxColumnHeight(gs.board)
+		gs.board.drawPiece(gs.activeShape, blockType)
+	}
+	gs.stats.MaxHeight = maxInt(gs.stats.MaxHeight, gs.maxHeight)
+}
+
+// isBoardEmpty checks if every visible row of the board is empty
+func isBoardEmpty(b Board) bool {
+	for r := 0; r < b.Rows()-HiddenRows; r++ {
+		for c := 0; c < b.Cols(); c++ {
+			if b[r][c] != Empty {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// InjectGarbage shifts the whole board up by lines rows and fills the
+// bottom lines rows with garbage. Row i of the garbage, counting up from the
+// bottom, has its only empty cell in column holeCols[i]; when holeCols is
+// shorter than lines its last entry is reused. Returns true if any block
+// was pushed into the hidden rows at the top, which ends the game.
+func (b Board) InjectGarbage(lines int, holeCols []int) bool {
+	if lines <= 0 {
+		return false
+	}
+	if lines > b.Rows() {
+		lines = b.Rows()
+	}
+
+	for r := b.Rows() - 1; r >= lines; r-- {
+		copy(b[r], b[r-lines])
+	}
+	for r := 0; r < lines; r++ {
+		hole := -1
+		if len(holeCols) > 0 {
+			hole = holeCols[len(holeCols)-1]
+			if r < len(holeCols) {
+				hole = holeCols[r]
+			}
+		}
+		for c := 0; c < b.Cols(); c++ {
+			b[r][c] = GarbageBlock
+			if c == hole {
+				b[r][c] = Empty
+			}
+		}
+	}
+
+	for r := b.Rows() - HiddenRows; r < b.Rows(); r++ {
+		for c := 0; c < b.Cols(); c++ {
+			if b[r][c] != Empty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deleteRow remoes a row by shifting everything above it down by one.
+func (b Board) deleteRow(row int) {
+	for r := row; r < b.Rows()-1; r++ {
+		copy(b[r], b[r+1])
+	}
+}
+
+// setPiece sets a value in the game board to a specific block type.
+func (b Board) setPiece(r, c int, val Block) {
+	b[r][c] = val
+}
+
+// fillShape sets
+func (b Board) fillShape(s Shape, val Block) {
+	for i := 0; i < 4; i++ {
+		b.setPiece(s[i].row, s[i].col, val)
+	}
+}
+
+// addPiece creates a piece at the top of the screen at its spawn column
+// and sets it to the piece that the player is controlling
+// (ie activeShape).
+func (gs *GameState) addPiece() {
+	piece := gs.queue.Next()
+	gs.stats.PiecesPlaced[piece]++
+	baseShape := PieceShape(piece)
+	baseShape = moveShape(gs.rows, spawnCol(piece, gs.cols), baseShape)
+	gs.board.fillShape(baseShape, PieceBlock(piece))
+	gs.currentPiece = piece
+	gs.activeShape = baseShape
+	gs.rotationState = 0 // Reset rotation state for new piece
+	gs.moveCount = 0
+}
+
+// Score180Rotation evaluates the placement reached by rotating the active
+// piece, s, twice in direction dir and then dropping it. The game is left
+// untouched. Returns -Inf if either rotation fails.
+func (gs *GameState) Score180Rotation(s Shape, dir int) float64 {
+	// Work on a copy of the game so the real piece and board don't move
+	sim := *gs
+	sim.board = gs.board.clone()
+	sim.board.drawPiece(sim.activeShape, Empty)
+	sim.activeShape = s
+	sim.board.drawPiece(s, PieceBlock(sim.currentPiece))
+	if !sim.rotatePiece(dir) || !sim.rotatePiece(dir) {
+		return math.Inf(-1)
+	}
+
+	// Drop the rotated piece and evaluate the resulting stack
+	for sim.applyGravity() > 0 {
+	}
+	lines := sim.board.clearFullRows()
+	return evaluateBoard(sim.board, lines)
+}
+
+// clearFullRows deletes every full row of the board and returns how many
+// were deleted.
+func (b Board) clearFullRows() int {
+	cleared := 0
+	for r := 0; r < b.Rows(); {
+		full := true
+		for c := 0; c < b.Cols(); c++ {
+			if b[r][c] == Empty {
+				full = false
+				break
+			}
+		}
+		if full {
+			b.deleteRow(r)
+			cleared++
+		} else {
+			r++
+		}
+	}
+	return cleared
+}
+
+// evaluateBoard scores a board state after a placement that cleared lines
+// rows. Higher is better. Uses the aggregate height, holes and bumpiness of
+// the stack with weights commonly used for Tetris bots.
+func evaluateBoard(b Board, lines int) float64 {
+	aggregateHeight := 0
+	holes := 0
+	bumpiness := 0
+	prevHeight := -1
+	for c := 0; c < b.Cols(); c++ {
+		height := 0
+		for r := b.Rows() - 1; r >= 0; r-- {
+			if b[r][c] != Empty {
+				if height == 0 {
+					height = r + 1
+				}
+			} else if height > 0 {
+				holes++
+			}
+		}
+		aggregateHeight += height
+		if prevHeight >= 0 {
+			bumpiness += int(math.Abs(float64(height - prevHeight)))
+		}
+		prevHeight = height
+	}
+
+	return -0.51*float64(aggregateHeight) + 0.76*float64(lines) -
+		0.36*float64(holes) - 0.18*float64(bumpiness)
+}
+
+// GhostPiece returns where the active piece would land if it were
+// dropped straight down.
+func (gs *GameState) GhostPiece() Shape {
+	b := gs.board.clone()
+	ghostShape := gs.activeShape
+	b.drawPiece(gs.activeShape, Empty)
+	for !b.checkCollision(moveShapeDown(ghostShape)) {
+		ghostShape = moveShapeDown(ghostShape)
+	}
+	return ghostShape
+}
+
+// RowClearPreview returns, for each row that dropping the active piece would
+// clear, what kind of clear it would be. Rows that would not be cleared are
+// not in the map.
+func (gs *GameState) RowClearPreview() map[int]ClearType {
+	b := gs.board.clone()
+	pieceType := b[gs.activeShape[0].row][gs.activeShape[0].col]
+	ghostShape := gs.GhostPiece()
+
+	// A T-spin only counts when the piece doesn't need to fall any further
+	tSpin := ghostShape == gs.activeShape && gs.classifyTSpin(b) != TSpinNone
+
+	// Place the piece at the ghost position on the board copy
+	b.drawPiece(gs.activeShape, Empty)
+	b.fillShape(ghostShape, pieceType)
+
+	var fullRows []int
+	for i := 0; i < 4; i++ {
+		r := ghostShape[i].row
+		full := true
+		for c := 0; c < b.Cols(); c++ {
+			if b[r][c] == Empty {
+				full = false
+				break
+			}
+		}
+		if full && !containsInt(fullRows, r) {
+			fullRows = append(fullRows, r)
+		}
+	}
+
+	preview := make(map[int]ClearType, len(fullRows))
+	for _, r := range fullRows {
+		switch {
+		case tSpin:
+			preview[r] = ClearTypeTSpin
+		case len(fullRows) == 4:
+			preview[r] = ClearTypeTetris
+		default:
+			preview[r] = ClearType(len(fullRows))
+		}
+	}
+	return preview
+}
+
+// containsInt checks if the value v is in the slice s
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeBoards returns a copy of the board with the non-empty cells of other
+// placed on top of it, shifted by offset (rows, cols). Cells shifted outside
+// of the board are dropped. An offset that lies outside of the board is an
+// error.
+func (b Board) MergeBoards(other Board, offset [2]int) (Board, error) {
+	if offset[0] <= -b.Rows() || offset[0] >= b.Rows() || offset[1] <= -b.Cols() || offset[1] >= b.Cols() {
+		return b, fmt.Errorf("MergeBoards: offset (%d, %d) is outside of the board", offset[0], offset[1])
+	}
+
+	b = b.clone()
+	for r := 0; r < other.Rows(); r++ {
+		for c := 0; c < other.Cols(); c++ {
+			if other[r][c] == Empty {
+				continue
+			}
+			newR := r + offset[0]
+			newC := c + offset[1]
+			if newR < 0 || newR >= b.Rows() || newC < 0 || newC >= b.Cols() {
+				continue
+			}
+			b[newR][newC] = other[r][c]
+		}
+	}
+	return b, nil
+}
+
+// blockChars maps the characters of the text form of a board to blocks. The
+// colors are mostly their first letter, with the special blocks in lower
+// case.
+var blockChars = map[rune]Block{
+	'.': Empty,
+	'G': Goluboy,
+	'S': Siniy,
+	'P': Pink,
+	'U': Purple,
+	'R': Red,
+	'Y': Yellow,
+	'N': Green,
+	'X': Gray,
+	'g': GoluboySpecial,
+	's': SiniySpecial,
+	'p': PinkSpecial,
+	'u': PurpleSpecial,
+	'r': RedSpecial,
+	'y': YellowSpecial,
+	'n': GreenSpecial,
+	'x': GraySpecial,
+}
+
+// String renders the board, hidden rows included, as one line of text per
+// row with the top row first, using the characters of blockChars. Useful
+// for debugging and writing down boards.
+func (b Board) String() string {
+	chars := make(map[Block]rune, len(blockChars))
+	for ch, block := range blockChars {
+		chars[block] = ch
+	}
+
+	var sb strings.Builder
+	for r := b.Rows() - 1; r >= 0; r-- {
+		for _, block := range b[r] {
+			ch, ok := chars[block]
+			if !ok {
+				ch = '?'
+			}
+			sb.WriteRune(ch)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// BoardFromString parses a board written the way Board.String writes it.
+// Blank lines and the space around each line are ignored so boards can be
+// written as indented raw strings. Every row must be the same width.
+func BoardFromString(s string) (Board, error) {
+	var rows [][]Block
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		row := make([]Block, 0, len(line))
+		for _, ch := range line {
+			block, ok := blockChars[ch]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown block %q", i+1, ch)
+			}
+			row = append(row, block)
+		}
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, fmt.Errorf("line %d: row is %d wide, expected %d", i+1, len(row), len(rows[0]))
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows in board")
+	}
+
+	// The text has the top row first
+	b := make(Board, len(rows))
+	for r := range rows {
+		b[len(rows)-1-r] = rows[r]
+	}
+	return b, nil
+}
+
+// MarshalJSON writes the board as an array of rows, bottom row first, each
+// an array of block values.
+func (b Board) MarshalJSON() ([]byte, error) {
+	return json.Marshal([][]Block(b))
+}
+
+// UnmarshalJSON reads a board written by MarshalJSON. Every row must be the
+// same width and hold known block values.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var rows [][]Block
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	for r, row := range rows {
+		if len(row) != len(rows[0]) {
+			return fmt.Errorf("board row %d is %d wide, expected %d", r, len(row), len(rows[0]))
+		}
+		for c, block := range row {
+			if block < Empty || block > GraySpecial {
+				return fmt.Errorf("board row %d column %d: unknown block %d", r, c, block)
+			}
+		}
+	}
+	*b = rows
+	return nil
+}
+
+// CellChange records a single cell of the board that differs between two
+// board states.
+type CellChange struct {
+	row    int
+	col    int
+	before Block
+	after  Block
+}
+
+// SnapshotDiff returns the cells that differ between the board states before
+// and after, which must be the same size. Used to only redraw or transmit
+// the parts of a board that changed.
+func SnapshotDiff(before, after Board) []CellChange {
+	var diff []CellChange
+	for r := 0; r < before.Rows(); r++ {
+		for c := 0; c < before.Cols(); c++ {
+			if before[r][c] != after[r][c] {
+				diff = append(diff, CellChange{row: r, col: c, before: before[r][c], after: after[r][c]})
+			}
+		}
+	}
+	return diff
+}
+
+// ApplyDiff sets the changed cells in diff on the board b.
+func ApplyDiff(b Board, diff []CellChange) {
+	for _, change := range diff {
+		b.setPiece(change.row, change.col, change.after)
+	}
+}
+
+// IsPartOfActiveShape checks if a given position is part of the active shape
+func (gs *GameState) IsPartOfActiveShape(row, col int) bool {
+	for i := 0; i < 4; i++ {
+		if gs.activeShape[i].row == row && gs.activeShape[i].col == col {
+			return true
+		}
+	}
+	return false
+}
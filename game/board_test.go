@@ -0,0 +1,1172 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+)
+
+// newTestGame returns a marathon game with the default settings whose
+// pieces are always dealt from the same seed
+func newTestGame(t testing.TB) *GameState {
+	t.Helper()
+	return NewSeededGameState(ModeMarathon, config.DefaultSettings(), 1)
+}
+
+// spawnPiece swaps the active piece of gs for a newly spawned p
+func spawnPiece(gs *GameState, p Piece) {
+	gs.board.drawPiece(gs.activeShape, Empty)
+	shape := moveShape(gs.rows, spawnCol(p, gs.cols), PieceShape(p))
+	gs.board.fillShape(shape, PieceBlock(p))
+	gs.currentPiece = p
+	gs.activeShape = shape
+	gs.rotationState = 0
+}
+
+// mustBoard parses a board written like Board.String, failing the test if
+// it can't be
+func mustBoard(t testing.TB, s string) Board {
+	t.Helper()
+	b, err := BoardFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSnapshotDiffGravityStep(t *testing.T) {
+	// An O piece moving down a row leaves its top two cells and fills two
+	// below it, so the step changes 4 cells
+	gs := newTestGame(t)
+	spawnPiece(gs, OPiece)
+	before := gs.board.clone()
+	if rows := gs.applyGravity(); rows != 1 {
+		t.Fatalf("applyGravity() = %d, want 1", rows)
+	}
+	after := gs.board.clone()
+
+	diff := SnapshotDiff(before, after)
+	if len(diff) != 4 {
+		t.Fatalf("SnapshotDiff() returned %d changes, want 4: %v", len(diff), diff)
+	}
+	for _, change := range diff {
+		if change.before != before[change.row][change.col] || change.after != after[change.row][change.col] {
+			t.Errorf("change %+v doesn't match the boards", change)
+		}
+	}
+
+	patched := before.clone()
+	ApplyDiff(patched, diff)
+	if !reflect.DeepEqual(patched, after) {
+		t.Errorf("ApplyDiff(before, SnapshotDiff(before, after)) =\n%vwant\n%v", patched, after)
+	}
+}
+
+func TestSnapshotDiffSameBoard(t *testing.T) {
+	gs := newTestGame(t)
+	if diff := SnapshotDiff(gs.board, gs.board.clone()); len(diff) != 0 {
+		t.Errorf("SnapshotDiff() of equal boards = %v, want no changes", diff)
+	}
+}
+
+// dropScore is the score evaluateBoard gives the active piece of gs dropped
+// straight down without rotating, leaving gs untouched
+func dropScore(gs *GameState) float64 {
+	sim := *gs
+	sim.board = gs.board.clone()
+	for sim.applyGravity() > 0 {
+	}
+	lines := sim.board.clearFullRows()
+	return evaluateBoard(sim.board, lines)
+}
+
+func TestScore180Rotation(t *testing.T) {
+	// The slot at the bottom only fits a T pointing up, which is the spawn
+	// T turned around
+	gs := newTestGame(t)
+	gs.board = mustBoard(t, `
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		..........
+		XXX...XXXX
+	`)
+	spawnPiece(gs, TPiece)
+	before := gs.board.clone()
+	shape := gs.activeShape
+
+	for _, dir := range []int{1, -1} {
+		rotated := gs.Score180Rotation(shape, dir)
+		if flat := dropScore(gs); rotated <= flat {
+			t.Errorf("Score180Rotation(dir %d) = %v, want better than %v without rotating", dir, rotated, flat)
+		}
+	}
+	if !reflect.DeepEqual(gs.board, before) || gs.activeShape != shape || gs.rotationState != 0 {
+		t.Error("Score180Rotation changed the game")
+	}
+}
+
+func TestScore180RotationBlocked(t *testing.T) {
+	// With every other cell filled the T can't turn at all
+	gs := newTestGame(t)
+	spawnPiece(gs, TPiece)
+	for r := range gs.board {
+		for c := range gs.board[r] {
+			if !gs.IsPartOfActiveShape(r, c) {
+				gs.board[r][c] = Gray
+			}
+		}
+	}
+	if score := gs.Score180Rotation(gs.activeShape, 1); !math.IsInf(score, -1) {
+		t.Errorf("Score180Rotation() = %v, want -Inf", score)
+	}
+}
+
+// placePiece makes p at shape s in rotation state the active piece of gs,
+// as though it had just been rotated there. The board is left as it is
+// apart from the cells of s, so the old piece should already be gone.
+func placePiece(gs *GameState, p Piece, s Shape, state int) {
+	gs.board.fillShape(s, PieceBlock(p))
+	gs.currentPiece = p
+	gs.activeShape = s
+	gs.rotationState = state
+	gs.lastMovementWasRotation = true
+}
+
+// tsdBoard has a T-spin double slot at the bottom for a T pointing down
+// with its center at row 1, column 4
+const tsdBoard = `
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	..........
+	XXXX......
+	XXX...XXXX
+	XXXX.XXXXX
+`
+
+// tsdShape is the T pointing down in the slot of tsdBoard, center second
+var tsdShape = Shape{{row: 1, col: 3}, {row: 1, col: 4}, {row: 1, col: 5}, {row: 0, col: 4}}
+
+func TestRowClearPreview(t *testing.T) {
+	tests := []struct {
+		name    string
+		board   string
+		piece   Piece
+		shape   Shape
+		state   int
+		rotated bool
+		want    map[int]ClearType
+	}{
+		{
+			name:    "T-spin double",
+			board:   tsdBoard,
+			piece:   TPiece,
+			shape:   tsdShape,
+			rotated: true,
+			want:    map[int]ClearType{0: ClearTypeTSpin, 1: ClearTypeTSpin},
+		},
+		{
+			name:  "T dropped into the slot",
+			board: tsdBoard,
+			piece: TPiece,
+			shape: tsdShape,
+			want:  map[int]ClearType{0: ClearTypeDouble, 1: ClearTypeDouble},
+		},
+		{
+			name: "Tetris",
+			board: `
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				..........
+				XXXXXXXXX.
+				XXXXXXXXX.
+				XXXXXXXXX.
+				XXXXXXXXX.
+			`,
+			piece: IPiece,
+			shape: Shape{{row: 18, col: 9}, {row: 19, col: 9}, {row: 20, col: 9}, {row: 21, col: 9}},
+			state: 1,
+			want:  map[int]ClearType{0: ClearTypeTetris, 1: ClearTypeTetris, 2: ClearTypeTetris, 3: ClearTypeTetris},
+		},
+		{
+			name:  "no clear",
+			board: tsdBoard,
+			piece: OPiece,
+			shape: Shape{{row: 21, col: 4}, {row: 21, col: 5}, {row: 20, col: 4}, {row: 20, col: 5}},
+			want:  map[int]ClearType{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := newTestGame(t)
+			gs.board = mustBoard(t, tt.board)
+			placePiece(gs, tt.piece, tt.shape, tt.state)
+			gs.lastMovementWasRotation = tt.rotated
+			if got := gs.RowClearPreview(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RowClearPreview() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeBoards(t *testing.T) {
+	base := mustBoard(t, `
+		....
+		....
+		....
+		X...
+	`)
+	block := mustBoard(t, `
+		SSS
+		SSS
+		SSS
+	`)
+	tests := []struct {
+		offset [2]int
+		want   string
+	}{
+		{[2]int{0, 0}, `
+			....
+			SSS.
+			SSS.
+			SSS.
+		`},
+		{[2]int{1, 1}, `
+			.SSS
+			.SSS
+			.SSS
+			X...
+		`},
+		{[2]int{2, 2}, `
+			..SS
+			..SS
+			....
+			X...
+		`},
+		{[2]int{-2, -1}, `
+			....
+			....
+			....
+			SS..
+		`},
+		{[2]int{3, 3}, `
+			...S
+			....
+			....
+			X...
+		`},
+	}
+	for _, tt := range tests {
+		got, err := base.MergeBoards(block, tt.offset)
+		if err != nil {
+			t.Errorf("MergeBoards(%v) returned error: %v", tt.offset, err)
+			continue
+		}
+		if want := mustBoard(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("MergeBoards(%v) =\n%vwant\n%v", tt.offset, got, want)
+		}
+	}
+	if want := mustBoard(t, "....\n....\n....\nX..."); !reflect.DeepEqual(base, want) {
+		t.Errorf("MergeBoards changed the receiver to\n%v", base)
+	}
+}
+
+func TestMergeBoardsOffsetOutside(t *testing.T) {
+	base := mustBoard(t, "....\n....\n....\n....")
+	block := mustBoard(t, "SSS\nSSS\nSSS")
+	for _, offset := range [][2]int{{4, 0}, {0, 4}, {-4, 0}, {0, -4}} {
+		if _, err := base.MergeBoards(block, offset); err == nil {
+			t.Errorf("MergeBoards(%v) returned no error", offset)
+		}
+	}
+}
+
+func TestIsBoardEmpty(t *testing.T) {
+	b := newBoard(20, 10)
+	if !isBoardEmpty(b) {
+		t.Error("isBoardEmpty(empty board) = false")
+	}
+
+	// A single block anywhere on the visible board keeps it from being empty
+	for r := 0; r < 20; r++ {
+		for c := 0; c < 10; c++ {
+			b[r][c] = Gray
+			if isBoardEmpty(b) {
+				t.Errorf("isBoardEmpty() = true with a block at row %d, column %d", r, c)
+			}
+			b[r][c] = Empty
+		}
+	}
+
+	// The hidden rows aren't part of the board that has to be cleared
+	b[20][4] = Gray
+	if !isBoardEmpty(b) {
+		t.Error("isBoardEmpty() = false with a block only in the hidden rows")
+	}
+}
+
+func TestInjectGarbage(t *testing.T) {
+	// The top two rows of each board are the hidden rows
+	start := `
+		....
+		....
+		....
+		.S..
+		SS..
+		PPP.`
+	tests := []struct {
+		name     string
+		lines    int
+		holes    []int
+		want     string
+		overflow bool
+	}{
+		{"none", 0, []int{0}, start, false},
+		{"one line", 1, []int{2}, `
+			....
+			....
+			.S..
+			SS..
+			PPP.
+			XX.X`, false},
+		{"a hole per line", 2, []int{0, 3}, `
+			....
+			.S..
+			SS..
+			PPP.
+			XXX.
+			.XXX`, true},
+		{"last hole reused", 3, []int{1}, `
+			.S..
+			SS..
+			PPP.
+			X.XX
+			X.XX
+			X.XX`, true},
+		{"no hole", 1, nil, `
+			....
+			....
+			.S..
+			SS..
+			PPP.
+			XXXX`, false},
+		{"more lines than rows", 9, []int{3}, `
+			XXX.
+			XXX.
+			XXX.
+			XXX.
+			XXX.
+			XXX.`, true},
+	}
+	for _, tt := range tests {
+		b := mustBoard(t, start)
+		overflow := b.InjectGarbage(tt.lines, tt.holes)
+		if want := mustBoard(t, tt.want); !reflect.DeepEqual(b, want) || overflow != tt.overflow {
+			t.Errorf("%s: InjectGarbage(%d, %v) = %t, board\n%v\nwant %t, board\n%v", tt.name, tt.lines, tt.holes, overflow, b, tt.overflow, want)
+		}
+	}
+}
+
+func TestPendingGarbageGameOver(t *testing.T) {
+	// Garbage is pushed up between a lock and the next piece
+	gs := newTestGame(t)
+	gs.board.drawPiece(gs.activeShape, Empty)
+	gs.pendingGarbage = 3
+	gs.injectPendingGarbage()
+	if gs.GameOver() || gs.pendingGarbage != 0 {
+		t.Fatalf("3 garbage lines: game over %t, %d pending, want false, 0", gs.GameOver(), gs.pendingGarbage)
+	}
+	for c := 0; c < gs.cols; c++ {
+		if gs.board[3][c] != Empty {
+			t.Errorf("row 3 column %d is %v after 3 garbage lines, want empty", c, gs.board[3][c])
+		}
+	}
+
+	// Garbage that pushes the stack into the hidden rows ends the game
+	gs.pendingGarbage = gs.rows - 3
+	gs.injectPendingGarbage()
+	if gs.GameOver() {
+		t.Fatal("garbage up to the top visible row ended the game")
+	}
+	gs.pendingGarbage = 1
+	gs.injectPendingGarbage()
+	if !gs.GameOver() {
+		t.Error("garbage pushed into the hidden rows didn't end the game")
+	}
+}
+
+func TestClassifyAllSpin(t *testing.T) {
+	for _, p := range allPieces {
+		for state := 0; state < 4; state++ {
+			s := shapeInState(p, state)
+			want := p != TPiece && p != OPiece
+
+			// Boxed in on every side it is a spin, on its own it isn't
+			b := newBoard(20, 10)
+			for r := range b {
+				for c := range b[r] {
+					b[r][c] = Gray
+				}
+			}
+			b.fillShape(s, PieceBlock(p))
+			if got := classifyAllSpin(b, p, s, state); got != want {
+				t.Errorf("%s in state %d boxed in: classifyAllSpin() = %t, want %t", PieceName(p), state, got, want)
+			}
+			b = newBoard(20, 10)
+			b.fillShape(s, PieceBlock(p))
+			if classifyAllSpin(b, p, s, state) {
+				t.Errorf("%s in state %d on an empty board: classifyAllSpin() = true", PieceName(p), state)
+			}
+		}
+	}
+}
+
+func TestClassifyAllSpinCorners(t *testing.T) {
+	// The cells of the piece are P on each board
+	tests := []struct {
+		name  string
+		board string
+		piece Piece
+		state int
+		want  bool
+	}{
+		// The S pivot is its lower middle cell, whose top right corner is
+		// part of the S itself
+		{"S, three corners", `
+			.XPP.
+			.PP..
+			.X.X.`, SPiece, 0, true},
+		{"S, two corners", `
+			.XPP.
+			.PP..
+			.X...`, SPiece, 0, false},
+		{"S on the floor", `
+			.XPP.
+			.PP..`, SPiece, 0, true},
+		{"J on the floor", `
+			P.X.
+			PPP.`, JPiece, 0, true},
+		{"J off the floor", `
+			P.X.
+			PPP.
+			....`, JPiece, 0, false},
+		{"L, two corners", `
+			...P.
+			.PPP.
+			.X.X.`, LPiece, 2, false},
+		{"Z, three corners", `
+			X.X..
+			PP...
+			XPP..`, ZPiece, 0, true},
+		// The corners of the I are on either side of its ends
+		{"flat I, three corners", `
+			.X..X.
+			.PPPP.
+			.X....`, IPiece, 0, true},
+		{"flat I, two corners", `
+			.X..X.
+			.PPPP.
+			......`, IPiece, 0, false},
+		{"upright I in a well", `
+			...
+			XP.
+			.P.
+			.P.
+			XPX`, IPiece, 1, true},
+		{"T", `
+			XPX
+			PPP
+			X.X`, TPiece, 2, false},
+	}
+	for _, tt := range tests {
+		b := mustBoard(t, tt.board)
+		var s Shape
+		n := 0
+		for r := range b {
+			for c, block := range b[r] {
+				if block == Pink {
+					s[n] = Point{row: r, col: c}
+					n++
+				}
+			}
+		}
+		if n != 4 {
+			t.Fatalf("%s: %d cells of the piece, want 4", tt.name, n)
+		}
+		// The pivot goes second, as the shapes of the game have it
+		s = pivotSecond(tt.piece, tt.state, s)
+		if got := classifyAllSpin(b, tt.piece, s, tt.state); got != tt.want {
+			t.Errorf("%s: classifyAllSpin() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+// pivotSecond orders the cells of s, which is piece p in rotation state,
+// the way the game orders that piece's shape, so the pivot comes second
+func pivotSecond(p Piece, state int, s Shape) Shape {
+	game := shapeInState(p, state)
+	moved := moveShape(shapeBottomRow(s)-shapeBottomRow(game), shapeLeftCol(s)-shapeLeftCol(game), game)
+	if !sameCells(moved, s) {
+		panic(fmt.Sprintf("%v isn't %s in state %d", s, PieceName(p), state))
+	}
+	return moved
+}
+
+func TestAllSpinScore(t *testing.T) {
+	// An S spun into the bottom two rows clears them both, for 1.5x a
+	// double when it was rotated in
+	board := `
+		X.........
+		XX..XXXXXX
+		X..XXXXXXX`
+	s := Shape{{row: 0, col: 1}, {row: 0, col: 2}, {row: 1, col: 2}, {row: 1, col: 3}}
+	for _, rotated := range []bool{true, false} {
+		gs := newTestGame(t)
+		gs.board = newBoard(gs.rows, gs.cols)
+		merged, err := gs.board.MergeBoards(mustBoard(t, board), [2]int{0, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs.board = merged
+		placePiece(gs, SPiece, pivotSecond(SPiece, 0, s), 0)
+		gs.lastMovementWasRotation = rotated
+		gs.lockPiece()
+		gs.finishLineClear()
+		want := 400
+		if rotated {
+			want = 600
+		}
+		if gs.linesCleared != 2 || gs.score != want {
+			t.Errorf("rotated %t: %d lines for %d points, want 2 for %d", rotated, gs.linesCleared, gs.score, want)
+		}
+	}
+}
+
+func TestColHeight(t *testing.T) {
+	// A board 4 rows high under its 2 hidden rows, whose blocks don't count
+	b := mustBoard(t, `
+		....X.
+		......
+		.X....
+		.....X
+		..X.X.
+		.X.X..`)
+	want := []int{0, 4, 2, 1, 2, 3}
+	for col, w := range want {
+		if got := b.ColHeight(col); got != w {
+			t.Errorf("ColHeight(%d) = %d, want %d", col, got, w)
+		}
+	}
+}
+
+func TestBoardStringRoundTrip(t *testing.T) {
+	// Every block there is, at the bottom and in the hidden rows of a full
+	// size board
+	b := newBoard(20, 10)
+	for block := Empty; block <= GraySpecial; block++ {
+		i := int(block)
+		b[i/10][i%10] = block
+		b[21-i/10][9-i%10] = block
+	}
+	got, err := BoardFromString(b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("BoardFromString(String()) =\n%v\nwant\n%v", got, b)
+	}
+
+	// The top row comes first
+	small := mustBoard(t, `
+		G...
+		.XS.`)
+	if s := small.String(); s != "G...\n.XS.\n" || small[1][0] != Goluboy || small[0][2] != Siniy {
+		t.Errorf("String() = %q, want the top row first", s)
+	}
+}
+
+func TestBoardFromStringErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":         "",
+		"blank lines":   "\n  \n\t\n",
+		"unknown block": "..\n.Q",
+		"ragged rows":   "...\n..",
+	}
+	for name, s := range tests {
+		if b, err := BoardFromString(s); err == nil {
+			t.Errorf("%s: BoardFromString(%q) = %v, want an error", name, s, b)
+		}
+	}
+}
+
+func TestBoardJSONRoundTrip(t *testing.T) {
+	b := mustBoard(t, `
+		....
+		.x..
+		GS.P
+		NNRY`)
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[[7,7,5,6],[1,2,0,3],[0,16,0,0],[0,0,0,0]]`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+	var got Board
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("board after a JSON round trip =\n%v\nwant\n%v", got, b)
+	}
+
+	for _, data := range []string{`[[0,0],[0]]`, `[[0,99]]`, `[[-1]]`, `{}`} {
+		if err := json.Unmarshal([]byte(data), &got); err == nil {
+			t.Errorf("json.Unmarshal(%s) returned no error", data)
+		}
+	}
+}
+
+func TestColumnHeights(t *testing.T) {
+	tests := []struct {
+		board string
+		want  []int
+	}{
+		{"....\n....\n....\n....", []int{0, 0, 0, 0}},
+		{"....\n....\nXXXX\nXXXX", []int{2, 2, 2, 2}},
+		{`
+			X...
+			....
+			.X..
+			..X.
+			...X`, []int{0, 3, 2, 1}},
+		{`
+			....
+			....
+			X.X.
+			..X.
+			X.XX`, []int{3, 0, 3, 1}},
+	}
+	for _, tt := range tests {
+		b := mustBoard(t, tt.board)
+		if got := ColumnHeights(b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ColumnHeights() of\n%vgot %v, want %v", b, got, tt.want)
+		}
+	}
+}
+
+func TestFindHolesAndOverhangs(t *testing.T) {
+	tests := []struct {
+		name      string
+		board     string
+		holes     [][2]int
+		overhangs [][2]int
+	}{
+		{"empty", "....\n....\n....", nil, nil},
+		{"flat stack", "....\nXXXX\nXXXX", nil, nil},
+		{"covered hole", `
+			....
+			.X..
+			.X..
+			....`, [][2]int{{0, 1}}, [][2]int{{1, 1}}},
+		{"tall gap", `
+			X...
+			....
+			....
+			X...`, [][2]int{{2, 0}, {1, 0}}, [][2]int{{3, 0}}},
+		{"holes in two columns", `
+			.XX.
+			X..X
+			XX.X`, [][2]int{{1, 1}, {1, 2}, {0, 2}}, [][2]int{{2, 1}, {2, 2}}},
+	}
+	for _, tt := range tests {
+		b := mustBoard(t, tt.board)
+		if got := FindHoles(b); !reflect.DeepEqual(got, tt.holes) {
+			t.Errorf("%s: FindHoles() = %v, want %v", tt.name, got, tt.holes)
+		}
+		if got := FindOverhangs(b); !reflect.DeepEqual(got, tt.overhangs) {
+			t.Errorf("%s: FindOverhangs() = %v, want %v", tt.name, got, tt.overhangs)
+		}
+	}
+}
+
+func TestMaxColumnHeight(t *testing.T) {
+	tests := []struct {
+		board string
+		want  int
+	}{
+		{"...\n...\n...\n...\n...", 0},
+		{"...\n...\n...\n...\n.X.", 1},
+		{"...\n...\nX..\n...\n.X.", 3},
+		{"XXX\n.X.\n...\n.X.\nXX.", 2}, // The hidden rows don't count
+	}
+	for _, tt := range tests {
+		if got := maxColumnHeight(mustBoard(t, tt.board)); got != tt.want {
+			t.Errorf("maxColumnHeight() of\n%sgot %d, want %d", tt.board, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateMaxHeight(t *testing.T) {
+	// The falling piece isn't part of the stack
+	gs := newTestGame(t)
+	for r := 0; r < 6; r++ {
+		gs.board[r][0] = Gray
+	}
+	gs.updateMaxHeight()
+	if gs.maxHeight != 6 || gs.stats.MaxHeight != 6 {
+		t.Errorf("height %d, highest %d, want 6 and 6", gs.maxHeight, gs.stats.MaxHeight)
+	}
+
+	// The highest the stack has been is kept once it comes down
+	for r := 2; r < 6; r++ {
+		gs.board[r][0] = Empty
+	}
+	gs.updateMaxHeight()
+	if gs.maxHeight != 2 || gs.stats.MaxHeight != 6 {
+		t.Errorf("height %d, highest %d after it came down, want 2 and 6", gs.maxHeight, gs.stats.MaxHeight)
+	}
+}
+
+func TestDetectTSDSlot(t *testing.T) {
+	// Boards 6 wide and 4 rows high under their 2 hidden rows
+	tests := []struct {
+		name     string
+		board    string
+		row, col int
+		found    bool
+	}{
+		{"tsdBoard", tsdBoard, 1, 4, true},
+		{"roof on the right", `
+			......
+			......
+			......
+			....XX
+			XX...X
+			XXX.XX`, 1, 3, true},
+		{"roof on the left", `
+			......
+			......
+			......
+			XX....
+			X...XX
+			XX.XXX`, 1, 2, true},
+		{"against the wall", `
+			......
+			......
+			......
+			X.....
+			...XXX
+			X.XXXX`, 1, 1, true},
+		{"over a well", `
+			......
+			......
+			XX....
+			X...XX
+			XX.XXX
+			XX.XXX`, 2, 2, true},
+		{"empty", `
+			......
+			......
+			......
+			......
+			......
+			......`, 0, 0, false},
+		{"flat", `
+			......
+			......
+			......
+			......
+			XXXXX.
+			XXXXX.`, 0, 0, false},
+		{"no roof", `
+			......
+			......
+			......
+			......
+			X...XX
+			XX.XXX`, 0, 0, false},
+		{"roof on both ends", `
+			......
+			......
+			......
+			XX.XX.
+			X...XX
+			XX.XXX`, 0, 0, false},
+		{"covered", `
+			......
+			......
+			......
+			XXX...
+			X...XX
+			XX.XXX`, 0, 0, false},
+		{"notch too wide", `
+			......
+			......
+			......
+			XX....
+			X....X
+			XX.XXX`, 0, 0, false},
+		{"hole row won't clear", `
+			......
+			......
+			......
+			XX....
+			X...XX
+			.X.XXX`, 0, 0, false},
+		{"roof in the hidden rows", `
+			......
+			XX....
+			X...XX
+			XX.XXX
+			XXXXX.
+			XXXXX.`, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := mustBoard(t, tt.board)
+			row, col, rotState, found := DetectTSDSlot(b)
+			if found != tt.found {
+				t.Fatalf("DetectTSDSlot() found = %t, want %t", found, tt.found)
+			}
+			if found && (row != tt.row || col != tt.col || rotState != 0) {
+				t.Errorf("DetectTSDSlot() = %d, %d, %d, want %d, %d, 0", row, col, rotState, tt.row, tt.col)
+			}
+		})
+	}
+}
+
+func TestDetectTSDSlotClears(t *testing.T) {
+	// A T put where the slot is clears two lines
+	gs := newTestGame(t)
+	gs.board = mustBoard(t, tsdBoard)
+	row, col, state, found := DetectTSDSlot(gs.board)
+	if !found {
+		t.Fatal("DetectTSDSlot() found no slot in tsdBoard")
+	}
+	shape := moveShape(row-1, col-1, PieceShape(TPiece))
+	if !sameCells(shape, tsdShape) {
+		t.Fatalf("T in the slot at %v, want %v", shape, tsdShape)
+	}
+	placePiece(gs, TPiece, shape, state)
+	gs.lockPiece()
+	gs.finishLineClear()
+	if gs.linesCleared != 2 {
+		t.Errorf("T in the slot cleared %d lines, want 2", gs.linesCleared)
+	}
+}
+
+func TestBoardHash(t *testing.T) {
+	// Saved replays hold hashes, so the hash of a board mustn't change
+	// from one build to the next
+	b := newBoard(20, 10)
+	const emptyHash uint64 = 0x994f9a28082f9ca5
+	if got := b.Hash(); got != emptyHash {
+		t.Errorf("Hash() of an empty board = %#x, want %#x", got, emptyHash)
+	}
+	if b.Hash() != b.clone().Hash() {
+		t.Error("a board and its copy hash differently")
+	}
+
+	// Any one cell changed to any other block changes the hash, and the
+	// same change hashes the same every time
+	seen := map[uint64]string{emptyHash: "empty board"}
+	for r := range b {
+		for c := range b[r] {
+			for block := Goluboy; block <= GraySpecial; block++ {
+				changed := b.clone()
+				changed[r][c] = block
+				h := changed.Hash()
+				what := fmt.Sprintf("block %d at %d, %d", block, r, c)
+				if other, ok := seen[h]; ok {
+					t.Fatalf("%s hashes the same as %s", what, other)
+				}
+				seen[h] = what
+				if changed.Hash() != h {
+					t.Fatalf("%s hashes differently the second time", what)
+				}
+			}
+		}
+	}
+}
+
+// pieceFixture parses a board written like Board.String with a piece drawn
+// on it in block. Returns the board without the piece and the piece's cells.
+func pieceFixture(t *testing.T, s string, block Block) (Board, Shape) {
+	t.Helper()
+	b := mustBoard(t, s)
+	var shape Shape
+	n := 0
+	for r := range b {
+		for c := range b[r] {
+			if b[r][c] != block {
+				continue
+			}
+			if n == len(shape) {
+				t.Fatalf("more than %d cells of block %d in\n%s", len(shape), block, s)
+			}
+			shape[n] = Point{row: r, col: c}
+			n++
+			b[r][c] = Empty
+		}
+	}
+	if n != len(shape) {
+		t.Fatalf("%d cells of block %d in\n%s, want %d", n, block, s, len(shape))
+	}
+	return b, shape
+}
+
+func TestCheckCollision(t *testing.T) {
+	// The T is drawn in U and moved by the rows and columns given, the top
+	// two rows of each board being hidden
+	tests := []struct {
+		name       string
+		board      string
+		rows, cols int
+		want       bool
+	}{
+		{"inside", `
+			......
+			......
+			......
+			..UUU.
+			...U..
+			......`, 0, 0, false},
+		{"at the left wall", `
+			......
+			......
+			......
+			UUU...
+			.U....
+			......`, 0, 0, false},
+		{"past the left wall", `
+			......
+			......
+			......
+			UUU...
+			.U....
+			......`, 0, -1, true},
+		{"at the right wall", `
+			......
+			......
+			......
+			...UUU
+			....U.
+			......`, 0, 0, false},
+		{"past the right wall", `
+			......
+			......
+			......
+			...UUU
+			....U.
+			......`, 0, 1, true},
+		{"on the floor", `
+			......
+			......
+			......
+			......
+			..UUU.
+			...U..`, 0, 0, false},
+		{"below the floor", `
+			......
+			......
+			......
+			......
+			..UUU.
+			...U..`, -1, 0, true},
+		{"on the stack", `
+			......
+			......
+			......
+			.UUU..
+			..U...
+			XXXXX.`, 0, 0, false},
+		{"into the stack", `
+			......
+			......
+			......
+			.UUU..
+			..U...
+			XXXXX.`, -1, 0, true},
+		{"into the stack sideways", `
+			......
+			......
+			......
+			......
+			UUUX..
+			.U....`, 0, 1, true},
+		{"in the hidden rows", `
+			.UUU..
+			..U...
+			......
+			......
+			......
+			......`, 0, 0, false},
+		{"above the board", `
+			.UUU..
+			..U...
+			......
+			......
+			......
+			......`, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s := pieceFixture(t, tt.board, Purple)
+			s = moveShape(tt.rows, tt.cols, s)
+			before := b.clone()
+			if got := b.checkCollision(s); got != tt.want {
+				t.Errorf("checkCollision(%v) = %t, want %t", s, got, tt.want)
+			}
+			if !reflect.DeepEqual(b, before) {
+				t.Errorf("checkCollision() changed the board to\n%v", b)
+			}
+		})
+	}
+}
+
+func TestCheckCollisionIAtWalls(t *testing.T) {
+	// Slid as far as it goes each way on an empty board, the I in each
+	// state ends up against the wall, floor or top and no further
+	b := mustBoard(t, strings.Repeat("..........\n", 22))
+	dirs := []struct {
+		name       string
+		rows, cols int
+		edge       func(p Point) bool
+	}{
+		{"left", 0, -1, func(p Point) bool { return p.col == 0 }},
+		{"right", 0, 1, func(p Point) bool { return p.col == b.Cols()-1 }},
+		{"down", -1, 0, func(p Point) bool { return p.row == 0 }},
+		{"up", 1, 0, func(p Point) bool { return p.row == b.Rows()-1 }},
+	}
+	for state := 0; state < 4; state++ {
+		for _, d := range dirs {
+			s := shapeInState(IPiece, state)
+			if b.checkCollision(s) {
+				t.Fatalf("I in state %d collides in the middle of the board at %v", state, s)
+			}
+			for !b.checkCollision(moveShape(d.rows, d.cols, s)) {
+				s = moveShape(d.rows, d.cols, s)
+			}
+			touching := false
+			for _, p := range s {
+				touching = touching || d.edge(p)
+			}
+			if !touching {
+				t.Errorf("I in state %d stopped moving %s at %v, short of the edge", state, d.name, s)
+			}
+		}
+	}
+}
+
+func TestIsTouchingFloor(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+		want  bool
+	}{
+		{"resting on the floor", `
+			......
+			......
+			......
+			......
+			..UUU.
+			...U..`, true},
+		{"a row above the floor", `
+			......
+			......
+			......
+			..UUU.
+			...U..
+			......`, false},
+		{"resting on the stack", `
+			......
+			......
+			......
+			.UUU..
+			..U...
+			XXXXX.`, true},
+		{"a row above the stack", `
+			......
+			......
+			.UUU..
+			..U...
+			......
+			XXXXX.`, false},
+		{"wing on the stack", `
+			......
+			......
+			......
+			.UUU..
+			.XU...
+			.X....`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := newTestGame(t)
+			b, s := pieceFixture(t, tt.board, Purple)
+			gs.board = b
+			placePiece(gs, TPiece, s, 2)
+			before := gs.board.clone()
+			if got := gs.isTouchingFloor(); got != tt.want {
+				t.Errorf("isTouchingFloor() = %t, want %t", got, tt.want)
+			}
+			if !reflect.DeepEqual(gs.board, before) {
+				t.Errorf("isTouchingFloor() changed the board to\n%v", gs.board)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+//go:build go1.18
+// +build go1.18
+
+// Fuzzing came in Go 1.18, after the version of go.mod, so older Go leaves
+// this file out.
+
+package game
+
+import (
+	"testing"
+
+	"github.com/zkry/golang-tetris/input"
+)
+
+// fuzzInput returns the input of a step of the game holding down the
+// actions whose bits are set in b, bit 0 for input.ActionMoveLeft and so on
+func fuzzInput(b byte) input.InputState {
+	var in input.InputState
+	for a := range in.Down {
+		in.Down[a] = b&(1<<a) != 0
+	}
+	in.MoveLeft = in.Down[input.ActionMoveLeft]
+	in.MoveRight = in.Down[input.ActionMoveRight]
+	in.RotateCW = in.Down[input.ActionRotateCW]
+	in.RotateCCW = in.Down[input.ActionRotateCCW]
+	in.Rotate180 = in.Down[input.ActionRotate180]
+	in.SoftDrop = in.Down[input.ActionSoftDrop]
+	in.HardDrop = in.Down[input.ActionHardDrop]
+	in.Hold = in.Down[input.ActionHold]
+	return in
+}
+
+// checkTransition fails the test unless the active piece of gs is its only
+// one: 4 different cells on the board holding its block, with every other
+// block on the board one of a piece locked and not cleared
+func checkTransition(t *testing.T, gs *GameState, step int) {
+	t.Helper()
+	if gs.score < 0 {
+		t.Fatalf("step %d: score %d", step, gs.score)
+	}
+	block := PieceBlock(gs.currentPiece)
+	for i, p := range gs.activeShape {
+		if p.row < 0 || p.row >= gs.board.Rows() || p.col < 0 || p.col >= gs.board.Cols() {
+			t.Fatalf("step %d: piece at %v is off the board", step, gs.activeShape)
+		}
+		if gs.board[p.row][p.col] != block {
+			t.Fatalf("step %d: piece at %v has block %d at %v, want %d\n%v", step, gs.activeShape, gs.board[p.row][p.col], p, block, gs.board)
+		}
+		for _, q := range gs.activeShape[:i] {
+			if p == q {
+				t.Fatalf("step %d: piece at %v has cell %v twice", step, gs.activeShape, p)
+			}
+		}
+	}
+
+	// Every piece dealt is the active one, held or locked
+	locked := gs.stats.TotalPlaced() - 1
+	if gs.holdPiece != NoPiece {
+		locked--
+	}
+	if got, want := blocks(gs.board)-len(gs.activeShape), 4*locked-gs.cols*gs.linesCleared; got != want {
+		t.Fatalf("step %d: %d blocks besides the piece, want %d from %d locked and %d lines cleared\n%v", step, got, want, locked, gs.linesCleared, gs.board)
+	}
+}
+
+// blocks returns the number of cells of b that aren't empty
+func blocks(b Board) int {
+	n := 0
+	for r := range b {
+		for c := range b[r] {
+			if b[r][c] != Empty {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func FuzzBoardTransitions(f *testing.F) {
+	// Each byte is a step of the game, its bits the actions held down
+	script := make([]byte, 600)
+	for i := range script {
+		down := scriptDown(i)
+		for a, d := range down {
+			if d {
+				script[i] |= 1 << a
+			}
+		}
+	}
+	hardDrop := byte(1 << input.ActionHardDrop)
+	hold := byte(1 << input.ActionHold)
+	f.Add([]byte{})
+	f.Add(script)
+	f.Add([]byte{hardDrop, 0, hardDrop, 0, hardDrop, 0, hardDrop})
+	f.Add([]byte{hold, 0, hold, 0, hardDrop, 0, hold, 0, hold})
+	f.Add([]byte{1, 1, 1, 1, 1, 1, 0, 4, 0, 4, 0, 4, 0, 4, 0, 64})
+	f.Add([]byte{2, 2, 2, 2, 2, 2, 0, 8, 0, 16, 0, 32, 32, 32, 32, 0, 64})
+	f.Add([]byte{255, 0, 255, 0, 255, 0, 255})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		gs := newTestGame(t)
+		checkTransition(t, gs, 0)
+		for i, b := range data {
+			gs.Update(fuzzInput(b), StepLength)
+			if gs.gameOver {
+				return
+			}
+			// The next piece isn't dealt until the cleared lines go
+			if !gs.clearAnim.active() {
+				checkTransition(t, gs, i+1)
+			}
+		}
+	})
+}
@@ -0,0 +1,528 @@
+// Package game holds the rules of the game: the board, the pieces and
+// everything that happens as they are moved, locked and cleared. It knows
+// nothing about windows or drawing, see the render package for that.
+package game
+
+import (
+	"math"
+
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// HiddenRows is the number of rows above the visible part of the board
+// that pieces spawn into
+const HiddenRows = 2
+
+// Point represents a coordinate on the game board with Point{row:0, col:0}
+// representing the bottom left
+type Point struct {
+	row int
+	col int
+}
+
+// Row returns the row of the point, counting up from the bottom
+func (p Point) Row() int { return p.row }
+
+// Col returns the column of the point, counting from the left
+func (p Point) Col() int { return p.col }
+
+// Board holds the entire game board pieces indexed by row and then column.
+// It has HiddenRows more rows than are shown on screen.
+type Board [][]Block
+
+// Block represents the color of the block
+type Block int
+
+// Different values a point on the grid can hold
+const (
+	Empty Block = iota
+	Goluboy
+	Siniy
+	Pink
+	Purple
+	Red
+	Yellow
+	Green
+	Gray
+	GoluboySpecial
+	SiniySpecial
+	PinkSpecial
+	PurpleSpecial
+	RedSpecial
+	YellowSpecial
+	GreenSpecial
+	GraySpecial
+)
+
+// GarbageBlock fills the garbage rows pushed up from the bottom of the
+// board. No piece is gray so garbage is never mistaken for a piece.
+const GarbageBlock = Gray
+
+// Piece is a constant for a shape of piece. There are 7 classic pieces like L, and O
+type Piece int
+
+// Various values that the pieces can be
+const (
+	IPiece Piece = iota
+	JPiece
+	LPiece
+	OPiece
+	SPiece
+	TPiece
+	ZPiece
+	NoPiece Piece = -1
+)
+
+// Shape is a type containing four points, which represents the four points
+// making a contiguous 'piece'.
+type Shape [4]Point
+
+// ClearType describes the kind of line clear a placement produces
+type ClearType int
+
+// Various kinds of line clears
+const (
+	ClearTypeNone ClearType = iota
+	ClearTypeSingle
+	ClearTypeDouble
+	ClearTypeTriple
+	ClearTypeTetris
+	ClearTypeTSpin
+)
+
+// TSpinType describes whether a placement is a T-spin and which kind
+type TSpinType int
+
+// Kinds of T-spin, following the 3-corner rule of the guideline
+const (
+	TSpinNone TSpinType = iota
+	TSpinMini           // 3 corners filled but not both in front of the T
+	TSpinFull           // 3 corners filled including both in front of the T
+)
+
+const linesPerLevel = 10 // Lines to clear to advance a level
+
+// StepLength is the seconds of game time simulated by each update
+const StepLength = 1.0 / 120
+
+// NextQueueLength is the number of upcoming pieces shown
+const NextQueueLength = 5
+
+const perfectClearDisplayTime = 1.5 // How long the perfect clear banner stays up
+
+const lineClearTime = 0.2     // How long full rows flash before they are deleted
+const lockFlashTime = 0.1     // How long a piece flashes white after locking
+const hardDropTrailTime = 0.3 // How long the trail behind a hard drop takes to fade
+const scorePopupSpeed = 1.5   // How fast line clear scores float up, in blocks a second
+const comboFadeTime = 1.5     // How long the combo fades out for after it was last extended
+const levelFlashTime = 0.4    // How long the board flashes when the level goes up
+const shakeTime = 0.2         // How long the board shakes after a hard drop
+const shakeAmplitude = 4.0    // How far the board shakes at first, in pixels
+const warningRows = 3         // How close to the top the stack gets before the player is warned
+const warningBlinkRate = 3.0  // How many times a second the warning blinks
+
+// Update advances the game by dt seconds, applying gravity, locking and
+// what the player does in this step, in. Nothing happens while the game is
+// paused.
+func (gs *GameState) Update(in input.InputState, dt float64) {
+	if gs.paused {
+		return
+	}
+
+	// Record every press and release for the replay
+	prevDown := gs.prevDown
+	gs.prevDown = in.Down
+	justPressed := func(a input.Action) bool { return in.Down[a] && !prevDown[a] }
+	justReleased := func(a input.Action) bool { return !in.Down[a] && prevDown[a] }
+	for a := input.Action(0); a < input.NumActions; a++ {
+		if justPressed(a) {
+			gs.replayEvents = append(gs.replayEvents, replay.ReplayEvent{Frame: gs.frame, EventType: replay.KeyDown, Key: uint8(a)})
+		} else if justReleased(a) {
+			gs.replayEvents = append(gs.replayEvents, replay.ReplayEvent{Frame: gs.frame, EventType: replay.KeyUp, Key: uint8(a)})
+		}
+	}
+	gs.frame++
+	if gs.frame%replay.HashInterval == 0 {
+		gs.frameHashes = append(gs.frameHashes, gs.board.Hash())
+	}
+
+	gs.elapsedTime += dt
+	gs.speed.record(dt, gs.linesCleared, gs.stats.TotalPlaced())
+	gs.scores.record(dt, gs.elapsedTime, gs.score, gs.linesCleared)
+
+	// A mode that reaches its goal ends the game with the piece where it is
+	gs.mode.OnTick(gs, dt)
+	if gs.checkModeOver() {
+		return
+	}
+	gs.checkAchievements()
+	gs.updateAchievementToast(dt)
+
+	// The lock flash keeps going while cleared rows flash
+	if gs.lockFlash.active {
+		gs.lockFlash.timer -= dt
+		if gs.lockFlash.timer <= 0 {
+			gs.lockFlash = lockFlashEffect{}
+		}
+	}
+
+	if gs.levelFlash.active {
+		gs.levelFlash.timer -= dt
+		if gs.levelFlash.timer <= 0 {
+			gs.levelFlash = levelFlashEffect{}
+		}
+	}
+
+	if gs.shakeTimer > 0 {
+		gs.shakeTimer = math.Max(gs.shakeTimer-dt, 0)
+	}
+
+	// Warn the player for as long as the stack is close to the top
+	gs.updateMaxHeight()
+	if gs.maxHeight >= gs.rows-warningRows {
+		gs.warningBlink += dt
+	} else {
+		gs.warningBlink = 0
+	}
+
+	// Fade the hard drop trail and drop the parts that are gone
+	trail := gs.hardDropTrail[:0]
+	for _, seg := range gs.hardDropTrail {
+		seg.Alpha -= dt / hardDropTrailTime
+		if seg.Alpha > 0 {
+			trail = append(trail, seg)
+		}
+	}
+	gs.hardDropTrail = trail
+
+	// Float the line clear scores up as they fade over a second
+	popups := gs.scorePopups[:0]
+	for _, p := range gs.scorePopups {
+		p.Y += scorePopupSpeed * dt
+		p.Alpha -= dt
+		if p.Alpha > 0 {
+			popups = append(popups, p)
+		}
+	}
+	gs.scorePopups = popups
+
+	// Hold everything else still until the cleared rows are deleted
+	if gs.clearAnim.active() {
+		gs.clearAnim.timer -= dt
+		if gs.clearAnim.timer <= 0 {
+			gs.finishLineClear()
+		}
+		return
+	}
+
+	gs.gravityTimer += dt
+
+	// Update lock delay timer if piece is on ground
+	if gs.isTouchingFloor() {
+		gs.lockDelayTimer += dt
+		if gs.lockDelayTimer >= gs.lockDelay {
+			gs.lockPiece()
+			gs.lockDelayTimer = 0
+			gs.lockResets = 0
+		}
+	} else {
+		gs.lockDelayTimer = 0
+	}
+
+	// Time Functions:
+	// Gravity, which the soft drop takes over from while it is held
+	if !in.SoftDrop && gs.gravityTimer > gs.gravitySpeed {
+		gs.gravityTimer = 0 // Reset completely for more consistent timing
+		gs.applyGravity()
+	}
+
+	// Move and rotate presses are counted for finesse
+	for _, a := range []input.Action{input.ActionMoveLeft, input.ActionMoveRight, input.ActionRotateCW, input.ActionRotateCCW, input.ActionRotate180} {
+		if justPressed(a) {
+			gs.moveCount++
+		}
+	}
+
+	// Moves from presses and from DAS/ARR
+	if in.MoveLeft {
+		gs.processMoveWithBounce(-1)
+	}
+	if in.MoveRight {
+		gs.processMoveWithBounce(1)
+	}
+
+	// Update rotation cooldown
+	if gs.rotationCooldown > 0 {
+		gs.rotationCooldown -= dt
+	}
+
+	// Faster, more responsive soft drop
+	if justPressed(input.ActionSoftDrop) {
+		gs.gravitySpeed = gs.settings.SoftDropSpeed
+		gs.softDropFrictionTimer = 0
+		gs.lastSoftDropTime = 0
+
+		// Immediate drop for responsiveness
+		gs.score += gs.applyGravity()
+		gs.softDropTimer = 0
+	}
+
+	if in.SoftDrop {
+		// More responsive soft drop system
+		if gs.softDropFrictionTimer > 0 {
+			gs.softDropFrictionTimer -= dt * 2 // Faster friction reduction
+		}
+
+		gs.lastSoftDropTime += dt
+
+		// More aggressive friction reduction for smoother continuous drops
+		if gs.lastSoftDropTime > 0.15 && gs.softDropFrictionTimer > 0 {
+			gs.softDropFrictionTimer = 0 // Just clear it completely after a short delay
+		}
+
+		// Apply soft drop gravity with less friction, a row every
+		// SoftDropSpeed seconds. Soft drops score a point a row.
+		gs.softDropTimer += dt
+		if gs.softDropFrictionTimer <= 0 && gs.softDropTimer >= gs.settings.SoftDropSpeed {
+			gs.softDropTimer = 0
+			gs.gravityTimer = 0
+			rows := gs.applyGravity()
+			gs.score += rows
+			if rows == 0 {
+				gs.softDropFrictionTimer = gs.settings.SoftDropFriction
+				gs.lastSoftDropTime = 0
+			}
+		}
+	}
+
+	if justReleased(input.ActionSoftDrop) {
+		gs.gravitySpeed = gs.baseSpeed
+		gs.softDropFrictionTimer = 0
+	}
+
+	// More responsive rotation with reduced cooldown
+	if in.RotateCW {
+		if gs.rotationCooldown <= 0 {
+			rotationSucceeded := gs.rotatePiece(1) // Clockwise rotation
+			if rotationSucceeded {
+				gs.rotationDirection = 1
+
+				// Reset lock delay if rotated and on ground
+				if gs.isTouchingFloor() && gs.lockResets < gs.maxLockResets {
+					gs.lockDelayTimer = 0
+					gs.lockResets++
+				}
+
+				// Shorter rotation cooldown for more responsive feel
+				gs.rotationCooldown = 0.03
+			}
+		}
+	}
+
+	if in.RotateCCW {
+		if gs.rotationCooldown <= 0 {
+			rotationSucceeded := gs.rotatePiece(-1) // Counter-clockwise rotation
+			if rotationSucceeded {
+				gs.rotationDirection = -1
+
+				// Reset lock delay if rotated and on ground
+				if gs.isTouchingFloor() && gs.lockResets < gs.maxLockResets {
+					gs.lockDelayTimer = 0
+					gs.lockResets++
+				}
+
+				// Shorter rotation cooldown for more responsive feel
+				gs.rotationCooldown = 0.03
+			}
+		}
+	}
+
+	if in.Rotate180 {
+		if gs.rotationCooldown <= 0 {
+			rotationSucceeded := gs.rotatePiece180()
+			if rotationSucceeded {
+				gs.rotationDirection = 2
+
+				// Reset lock delay if rotated and on ground
+				if gs.isTouchingFloor() && gs.lockResets < gs.maxLockResets {
+					gs.lockDelayTimer = 0
+					gs.lockResets++
+				}
+
+				gs.rotationCooldown = 0.03
+			}
+		}
+	}
+
+	// More responsive hard drop
+	if in.HardDrop {
+		// Skip the visual feedback drop and go straight to hard drop for
+		// immediate response. Hard drops score two points a row.
+		gs.score += 2 * gs.instafall()
+	}
+
+	// More responsive hold
+	if in.Hold && gs.canHold {
+		gs.holdCurrentPiece()
+	}
+
+	if gs.perfectClearTimer > 0 {
+		gs.perfectClearTimer -= dt
+	}
+	if gs.comboTimer > 0 {
+		gs.comboTimer -= dt
+	}
+
+	// Enhanced visual feedback
+	if gs.visualFeedbackActive {
+		gs.lastTapTime += dt
+		if gs.lastTapTime > 0.08 { // Shorter duration for snappier feedback
+			gs.visualFeedbackActive = false
+		}
+	}
+}
+
+// checkModeOver ends the game once the goal of its mode has been reached
+// and reports whether it has
+func (gs *GameState) checkModeOver() bool {
+	if !gs.mode.IsOver(gs) {
+		return false
+	}
+	gs.gameOver = true
+	gs.modeComplete = true
+	return true
+}
+
+// applyLevelSpeed sets gravity to the speed of the current level, keeping a
+// soft drop in progress going
+func (gs *GameState) applyLevelSpeed() {
+	softDropping := gs.gravitySpeed != gs.baseSpeed
+	gs.baseSpeed = levelSpeed(gs.level)
+	if !softDropping {
+		gs.gravitySpeed = gs.baseSpeed
+	}
+}
+
+// levelSpeed returns the time in seconds between gravity steps at level
+func levelSpeed(level int) float64 {
+	return math.Max(0.8-float64(level-1)*0.07, 0.05)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// pieceNames are the letters the pieces are known by, indexed by Piece
+var pieceNames = [7]string{"I", "J", "L", "O", "S", "T", "Z"}
+
+// PieceName returns the letter piece p is known by, such as "T", or an
+// empty string for NoPiece
+func PieceName(p Piece) string {
+	if p < IPiece || p > ZPiece {
+		return ""
+	}
+	return pieceNames[p]
+}
+
+// PieceBlock associates a pieces shape (Piece) with it's color/image (Block).
+func PieceBlock(p Piece) Block {
+	switch p {
+	case LPiece:
+		return Goluboy
+	case IPiece:
+		return Siniy
+	case OPiece:
+		return Pink
+	case TPiece:
+		return Purple
+	case SPiece:
+		return Red
+	case ZPiece:
+		return Yellow
+	case JPiece:
+		return Green
+	}
+	panic("PieceBlock: Invalid piece passed in")
+}
+
+// classifyTSpin checks if a T-spin was performed for scoring. The front
+// corners are the two on the side the T points to, which follows from the
+// rotation state: 0 points down, 1 right, 2 up and 3 left.
+func (gs *GameState) classifyTSpin(board Board) TSpinType {
+	// Only check for T-spins with T pieces
+	if gs.currentPiece != TPiece || !gs.lastMovementWasRotation {
+		return TSpinNone
+	}
+
+	// For a T-spin, at least 3 of the 4 corners around the T's center must be blocked
+	centerRow := gs.activeShape[1].row
+	centerCol := gs.activeShape[1].col
+
+	// Check each of the 4 corners around the T's center
+	corners := [][2]int{
+		{centerRow + 1, centerCol + 1}, // top-right
+		{centerRow + 1, centerCol - 1}, // top-left
+		{centerRow - 1, centerCol + 1}, // bottom-right
+		{centerRow - 1, centerCol - 1}, // bottom-left
+	}
+
+	// Indexes into corners of the two front corners for each rotation state
+	front := [4][2]int{
+		{2, 3}, // down: bottom-right, bottom-left
+		{0, 2}, // right: top-right, bottom-right
+		{0, 1}, // up: top-right, top-left
+		{1, 3}, // left: top-left, bottom-left
+	}
+
+	var blocked [4]bool
+	blockedCorners := 0
+	for i, corner := range corners {
+		r, c := corner[0], corner[1]
+		// Check if corner is blocked (either by wall or another block)
+		if r < 0 || r >= board.Rows() || c < 0 || c >= board.Cols() || board[r][c] != Empty {
+			blocked[i] = true
+			blockedCorners++
+		}
+	}
+
+	// Require at least 3 corners to be blocked for a T-spin
+	if blockedCorners < 3 {
+		return TSpinNone
+	}
+	f := front[gs.rotationState]
+	if blocked[f[0]] && blocked[f[1]] {
+		return TSpinFull
+	}
+	return TSpinMini
+}
+
+// processMoveWithBounce processes directional movement with debouncing to prevent input stuttering
+func (gs *GameState) processMoveWithBounce(direction int) bool {
+	// Always move at least once for snappy feel
+	moveSucceeded := gs.movePiece(direction)
+
+	if moveSucceeded {
+		gs.lastTapTime = 0
+		gs.visualFeedbackActive = true
+
+		// Reset lock delay if moved and on ground
+		if gs.isTouchingFloor() && gs.lockResets < gs.maxLockResets {
+			gs.lockDelayTimer = 0
+			gs.lockResets++
+		}
+		return true
+	}
+
+	return false
+}
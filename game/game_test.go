@@ -0,0 +1,432 @@
+package game
+
+import (
+	"go/build"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// softDropRows holds soft drop in a new game for seconds and returns the
+// number of rows the piece fell
+func softDropRows(t *testing.T, speed, seconds float64) int {
+	t.Helper()
+	settings := config.DefaultSettings()
+	settings.SoftDropSpeed = speed
+	gs := NewSeededGameState(ModeMarathon, settings, 1)
+	top := shapeBottomRow(gs.activeShape)
+	for i := 0; i < int(seconds/StepLength); i++ {
+		gs.Update(input.InputState{Down: [input.NumActions]bool{input.ActionSoftDrop: true}, SoftDrop: true}, StepLength)
+	}
+	return top - shapeBottomRow(gs.activeShape)
+}
+
+func TestSoftDropSpeed(t *testing.T) {
+	// One row straight away, then a row every SoftDropSpeed seconds
+	tests := []struct {
+		speed    float64
+		min, max int
+	}{
+		{0.1, 10, 11},
+		{0.2, 5, 6},
+		{0.25, 4, 5},
+	}
+	for _, tt := range tests {
+		if rows := softDropRows(t, tt.speed, 1); rows < tt.min || rows > tt.max {
+			t.Errorf("soft drop at %v seconds a row fell %d rows in a second, want %d to %d", tt.speed, rows, tt.min, tt.max)
+		}
+	}
+}
+
+func TestLineClearAnimation(t *testing.T) {
+	gs := newTestGame(t)
+	gs.board = newBoard(gs.rows, gs.cols)
+	for c := 0; c < gs.cols-1; c++ {
+		gs.board[0][c] = Gray
+	}
+	col := gs.cols - 1
+	shape := Shape{{row: 0, col: col}, {row: 1, col: col}, {row: 2, col: col}, {row: 3, col: col}}
+	placePiece(gs, IPiece, shape, 1)
+	gs.lockPiece()
+
+	// The full row flashes on the board before it is deleted, and nothing
+	// moves until it is
+	rows, _ := gs.ClearingRows()
+	if len(rows) != 1 || rows[0] != 0 {
+		t.Fatalf("ClearingRows() = %v, want [0]", rows)
+	}
+	for i := 0; i < int(lineClearTime/StepLength)-1; i++ {
+		gs.Update(input.InputState{HardDrop: true}, StepLength)
+		if gs.board[0][0] != Gray || gs.board[0][col] != PieceBlock(IPiece) {
+			t.Fatalf("row 0 was deleted after %d steps, before the animation ended", i+1)
+		}
+	}
+	if gs.stats.HardDrops != 0 {
+		t.Error("a piece was dropped during the animation")
+	}
+
+	for i := 0; i < 2; i++ {
+		gs.Update(input.InputState{}, StepLength)
+	}
+	if rows, _ := gs.ClearingRows(); len(rows) != 0 {
+		t.Fatalf("ClearingRows() = %v after the animation, want none", rows)
+	}
+	if gs.board[0][0] != Empty || gs.board[0][col] != PieceBlock(IPiece) || gs.board[3][col] != Empty {
+		t.Errorf("row 0 wasn't deleted after the animation:\n%v", gs.board)
+	}
+}
+
+func TestNarrowBoard(t *testing.T) {
+	settings := config.DefaultSettings()
+	settings.Rows, settings.Cols = 20, 8
+	gs := NewSeededGameState(ModeMarathon, settings, 1)
+	if gs.board.Rows() != 20+HiddenRows || gs.board.Cols() != 8 {
+		t.Fatalf("board is %dx%d, want %dx8", gs.board.Rows(), gs.board.Cols(), 20+HiddenRows)
+	}
+
+	// The walls are where the narrower board ends
+	spawnPiece(gs, IPiece)
+	if col := shapeLeftCol(gs.activeShape); col != 2 {
+		t.Errorf("I spawned at column %d, want 2", col)
+	}
+	moves := 0
+	for gs.movePiece(1) {
+		moves++
+	}
+	if moves != 2 || shapeLeftCol(gs.activeShape) != 4 {
+		t.Errorf("I moved right %d times to column %d, want 2 to column 4", moves, shapeLeftCol(gs.activeShape))
+	}
+	for gs.movePiece(-1) {
+	}
+	if col := shapeLeftCol(gs.activeShape); col != 0 {
+		t.Errorf("I moved left to column %d, want 0", col)
+	}
+
+	// Gravity takes it all the way to the floor
+	rows := 0
+	for gs.applyGravity() > 0 {
+		rows++
+	}
+	if bottom := shapeBottomRow(gs.activeShape); bottom != 0 || rows != gs.rows+HiddenRows-1 {
+		t.Errorf("I fell %d rows to row %d, want %d to row 0", rows, bottom, gs.rows+HiddenRows-1)
+	}
+
+	// A full row is only 8 blocks wide
+	lockClear(t, gs, 2)
+	if gs.board[2][0] != Gray || gs.board[4][0] != Empty {
+		t.Errorf("blocks above the cleared rows didn't fall 2 rows:\n%v", gs.board)
+	}
+}
+
+func TestClassifyTSpin(t *testing.T) {
+	// The corners of a T centered at row 2, column 2, or at the edge
+	// where a column or row is given
+	tests := []struct {
+		name     string
+		board    string
+		state    int
+		row, col int
+		moved    bool // Moved rather than rotated into place
+		notT     bool // An S in the T's place
+		want     TSpinType
+	}{
+		{name: "no corners", board: ".....\n.....\n.....\n.....\n.....", want: TSpinNone},
+		{name: "two front corners", board: ".....\n.....\n.....\n.X.X.\n.....", want: TSpinNone},
+		{name: "down, three corners", board: ".....\n.X...\n.....\n.X.X.\n.....", want: TSpinFull},
+		{name: "down, back corners", board: ".....\n.X.X.\n.....\n.X...\n.....", want: TSpinMini},
+		{name: "four corners", board: ".....\n.X.X.\n.....\n.X.X.\n.....", state: 3, want: TSpinFull},
+		{name: "up, three corners", board: ".....\n.X.X.\n.....\n.X...\n.....", state: 2, want: TSpinFull},
+		{name: "up, back corners", board: ".....\n.X...\n.....\n.X.X.\n.....", state: 2, want: TSpinMini},
+		{name: "right, three corners", board: ".....\n.X.X.\n.....\n...X.\n.....", state: 1, want: TSpinFull},
+		{name: "right, back corners", board: ".....\n.X.X.\n.....\n.X...\n.....", state: 1, want: TSpinMini},
+		{name: "left, three corners", board: ".....\n.X...\n.....\n.X.X.\n.....", state: 3, want: TSpinFull},
+		{name: "left, back corners", board: ".....\n...X.\n.....\n.X.X.\n.....", state: 3, want: TSpinMini},
+		{name: "right against the wall", board: ".....\n.X...\n.....\n.....\n.....", state: 1, col: -2, want: TSpinMini},
+		{name: "left against the wall", board: ".....\n.....\n.....\n...X.\n.....", state: 3, col: 2, want: TSpinMini},
+		{name: "down on the floor", board: ".....\n.....\n.....\n.X...\n.....", row: -2, want: TSpinFull},
+		{name: "moved in", board: ".....\n.X.X.\n.....\n.X.X.\n.....", moved: true, want: TSpinNone},
+		{name: "not a T", board: ".....\n.X.X.\n.....\n.X.X.\n.....", notT: true, want: TSpinNone},
+	}
+	for _, tt := range tests {
+		gs := newTestGame(t)
+		s := shapeInState(TPiece, tt.state)
+		s = moveShape(2+tt.row-s[1].row, 2+tt.col-s[1].col, s)
+		gs.currentPiece = TPiece
+		if tt.notT {
+			gs.currentPiece = SPiece
+		}
+		gs.activeShape = s
+		gs.rotationState = tt.state
+		gs.lastMovementWasRotation = !tt.moved
+		if got := gs.classifyTSpin(mustBoard(t, tt.board)); got != tt.want {
+			t.Errorf("%s: classifyTSpin() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDropScoring(t *testing.T) {
+	// Soft drop scores a point a row
+	gs := newTestGame(t)
+	top := shapeBottomRow(gs.activeShape)
+	softDrop := input.InputState{Down: [input.NumActions]bool{input.ActionSoftDrop: true}, SoftDrop: true}
+	for i := 0; i < int(0.5/StepLength); i++ {
+		gs.Update(softDrop, StepLength)
+	}
+	rows := top - shapeBottomRow(gs.activeShape)
+	if rows == 0 || gs.score != rows {
+		t.Errorf("soft drop of %d rows scored %d, want %d", rows, gs.score, rows)
+	}
+
+	// Hard drop two a row, onto the floor and onto a stack
+	for _, stack := range []int{0, 5} {
+		gs := newTestGame(t)
+		for r := 0; r < stack; r++ {
+			for c := 1; c < gs.cols; c++ {
+				gs.board[r][c] = Gray
+			}
+		}
+		rows := shapeBottomRow(gs.activeShape) - stack
+		gs.Update(input.InputState{HardDrop: true}, StepLength)
+		if gs.score != 2*rows {
+			t.Errorf("hard drop of %d rows onto a stack %d high scored %d, want %d", rows, stack, gs.score, 2*rows)
+		}
+	}
+}
+
+func TestGravityDoesntScore(t *testing.T) {
+	gs := newTestGame(t)
+	for gs.stats.TotalPlaced() < 3 {
+		gs.Update(input.InputState{}, StepLength)
+	}
+	if gs.score != 0 {
+		t.Errorf("pieces left to fall and lock scored %d, want 0", gs.score)
+	}
+}
+
+// wait runs gs for steps steps without any input
+func wait(gs *GameState, steps int) {
+	for i := 0; i < steps; i++ {
+		gs.Update(input.InputState{}, StepLength)
+	}
+}
+
+// flashSteps is the number of steps an effect lasting seconds takes
+func flashSteps(seconds float64) int {
+	return int(math.Round(seconds / StepLength))
+}
+
+func TestLockFlash(t *testing.T) {
+	gs := newTestGame(t)
+	piece := gs.currentPiece
+	landed := moveShape(-shapeBottomRow(gs.activeShape), 0, gs.activeShape)
+	if _, _, active := gs.LockFlash(); active {
+		t.Fatal("lock flash before anything locked")
+	}
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	shape, block, active := gs.LockFlash()
+	if !active || shape != landed || block != PieceBlock(piece) {
+		t.Fatalf("LockFlash() = %v, %v, %t after a hard drop, want %v, %v, true", shape, block, active, landed, PieceBlock(piece))
+	}
+
+	// It lasts lockFlashTime
+	wait(gs, flashSteps(lockFlashTime)-2)
+	if _, _, active := gs.LockFlash(); !active {
+		t.Error("lock flash ended early")
+	}
+	wait(gs, 3)
+	if _, _, active := gs.LockFlash(); active {
+		t.Errorf("lock flash still on after %v seconds", lockFlashTime)
+	}
+}
+
+func TestHardDropTrail(t *testing.T) {
+	gs := newTestGame(t)
+	start := gs.activeShape
+	rows := shapeBottomRow(start)
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+
+	// Every cell the piece fell through, brightest just above where it
+	// landed
+	trail := gs.HardDropTrail()
+	if len(trail) != 4*rows {
+		t.Fatalf("trail of %d segments after a %d row drop, want %d", len(trail), rows, 4*rows)
+	}
+	for i, seg := range trail {
+		fell := i/4 + 1
+		want := 0.8 * float64(fell) / float64(rows)
+		if !shapeContains(moveShape(-(fell-1), 0, start), Point{row: seg.Row, col: seg.Col}) || math.Abs(seg.Alpha-want) > 1e-9 {
+			t.Errorf("segment %d = %+v, want a cell of the piece %d rows down at alpha %v", i, seg, fell-1, want)
+		}
+	}
+
+	// It fades out within hardDropTrailTime
+	wait(gs, flashSteps(hardDropTrailTime/2))
+	faded := gs.HardDropTrail()
+	if len(faded) == 0 || faded[len(faded)-1].Alpha >= 0.8 {
+		t.Errorf("trail %v half way through, want it fading", faded)
+	}
+	wait(gs, flashSteps(hardDropTrailTime/2))
+	if len(gs.HardDropTrail()) != 0 {
+		t.Errorf("trail %v after %v seconds, want it gone", gs.HardDropTrail(), hardDropTrailTime)
+	}
+
+	// A piece already on the floor leaves none
+	for gs.applyGravity() > 0 {
+	}
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	if len(gs.HardDropTrail()) != 0 {
+		t.Errorf("hard drop of no rows left the trail %v", gs.HardDropTrail())
+	}
+}
+
+func TestScorePopups(t *testing.T) {
+	gs := newTestGame(t)
+	lockClear(t, gs, 0)
+	if len(gs.ScorePopups()) != 0 {
+		t.Fatalf("lock without a clear showed %v", gs.ScorePopups())
+	}
+
+	// Just above the top cleared row, in the middle of the board
+	lockClear(t, gs, 2)
+	want := []ScorePopup{{Text: "+400", X: 5, Y: 2, Alpha: 1}}
+	if got := gs.ScorePopups(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("popups after a double = %+v, want %+v", got, want)
+	}
+
+	// Rising and fading over a second
+	wait(gs, flashSteps(0.5))
+	p := gs.ScorePopups()
+	if len(p) != 1 || math.Abs(p[0].Y-(2+scorePopupSpeed/2)) > 1e-9 || math.Abs(p[0].Alpha-0.5) > 1e-9 {
+		t.Errorf("popups half a second later = %+v, want one at Y %v and alpha 0.5", p, 2+scorePopupSpeed/2)
+	}
+	wait(gs, flashSteps(0.5)+1)
+	if len(gs.ScorePopups()) != 0 {
+		t.Errorf("popups a second later = %+v, want none", gs.ScorePopups())
+	}
+}
+
+func TestComboIndicator(t *testing.T) {
+	gs := newTestGame(t)
+	lockClear(t, gs, 1)
+	lockClear(t, gs, 1)
+	if combo, fade := gs.Combo(); combo != 0 || fade != 0 {
+		t.Errorf("Combo() = %d, %v after a combo of 1, want nothing shown", combo, fade)
+	}
+	lockClear(t, gs, 1)
+	if combo, fade := gs.Combo(); combo != 2 || fade != 1 {
+		t.Errorf("Combo() = %d, %v after a combo of 2, want 2, 1", combo, fade)
+	}
+
+	// Breaking the combo leaves it to fade out
+	lockClear(t, gs, 0)
+	wait(gs, flashSteps(comboFadeTime/2))
+	if combo, fade := gs.Combo(); combo != 2 || math.Abs(fade-0.5) > 1e-9 {
+		t.Errorf("Combo() = %d, %v half way through fading, want 2, 0.5", combo, fade)
+	}
+	wait(gs, flashSteps(comboFadeTime/2)+1)
+	if _, fade := gs.Combo(); fade > 0 {
+		t.Errorf("combo fade %v after %v seconds, want it gone", fade, comboFadeTime)
+	}
+}
+
+func TestBackToBackIndicator(t *testing.T) {
+	tests := []struct {
+		lines  int
+		active bool
+		count  int
+	}{
+		{4, true, 0},
+		{0, true, 0},
+		{4, true, 1},
+		{4, true, 2},
+		{1, false, 0},
+		{4, true, 0},
+	}
+	gs := newTestGame(t)
+	for i, tt := range tests {
+		lockClear(t, gs, tt.lines)
+		if active, count := gs.BackToBack(); active != tt.active || count != tt.count {
+			t.Errorf("lock %d clearing %d lines: BackToBack() = %t, %d, want %t, %d", i, tt.lines, active, count, tt.active, tt.count)
+		}
+	}
+}
+
+func TestLevelFlash(t *testing.T) {
+	gs := newTestGame(t)
+	gs.linesCleared = linesPerLevel - 2
+	lockClear(t, gs, 1)
+	if level, fade := gs.LevelFlash(); level != 1 || fade != 0 {
+		t.Errorf("LevelFlash() = %d, %v without a level up, want 1, 0", level, fade)
+	}
+	lockClear(t, gs, 1)
+	if level, fade := gs.LevelFlash(); level != 2 || fade != 1 {
+		t.Errorf("LevelFlash() = %d, %v at the level up, want 2, 1", level, fade)
+	}
+	wait(gs, flashSteps(levelFlashTime/2))
+	if level, fade := gs.LevelFlash(); level != 2 || math.Abs(fade-0.5) > 1e-9 {
+		t.Errorf("LevelFlash() = %d, %v half way through, want 2, 0.5", level, fade)
+	}
+	wait(gs, flashSteps(levelFlashTime/2)+1)
+	if level, fade := gs.LevelFlash(); level != 2 || fade != 0 {
+		t.Errorf("LevelFlash() = %d, %v after %v seconds, want 2, 0", level, fade, levelFlashTime)
+	}
+}
+
+func TestShakeTimer(t *testing.T) {
+	gs := newTestGame(t)
+	if gs.Shake() != 0 {
+		t.Fatalf("Shake() = %v before a hard drop, want 0", gs.Shake())
+	}
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	if gs.shakeTimer != shakeTime || gs.Shake() != shakeAmplitude {
+		t.Fatalf("after a hard drop the shake timer is %v and Shake() %v, want %v and %v", gs.shakeTimer, gs.Shake(), shakeTime, shakeAmplitude)
+	}
+
+	// It counts down by each step and stops at 0
+	for i := 1; i <= flashSteps(shakeTime)+10; i++ {
+		wait(gs, 1)
+		want := math.Max(shakeTime-float64(i)*StepLength, 0)
+		if math.Abs(gs.shakeTimer-want) > 1e-9 || gs.shakeTimer < 0 {
+			t.Fatalf("shake timer %v after %d steps, want %v", gs.shakeTimer, i, want)
+		}
+		if wantShake := shakeAmplitude * gs.shakeTimer / shakeTime; gs.Shake() != wantShake {
+			t.Fatalf("Shake() = %v with %v seconds left, want %v", gs.Shake(), gs.shakeTimer, wantShake)
+		}
+	}
+	if gs.Shake() != 0 {
+		t.Errorf("Shake() = %v after %v seconds, want 0", gs.Shake(), shakeTime)
+	}
+}
+
+func TestNoPixelDependency(t *testing.T) {
+	// The game and the packages of this module it uses, but not their tests,
+	// must build without a window or anything else of the platform, so
+	// they build for the browser as they are
+	const module = "github.com/zkry/golang-tetris"
+	seen := map[string]bool{module + "/game": true}
+	queue := []string{module + "/game"}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		pkg, err := build.Default.ImportDir(filepath.Join("..", strings.TrimPrefix(path, module)), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range pkg.Imports {
+			for _, platform := range []string{"github.com/faiface/pixel", "github.com/faiface/beep", "github.com/veandco/go-sdl2", "syscall/js"} {
+				if strings.HasPrefix(imp, platform) {
+					t.Errorf("%s imports %s", path, imp)
+				}
+			}
+			if strings.HasPrefix(imp, module+"/") && !seen[imp] {
+				seen[imp] = true
+				queue = append(queue, imp)
+			}
+		}
+	}
+}
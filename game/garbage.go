@@ -0,0 +1,66 @@
+package game
+
+// GarbageTable is the garbage lines a lock sends to the opponent in a
+// versus game, following the guideline attack table
+type GarbageTable struct {
+	// Lines sent for clearing 0 to 4 lines at once
+	Lines [5]int
+
+	// Lines sent for a T-spin clearing 0 to 3 lines, and for a mini T-spin
+	// clearing 0 to 2 lines
+	TSpinLines     [4]int
+	TSpinMiniLines [3]int
+
+	// Lines added to a Tetris or T-spin that follows another
+	BackToBackBonus int
+
+	// Lines sent for clearing every block off the board, in place of
+	// everything else the clear would send
+	PerfectClear int
+}
+
+// DefaultGarbageTable returns the guideline attack table
+func DefaultGarbageTable() GarbageTable {
+	return GarbageTable{
+		Lines:           [5]int{0, 0, 1, 2, 4},
+		TSpinLines:      [4]int{0, 2, 4, 6},
+		TSpinMiniLines:  [3]int{0, 0, 1},
+		BackToBackBonus: 1,
+		PerfectClear:    10,
+	}
+}
+
+// Calculate returns the garbage lines sent by a lock that cleared lines
+// lines with a T-spin of tSpinType. combo, btbActive and perfectClear are as
+// for Scorer.Award: combo counts the clears in a row after the first, and a
+// combo adds a line for each clear in a row past the second.
+func (t GarbageTable) Calculate(lines int, tSpinType TSpinType, combo int, btbActive bool, perfectClear bool) int {
+	if lines <= 0 {
+		return 0
+	}
+	if perfectClear {
+		return t.PerfectClear
+	}
+
+	sent := t.Lines[minInt(lines, len(t.Lines)-1)]
+	switch tSpinType {
+	case TSpinFull:
+		sent = t.TSpinLines[minInt(lines, len(t.TSpinLines)-1)]
+	case TSpinMini:
+		sent = t.TSpinMiniLines[minInt(lines, len(t.TSpinMiniLines)-1)]
+	}
+	if btbActive && (lines == 4 || tSpinType != TSpinNone) {
+		sent += t.BackToBackBonus
+	}
+	return sent + maxInt(combo-1, 0)
+}
+
+// lineClear is what a lock that cleared lines did, as the garbage table
+// weighs it
+type lineClear struct {
+	lines        int
+	tSpinType    TSpinType
+	combo        int
+	btbActive    bool // Whether the clear before it was a Tetris or T-spin
+	perfectClear bool
+}
@@ -0,0 +1,91 @@
+package game
+
+import "testing"
+
+func TestDefaultGarbageTable(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        int
+		tSpin        TSpinType
+		combo        int
+		btbActive    bool
+		perfectClear bool
+		want         int
+	}{
+		// Line clears
+		{"nothing", 0, TSpinNone, -1, false, false, 0},
+		{"single", 1, TSpinNone, 0, false, false, 0},
+		{"double", 2, TSpinNone, 0, false, false, 1},
+		{"triple", 3, TSpinNone, 0, false, false, 2},
+		{"tetris", 4, TSpinNone, 0, false, false, 4},
+
+		// T-spins
+		{"t-spin no lines", 0, TSpinFull, -1, false, false, 0},
+		{"t-spin single", 1, TSpinFull, 0, false, false, 2},
+		{"t-spin double", 2, TSpinFull, 0, false, false, 4},
+		{"t-spin triple", 3, TSpinFull, 0, false, false, 6},
+		{"mini t-spin no lines", 0, TSpinMini, -1, false, false, 0},
+		{"mini t-spin single", 1, TSpinMini, 0, false, false, 0},
+		{"mini t-spin double", 2, TSpinMini, 0, false, false, 1},
+
+		// Back to back only counts for the clears that keep it going
+		{"back to back single", 1, TSpinNone, 0, true, false, 0},
+		{"back to back double", 2, TSpinNone, 0, true, false, 1},
+		{"back to back triple", 3, TSpinNone, 0, true, false, 2},
+		{"back to back tetris", 4, TSpinNone, 0, true, false, 5},
+		{"back to back t-spin single", 1, TSpinFull, 0, true, false, 3},
+		{"back to back t-spin double", 2, TSpinFull, 0, true, false, 5},
+		{"back to back t-spin triple", 3, TSpinFull, 0, true, false, 7},
+		{"back to back mini t-spin single", 1, TSpinMini, 0, true, false, 1},
+		{"back to back mini t-spin double", 2, TSpinMini, 0, true, false, 2},
+
+		// Combos add combo-1 lines
+		{"single combo 1", 1, TSpinNone, 1, false, false, 0},
+		{"single combo 2", 1, TSpinNone, 2, false, false, 1},
+		{"single combo 5", 1, TSpinNone, 5, false, false, 4},
+		{"double combo 3", 2, TSpinNone, 3, false, false, 3},
+		{"tetris combo 10", 4, TSpinNone, 10, false, false, 13},
+		{"back to back t-spin double combo 4", 2, TSpinFull, 4, true, false, 8},
+
+		// A perfect clear sends 10 lines whatever else the clear was
+		{"perfect clear single", 1, TSpinNone, 0, false, true, 10},
+		{"perfect clear tetris", 4, TSpinNone, 0, false, true, 10},
+		{"perfect clear back to back tetris combo", 4, TSpinNone, 3, true, true, 10},
+		{"perfect clear t-spin double", 2, TSpinFull, 0, false, true, 10},
+	}
+	table := DefaultGarbageTable()
+	for _, tt := range tests {
+		if got := table.Calculate(tt.lines, tt.tSpin, tt.combo, tt.btbActive, tt.perfectClear); got != tt.want {
+			t.Errorf("%s: Calculate(%d, %v, %d, %t, %t) = %d, want %d", tt.name, tt.lines, tt.tSpin, tt.combo, tt.btbActive, tt.perfectClear, got, tt.want)
+		}
+	}
+}
+
+func TestGarbageTableCustom(t *testing.T) {
+	// Every entry of a table is used, so a mode can send its own amounts
+	table := GarbageTable{
+		Lines:           [5]int{0, 1, 2, 3, 5},
+		TSpinLines:      [4]int{1, 3, 5, 7},
+		TSpinMiniLines:  [3]int{0, 1, 2},
+		BackToBackBonus: 2,
+		PerfectClear:    6,
+	}
+	tests := []struct {
+		lines     int
+		tSpin     TSpinType
+		btbActive bool
+		perfect   bool
+		want      int
+	}{
+		{1, TSpinNone, false, false, 1},
+		{4, TSpinNone, true, false, 7},
+		{2, TSpinFull, false, false, 5},
+		{1, TSpinMini, true, false, 3},
+		{3, TSpinNone, false, true, 6},
+	}
+	for _, tt := range tests {
+		if got := table.Calculate(tt.lines, tt.tSpin, 0, tt.btbActive, tt.perfect); got != tt.want {
+			t.Errorf("Calculate(%d, %v, 0, %t, %t) = %d, want %d", tt.lines, tt.tSpin, tt.btbActive, tt.perfect, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"os"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// Size of a GIF when no size is asked for
+const (
+	DefaultGIFWidth  = 200
+	DefaultGIFHeight = 400
+)
+
+// gifFrameSteps is the number of steps of the game between frames of a GIF,
+// 30 frames a second at 120 steps a second
+const gifFrameSteps = 4
+
+// gifFrameDelay is how long each frame of a GIF is shown in hundredths of a
+// second, as near to gifFrameSteps steps as a GIF can get
+const gifFrameDelay = 3
+
+// gifLineColor is the index in gifPalette of the lines between cells
+const gifLineColor = uint8(GraySpecial + 1)
+
+// gifPalette holds the color of every Block, indexed by Block, followed by
+// the color of the lines between cells. Special blocks are a lighter shade
+// of their color.
+var gifPalette = color.Palette{
+	Empty:          color.RGBA{R: 16, G: 16, B: 24, A: 255},
+	Goluboy:        color.RGBA{R: 102, G: 204, B: 255, A: 255},
+	Siniy:          color.RGBA{R: 51, G: 77, B: 255, A: 255},
+	Pink:           color.RGBA{R: 255, G: 128, B: 204, A: 255},
+	Purple:         color.RGBA{R: 153, G: 77, B: 230, A: 255},
+	Red:            color.RGBA{R: 255, G: 51, B: 51, A: 255},
+	Yellow:         color.RGBA{R: 255, G: 230, B: 51, A: 255},
+	Green:          color.RGBA{R: 77, G: 230, B: 77, A: 255},
+	Gray:           color.RGBA{R: 128, G: 128, B: 128, A: 255},
+	GoluboySpecial: color.RGBA{R: 179, G: 230, B: 255, A: 255},
+	SiniySpecial:   color.RGBA{R: 153, G: 166, B: 255, A: 255},
+	PinkSpecial:    color.RGBA{R: 255, G: 191, B: 230, A: 255},
+	PurpleSpecial:  color.RGBA{R: 204, G: 166, B: 242, A: 255},
+	RedSpecial:     color.RGBA{R: 255, G: 153, B: 153, A: 255},
+	YellowSpecial:  color.RGBA{R: 255, G: 242, B: 153, A: 255},
+	GreenSpecial:   color.RGBA{R: 166, G: 242, B: 166, A: 255},
+	GraySpecial:    color.RGBA{R: 192, G: 192, B: 192, A: 255},
+	gifLineColor:   color.RGBA{R: 40, G: 40, B: 52, A: 255},
+}
+
+// ExportReplayToGIF plays the replay at replayPath as SimulateGame does and
+// writes the visible board as an animated GIF of width by height pixels to
+// outputPath. A width or height of 0 uses DefaultGIFWidth or
+// DefaultGIFHeight.
+func ExportReplayToGIF(replayPath, outputPath string, width, height int) error {
+	seed, events, hashes, err := replay.LoadReplay(replayPath)
+	if err != nil {
+		return fmt.Errorf("loading %s: %v", replayPath, err)
+	}
+	gs := NewSeededGameState(ModeMarathon, config.DefaultSettings(), seed)
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := gs.EncodeGIF(f, replay.NewReplayPlayer(seed, events, hashes), width, height); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// EncodeGIF plays player on the game until it is over or the replay's last
+// event has been played, and writes the visible board every gifFrameSteps
+// steps as an animated GIF of width by height pixels to w. A width or height
+// of 0 uses DefaultGIFWidth or DefaultGIFHeight.
+func (gs *GameState) EncodeGIF(w io.Writer, player *replay.ReplayPlayer, width, height int) error {
+	if width == 0 {
+		width = DefaultGIFWidth
+	}
+	if height == 0 {
+		height = DefaultGIFHeight
+	}
+	rows := gs.board.Rows() - HiddenRows
+	cols := gs.board.Cols()
+	if width < cols || height < rows {
+		return fmt.Errorf("a %dx%d GIF is too small for a board of %d by %d", width, height, cols, rows)
+	}
+
+	// Each frame shows the board as it is at the end of a step, every
+	// gifFrameSteps steps starting with the board before the first one
+	anim := &gif.GIF{}
+	addFrame := func() {
+		anim.Image = append(anim.Image, gs.board.gifFrame(width, height))
+		anim.Delay = append(anim.Delay, gifFrameDelay)
+	}
+	addFrame()
+	steps := 0
+	if err := gs.simulate(player, int(player.LastFrame())+1, func() {
+		steps++
+		if steps%gifFrameSteps == 0 || gs.gameOver {
+			addFrame()
+		}
+	}); err != nil {
+		return err
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// gifFrame draws the visible rows of b on a width by height image in the
+// colors of gifPalette, the top visible row at the top
+func (b Board) gifFrame(width, height int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, width, height), gifPalette)
+	rows := b.Rows() - HiddenRows
+	cols := b.Cols()
+	for y := 0; y < height; y++ {
+		r := rows - 1 - y*rows/height
+		for x := 0; x < width; x++ {
+			c := x * cols / width
+			// Cells are outlined on their left and top edges, when they
+			// are big enough to spare the pixels
+			if (width >= 3*cols && x == c*width/cols) || (height >= 3*rows && y == (rows-1-r)*height/rows) {
+				img.SetColorIndex(x, y, gifLineColor)
+				continue
+			}
+			img.SetColorIndex(x, y, uint8(b[r][c]))
+		}
+	}
+	return img
+}
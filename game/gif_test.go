@@ -0,0 +1,96 @@
+package game
+
+import (
+	"bytes"
+	"image/gif"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// recordReplay plays scriptDown for frames steps and saves the game as a
+// replay file, returning its path
+func recordReplay(t *testing.T, frames int) string {
+	t.Helper()
+	rec := newTestGame(t)
+	var in input.PlayerInput
+	handler := input.NewInputHandler(rec.settings.DAS, rec.settings.ARR)
+	for i := 0; i < frames && !rec.GameOver(); i++ {
+		in.Next(scriptDown(i))
+		rec.Update(handler.Update(StepLength, in.Pressed, in.JustPressed, in.JustReleased), StepLength)
+	}
+	path := filepath.Join(t.TempDir(), "game.trep")
+	if err := replay.SaveReplay(path, rec.seed, rec.ReplayEvents(), rec.FrameHashes()); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExportReplayToGIF(t *testing.T) {
+	// 5 seconds of play
+	path := recordReplay(t, 5*120)
+	out := filepath.Join(filepath.Dir(path), "game.gif")
+	if err := ExportReplayToGIF(path, out, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("GIF89a")) {
+		t.Fatalf("GIF starts with %q, want GIF89a", data[:minInt(len(data), 6)])
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, events, _, err := replay.LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := int(events[len(events)-1].Frame) + 1
+	if want := 1 + steps/gifFrameSteps; len(anim.Image) < want {
+		t.Errorf("GIF of %d steps has %d frames, want at least %d", steps, len(anim.Image), want)
+	}
+	for i, img := range anim.Image {
+		if b := img.Bounds(); b.Dx() != DefaultGIFWidth || b.Dy() != DefaultGIFHeight {
+			t.Fatalf("frame %d is %dx%d, want %dx%d", i, b.Dx(), b.Dy(), DefaultGIFWidth, DefaultGIFHeight)
+		}
+		if anim.Delay[i] != gifFrameDelay {
+			t.Errorf("frame %d shown for %d, want %d", i, anim.Delay[i], gifFrameDelay)
+		}
+	}
+	if bytes.Equal(anim.Image[0].Pix, anim.Image[len(anim.Image)-1].Pix) {
+		t.Error("the first and last frames are the same, want the pieces played")
+	}
+}
+
+func TestExportReplayToGIFTooSmall(t *testing.T) {
+	path := recordReplay(t, 120)
+	err := ExportReplayToGIF(path, filepath.Join(filepath.Dir(path), "game.gif"), 5, 5)
+	if err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Errorf("ExportReplayToGIF(5x5) error = %v, want one that it is too small", err)
+	}
+}
+
+func TestGIFPalette(t *testing.T) {
+	// A color for every block and the lines, none of them the same
+	if len(gifPalette) != int(gifLineColor)+1 {
+		t.Fatalf("palette has %d colors, want %d", len(gifPalette), gifLineColor+1)
+	}
+	seen := make(map[interface{}]int)
+	for i, c := range gifPalette {
+		if c == nil {
+			t.Fatalf("palette color %d is missing", i)
+		}
+		if j, ok := seen[c]; ok {
+			t.Errorf("palette colors %d and %d are the same", j, i)
+		}
+		seen[c] = i
+	}
+}
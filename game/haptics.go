@@ -0,0 +1,14 @@
+package game
+
+// Haptics gives feedback for locks and line clears, such as by rumbling a
+// gamepad
+type Haptics interface {
+	OnPieceLock()
+	OnLineClear(lines int)
+}
+
+// SetHaptics has the game give feedback through h, or none when h is nil.
+// Games without it, such as simulated ones, give none.
+func (gs *GameState) SetHaptics(h Haptics) {
+	gs.haptics = h
+}
@@ -0,0 +1,80 @@
+package game
+
+// PlacementHistorySize is the number of pieces a PlacementHistory keeps
+const PlacementHistorySize = 20
+
+// efficiencyPieces is the number of pieces Efficiency looks back over
+const efficiencyPieces = 10
+
+// PlacedPiece is a piece as it was locked into the board
+type PlacedPiece struct {
+	Piece         Piece
+	RotationState int
+	Col           int     // Leftmost column of the piece
+	Row           int     // Bottom row of the piece
+	LinesCleared  int     // Lines the lock cleared
+	ScoreDelta    int     // Points the lock scored for its clear
+	Timestamp     float64 // Seconds into the game the piece locked
+}
+
+// PlacementHistory is a ring buffer of the last PlacementHistorySize pieces
+// locked, the oldest overwritten once it is full. Being an array it is
+// copied by value, so a snapshot of a game keeps its own history.
+type PlacementHistory struct {
+	entries [PlacementHistorySize]PlacedPiece
+	next    int // Index of the slot the next piece is written to
+	count   int // Number of slots written, up to PlacementHistorySize
+}
+
+// add records p, overwriting the oldest piece when the history is full
+func (ph *PlacementHistory) add(p PlacedPiece) {
+	ph.entries[ph.next] = p
+	ph.next = (ph.next + 1) % len(ph.entries)
+	if ph.count < len(ph.entries) {
+		ph.count++
+	}
+}
+
+// setLastResult fills in what the last piece recorded cleared and scored,
+// which is only known once its lock has been scored
+func (ph *PlacementHistory) setLastResult(lines, score int) {
+	if ph.count == 0 {
+		return
+	}
+	last := &ph.entries[(ph.next+len(ph.entries)-1)%len(ph.entries)]
+	last.LinesCleared = lines
+	last.ScoreDelta = score
+}
+
+// Len returns the number of pieces in the history
+func (ph *PlacementHistory) Len() int {
+	return ph.count
+}
+
+// Last returns up to the last n pieces locked, oldest first
+func (ph *PlacementHistory) Last(n int) []PlacedPiece {
+	if n > ph.count {
+		n = ph.count
+	}
+	if n <= 0 {
+		return nil
+	}
+	last := make([]PlacedPiece, n)
+	for i := range last {
+		last[i] = ph.entries[(ph.next-n+i+len(ph.entries))%len(ph.entries)]
+	}
+	return last
+}
+
+// Efficiency returns the lines cleared by the last 10 pieces locked divided
+// by 10, or 0 before 10 pieces have locked
+func (ph *PlacementHistory) Efficiency() float64 {
+	if ph.count < efficiencyPieces {
+		return 0
+	}
+	lines := 0
+	for _, p := range ph.Last(efficiencyPieces) {
+		lines += p.LinesCleared
+	}
+	return float64(lines) / efficiencyPieces
+}
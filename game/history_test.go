@@ -0,0 +1,105 @@
+package game
+
+import "testing"
+
+func TestPlacementHistoryLast(t *testing.T) {
+	var ph PlacementHistory
+	if got := ph.Last(5); got != nil {
+		t.Errorf("Last(5) of an empty history = %v, want nil", got)
+	}
+	for i := 0; i < 3; i++ {
+		ph.add(PlacedPiece{Col: i})
+	}
+
+	// Never more than asked for or recorded, oldest first
+	for n, want := range []int{0, 1, 2, 3, 3, 3} {
+		got := ph.Last(n)
+		if len(got) != want {
+			t.Errorf("Last(%d) of 3 has %d pieces, want %d", n, len(got), want)
+			continue
+		}
+		for i, p := range got {
+			if p.Col != 3-want+i {
+				t.Errorf("Last(%d)[%d] is piece %d, want %d", n, i, p.Col, 3-want+i)
+			}
+		}
+	}
+	if got := ph.Last(-1); got != nil {
+		t.Errorf("Last(-1) = %v, want nil", got)
+	}
+}
+
+func TestPlacementHistoryWraps(t *testing.T) {
+	// Once full each piece added overwrites the oldest
+	var ph PlacementHistory
+	for i := 0; i < PlacementHistorySize+7; i++ {
+		ph.add(PlacedPiece{Col: i})
+		if want := minInt(i+1, PlacementHistorySize); ph.Len() != want {
+			t.Fatalf("Len() = %d after %d added, want %d", ph.Len(), i+1, want)
+		}
+		last := ph.Last(5)
+		if len(last) > 5 || last[len(last)-1].Col != i {
+			t.Fatalf("Last(5) after %d added = %v, want up to 5 ending with %d", i+1, last, i)
+		}
+	}
+	all := ph.Last(PlacementHistorySize + 1)
+	if len(all) != PlacementHistorySize {
+		t.Fatalf("Last() of a full history has %d pieces, want %d", len(all), PlacementHistorySize)
+	}
+	for i, p := range all {
+		if p.Col != i+7 {
+			t.Errorf("Last()[%d] is piece %d, want %d", i, p.Col, i+7)
+		}
+	}
+}
+
+func TestPlacementHistoryLastResult(t *testing.T) {
+	var ph PlacementHistory
+	ph.setLastResult(4, 800) // Nothing to set
+	if ph.Len() != 0 {
+		t.Fatalf("setLastResult() on an empty history recorded a piece")
+	}
+
+	// The result goes on the newest piece, across the wrap too
+	for i := 0; i < PlacementHistorySize+1; i++ {
+		ph.add(PlacedPiece{Col: i})
+		ph.setLastResult(i%4, i*100)
+	}
+	for _, p := range ph.Last(PlacementHistorySize) {
+		if p.LinesCleared != p.Col%4 || p.ScoreDelta != p.Col*100 {
+			t.Errorf("piece %d cleared %d lines for %d, want %d for %d", p.Col, p.LinesCleared, p.ScoreDelta, p.Col%4, p.Col*100)
+		}
+	}
+}
+
+func TestPlacementHistoryEfficiency(t *testing.T) {
+	var ph PlacementHistory
+	for i := 0; i < efficiencyPieces-1; i++ {
+		ph.add(PlacedPiece{LinesCleared: 4})
+	}
+	if got := ph.Efficiency(); got != 0 {
+		t.Errorf("Efficiency() of %d pieces = %v, want 0", ph.Len(), got)
+	}
+
+	// Only the last 10 count, the 4 line clears of earlier pieces gone
+	for i := 0; i < efficiencyPieces; i++ {
+		ph.add(PlacedPiece{LinesCleared: i % 2})
+	}
+	if got := ph.Efficiency(); got != 0.5 {
+		t.Errorf("Efficiency() = %v, want 0.5", got)
+	}
+}
+
+func TestPlacementHistoryLocks(t *testing.T) {
+	// A Tetris locked in a game is recorded with what it cleared
+	gs := newTestGame(t)
+	before := gs.score
+	lockClear(t, gs, 4)
+	last := gs.PlacementHistory().Last(1)
+	if len(last) != 1 {
+		t.Fatalf("%d pieces in the history after a lock, want 1", len(last))
+	}
+	if p := last[0]; p.Piece != IPiece || p.LinesCleared != 4 || p.ScoreDelta != gs.score-before {
+		t.Errorf("history has %+v, want an I that cleared 4 lines for %d", p, gs.score-before)
+	}
+}
@@ -0,0 +1,239 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+)
+
+// GameMode is the set of rules a game is played with. The game calls into
+// its mode as it is played, so a new mode only needs a type that implements
+// GameMode and a case in ParseGameMode.
+type GameMode interface {
+	// Name returns the name of the mode as accepted by ParseGameMode
+	Name() string
+
+	// OnPieceLock is called whenever a piece locks, before its lines are
+	// cleared
+	OnPieceLock(gs *GameState)
+
+	// OnLinesClear is called after a lock clears lines, once the level for
+	// the new total has been worked out
+	OnLinesClear(gs *GameState, lines int)
+
+	// IsOver reports whether the goal of the mode has been reached, which
+	// ends the game. Topping out ends every mode regardless.
+	IsOver(gs *GameState) bool
+
+	// HUDExtras returns the text the mode shows in the HUD
+	HUDExtras(gs *GameState) []HUDItem
+
+	// OnTick is called for every step of dt seconds the game is played
+	OnTick(gs *GameState, dt float64)
+}
+
+// HUDItem is a line of text in the HUD. The position is at a UI scale of 1.
+type HUDItem struct {
+	Text  string
+	X, Y  float64
+	Scale float64 // Size of the text, relative to the UI scale
+	Color color.RGBA
+}
+
+// hudX and hudY are where the HUD starts, to the right of the board below
+// the score
+const (
+	hudX = 500.0
+	hudY = 250.0
+)
+
+// The modes that can be played
+var (
+	ModeMarathon       GameMode = MarathonMode{}
+	ModeSprint         GameMode = SprintMode{}
+	ModeUltra          GameMode = UltraMode{}
+	ModeSurvival       GameMode = SurvivalMode{}
+	ModePractice       GameMode = PracticeMode{Undos: 5}
+	ModeDailyChallenge GameMode = DailyChallengeMode{}
+	ModeTwoPlayer      GameMode = TwoPlayerMode{Garbage: DefaultGarbageTable()}
+)
+
+// sprintLines is the number of lines to clear to finish a sprint
+const sprintLines = 40
+
+// ultraTime is the number of seconds an ultra game lasts
+const ultraTime = 120.0
+
+// Survival waves start after firstWaveTime seconds and come
+// waveTimeStep seconds sooner each time down to minWaveInterval, pushing up
+// one more garbage line each wave up to maxWaveLines
+const (
+	firstWaveTime   = 20.0
+	waveTimeStep    = 5.0
+	minWaveInterval = 10.0
+	maxWaveLines    = 4
+)
+
+// ParseGameMode converts the name of a game mode into its GameMode
+func ParseGameMode(name string) (GameMode, error) {
+	switch name {
+	case "marathon":
+		return ModeMarathon, nil
+	case "sprint":
+		return ModeSprint, nil
+	case "ultra":
+		return ModeUltra, nil
+	case "survival":
+		return ModeSurvival, nil
+	case "practice":
+		return ModePractice, nil
+	case "daily":
+		return ModeDailyChallenge, nil
+	case "twoplayer":
+		return ModeTwoPlayer, nil
+	}
+	return ModeMarathon, fmt.Errorf("unknown game mode %q", name)
+}
+
+// MarathonMode is played until topping out while the game speeds up
+type MarathonMode struct{}
+
+// Name implements GameMode
+func (MarathonMode) Name() string { return "marathon" }
+
+// OnPieceLock implements GameMode
+func (MarathonMode) OnPieceLock(gs *GameState) {}
+
+// OnLinesClear speeds gravity up to the new level
+func (MarathonMode) OnLinesClear(gs *GameState, lines int) { gs.applyLevelSpeed() }
+
+// IsOver implements GameMode, a marathon only ends by topping out
+func (MarathonMode) IsOver(gs *GameState) bool { return false }
+
+// HUDExtras implements GameMode
+func (MarathonMode) HUDExtras(gs *GameState) []HUDItem { return nil }
+
+// OnTick implements GameMode
+func (MarathonMode) OnTick(gs *GameState, dt float64) {}
+
+// SprintMode is won by clearing 40 lines as fast as possible, at the speed
+// of level 1 throughout
+type SprintMode struct{}
+
+// Name implements GameMode
+func (SprintMode) Name() string { return "sprint" }
+
+// OnPieceLock implements GameMode
+func (SprintMode) OnPieceLock(gs *GameState) {}
+
+// OnLinesClear counts the lines down to the goal
+func (SprintMode) OnLinesClear(gs *GameState, lines int) {
+	gs.sprintLinesLeft = maxInt(gs.sprintLinesLeft-lines, 0)
+}
+
+// IsOver reports whether every line of the sprint has been cleared
+func (SprintMode) IsOver(gs *GameState) bool { return gs.sprintLinesLeft == 0 }
+
+// HUDExtras implements GameMode, the lines left replace the score instead
+func (SprintMode) HUDExtras(gs *GameState) []HUDItem { return nil }
+
+// OnTick implements GameMode
+func (SprintMode) OnTick(gs *GameState, dt float64) {}
+
+// DailyChallengeMode is a sprint dealt from the seed of the day, see
+// DailySeed, so every player races the same pieces on the same day
+type DailyChallengeMode struct {
+	SprintMode
+}
+
+// Name implements GameMode
+func (DailyChallengeMode) Name() string { return "daily" }
+
+// IsSprint reports whether mode is won by clearing sprintLines lines, which
+// makes the time taken its result rather than the score
+func IsSprint(mode GameMode) bool {
+	return mode == ModeSprint || mode == ModeDailyChallenge
+}
+
+// DailySeed returns the seed of the daily challenge on the UTC day of t,
+// which is the date written as the number yyyymmdd
+func DailySeed(t time.Time) int64 {
+	year, month, day := t.UTC().Date()
+	return int64(year*10000 + int(month)*100 + day)
+}
+
+// UltraMode is scoring as much as possible in a fixed time. When time runs
+// out the piece stays where it is.
+type UltraMode struct{}
+
+// Name implements GameMode
+func (UltraMode) Name() string { return "ultra" }
+
+// OnPieceLock implements GameMode
+func (UltraMode) OnPieceLock(gs *GameState) {}
+
+// OnLinesClear speeds gravity up to the new level
+func (UltraMode) OnLinesClear(gs *GameState, lines int) { gs.applyLevelSpeed() }
+
+// IsOver reports whether time has run out
+func (UltraMode) IsOver(gs *GameState) bool { return gs.ultraTimeLeft <= 0 }
+
+// HUDExtras shows a large countdown that turns red when time is running out
+func (UltraMode) HUDExtras(gs *GameState) []HUDItem {
+	c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if gs.ultraTimeLeft < 30 {
+		c = color.RGBA{R: 255, A: 255}
+	}
+	return []HUDItem{{Text: formatCountdown(gs.ultraTimeLeft), X: hudX, Y: hudY, Scale: 2.5, Color: c}}
+}
+
+// OnTick counts the time left down
+func (UltraMode) OnTick(gs *GameState, dt float64) {
+	gs.ultraTimeLeft = math.Max(gs.ultraTimeLeft-dt, 0)
+}
+
+// SurvivalMode is lasting as long as possible while garbage rises in waves
+// that come quicker and grow over time, at the speed of level 1 throughout
+type SurvivalMode struct{}
+
+// Name implements GameMode
+func (SurvivalMode) Name() string { return "survival" }
+
+// OnPieceLock implements GameMode
+func (SurvivalMode) OnPieceLock(gs *GameState) {}
+
+// OnLinesClear implements GameMode
+func (SurvivalMode) OnLinesClear(gs *GameState, lines int) {}
+
+// IsOver implements GameMode, survival only ends by topping out
+func (SurvivalMode) IsOver(gs *GameState) bool { return false }
+
+// HUDExtras shows the number of waves sent
+func (SurvivalMode) HUDExtras(gs *GameState) []HUDItem {
+	return []HUDItem{{Text: fmt.Sprintf("Wave %d", gs.survivalWave), X: hudX, Y: hudY, Scale: 2.5, Color: color.RGBA{R: 255, G: 255, B: 255, A: 255}}}
+}
+
+// OnTick queues up the garbage of each wave, which rises when the next
+// piece locks
+func (SurvivalMode) OnTick(gs *GameState, dt float64) {
+	gs.nextWaveTime -= dt
+	if gs.nextWaveTime <= 0 {
+		gs.survivalWave++
+		gs.pendingGarbage += minInt(gs.survivalWave, maxWaveLines)
+		gs.nextWaveTime += waveInterval(gs.survivalWave)
+	}
+}
+
+// waveInterval returns the number of seconds between the given survival
+// wave and the next one
+func waveInterval(wave int) float64 {
+	return math.Max(firstWaveTime-waveTimeStep*float64(wave), minWaveInterval)
+}
+
+// formatCountdown formats a time in seconds as mm:ss, rounding up so the
+// countdown only shows 00:00 once time has run out
+func formatCountdown(seconds float64) string {
+	secs := int(math.Ceil(seconds))
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
@@ -0,0 +1,205 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+)
+
+func TestUltraTimeUp(t *testing.T) {
+	gs := NewSeededGameState(ModeUltra, config.DefaultSettings(), 1)
+	if gs.ultraTimeLeft != ultraTime {
+		t.Fatalf("ultra starts with %v seconds, want %v", gs.ultraTimeLeft, ultraTime)
+	}
+
+	// Run the clock down to the last second
+	gs.ultraTimeLeft = 1
+	for i := 0; i < 110; i++ {
+		gs.Update(input.InputState{}, StepLength)
+	}
+	if gs.GameOver() {
+		t.Fatalf("game over with %v seconds left", gs.ultraTimeLeft)
+	}
+	for i := 0; i < 20; i++ {
+		gs.Update(input.InputState{}, StepLength)
+	}
+	if !gs.GameOver() || !gs.ModeComplete() || gs.ultraTimeLeft != 0 {
+		t.Fatalf("game over %t, mode complete %t with %v seconds left, want time up", gs.GameOver(), gs.ModeComplete(), gs.ultraTimeLeft)
+	}
+
+	// The piece stays where it was when time ran out
+	board, score := gs.board.clone(), gs.score
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	if !reflect.DeepEqual(gs.board, board) || gs.score != score {
+		t.Error("the game went on after time was up")
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{120, "02:00"},
+		{119.5, "02:00"},
+		{60, "01:00"},
+		{59.999, "01:00"},
+		{59, "00:59"},
+		{0.001, "00:01"},
+		{0, "00:00"},
+	}
+	for _, tt := range tests {
+		if got := formatCountdown(tt.seconds); got != tt.want {
+			t.Errorf("formatCountdown(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestWaveInterval(t *testing.T) {
+	tests := []struct {
+		wave int
+		want float64
+	}{
+		{0, 20},
+		{1, 15},
+		{2, 10},
+		{3, 10},
+		{50, 10},
+	}
+	for _, tt := range tests {
+		if got := waveInterval(tt.wave); got != tt.want {
+			t.Errorf("waveInterval(%d) = %v, want %v", tt.wave, got, tt.want)
+		}
+	}
+}
+
+func TestSurvivalWaves(t *testing.T) {
+	// Each wave comes at its time with one more line of garbage than the
+	// last, up to maxWaveLines
+	tests := []struct {
+		time    float64
+		garbage int
+	}{
+		{20, 1},
+		{35, 2},
+		{45, 3},
+		{55, 4},
+		{65, 4},
+		{75, 4},
+	}
+	gs := NewSeededGameState(ModeSurvival, config.DefaultSettings(), 1)
+	var elapsed float64
+	for i, tt := range tests {
+		for elapsed+0.5 < tt.time {
+			SurvivalMode{}.OnTick(gs, 0.5)
+			elapsed += 0.5
+			if gs.survivalWave != i {
+				t.Fatalf("wave %d came at %vs, want %vs", gs.survivalWave, elapsed, tt.time)
+			}
+		}
+		gs.pendingGarbage = 0
+		SurvivalMode{}.OnTick(gs, 0.5)
+		elapsed += 0.5
+		if gs.survivalWave != i+1 || gs.pendingGarbage != tt.garbage {
+			t.Errorf("at %vs wave %d with %d lines of garbage, want wave %d with %d", elapsed, gs.survivalWave, gs.pendingGarbage, i+1, tt.garbage)
+		}
+	}
+}
+
+func TestModeIsOver(t *testing.T) {
+	tests := []struct {
+		mode  GameMode
+		setup func(gs *GameState)
+		want  bool
+	}{
+		{ModeMarathon, func(gs *GameState) { gs.linesCleared, gs.elapsedTime = 500, 3600 }, false},
+		{ModeSprint, func(gs *GameState) { gs.sprintLinesLeft = 1 }, false},
+		{ModeSprint, func(gs *GameState) { gs.sprintLinesLeft = 0 }, true},
+		{ModeDailyChallenge, func(gs *GameState) { gs.sprintLinesLeft = 1 }, false},
+		{ModeDailyChallenge, func(gs *GameState) { gs.sprintLinesLeft = 0 }, true},
+		{ModeUltra, func(gs *GameState) { gs.ultraTimeLeft = 0.01 }, false},
+		{ModeUltra, func(gs *GameState) { gs.ultraTimeLeft = 0 }, true},
+		{ModeSurvival, func(gs *GameState) { gs.survivalWave, gs.elapsedTime = 50, 3600 }, false},
+		{ModePractice, func(gs *GameState) { gs.linesCleared = 500 }, false},
+		{ModeTwoPlayer, func(gs *GameState) { gs.linesCleared, gs.garbageSent = 500, 100 }, false},
+	}
+	for _, tt := range tests {
+		gs := NewSeededGameState(tt.mode, config.DefaultSettings(), 1)
+		if tt.mode.IsOver(gs) {
+			t.Errorf("%s is over as soon as it starts", tt.mode.Name())
+		}
+		tt.setup(gs)
+		if got := tt.mode.IsOver(gs); got != tt.want {
+			t.Errorf("%s: IsOver() = %t, want %t", tt.mode.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestSprintComplete(t *testing.T) {
+	for _, mode := range []GameMode{ModeSprint, ModeDailyChallenge} {
+		gs := NewSeededGameState(mode, config.DefaultSettings(), 1)
+		for gs.linesCleared < sprintLines-4 {
+			lockClear(t, gs, 4)
+		}
+		lockClear(t, gs, 3)
+		if gs.GameOver() || gs.SprintLinesLeft() != 1 {
+			t.Fatalf("%s: game over %t with %d lines left, want it going with 1", mode.Name(), gs.GameOver(), gs.SprintLinesLeft())
+		}
+		lockClear(t, gs, 2)
+		if !gs.GameOver() || !gs.ModeComplete() || gs.SprintLinesLeft() != 0 {
+			t.Errorf("%s: game over %t, complete %t with %d lines left, want the sprint done", mode.Name(), gs.GameOver(), gs.ModeComplete(), gs.SprintLinesLeft())
+		}
+	}
+}
+
+func TestParseGameMode(t *testing.T) {
+	for _, mode := range []GameMode{ModeMarathon, ModeSprint, ModeUltra, ModeSurvival, ModePractice, ModeDailyChallenge, ModeTwoPlayer} {
+		if got, err := ParseGameMode(mode.Name()); err != nil || got != mode {
+			t.Errorf("ParseGameMode(%q) = %v, %v", mode.Name(), got, err)
+		}
+	}
+	if _, err := ParseGameMode("zen"); err == nil {
+		t.Error(`ParseGameMode("zen") returned no error`)
+	}
+}
+
+func TestDailySeed(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		t    time.Time
+		want int64
+	}{
+		{time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), 20240309},
+		{time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC), 20240309},
+		{time.Date(2024, 3, 9, 23, 59, 59, 999999999, time.UTC), 20240309},
+		{time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), 20240310},
+		// The day is the UTC day wherever the player is
+		{time.Date(2024, 3, 9, 18, 59, 59, 0, est), 20240309},
+		{time.Date(2024, 3, 9, 19, 0, 0, 0, est), 20240310},
+		{time.Date(2024, 3, 10, 8, 59, 59, 0, jst), 20240309},
+		{time.Date(2024, 3, 10, 9, 0, 0, 0, jst), 20240310},
+		// Across the end of a month and a year
+		{time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC), 20240229},
+		{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 20240301},
+		{time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), 20241231},
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 20250101},
+	}
+	for _, tt := range tests {
+		if got := DailySeed(tt.t); got != tt.want {
+			t.Errorf("DailySeed(%v) = %d, want %d", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestDailySeedPieces(t *testing.T) {
+	// Players anywhere on the same day are dealt the same pieces
+	a := NewSeededGameState(ModeDailyChallenge, config.DefaultSettings(), DailySeed(time.Date(2024, 3, 9, 1, 0, 0, 0, time.UTC)))
+	b := NewSeededGameState(ModeDailyChallenge, config.DefaultSettings(), DailySeed(time.Date(2024, 3, 9, 18, 0, 0, 0, time.FixedZone("EST", -5*60*60))))
+	if got, want := dealt(a, 14), dealt(b, 14); !reflect.DeepEqual(got, want) {
+		t.Errorf("pieces dealt on the same day: %v and %v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package game
+
+// Placement is somewhere the active piece can be hard dropped by rotating
+// it, then moving it sideways, then dropping it
+type Placement struct {
+	Rotation int   // Quarter turns clockwise before moving: 0, 1, 2 for a 180 or 3 for a counter-clockwise turn
+	Shift    int   // Columns moved after rotating, negative to the left
+	Shape    Shape // Where the piece lands
+	Board    Board // The board once the piece has locked and full rows are cleared
+	Lines    int   // Rows cleared by the piece
+}
+
+// Placements returns every placement of the active piece reachable by at
+// most one rotation followed by moves in a single direction, rotating and
+// moving the piece like the player would, wall kicks included. The game is
+// left untouched.
+func (gs *GameState) Placements() []Placement {
+	rotations := 4
+	if gs.currentPiece == OPiece {
+		rotations = 1
+	}
+
+	var placements []Placement
+	for rot := 0; rot < rotations; rot++ {
+		// Work on a copy of the game so the real piece and board don't move
+		rotated := *gs
+		rotated.board = gs.board.clone()
+		switch rot {
+		case 1:
+			if !rotated.rotatePiece(1) {
+				continue
+			}
+		case 2:
+			if !rotated.rotatePiece180() {
+				continue
+			}
+		case 3:
+			if !rotated.rotatePiece(-1) {
+				continue
+			}
+		}
+
+		placements = append(placements, dropPlacement(rotated, rot, 0))
+		for _, dir := range []int{-1, 1} {
+			sim := rotated
+			sim.board = rotated.board.clone()
+			for shift := dir; sim.movePiece(dir); shift += dir {
+				placements = append(placements, dropPlacement(sim, rot, shift))
+			}
+		}
+	}
+	return placements
+}
+
+// dropPlacement returns the placement of dropping the active piece of gs, a
+// copy of a game, from where it is
+func dropPlacement(gs GameState, rot, shift int) Placement {
+	gs.board = gs.board.clone()
+	for gs.applyGravity() > 0 {
+	}
+	shape := gs.activeShape
+	lines := gs.board.clearFullRows()
+	return Placement{Rotation: rot, Shift: shift, Shape: shape, Board: gs.board, Lines: lines}
+}
+
+// PlacementsAfter returns every placement of the next piece once the active
+// piece has been dropped to p, as Placements does. Returns nil when the next
+// piece can't come into play on the board p leaves.
+func (gs *GameState) PlacementsAfter(p Placement) []Placement {
+	next := gs.queue.Peek(0)
+	sim := *gs
+	sim.board = p.Board.clone()
+	shape := moveShape(gs.rows, spawnCol(next, gs.cols), PieceShape(next))
+	if sim.board.checkCollision(shape) {
+		return nil
+	}
+	sim.board.fillShape(shape, PieceBlock(next))
+	sim.currentPiece = next
+	sim.activeShape = shape
+	sim.rotationState = 0
+	return sim.Placements()
+}
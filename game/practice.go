@@ -0,0 +1,146 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// PracticeMode is played at the speed of level 1 for as long as the player
+// likes, with the last Undos pieces able to be taken back to try another
+// placement
+type PracticeMode struct {
+	Undos int // Number of piece locks kept to be undone
+}
+
+// Name implements GameMode
+func (PracticeMode) Name() string { return "practice" }
+
+// OnPieceLock keeps the game as it was just before the lock so it can be
+// undone, dropping the oldest lock once Undos are kept
+func (m PracticeMode) OnPieceLock(gs *GameState) {
+	if m.Undos <= 0 {
+		return
+	}
+	if len(gs.practiceUndos) == m.Undos {
+		gs.practiceUndos = append(gs.practiceUndos[:0], gs.practiceUndos[1:]...)
+	}
+	gs.practiceUndos = append(gs.practiceUndos, gs.snapshot())
+}
+
+// OnLinesClear implements GameMode
+func (PracticeMode) OnLinesClear(gs *GameState, lines int) {}
+
+// IsOver implements GameMode, practice only ends by topping out
+func (PracticeMode) IsOver(gs *GameState) bool { return false }
+
+// HUDExtras shows that the game is a practice game and how many pieces can
+// be undone
+func (PracticeMode) HUDExtras(gs *GameState) []HUDItem {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	return []HUDItem{
+		{Text: "PRACTICE MODE", X: hudX, Y: hudY, Scale: 2, Color: white},
+		{Text: fmt.Sprintf("Ctrl+Z to undo (%d)", len(gs.practiceUndos)), X: hudX, Y: hudY - 30, Scale: 1, Color: white},
+	}
+}
+
+// OnTick implements GameMode
+func (PracticeMode) OnTick(gs *GameState, dt float64) {}
+
+// practiceSnapshot is a practice game as it was just before a piece locked,
+// with the piece still under the player's control
+type practiceSnapshot struct {
+	board         Board
+	activeShape   Shape
+	currentPiece  Piece
+	rotationState int
+	holdPiece     Piece
+	canHold       bool
+	queue         *PieceQueue
+
+	score        int
+	level        int
+	linesCleared int
+	combo        int
+	btbActive    bool
+	btbCount     int
+	stats        Stats
+	placements   PlacementHistory
+
+	lastClearWasPC          bool
+	allClearStreak          int
+	lastMovementWasRotation bool
+	lastRotationPoint       Shape
+}
+
+// snapshot copies everything a piece lock changes, deep copying the board
+// and the queue so later moves don't change the copy
+func (gs *GameState) snapshot() practiceSnapshot {
+	return practiceSnapshot{
+		board:                   gs.board.clone(),
+		activeShape:             gs.activeShape,
+		currentPiece:            gs.currentPiece,
+		rotationState:           gs.rotationState,
+		holdPiece:               gs.holdPiece,
+		canHold:                 gs.canHold,
+		queue:                   gs.queue.clone(),
+		score:                   gs.score,
+		level:                   gs.level,
+		linesCleared:            gs.linesCleared,
+		combo:                   gs.combo,
+		btbActive:               gs.btbActive,
+		btbCount:                gs.btbCount,
+		stats:                   gs.stats,
+		placements:              gs.placements,
+		lastClearWasPC:          gs.lastClearWasPC,
+		allClearStreak:          gs.allClearStreak,
+		lastMovementWasRotation: gs.lastMovementWasRotation,
+		lastRotationPoint:       gs.lastRotationPoint,
+	}
+}
+
+// Undo takes back the last piece lock of a practice game, putting the
+// piece back where it locked under the player's control. Returns false when
+// there is no lock left to undo. Pieces that weren't yet in the preview may
+// be dealt in a different order after an undo.
+func (gs *GameState) Undo() bool {
+	if gs.gameOver || len(gs.practiceUndos) == 0 {
+		return false
+	}
+	s := gs.practiceUndos[len(gs.practiceUndos)-1]
+	gs.practiceUndos = gs.practiceUndos[:len(gs.practiceUndos)-1]
+
+	gs.board = s.board
+	gs.activeShape = s.activeShape
+	gs.currentPiece = s.currentPiece
+	gs.rotationState = s.rotationState
+	gs.holdPiece = s.holdPiece
+	gs.canHold = s.canHold
+	gs.queue = s.queue
+	gs.score = s.score
+	gs.level = s.level
+	gs.linesCleared = s.linesCleared
+	gs.combo = s.combo
+	gs.btbActive = s.btbActive
+	gs.btbCount = s.btbCount
+	gs.stats = s.stats
+	gs.placements = s.placements
+	gs.lastClearWasPC = s.lastClearWasPC
+	gs.allClearStreak = s.allClearStreak
+	gs.lastMovementWasRotation = s.lastMovementWasRotation
+	gs.lastRotationPoint = s.lastRotationPoint
+
+	// Give the piece a fresh lock delay and drop what the lock set off
+	gs.gravityTimer = 0
+	gs.lockDelayTimer = 0
+	gs.lockResets = 0
+	gs.moveCount = 0
+	gs.clearAnim = lineClearAnimation{}
+	gs.lockFlash = lockFlashEffect{}
+	gs.levelFlash = levelFlashEffect{}
+	gs.hardDropTrail = nil
+	gs.scorePopups = nil
+	gs.perfectClearTimer = 0
+	gs.comboShown = 0
+	gs.comboTimer = 0
+	return true
+}
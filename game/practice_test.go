@@ -0,0 +1,89 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+)
+
+func newPracticeGame(t *testing.T) *GameState {
+	t.Helper()
+	return NewSeededGameState(ModePractice, config.DefaultSettings(), 1)
+}
+
+func TestUndoRestoresBoard(t *testing.T) {
+	gs := newPracticeGame(t)
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	gs.Update(input.InputState{MoveLeft: true}, StepLength)
+
+	// The board just before the second piece locks, with it landed
+	before := gs.board.clone()
+	block := PieceBlock(gs.currentPiece)
+	landed := moveShape(-shapeBottomRow(gs.activeShape), 0, gs.activeShape)
+	for before.checkCollision(landed) {
+		landed = moveShape(1, 0, landed)
+	}
+	before.drawPiece(gs.activeShape, Empty)
+	before.fillShape(landed, block)
+	piece, next, hold, score := gs.currentPiece, gs.NextPieces(), gs.holdPiece, gs.score
+
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	if reflect.DeepEqual(gs.board, before) {
+		t.Fatal("hard drop didn't change the board")
+	}
+	if !gs.Undo() {
+		t.Fatal("Undo() = false after a lock")
+	}
+	if !reflect.DeepEqual(gs.board, before) || gs.activeShape != landed {
+		t.Errorf("board after Undo() =\n%v\nwant\n%v", gs.board, before)
+	}
+	if gs.currentPiece != piece || gs.NextPieces() != next || gs.holdPiece != hold || gs.score != score {
+		t.Errorf("after Undo() piece %v, next %v, hold %v, score %d, want %v, %v, %v, %d", gs.currentPiece, gs.NextPieces(), gs.holdPiece, gs.score, piece, next, hold, score)
+	}
+
+	// The piece can be placed again
+	gs.Update(input.InputState{}, StepLength)
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	if gs.currentPiece != next[0] {
+		t.Errorf("piece %v after placing the undone piece again, want %v", gs.currentPiece, next[0])
+	}
+}
+
+func TestUndoLineClear(t *testing.T) {
+	gs := newPracticeGame(t)
+	lockClear(t, gs, 2)
+	if !gs.Undo() {
+		t.Fatal("Undo() = false after a lock")
+	}
+	if gs.linesCleared != 0 || gs.score != 0 || gs.stats.LineClears[2] != 0 {
+		t.Errorf("after undoing a double: %d lines, %d points, %d doubles, want none", gs.linesCleared, gs.score, gs.stats.LineClears[2])
+	}
+	for r := 0; r < 2; r++ {
+		if gs.board[r][0] != Gray {
+			t.Errorf("row %d isn't back after undoing its clear:\n%v", r, gs.board)
+		}
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	gs := newPracticeGame(t)
+	for i := 0; i < 7; i++ {
+		lockClear(t, gs, 0)
+	}
+	undos := 0
+	for gs.Undo() {
+		undos++
+	}
+	if want := ModePractice.(PracticeMode).Undos; undos != want {
+		t.Errorf("%d locks undone, want %d", undos, want)
+	}
+
+	// Other modes keep nothing to undo
+	gs = newTestGame(t)
+	lockClear(t, gs, 0)
+	if gs.Undo() {
+		t.Error("Undo() = true in a marathon")
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import "math/rand"
+
+// PieceQueue deals pieces from shuffled bags of all 7 pieces, so no piece
+// is ever more than 12 pieces away, and keeps the next previewSize pieces
+// visible ahead of time. The same seed always deals the same pieces.
+type PieceQueue struct {
+	bag         []Piece // Rest of the current bag, taken from the front
+	preview     []Piece // Upcoming pieces, the next one first
+	previewSize int
+	rng         *rand.Rand
+}
+
+// NewPieceQueue creates a queue that deals from seed and shows previewSize
+// pieces ahead
+func NewPieceQueue(seed int64, previewSize int) *PieceQueue {
+	q := &PieceQueue{previewSize: previewSize}
+	q.Reset(seed)
+	return q
+}
+
+// Reset throws away the pieces in the queue and starts dealing again from
+// seed
+func (q *PieceQueue) Reset(seed int64) {
+	q.rng = rand.New(rand.NewSource(seed))
+	q.bag = nil
+	q.preview = make([]Piece, q.previewSize)
+	for i := range q.preview {
+		q.preview[i] = q.draw()
+	}
+}
+
+// Next takes the piece at the front of the queue and tops the preview up
+// from the bag, starting a new bag when the last one runs out
+func (q *PieceQueue) Next() Piece {
+	if q.previewSize == 0 {
+		return q.draw()
+	}
+	piece := q.preview[0]
+	copy(q.preview, q.preview[1:])
+	q.preview[q.previewSize-1] = q.draw()
+	return piece
+}
+
+// Peek returns the piece i places from the front of the queue without
+// taking it, 0 being the piece Next returns. i must be below the preview
+// size.
+func (q *PieceQueue) Peek(i int) Piece {
+	return q.preview[i]
+}
+
+// clone returns a copy of the queue that deals the pieces already in the
+// preview and bag the same way. The copy shares the random source, so the
+// bags after them differ between the two.
+func (q *PieceQueue) clone() *PieceQueue {
+	c := *q
+	c.bag = append([]Piece(nil), q.bag...)
+	c.preview = append([]Piece(nil), q.preview...)
+	return &c
+}
+
+// draw takes the next piece from the bag, filling a new bag when it is
+// empty
+func (q *PieceQueue) draw() Piece {
+	if len(q.bag) == 0 {
+		q.fillBag()
+	}
+	piece := q.bag[0]
+	q.bag = q.bag[1:]
+	return piece
+}
+
+// fillBag creates a new shuffled bag of all 7 pieces
+func (q *PieceQueue) fillBag() {
+	q.bag = make([]Piece, 7)
+	for i := range q.bag {
+		q.bag[i] = Piece(i)
+	}
+
+	// Shuffle the bag using Fisher-Yates algorithm
+	for i := 6; i > 0; i-- {
+		j := q.rng.Intn(i + 1)
+		q.bag[i], q.bag[j] = q.bag[j], q.bag[i]
+	}
+}
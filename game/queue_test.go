@@ -0,0 +1,88 @@
+package game
+
+import "testing"
+
+// deal takes n pieces from q
+func deal(q *PieceQueue, n int) []Piece {
+	pieces := make([]Piece, n)
+	for i := range pieces {
+		pieces[i] = q.Next()
+	}
+	return pieces
+}
+
+func TestPieceQueueSameSeed(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		a := deal(NewPieceQueue(seed, NextQueueLength), 70)
+		b := deal(NewPieceQueue(seed, NextQueueLength), 70)
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("seed %d: piece %d is %v and %v", seed, i, a[i], b[i])
+			}
+		}
+
+		// The preview doesn't change what is dealt
+		c := deal(NewPieceQueue(seed, 0), 70)
+		for i := range a {
+			if a[i] != c[i] {
+				t.Fatalf("seed %d: piece %d is %v with a preview and %v without", seed, i, a[i], c[i])
+			}
+		}
+	}
+
+	a := deal(NewPieceQueue(1, NextQueueLength), 14)
+	b := deal(NewPieceQueue(2, NextQueueLength), 14)
+	same := true
+	for i := range a {
+		same = same && a[i] == b[i]
+	}
+	if same {
+		t.Error("seeds 1 and 2 dealt the same pieces")
+	}
+}
+
+func TestPieceQueueBags(t *testing.T) {
+	pieces := deal(NewPieceQueue(3, NextQueueLength), 7*20)
+	for bag := 0; bag < 20; bag++ {
+		var count [7]int
+		for _, p := range pieces[bag*7 : bag*7+7] {
+			count[p]++
+		}
+		if count != [7]int{1, 1, 1, 1, 1, 1, 1} {
+			t.Errorf("bag %d %v has pieces %v, want one of each", bag, pieces[bag*7:bag*7+7], count)
+		}
+	}
+}
+
+func TestPieceQueuePeek(t *testing.T) {
+	q := NewPieceQueue(4, NextQueueLength)
+	for n := 0; n < 30; n++ {
+		var preview [NextQueueLength]Piece
+		for i := range preview {
+			preview[i] = q.Peek(i)
+			if again := q.Peek(i); again != preview[i] {
+				t.Fatalf("Peek(%d) = %v then %v", i, preview[i], again)
+			}
+		}
+		if p := q.Next(); p != preview[0] {
+			t.Fatalf("Next() = %v, Peek(0) was %v", p, preview[0])
+		}
+		for i := 1; i < NextQueueLength; i++ {
+			if p := q.Peek(i - 1); p != preview[i] {
+				t.Fatalf("Peek(%d) = %v after Next(), was Peek(%d) = %v before", i-1, p, i, preview[i])
+			}
+		}
+	}
+}
+
+func TestPieceQueueReset(t *testing.T) {
+	q := NewPieceQueue(5, NextQueueLength)
+	first := deal(q, 10)
+	q.Reset(5)
+	again := deal(q, 10)
+	for i := range first {
+		if first[i] != again[i] {
+			t.Fatalf("piece %d after Reset is %v, want %v", i, again[i], first[i])
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package game
+
+// ScoringRules are the points awarded for locking a piece. Which clears are
+// worth what differs between versions of the game, see GuidelineScorer and
+// ClassicScorer.
+type ScoringRules struct {
+	// Points for clearing 0 to 4 lines at once
+	LineMultipliers [5]int
+
+	// Points added to a T-spin that clears lines, whose lines also count once
+	// more for each line cleared. A mini T-spin, or a T-spin that clears
+	// nothing, is worth LineMultipliers[1] a line instead. Spins only score
+	// when TSpinBonus is above 0, and an all-spin is then worth 1.5x.
+	TSpinBonus int
+
+	// Points for each clear in a row after the first
+	ComboBase int
+
+	// Tetrises and T-spins that follow another are worth
+	// BTBMultiplierNumerator/BTBMultiplierDenominator times as much
+	BTBMultiplierNumerator   int
+	BTBMultiplierDenominator int
+
+	// Points for clearing every block off the board, indexed by the number
+	// of lines cleared, and for doing so with a T-spin
+	PerfectClearBonus      [5]int
+	TSpinPerfectClearBonus int
+
+	// Points added to a perfect clear for being the 1st, 2nd, 3rd and 4th or
+	// later perfect clear in a row
+	PerfectClearStreakBonus [4]int
+}
+
+// Scorer works out the points a piece lock is worth with its Rules
+type Scorer struct {
+	Rules ScoringRules
+}
+
+// GuidelineScorer returns a Scorer with the modern guideline rules, which
+// reward T-spins, combos, back-to-back clears and perfect clears
+func GuidelineScorer() *Scorer {
+	return &Scorer{Rules: ScoringRules{
+		LineMultipliers:          [5]int{0, 100, 400, 900, 1600},
+		TSpinBonus:               400,
+		ComboBase:                50,
+		BTBMultiplierNumerator:   3,
+		BTBMultiplierDenominator: 2,
+		PerfectClearBonus:        [5]int{0, 800, 1200, 1800, 2000},
+		TSpinPerfectClearBonus:   2800,
+		PerfectClearStreakBonus:  [4]int{1000, 1500, 2000, 3000},
+	}}
+}
+
+// ClassicScorer returns a Scorer with the line clear values of NES Tetris at
+// level 0. Nothing but the lines cleared scores.
+func ClassicScorer() *Scorer {
+	return &Scorer{Rules: ScoringRules{
+		LineMultipliers:          [5]int{0, 40, 100, 300, 1200},
+		BTBMultiplierNumerator:   1,
+		BTBMultiplierDenominator: 1,
+	}}
+}
+
+// Award returns the points for locking a piece that cleared lines lines
+// with a T-spin of tSpinType, or an all-spin when allSpin is set. combo is
+// the number of clears in a row after the first, including this one, and
+// btbActive whether the last clear was a Tetris or T-spin. perfectClears is
+// the number of perfect clears in a row including this lock, 0 when the lock
+// didn't clear every block off the board.
+func (s *Scorer) Award(lines int, tSpinType TSpinType, allSpin bool, combo int, btbActive bool, perfectClears int) int {
+	r := s.Rules
+	spins := r.TSpinBonus > 0
+	tSpin := spins && tSpinType != TSpinNone
+	if lines == 0 {
+		if tSpin {
+			return r.LineMultipliers[1]
+		}
+		return 0
+	}
+
+	score := r.LineMultipliers[lines]
+	if tSpinType == TSpinMini && tSpin {
+		score = r.LineMultipliers[1] * lines
+	} else if tSpin {
+		score = score*(lines+1) + r.TSpinBonus
+	}
+	if allSpin && spins {
+		score += score / 2
+	}
+	if btbActive && (lines == 4 || tSpin) {
+		score = score * r.BTBMultiplierNumerator / r.BTBMultiplierDenominator
+	}
+
+	if combo >= 1 {
+		score += r.ComboBase * combo
+	}
+	if perfectClears > 0 {
+		if tSpin {
+			score += r.TSpinPerfectClearBonus
+		} else {
+			score += r.PerfectClearBonus[lines]
+		}
+		streak := len(r.PerfectClearStreakBonus)
+		if perfectClears < streak {
+			streak = perfectClears
+		}
+		score += r.PerfectClearStreakBonus[streak-1]
+	}
+	return score
+}
@@ -0,0 +1,271 @@
+package game
+
+import "testing"
+
+// lockClear locks a vertical I piece in the right column of a board with
+// lines full rows below it, so the lock clears lines rows. A block left
+// over on the left keeps it from being a perfect clear.
+func lockClear(t *testing.T, gs *GameState, lines int) {
+	t.Helper()
+	gs.board = newBoard(gs.rows, gs.cols)
+	for r := 0; r < lines; r++ {
+		for c := 0; c < gs.cols-1; c++ {
+			gs.board[r][c] = Gray
+		}
+	}
+	gs.board[4][0] = Gray
+	col := gs.cols - 1
+	placePiece(gs, IPiece, Shape{{row: 0, col: col}, {row: 1, col: col}, {row: 2, col: col}, {row: 3, col: col}}, 1)
+	gs.lastMovementWasRotation = false
+	linesBefore := gs.linesCleared
+	gs.lockPiece()
+	if gs.clearAnim.active() {
+		gs.finishLineClear()
+	}
+	if got := gs.linesCleared - linesBefore; got != lines {
+		t.Fatalf("lock cleared %d lines, want %d", got, lines)
+	}
+}
+
+func TestComboScore(t *testing.T) {
+	tests := []struct {
+		lines int
+		combo int
+		score int // Total score after the lock
+	}{
+		{1, 0, 100},
+		{1, 1, 100 + 150},
+		{2, 2, 250 + 500},
+		{3, 3, 750 + 1050},
+		{0, -1, 1800},
+		{1, 0, 1800 + 100},
+		{1, 1, 1900 + 150},
+	}
+	gs := newTestGame(t)
+	for i, tt := range tests {
+		lockClear(t, gs, tt.lines)
+		if gs.combo != tt.combo || gs.score != tt.score {
+			t.Errorf("lock %d clearing %d lines: combo %d, score %d, want %d and %d", i, tt.lines, gs.combo, gs.score, tt.combo, tt.score)
+		}
+	}
+}
+
+func TestBackToBackTetris(t *testing.T) {
+	// The lock without a clear in between keeps the combo bonus out of the
+	// second Tetris, which is worth 1.5x
+	gs := newTestGame(t)
+	lockClear(t, gs, 4)
+	lockClear(t, gs, 0)
+	lockClear(t, gs, 4)
+	if want := 1600 + 2400; gs.score != want {
+		t.Errorf("score after Tetris, Tetris = %d, want %d", gs.score, want)
+	}
+	if !gs.btbActive || gs.btbCount != 1 {
+		t.Errorf("back to back = %t x%d, want true x1", gs.btbActive, gs.btbCount)
+	}
+}
+
+func TestBackToBackBroken(t *testing.T) {
+	gs := newTestGame(t)
+	lockClear(t, gs, 4)
+	lockClear(t, gs, 0)
+	lockClear(t, gs, 1)
+	if gs.btbActive || gs.btbCount != 0 {
+		t.Errorf("back to back = %t x%d after a single, want false x0", gs.btbActive, gs.btbCount)
+	}
+	lockClear(t, gs, 0)
+	lockClear(t, gs, 4)
+	if want := 1600 + 100 + 1600; gs.score != want {
+		t.Errorf("score after Tetris, single, Tetris = %d, want %d", gs.score, want)
+	}
+	if !gs.btbActive || gs.btbCount != 0 {
+		t.Errorf("back to back = %t x%d, want true x0", gs.btbActive, gs.btbCount)
+	}
+}
+
+// lockPerfectClear locks an I piece into a board of lines full rows and
+// nothing else, clearing the board. The piece lies flat for a single and
+// stands in the right column for a Tetris.
+func lockPerfectClear(t *testing.T, gs *GameState, lines int) {
+	t.Helper()
+	var shape Shape
+	switch lines {
+	case 1:
+		c := gs.cols - 4
+		shape = Shape{{row: 0, col: c}, {row: 0, col: c + 1}, {row: 0, col: c + 2}, {row: 0, col: c + 3}}
+	case 4:
+		c := gs.cols - 1
+		shape = Shape{{row: 0, col: c}, {row: 1, col: c}, {row: 2, col: c}, {row: 3, col: c}}
+	default:
+		t.Fatalf("lockPerfectClear(%d): only singles and Tetrises", lines)
+	}
+	gs.board = newBoard(gs.rows, gs.cols)
+	for r := 0; r < lines; r++ {
+		for c := 0; c < gs.cols; c++ {
+			gs.board[r][c] = Gray
+		}
+	}
+	for _, m := range shape {
+		gs.board[m.row][m.col] = Empty
+	}
+	placePiece(gs, IPiece, shape, 0)
+	gs.lastMovementWasRotation = false
+	gs.lockPiece()
+	gs.finishLineClear()
+	if !isBoardEmpty(gs.board) {
+		t.Fatalf("board not empty after a %d line perfect clear:\n%s", lines, gs.board)
+	}
+}
+
+func TestPerfectClear(t *testing.T) {
+	gs := newTestGame(t)
+	lockPerfectClear(t, gs, 4)
+
+	// A Tetris, the 4-line perfect clear bonus and the first of a streak
+	if want := 1600 + 2000 + 1000; gs.score != want {
+		t.Errorf("score after a perfect clear Tetris = %d, want %d", gs.score, want)
+	}
+	if !gs.lastClearWasPC || !gs.PerfectClearShown() || gs.allClearStreak != 1 {
+		t.Errorf("perfect clear = %t, shown %t, streak %d, want true, true, 1", gs.lastClearWasPC, gs.PerfectClearShown(), gs.allClearStreak)
+	}
+
+	// A clear that leaves a block behind isn't one
+	lockClear(t, gs, 1)
+	if gs.lastClearWasPC || gs.allClearStreak != 0 {
+		t.Errorf("perfect clear = %t, streak %d after a single, want false, 0", gs.lastClearWasPC, gs.allClearStreak)
+	}
+}
+
+func TestPerfectClearStreak(t *testing.T) {
+	// Singles that each clear the board. Every one after the first also
+	// scores a combo, and the streak bonus tops out at the fourth.
+	tests := []struct {
+		streak int
+		bonus  int
+	}{
+		{1, 1000},
+		{2, 1500},
+		{3, 2000},
+		{4, 3000},
+		{5, 3000},
+		{6, 3000},
+	}
+	gs := newTestGame(t)
+	for i, tt := range tests {
+		before := gs.score
+		lockPerfectClear(t, gs, 1)
+		if gs.allClearStreak != tt.streak || gs.AllClearStreak() != tt.streak {
+			t.Errorf("perfect clear %d: streak %d, want %d", i+1, gs.allClearStreak, tt.streak)
+		}
+		if got, want := gs.score-before, 100+50*i+800+tt.bonus; got != want {
+			t.Errorf("perfect clear %d scored %d, want %d", i+1, got, want)
+		}
+	}
+}
+
+func TestPerfectClearStreakReset(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int // Lines cleared by the lock that breaks the streak
+	}{
+		{"no clear", 0},
+		{"single", 1},
+		{"tetris", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := newTestGame(t)
+			lockPerfectClear(t, gs, 1)
+			lockPerfectClear(t, gs, 1)
+			lockClear(t, gs, tt.lines)
+			if gs.allClearStreak != 0 || gs.lastClearWasPC {
+				t.Errorf("streak %d, perfect clear %t, want 0, false", gs.allClearStreak, gs.lastClearWasPC)
+			}
+
+			// A new streak starts back at the first bonus
+			before := gs.score
+			lockPerfectClear(t, gs, 1)
+			if gs.allClearStreak != 1 {
+				t.Errorf("streak %d after starting over, want 1", gs.allClearStreak)
+			}
+			if got, want := gs.score-before, 100+50*gs.combo+800+1000; got != want {
+				t.Errorf("perfect clear after the reset scored %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+// award is the arguments of Scorer.Award
+type award struct {
+	lines         int
+	tSpin         TSpinType
+	allSpin       bool
+	combo         int
+	btbActive     bool
+	perfectClears int
+}
+
+func TestGuidelineScorer(t *testing.T) {
+	tests := []struct {
+		award
+		want int
+	}{
+		{award{lines: 0, combo: -1}, 0},
+		{award{lines: 1}, 100},
+		{award{lines: 2}, 400},
+		{award{lines: 3}, 900},
+		{award{lines: 4}, 1600},
+		{award{lines: 0, tSpin: TSpinFull, combo: -1}, 100},
+		{award{lines: 0, tSpin: TSpinMini, combo: -1}, 100},
+		{award{lines: 1, tSpin: TSpinFull}, 100*2 + 400},
+		{award{lines: 2, tSpin: TSpinFull}, 400*3 + 400},
+		{award{lines: 3, tSpin: TSpinFull}, 900*4 + 400},
+		{award{lines: 1, tSpin: TSpinMini}, 100},
+		{award{lines: 2, tSpin: TSpinMini}, 200},
+		{award{lines: 2, allSpin: true}, 600},
+		{award{lines: 4, btbActive: true}, 2400},
+		{award{lines: 2, tSpin: TSpinFull, btbActive: true}, 2400},
+		{award{lines: 1, btbActive: true}, 100},
+		{award{lines: 1, combo: 1}, 150},
+		{award{lines: 3, combo: 4}, 1100},
+		{award{lines: 4, perfectClears: 1}, 1600 + 2000 + 1000},
+		{award{lines: 1, perfectClears: 2}, 100 + 800 + 1500},
+		{award{lines: 2, tSpin: TSpinFull, perfectClears: 5}, 1600 + 2800 + 3000},
+		{award{lines: 4, combo: 2, btbActive: true, perfectClears: 3}, 2400 + 100 + 2000 + 2000},
+	}
+	s := GuidelineScorer()
+	for _, tt := range tests {
+		a := tt.award
+		if got := s.Award(a.lines, a.tSpin, a.allSpin, a.combo, a.btbActive, a.perfectClears); got != tt.want {
+			t.Errorf("Award(%+v) = %d, want %d", a, got, tt.want)
+		}
+	}
+}
+
+func TestClassicScorer(t *testing.T) {
+	// Only the lines count
+	tests := []struct {
+		award
+		want int
+	}{
+		{award{lines: 0, combo: -1}, 0},
+		{award{lines: 1}, 40},
+		{award{lines: 2}, 100},
+		{award{lines: 3}, 300},
+		{award{lines: 4}, 1200},
+		{award{lines: 0, tSpin: TSpinFull, combo: -1}, 0},
+		{award{lines: 1, tSpin: TSpinFull}, 40},
+		{award{lines: 2, tSpin: TSpinMini}, 100},
+		{award{lines: 2, allSpin: true}, 100},
+		{award{lines: 4, btbActive: true}, 1200},
+		{award{lines: 1, combo: 5}, 40},
+		{award{lines: 4, perfectClears: 1}, 1200},
+	}
+	s := ClassicScorer()
+	for _, tt := range tests {
+		a := tt.award
+		if got := s.Award(a.lines, a.tSpin, a.allSpin, a.combo, a.btbActive, a.perfectClears); got != tt.want {
+			t.Errorf("Award(%+v) = %d, want %d", a, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,15 @@
-package main
+package game
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/zkry/golang-tetris/config"
+)
 
 // Cache for rotated shapes to avoid recalculating them
 var (
 	rotationCacheMutex sync.RWMutex
-	rotationCache     = make(map[Piece]map[int]map[int]Shape) // Piece -> rotationState -> direction -> Shape
+	rotationCache      = make(map[Piece]map[int]map[int]Shape) // Piece -> rotationState -> direction -> Shape
 )
 
 // moveShape shifts a shape in a directy according to a given row and column.
@@ -31,17 +35,17 @@ func moveShapeLeft(s Shape) Shape {
 }
 
 // isGameOver checks if any of the Points in a shape are in the invisable rows
-// (ie rows 20 and 21)
-func isGameOver(s Shape) bool {
+// above the first rows rows of the board
+func isGameOver(s Shape, rows int) bool {
 	for i := 0; i < 4; i++ {
-		if s[i].row >= 20 {
+		if s[i].row >= rows {
 			return true
 		}
 	}
 	return false
 }
 
-func getShapeWidth(s Shape) int {
+func ShapeWidth(s Shape) int {
 	maxWidth := 0
 	for i := 1; i < 4; i++ {
 		w := s[i].col - s[0].col
@@ -52,9 +56,9 @@ func getShapeWidth(s Shape) int {
 	return maxWidth
 }
 
-func getShapeHeight(s Shape) int {
+func ShapeHeight(s Shape) int {
 	maxHeight := -1
-	minHeight := 22
+	minHeight := s[0].row
 	for i := 0; i < 4; i++ {
 		if s[i].row < minHeight {
 			minHeight = s[i].row
@@ -66,42 +70,23 @@ func getShapeHeight(s Shape) int {
 	return maxHeight - minHeight
 }
 
-// rotateShape rotates a shape by 90 degrees based on the pivot point
-// which is always the second element in the shape array (ie s[1]),
-// except for the I piece which has a special pivot point.
-func rotateShape(s Shape) Shape {
+// rotateShape rotates a shape, s, of piece p in rotation state, state, by
+// 90 degrees based on the pivot point which is always the second element in
+// the shape array (ie s[1]), except for the I piece which has a special pivot
+// point.
+func rotateShape(s Shape, p Piece, state int) Shape {
 	// Special case: don't rotate O piece
-	if currentPiece == OPiece {
+	if p == OPiece {
 		return s
 	}
 
 	// Check if the rotation is already cached
 	rotationCacheMutex.RLock()
-	if pieceCache, exists := rotationCache[currentPiece]; exists {
-		if stateCache, exists := pieceCache[rotationState]; exists {
+	if pieceCache, exists := rotationCache[p]; exists {
+		if stateCache, exists := pieceCache[state]; exists {
 			if cachedShape, exists := stateCache[1]; exists {
-				// Need to make a clean copy to avoid modifying cached shape
-				var shapeCopy Shape
-				copy(shapeCopy[:], cachedShape[:])
-				// Adjust position based on the current shape's position
-
-				// For I piece, the pivot is between blocks
-				var offsetRow, offsetCol int
-				if currentPiece == IPiece {
-					// For I piece, use the center point between blocks 1 and 2 as pivot
-					pivotRow := (s[1].row + s[2].row) / 2
-					pivotCol := (s[1].col + s[2].col) / 2
-					cachedPivotRow := (cachedShape[1].row + cachedShape[2].row) / 2
-					cachedPivotCol := (cachedShape[1].col + cachedShape[2].col) / 2
-					offsetRow = pivotRow - cachedPivotRow
-					offsetCol = pivotCol - cachedPivotCol
-				} else {
-					offsetRow = s[1].row - cachedShape[1].row
-					offsetCol = s[1].col - cachedShape[1].col
-				}
-
 				rotationCacheMutex.RUnlock()
-				return moveShape(offsetRow, offsetCol, shapeCopy)
+				return placeRotation(s, cachedShape, p)
 			}
 		}
 	}
@@ -109,11 +94,10 @@ func rotateShape(s Shape) Shape {
 
 	var retShape Shape
 
-	if currentPiece == IPiece {
+	if p == IPiece {
 		// For I piece in SRS, the rotation center is between blocks
 		// Calculate virtual center point between blocks 1 and 2
-		pivotRow := (s[1].row + s[2].row) / 2
-		pivotCol := (s[1].col + s[2].col) / 2
+		pivotRow, pivotCol := iPivot(s)
 
 		// Perform rotation around this center point
 		for i := 0; i < 4; i++ {
@@ -141,10 +125,9 @@ func rotateShape(s Shape) Shape {
 	// Cache this rotation for future use
 	// Store only the basic shape (offset from 0,0) in the cache
 	var offsetRow, offsetCol int
-	if currentPiece == IPiece {
+	if p == IPiece {
 		// For I piece, normalize based on virtual center
-		pivotRow := (retShape[1].row + retShape[2].row) / 2
-		pivotCol := (retShape[1].col + retShape[2].col) / 2
+		pivotRow, pivotCol := iPivot(retShape)
 		offsetRow = -pivotRow
 		offsetCol = -pivotCol
 	} else {
@@ -155,53 +138,62 @@ func rotateShape(s Shape) Shape {
 	normalizedShape := moveShape(offsetRow, offsetCol, retShape)
 
 	rotationCacheMutex.Lock()
-	if _, exists := rotationCache[currentPiece]; !exists {
-		rotationCache[currentPiece] = make(map[int]map[int]Shape)
+	if _, exists := rotationCache[p]; !exists {
+		rotationCache[p] = make(map[int]map[int]Shape)
 	}
-	if _, exists := rotationCache[currentPiece][rotationState]; !exists {
-		rotationCache[currentPiece][rotationState] = make(map[int]Shape)
+	if _, exists := rotationCache[p][state]; !exists {
+		rotationCache[p][state] = make(map[int]Shape)
 	}
-	rotationCache[currentPiece][rotationState][1] = normalizedShape
+	rotationCache[p][state][1] = normalizedShape
 	rotationCacheMutex.Unlock()
 
-	return retShape
+	// Placed as a cached rotation is, so the first rotation from a state
+	// lands where every later one does
+	return placeRotation(s, normalizedShape, p)
+}
+
+// iPivot returns the center of an I piece, s, between its second and third
+// blocks. Halving rounds down, so the center moves with the piece wherever
+// it is, below the floor or past the left wall included.
+func iPivot(s Shape) (row, col int) {
+	return (s[1].row + s[2].row) >> 1, (s[1].col + s[2].col) >> 1
+}
+
+// placeRotation moves rotated, a rotation of s normalized for the cache, to
+// where s is: for the I piece its center is put on the center of s, for
+// other pieces its pivot on the pivot of s.
+func placeRotation(s, rotated Shape, p Piece) Shape {
+	if p == IPiece {
+		pivotRow, pivotCol := iPivot(s)
+		rotatedRow, rotatedCol := iPivot(rotated)
+		return moveShape(pivotRow-rotatedRow, pivotCol-rotatedCol, rotated)
+	}
+	return moveShape(s[1].row-rotated[1].row, s[1].col-rotated[1].col, rotated)
 }
 
-// rotateShapeCounterClockwise rotates a shape 90 degrees counter-clockwise
-// based on the pivot point which is always the second element (s[1]),
-// except for the I piece which has a special pivot point.
-func rotateShapeCounterClockwise(s Shape) Shape {
+// rotateShape180 rotates a shape, s, of piece p in rotation state, state, by
+// 180 degrees by rotating it clockwise twice.
+func rotateShape180(s Shape, p Piece, state int) Shape {
+	return rotateShape(rotateShape(s, p, state), p, (state+1)%4)
+}
+
+// rotateShapeCounterClockwise rotates a shape, s, of piece p in rotation
+// state, state, 90 degrees counter-clockwise based on the pivot point which is
+// always the second element (s[1]), except for the I piece which has a special
+// pivot point.
+func rotateShapeCounterClockwise(s Shape, p Piece, state int) Shape {
 	// Special case: don't rotate O piece
-	if currentPiece == OPiece {
+	if p == OPiece {
 		return s
 	}
 
 	// Check if the rotation is already cached
 	rotationCacheMutex.RLock()
-	if pieceCache, exists := rotationCache[currentPiece]; exists {
-		if stateCache, exists := pieceCache[rotationState]; exists {
+	if pieceCache, exists := rotationCache[p]; exists {
+		if stateCache, exists := pieceCache[state]; exists {
 			if cachedShape, exists := stateCache[-1]; exists {
-				// Need to make a clean copy to avoid modifying cached shape
-				var shapeCopy Shape
-				copy(shapeCopy[:], cachedShape[:])
-
-				// For I piece, the pivot is between blocks
-				var offsetRow, offsetCol int
-				if currentPiece == IPiece {
-					// For I piece, use the center point between blocks 1 and 2 as pivot
-					pivotRow := (s[1].row + s[2].row) / 2
-					pivotCol := (s[1].col + s[2].col) / 2
-					cachedPivotRow := (cachedShape[1].row + cachedShape[2].row) / 2
-					cachedPivotCol := (cachedShape[1].col + cachedShape[2].col) / 2
-					offsetRow = pivotRow - cachedPivotRow
-					offsetCol = pivotCol - cachedPivotCol
-				} else {
-					offsetRow = s[1].row - cachedShape[1].row
-					offsetCol = s[1].col - cachedShape[1].col
-				}
-
 				rotationCacheMutex.RUnlock()
-				return moveShape(offsetRow, offsetCol, shapeCopy)
+				return placeRotation(s, cachedShape, p)
 			}
 		}
 	}
@@ -209,11 +201,10 @@ func rotateShapeCounterClockwise(s Shape) Shape {
 
 	var retShape Shape
 
-	if currentPiece == IPiece {
+	if p == IPiece {
 		// For I piece in SRS, the rotation center is between blocks
 		// Calculate virtual center point between blocks 1 and 2
-		pivotRow := (s[1].row + s[2].row) / 2
-		pivotCol := (s[1].col + s[2].col) / 2
+		pivotRow, pivotCol := iPivot(s)
 
 		// Perform rotation around this center point
 		for i := 0; i < 4; i++ {
@@ -241,10 +232,9 @@ func rotateShapeCounterClockwise(s Shape) Shape {
 	// Cache this rotation for future use
 	// Store only the basic shape (offset from 0,0) in the cache
 	var offsetRow, offsetCol int
-	if currentPiece == IPiece {
+	if p == IPiece {
 		// For I piece, normalize based on virtual center
-		pivotRow := (retShape[1].row + retShape[2].row) / 2
-		pivotCol := (retShape[1].col + retShape[2].col) / 2
+		pivotRow, pivotCol := iPivot(retShape)
 		offsetRow = -pivotRow
 		offsetCol = -pivotCol
 	} else {
@@ -255,22 +245,24 @@ func rotateShapeCounterClockwise(s Shape) Shape {
 	normalizedShape := moveShape(offsetRow, offsetCol, retShape)
 
 	rotationCacheMutex.Lock()
-	if _, exists := rotationCache[currentPiece]; !exists {
-		rotationCache[currentPiece] = make(map[int]map[int]Shape)
+	if _, exists := rotationCache[p]; !exists {
+		rotationCache[p] = make(map[int]map[int]Shape)
 	}
-	if _, exists := rotationCache[currentPiece][rotationState]; !exists {
-		rotationCache[currentPiece][rotationState] = make(map[int]Shape)
+	if _, exists := rotationCache[p][state]; !exists {
+		rotationCache[p][state] = make(map[int]Shape)
 	}
-	rotationCache[currentPiece][rotationState][-1] = normalizedShape
+	rotationCache[p][state][-1] = normalizedShape
 	rotationCacheMutex.Unlock()
 
-	return retShape
+	// Placed as a cached rotation is, so the first rotation from a state
+	// lands where every later one does
+	return placeRotation(s, normalizedShape, p)
 }
 
-// getShapeFromPiece returns the shape based on the piece type. There
+// PieceShape returns the shape based on the piece type. There
 // are seven shapes available: LPiece, IPiece, OPiece, TPiece, SPiece,
 // ZPiece, and JPiece.
-func getShapeFromPiece(p Piece) Shape {
+func PieceShape(p Piece) Shape {
 	var retShape Shape
 	switch p {
 	case LPiece:
@@ -325,11 +317,26 @@ func getShapeFromPiece(p Piece) Shape {
 			Point{row: 0, col: 2},
 		}
 	default:
-		panic("getShapeFromPiece(Piece): Invalid piece entered")
+		panic("PieceShape(Piece): Invalid piece entered")
 	}
 	return retShape
 }
 
+// spawnCol returns the column of the left edge of a newly spawned piece, p.
+// Pieces spawn centered on the board, rounding to the left, as in the
+// Tetris guideline.
+func spawnCol(p Piece, cols int) int {
+	switch p {
+	case IPiece:
+		return (cols - 4) / 2
+	case OPiece:
+		return (cols - 2) / 2
+	default:
+		// The rest are 3 wide and lean to the left
+		return (cols - 3) / 2
+	}
+}
+
 // wallKickData returns the wall kick offsets to test for the given piece and rotation.
 // According to SRS (Super Rotation System) rules, but with enhanced kicks for better responsiveness.
 // state is the current rotation state (0-3), where:
@@ -390,11 +397,11 @@ func wallKickData(piece Piece, state int, direction int) [][2]int {
 			// 0->L
 			{{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}, {2, 0}, {2, 1}, {0, -3}, {1, -3}, {2, -2}, {-2, 0}},
 			// R->0
-			{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}, {-2, 0}, {-2, -1}, {0, 3}, {-1, 3}, {-2, 2}, {2, 0}},
+			{{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}, {2, 0}, {2, -1}, {0, 3}, {1, 3}, {2, 2}, {-2, 0}},
 			// 2->R
 			{{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}, {-2, 0}, {-2, 1}, {0, -3}, {-1, -3}, {-2, -2}, {2, 0}},
 			// L->2
-			{{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}, {2, 0}, {2, -1}, {0, 3}, {1, 3}, {2, 2}, {-2, 0}},
+			{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}, {-2, 0}, {-2, -1}, {0, 3}, {-1, 3}, {-2, 2}, {2, 0}},
 		}
 
 		if direction == 1 {
@@ -408,6 +415,73 @@ func wallKickData(piece Piece, state int, direction int) [][2]int {
 	return [][2]int{{0, 0}}
 }
 
+// standardWallKickData returns the wall kick offsets to test for the given
+// piece and rotation exactly as they are in the SRS tables of the Tetris
+// guideline, with no extra kicks. The arguments are the same as for
+// wallKickData.
+func standardWallKickData(piece Piece, state int, direction int) [][2]int {
+	if piece == IPiece {
+		kicksClockwise := [][][2]int{
+			{{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}}, // 0->R
+			{{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}}, // R->2
+			{{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}}, // 2->L
+			{{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}}, // L->0
+		}
+		kicksCounterClockwise := [][][2]int{
+			{{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}}, // 0->L
+			{{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}}, // R->0
+			{{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}}, // 2->R
+			{{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}}, // L->2
+		}
+		if direction == 1 {
+			return kicksClockwise[state]
+		}
+		return kicksCounterClockwise[state]
+	} else if piece != OPiece {
+		kicksClockwise := [][][2]int{
+			{{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}}, // 0->R
+			{{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},     // R->2
+			{{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},    // 2->L
+			{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},  // L->0
+		}
+		kicksCounterClockwise := [][][2]int{
+			{{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},    // 0->L
+			{{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},     // R->0
+			{{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}}, // 2->R
+			{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},  // L->2
+		}
+		if direction == 1 {
+			return kicksClockwise[state]
+		}
+		return kicksCounterClockwise[state]
+	}
+
+	// O piece doesn't need wall kicks
+	return [][2]int{{0, 0}}
+}
+
+// wallKick180Data returns the wall kick offsets to test when rotating piece
+// by 180 degrees from rotation state, state. There is no 180 degree table in
+// SRS so the clockwise (state->state+1) and counter-clockwise
+// (state+1->state) kicks are combined, leaving out duplicates. In mode
+// WKMStandard they are the guideline kicks of standardWallKickData.
+func wallKick180Data(piece Piece, state int, mode config.WallKickMode) [][2]int {
+	kickData := wallKickData
+	if mode == config.WKMStandard {
+		kickData = standardWallKickData
+	}
+	var kicks [][2]int
+	seen := make(map[[2]int]bool)
+	all := append(kickData(piece, state, 1), kickData(piece, (state+1)%4, -1)...)
+	for _, kick := range all {
+		if !seen[kick] {
+			seen[kick] = true
+			kicks = append(kicks, kick)
+		}
+	}
+	return kicks
+}
+
 // getExtraIKicks provides additional wall kick options for the I piece
 // beyond the standard SRS kicks to make rotation feel more responsive
 func getExtraIKicks(state int, direction int) [][2]int {
@@ -441,3 +515,100 @@ func getExtraIKicks(state int, direction int) [][2]int {
 		return counterClockwiseExtraKicks[state]
 	}
 }
+
+// finesse holds the fewest key presses that take each piece from its spawn
+// position to a rotation state with its left edge in a column of an empty
+// board, indexed by piece, rotation state and column. Holding a direction
+// to the wall (DAS) counts as one press. Unreachable placements are -1.
+// Only boards that are finesseCols wide are covered.
+var finesse = buildFinesseTable()
+
+// finesseCols is the board width the finesse table is built for
+const finesseCols = 10
+
+// buildFinesseTable fills the finesse table with a breadth first search over
+// the rotation states and columns each piece can reach, using the game's
+// own movement and rotation so wall kicks are taken into account.
+func buildFinesseTable() [7][4][finesseCols]int {
+	var table [7][4][finesseCols]int
+	for p := range table {
+		for state := range table[p] {
+			for col := range table[p][state] {
+				table[p][state][col] = -1
+			}
+		}
+	}
+
+	// Every key press a piece can be placed with
+	actions := []func(gs *GameState){
+		func(gs *GameState) { gs.movePiece(-1) },
+		func(gs *GameState) { gs.movePiece(1) },
+		func(gs *GameState) {
+			for gs.movePiece(-1) {
+			}
+		},
+		func(gs *GameState) {
+			for gs.movePiece(1) {
+			}
+		},
+		func(gs *GameState) { gs.rotatePiece(1) },
+		func(gs *GameState) { gs.rotatePiece(-1) },
+		func(gs *GameState) { gs.rotatePiece180() },
+	}
+
+	type node struct {
+		shape Shape
+		state int
+	}
+	for p := IPiece; p <= ZPiece; p++ {
+		gs := &GameState{currentPiece: p}
+		start := node{moveShape(10, spawnCol(p, finesseCols), PieceShape(p)), 0}
+		table[p][0][shapeLeftCol(start.shape)] = 0
+		queue := []node{start}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			presses := table[p][n.state][shapeLeftCol(n.shape)]
+
+			for _, action := range actions {
+				gs.board = newBoard(20, finesseCols)
+				gs.activeShape = n.shape
+				gs.rotationState = n.state
+				gs.board.drawPiece(n.shape, PieceBlock(p))
+				action(gs)
+
+				// Keep the piece away from the floor and ceiling so kicks
+				// behave the same at every depth
+				next := node{moveShape(10-shapeBottomRow(gs.activeShape), 0, gs.activeShape), gs.rotationState}
+				col := shapeLeftCol(next.shape)
+				if table[p][next.state][col] == -1 {
+					table[p][next.state][col] = presses + 1
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return table
+}
+
+// shapeLeftCol returns the leftmost column of a shape, s
+func shapeLeftCol(s Shape) int {
+	col := s[0].col
+	for i := 1; i < 4; i++ {
+		if s[i].col < col {
+			col = s[i].col
+		}
+	}
+	return col
+}
+
+// shapeBottomRow returns the lowest row of a shape, s
+func shapeBottomRow(s Shape) int {
+	row := s[0].row
+	for i := 1; i < 4; i++ {
+		if s[i].row < row {
+			row = s[i].row
+		}
+	}
+	return row
+}
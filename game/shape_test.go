@@ -0,0 +1,516 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+)
+
+// allPieces are the seven pieces in Piece order
+var allPieces = []Piece{IPiece, JPiece, LPiece, OPiece, SPiece, TPiece, ZPiece}
+
+func TestSpawnCol(t *testing.T) {
+	want := map[Piece]int{
+		IPiece: 3,
+		JPiece: 3,
+		LPiece: 3,
+		OPiece: 4,
+		SPiece: 3,
+		TPiece: 3,
+		ZPiece: 3,
+	}
+	for _, p := range allPieces {
+		if got := spawnCol(p, 10); got != want[p] {
+			t.Errorf("spawnCol(%s, 10) = %d, want %d", PieceName(p), got, want[p])
+		}
+	}
+}
+
+func TestSpawnSameColumn(t *testing.T) {
+	// Every piece dealt, and every piece taken back out of hold, spawns in
+	// the same column as the last one of its kind
+	gs := newTestGame(t)
+	cols := make(map[Piece]int)
+	check := func(how string) {
+		t.Helper()
+		col := shapeLeftCol(gs.activeShape)
+		if want, ok := cols[gs.currentPiece]; ok && col != want {
+			t.Errorf("%s %s spawned at column %d, want %d as before", how, PieceName(gs.currentPiece), col, want)
+		}
+		if want := spawnCol(gs.currentPiece, gs.cols); col != want {
+			t.Errorf("%s %s spawned at column %d, want %d", how, PieceName(gs.currentPiece), col, want)
+		}
+		cols[gs.currentPiece] = col
+	}
+	for i := 0; i < 28; i++ {
+		check("dealt")
+		if i%3 == 0 {
+			gs.canHold = true
+			gs.holdCurrentPiece()
+			check("held")
+		}
+		gs.board.drawPiece(gs.activeShape, Empty)
+		gs.addPiece()
+	}
+}
+
+// sameCells reports whether shapes a and b cover the same cells, in any
+// order
+func sameCells(a, b Shape) bool {
+	for _, p := range a {
+		found := false
+		for _, q := range b {
+			if p == q {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// midBoard returns the spawn shape of p moved away from the walls and floor
+func midBoard(p Piece) Shape {
+	return moveShape(10, 4, PieceShape(p))
+}
+
+// shapeInState returns p away from the walls turned clockwise from its
+// spawn shape into rotation state
+func shapeInState(p Piece, state int) Shape {
+	s := midBoard(p)
+	for i := 0; i < state; i++ {
+		s = rotateShape(s, p, i)
+	}
+	return s
+}
+
+func TestRotateShape180Twice(t *testing.T) {
+	for _, p := range allPieces {
+		for state := 0; state < 4; state++ {
+			s := shapeInState(p, state)
+			once := rotateShape180(s, p, state)
+			if twice := rotateShape180(once, p, (state+2)%4); !sameCells(twice, s) {
+				t.Errorf("%s from state %d: two 180° rotations gave %v, want %v", PieceName(p), state, twice, s)
+			}
+		}
+	}
+}
+
+func TestRotatePiece180FourTimes(t *testing.T) {
+	for _, p := range allPieces {
+		gs := newTestGame(t)
+		gs.board.drawPiece(gs.activeShape, Empty)
+		start := midBoard(p)
+		placePiece(gs, p, start, 0)
+		for i := 1; i <= 4; i++ {
+			rotated := gs.rotatePiece180()
+			if p == OPiece {
+				if rotated || gs.activeShape != start {
+					t.Errorf("O piece rotated")
+				}
+				continue
+			}
+			if !rotated {
+				t.Fatalf("%s: 180° rotation %d failed on an empty board", PieceName(p), i)
+			}
+			if want := (2 * i) % 4; gs.rotationState != want {
+				t.Errorf("%s: rotation state %d after %d 180° rotations, want %d", PieceName(p), gs.rotationState, i, want)
+			}
+			if i%2 == 0 && !sameCells(gs.activeShape, start) {
+				t.Errorf("%s: %d 180° rotations gave %v, want %v", PieceName(p), i, gs.activeShape, start)
+			}
+		}
+	}
+}
+
+// stateNames are the guideline names of the rotation states
+var stateNames = [4]string{"0", "R", "2", "L"}
+
+// guidelineKicks are the SRS kicks of the Tetris guideline as (x, y)
+// offsets with y up, by transition
+var guidelineKicks = map[string]map[string][][2]int{
+	"JLSTZ": {
+		"0->R": {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+		"R->0": {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+		"R->2": {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+		"2->R": {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+		"2->L": {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+		"L->2": {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+		"L->0": {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+		"0->L": {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+	},
+	"I": {
+		"0->R": {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+		"R->0": {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+		"R->2": {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+		"2->R": {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+		"2->L": {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+		"L->2": {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+		"L->0": {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+		"0->L": {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+	},
+}
+
+// kickTable returns the name of the guideline kick table of p
+func kickTable(p Piece) string {
+	if p == IPiece {
+		return "I"
+	}
+	return "JLSTZ"
+}
+
+// transition names the rotation from state in direction
+func transition(state, direction int) string {
+	return stateNames[state] + "->" + stateNames[(state+direction+4)%4]
+}
+
+func TestStandardWallKickData(t *testing.T) {
+	for _, p := range allPieces {
+		if p == OPiece {
+			continue
+		}
+		for state := 0; state < 4; state++ {
+			for _, dir := range []int{1, -1} {
+				name := transition(state, dir)
+				want := guidelineKicks[kickTable(p)][name]
+				if got := standardWallKickData(p, state, dir); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s %s kicks = %v, want %v", PieceName(p), name, got, want)
+				}
+			}
+		}
+	}
+}
+
+// kickFuncs are the kick tables of each wall kick mode
+var kickFuncs = []struct {
+	name  string
+	kicks func(Piece, int, int) [][2]int
+}{
+	{"wallKickData", wallKickData},
+	{"standardWallKickData", standardWallKickData},
+}
+
+// duplicateKick returns a kick found twice in kicks, if any
+func duplicateKick(kicks [][2]int) ([2]int, bool) {
+	seen := make(map[[2]int]bool)
+	for _, k := range kicks {
+		if seen[k] {
+			return k, true
+		}
+		seen[k] = true
+	}
+	return [2]int{}, false
+}
+
+func TestWallKickDataO(t *testing.T) {
+	// The O turns in place, whatever the mode
+	want := [][2]int{{0, 0}}
+	for state := 0; state < 4; state++ {
+		for _, f := range kickFuncs {
+			for _, dir := range []int{1, -1} {
+				if got := f.kicks(OPiece, state, dir); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: O %s kicks = %v, want %v", f.name, transition(state, dir), got, want)
+				}
+			}
+		}
+		for _, mode := range []config.WallKickMode{config.WKMGenerous, config.WKMStandard} {
+			if got := wallKick180Data(OPiece, state, mode); !reflect.DeepEqual(got, want) {
+				t.Errorf("O 180 kicks from state %s in mode %s = %v, want %v", stateNames[state], mode, got, want)
+			}
+		}
+	}
+}
+
+func TestWallKickDataLists(t *testing.T) {
+	// Every list tries the piece where it turned first and no offset twice,
+	// and the enhanced kicks only add to the guideline ones
+	for _, p := range allPieces {
+		if p == OPiece {
+			continue
+		}
+		for state := 0; state < 4; state++ {
+			for _, dir := range []int{1, -1} {
+				name := transition(state, dir)
+				want := guidelineKicks[kickTable(p)][name]
+				for _, f := range kickFuncs {
+					got := f.kicks(p, state, dir)
+					if len(got) == 0 || got[0] != [2]int{0, 0} {
+						t.Errorf("%s: %s %s kicks = %v, want them to start with (0, 0)", f.name, PieceName(p), name, got)
+						continue
+					}
+					if k, ok := duplicateKick(got); ok {
+						t.Errorf("%s: %s %s kicks = %v, with %v twice", f.name, PieceName(p), name, got, k)
+					}
+					if len(got) < len(want) || !reflect.DeepEqual(got[:len(want)], want) {
+						t.Errorf("%s: %s %s kicks = %v, want them to start with %v", f.name, PieceName(p), name, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWallKickSymmetry(t *testing.T) {
+	// Turning back from a state tries the kicks of turning into it the
+	// other way round: those of N->N+1 negated are those of N+1->N
+	for _, p := range allPieces {
+		if p == OPiece {
+			continue
+		}
+		for state := 0; state < 4; state++ {
+			next := (state + 1) % 4
+			for _, f := range kickFuncs {
+				cw, ccw := f.kicks(p, state, 1), f.kicks(p, next, -1)
+				// Past the guideline kicks the enhanced I tries its own
+				n := minInt(len(cw), len(ccw))
+				if f.name == "wallKickData" {
+					n = len(guidelineKicks[kickTable(p)][transition(state, 1)])
+				}
+				for i := 0; i < n; i++ {
+					if ccw[i] != [2]int{-cw[i][0], -cw[i][1]} {
+						t.Errorf("%s: %s kick %d of %s is %v, want %s's %v negated", f.name, PieceName(p), i, transition(next, -1), ccw[i], transition(state, 1), cw[i])
+					}
+				}
+				if f.name == "standardWallKickData" && len(cw) != len(ccw) {
+					t.Errorf("%s: %s has %d kicks %s and %d back", f.name, PieceName(p), len(cw), transition(state, 1), len(ccw))
+				}
+			}
+		}
+	}
+}
+
+func TestWallKick180Standard(t *testing.T) {
+	// The standard 180 kicks are the guideline kicks of the two quarter
+	// turns it is made of, each once
+	for _, p := range allPieces {
+		if p == OPiece {
+			continue
+		}
+		for state := 0; state < 4; state++ {
+			table := guidelineKicks[kickTable(p)]
+			allowed := make(map[[2]int]bool)
+			for _, k := range append(table[transition(state, 1)], table[transition((state+1)%4, -1)]...) {
+				allowed[k] = true
+			}
+			got := wallKick180Data(p, state, config.WKMStandard)
+			if len(got) != len(allowed) {
+				t.Errorf("%s 180 kicks from state %s = %v, want the %d of %s and %s", PieceName(p), stateNames[state], got, len(allowed), transition(state, 1), transition((state+1)%4, -1))
+			}
+			if k, ok := duplicateKick(got); ok {
+				t.Errorf("%s 180 kicks from state %s = %v, with %v twice", PieceName(p), stateNames[state], got, k)
+			}
+			for _, k := range got {
+				if !allowed[k] {
+					t.Errorf("%s 180 kicks from state %s has %v, not a guideline kick", PieceName(p), stateNames[state], k)
+				}
+			}
+		}
+	}
+}
+
+func TestStandardKickApplied(t *testing.T) {
+	// For each kick of each transition of I and T, a board with only room
+	// for the piece where that kick puts it. The piece must end up there,
+	// or where an earlier kick fits in the cells it left.
+	settings := config.DefaultSettings()
+	settings.WallKickMode = config.WKMStandard
+	for _, p := range []Piece{IPiece, TPiece} {
+		for state := 0; state < 4; state++ {
+			for _, dir := range []int{1, -1} {
+				name := transition(state, dir)
+				start := shapeInState(p, state)
+				turned := rotateShape(start, p, state)
+				if dir == -1 {
+					turned = rotateShapeCounterClockwise(start, p, state)
+				}
+				kicks := guidelineKicks[kickTable(p)][name]
+				for k, kick := range kicks {
+					target := moveShape(kick[1], kick[0], turned) // y is up a row, x right a column
+					gs := NewSeededGameState(ModeMarathon, settings, 1)
+					for r := range gs.board {
+						for c := range gs.board[r] {
+							gs.board[r][c] = Gray
+						}
+					}
+					gs.board.fillShape(target, Empty)
+					placePiece(gs, p, start, state)
+
+					want := target
+					for _, earlier := range kicks[:k] {
+						s := moveShape(earlier[1], earlier[0], turned)
+						if fitsIn(s, start, target) {
+							want = s
+							break
+						}
+					}
+					if !gs.rotatePiece(dir) {
+						t.Errorf("%s %s kick %d %v: rotation failed", PieceName(p), name, k, kick)
+						continue
+					}
+					if !sameCells(gs.activeShape, want) || gs.rotationState != (state+dir+4)%4 {
+						t.Errorf("%s %s kick %d %v: piece at %v in state %d, want %v", PieceName(p), name, k, kick, gs.activeShape, gs.rotationState, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// fitsIn reports whether every cell of s is a cell of a or b
+func fitsIn(s, a, b Shape) bool {
+	for _, p := range s {
+		found := false
+		for i := range a {
+			found = found || p == a[i] || p == b[i]
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// coldRotationCache empties the rotation cache for the test, restoring it
+// at the end
+func coldRotationCache(t *testing.T) {
+	keepRotationCache(t)
+	rotationCacheMutex.Lock()
+	rotationCache = make(map[Piece]map[int]map[int]Shape)
+	rotationCacheMutex.Unlock()
+}
+
+// shapeBounds returns the number of columns and rows s spans
+func shapeBounds(s Shape) (cols, rows int) {
+	minRow, maxRow, minCol, maxCol := s[0].row, s[0].row, s[0].col, s[0].col
+	for _, p := range s {
+		minRow, maxRow = minInt(minRow, p.row), maxInt(maxRow, p.row)
+		minCol, maxCol = minInt(minCol, p.col), maxInt(maxCol, p.col)
+	}
+	return maxCol - minCol + 1, maxRow - minRow + 1
+}
+
+func TestRotateCacheHit(t *testing.T) {
+	// The rotation worked out and the one cached agree, whichever way a
+	// piece is first turned
+	coldRotationCache(t)
+	for _, p := range allPieces {
+		for state := 0; state < 4; state++ {
+			s := moveShape(10, 4, PieceShape(p))
+			for i := 0; i < state; i++ {
+				s = rotateShape(s, p, i)
+			}
+			for _, dir := range []int{-1, 1} {
+				rotate := rotateShape
+				if dir == -1 {
+					rotate = rotateShapeCounterClockwise
+				}
+				miss := rotate(s, p, state)
+				if hit := rotate(s, p, state); hit != miss {
+					t.Errorf("%s %s: %v from the cache, %v worked out", PieceName(p), transition(state, dir), hit, miss)
+				}
+			}
+		}
+	}
+}
+
+func TestRotateFourTimes(t *testing.T) {
+	// Four turns the same way bring every piece back to where it started,
+	// both from the cache and worked out
+	for _, cold := range []bool{true, false} {
+		if cold {
+			coldRotationCache(t)
+		}
+		for _, p := range allPieces {
+			for state := 0; state < 4; state++ {
+				start := shapeInState(p, state)
+				cw, ccw := start, start
+				for i := 0; i < 4; i++ {
+					cw = rotateShape(cw, p, (state+i)%4)
+					ccw = rotateShapeCounterClockwise(ccw, p, (state-i+4)%4)
+				}
+				if cw != start {
+					t.Errorf("%s from state %s: 4 clockwise turns gave %v, want %v", PieceName(p), stateNames[state], cw, start)
+				}
+				if ccw != start {
+					t.Errorf("%s from state %s: 4 counter-clockwise turns gave %v, want %v", PieceName(p), stateNames[state], ccw, start)
+				}
+			}
+		}
+	}
+}
+
+func TestRotateThereAndBack(t *testing.T) {
+	// A turn undone the other way leaves the piece exactly as it was
+	for _, cold := range []bool{true, false} {
+		if cold {
+			coldRotationCache(t)
+		}
+		for _, p := range allPieces {
+			for state := 0; state < 4; state++ {
+				start := shapeInState(p, state)
+				next, prev := (state+1)%4, (state+3)%4
+				if back := rotateShapeCounterClockwise(rotateShape(start, p, state), p, next); back != start {
+					t.Errorf("%s %s and back gave %v, want %v", PieceName(p), transition(state, 1), back, start)
+				}
+				if back := rotateShape(rotateShapeCounterClockwise(start, p, state), p, prev); back != start {
+					t.Errorf("%s %s and back gave %v, want %v", PieceName(p), transition(state, -1), back, start)
+				}
+			}
+		}
+	}
+}
+
+func TestRotateO(t *testing.T) {
+	coldRotationCache(t)
+	s := midBoard(OPiece)
+	for state := 0; state < 4; state++ {
+		if got := rotateShape(s, OPiece, state); got != s {
+			t.Errorf("O turned clockwise from state %s to %v", stateNames[state], got)
+		}
+		if got := rotateShapeCounterClockwise(s, OPiece, state); got != s {
+			t.Errorf("O turned counter-clockwise from state %s to %v", stateNames[state], got)
+		}
+	}
+	rotationCacheMutex.RLock()
+	defer rotationCacheMutex.RUnlock()
+	if _, ok := rotationCache[OPiece]; ok {
+		t.Error("O rotations were cached")
+	}
+}
+
+func TestRotateIBounds(t *testing.T) {
+	// Flat in the spawn and 2 states, upright in R and L
+	want := [4][2]int{{4, 1}, {1, 4}, {4, 1}, {1, 4}}
+	for state := 0; state < 4; state++ {
+		cols, rows := shapeBounds(shapeInState(IPiece, state))
+		if cols != want[state][0] || rows != want[state][1] {
+			t.Errorf("I in state %s spans %dx%d, want %dx%d", stateNames[state], cols, rows, want[state][0], want[state][1])
+		}
+		cols, rows = shapeBounds(rotateShapeCounterClockwise(shapeInState(IPiece, (state+1)%4), IPiece, (state+1)%4))
+		if cols != want[state][0] || rows != want[state][1] {
+			t.Errorf("I turned counter-clockwise into state %s spans %dx%d, want %dx%d", stateNames[state], cols, rows, want[state][0], want[state][1])
+		}
+	}
+}
+
+func TestRotateTStem(t *testing.T) {
+	// Row 0 is the bottom, so the T spawns with its stem pointing down and
+	// turns clockwise to point right, up, then left
+	want := [4]Point{{row: -1}, {col: 1}, {row: 1}, {col: -1}}
+	for state := 0; state < 4; state++ {
+		s := shapeInState(TPiece, state)
+		stem := Point{row: s[3].row - s[1].row, col: s[3].col - s[1].col}
+		if stem != want[state] {
+			t.Errorf("T in state %s has its stem at %v from the center, want %v", stateNames[state], stem, want[state])
+		}
+		for i, p := range s {
+			if i != 1 && i != 3 && p.row-s[1].row == stem.row && p.col-s[1].col == stem.col {
+				t.Errorf("T in state %s has two stems: %v", stateNames[state], s)
+			}
+		}
+	}
+}
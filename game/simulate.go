@@ -0,0 +1,56 @@
+package game
+
+import (
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// SimulateGame plays a marathon game with the default settings, dealt from
+// seed, without opening a window. The inputs are fed to the game on the
+// frames they were recorded on, like a replay that is played back. Stops
+// when the game is over or after maxFrames steps, when maxFrames is above 0.
+func SimulateGame(seed int64, inputs []replay.ReplayEvent, maxFrames int) *GameState {
+	gs := NewSeededGameState(ModeMarathon, config.DefaultSettings(), seed)
+	gs.Simulate(replay.NewReplayPlayer(seed, inputs, nil), maxFrames)
+	return gs
+}
+
+// Simulate runs the game in fixed steps with the input of player until it is
+// over or maxFrames steps have run, when maxFrames is above 0. The game
+// keeps going without input once the player runs out of events. It stops
+// early with an error when the board no longer matches the replay's hashes.
+func (gs *GameState) Simulate(player *replay.ReplayPlayer, maxFrames int) error {
+	return gs.simulate(player, maxFrames, nil)
+}
+
+// CheckReplay compares the board to the hash player recorded for the
+// current frame, if there is one, and returns an error when they differ
+func (gs *GameState) CheckReplay(player *replay.ReplayPlayer) error {
+	if len(gs.frameHashes) == 0 {
+		return nil
+	}
+	return player.CheckHash(gs.frame, gs.frameHashes[len(gs.frameHashes)-1])
+}
+
+// simulate is Simulate, calling afterStep after every step when it is not
+// nil
+func (gs *GameState) simulate(player *replay.ReplayPlayer, maxFrames int, afterStep func()) error {
+	var in input.PlayerInput
+	var down [input.NumActions]bool
+	handler := input.NewInputHandler(gs.settings.DAS, gs.settings.ARR)
+	for step := 0; !gs.gameOver && (maxFrames <= 0 || step < maxFrames); step++ {
+		for _, e := range player.NextEvents(gs.frame) {
+			down[e.Key] = e.EventType == replay.KeyDown
+		}
+		in.Next(down)
+		gs.Update(handler.Update(StepLength, in.Pressed, in.JustPressed, in.JustReleased), StepLength)
+		if afterStep != nil {
+			afterStep()
+		}
+		if err := gs.CheckReplay(player); err != nil {
+			return err
+		}
+	}
+	return nil
+}
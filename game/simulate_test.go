@@ -0,0 +1,82 @@
+package game
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// scriptDown returns the actions held on frame of a scripted game that
+// shifts, turns and drops every piece, some of them with soft drop
+func scriptDown(frame int) [input.NumActions]bool {
+	var down [input.NumActions]bool
+	piece, f := frame/40, frame%40
+	switch {
+	case f < 3*(piece%4):
+		down[input.ActionMoveLeft] = piece%2 == 0
+		down[input.ActionMoveRight] = piece%2 == 1
+	case f >= 15 && f < 17:
+		down[input.ActionRotateCW+input.Action(piece%3)] = true
+	case f >= 20 && f < 30 && piece%5 == 0:
+		down[input.ActionSoftDrop] = true
+	case f >= 35 && f < 37:
+		down[input.ActionHardDrop] = true
+	case f == 38 && piece%7 == 3:
+		down[input.ActionHold] = true
+	}
+	return down
+}
+
+func TestReplayPlaysBackRecording(t *testing.T) {
+	const frames = 3000
+	rec := newTestGame(t)
+	var in input.PlayerInput
+	handler := input.NewInputHandler(rec.settings.DAS, rec.settings.ARR)
+	for i := 0; i < frames && !rec.GameOver(); i++ {
+		in.Next(scriptDown(i))
+		rec.Update(handler.Update(StepLength, in.Pressed, in.JustPressed, in.JustReleased), StepLength)
+	}
+	if rec.stats.HardDrops < 10 || rec.score == 0 {
+		t.Fatalf("script hard dropped %d pieces for %d points, want a real game", rec.stats.HardDrops, rec.score)
+	}
+
+	// Through a replay file, as a saved game is watched
+	path := filepath.Join(t.TempDir(), "game.trep")
+	if err := replay.SaveReplay(path, rec.seed, rec.ReplayEvents(), rec.FrameHashes()); err != nil {
+		t.Fatal(err)
+	}
+	seed, events, hashes, err := replay.LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	play := NewSeededGameState(ModeMarathon, rec.settings, seed)
+	if err := play.Simulate(replay.NewReplayPlayer(seed, events, hashes), int(rec.frame)); err != nil {
+		t.Fatal(err)
+	}
+	if play.score != rec.score || play.linesCleared != rec.linesCleared || play.frame != rec.frame {
+		t.Errorf("replay scored %d with %d lines in %d frames, want %d with %d in %d", play.score, play.linesCleared, play.frame, rec.score, rec.linesCleared, rec.frame)
+	}
+	if !reflect.DeepEqual(play.board, rec.board) || play.stats != rec.stats {
+		t.Errorf("replay ended on\n%v\nwant\n%v", play.board, rec.board)
+	}
+}
+
+func TestReplayDesync(t *testing.T) {
+	rec := SimulateGame(1, []replay.ReplayEvent{{Frame: 10, EventType: replay.KeyDown, Key: uint8(input.ActionHardDrop)}}, 2*replay.HashInterval)
+	hashes := rec.FrameHashes()
+	if len(hashes) != 2 {
+		t.Fatalf("%d hashes after %d frames, want 2", len(hashes), rec.frame)
+	}
+
+	// Without the hard drop the board differs by the first hash
+	play := newTestGame(t)
+	if err := play.Simulate(replay.NewReplayPlayer(1, nil, hashes), 0); err == nil {
+		t.Error("a replay played back without its input didn't desync")
+	}
+	if play.frame != replay.HashInterval {
+		t.Errorf("desync caught at frame %d, want %d", play.frame, replay.HashInterval)
+	}
+}
@@ -0,0 +1,399 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// GameState holds everything about a single running game: the board, the
+// pieces, the score and all of the timers used for gravity and input
+// handling. Each GameState is independent of any other.
+type GameState struct {
+	mode         GameMode
+	settings     config.Settings // Handling settings, kept across resets
+	board        Board
+	rows, cols   int   // Size of the visible part of the board, kept across resets
+	startBoard   Board // Board the game starts on, nil for an empty one, kept across resets
+	activeShape  Shape // The shape that the player controls
+	currentPiece Piece
+	holdPiece    Piece
+	canHold      bool
+	score        int
+	scorer       *Scorer // Points awarded for each piece lock
+	gameOver     bool
+	paused       bool
+	modeComplete bool // Whether the game ended by reaching the goal of the mode
+
+	level        int
+	linesCleared int
+	elapsedTime  float64 // Seconds played, not counting time paused
+
+	// Mode specific state
+	sprintLinesLeft int                // Lines left to clear in a sprint
+	ultraTimeLeft   float64            // Seconds left in an ultra game
+	survivalWave    int                // Garbage waves sent so far in a survival game
+	nextWaveTime    float64            // Seconds until the next survival wave
+	practiceUndos   []practiceSnapshot // Piece locks that can be undone in practice, the last one last
+
+	combo     int  // Number of consecutive line clears after the first, -1 when there is no combo
+	btbActive bool // Whether the last line clear was a Tetris or T-spin
+	btbCount  int  // Number of back-to-back bonuses in the current chain
+
+	comboShown int     // Combo shown in the HUD, kept after the combo breaks
+	comboTimer float64 // Time left before the combo disappears from the HUD
+
+	clearAnim  lineClearAnimation // Rows flashing before they are deleted
+	lockFlash  lockFlashEffect    // The last piece to lock flashing white
+	levelFlash levelFlashEffect   // The board flashing on level up
+
+	hardDropTrail  []TrailSegment // Cells the last hard dropped piece fell through
+	scorePopups    []ScorePopup   // Points for recent line clears rising off the board
+	shakeTimer     float64        // Time left for the board to shake
+	shakeAmplitude float64        // How far the board shakes when the shake starts
+	maxHeight      int            // Height of the highest column of the stack
+	warningBlink   float64        // Time the stack has been close to the top
+
+	showHeightOverlay bool // Whether column heights are drawn over the board, kept across resets
+	showColHeights    bool // Whether column heights are written above the board, kept across resets
+	showSurface       bool // Whether overhangs and holes are highlighted, kept across resets
+	showTSDHint       bool // Whether T-spin double setups are pointed out, kept across resets
+	showPieceCounts   bool // Whether the pieces placed are charted by the board, kept across resets
+	currentTheme      int  // Index of the color theme the game is drawn in, kept across resets
+	showDebugOverlay  bool // Whether frame rate and memory use are shown, kept across resets
+	showInputOverlay  bool // Whether the actions held down are drawn, kept across resets
+
+	pendingGarbage int       // Garbage lines pushed up when the next piece locks
+	garbageSent    int       // Garbage lines sent to the opponent, see TakeGarbageSent
+	lastClear      lineClear // The last lock that cleared lines, for the mode to send garbage for
+
+	haptics Haptics // Feedback for locks and clears, nil for none, kept across resets
+
+	achievements          []Achievement // Achievements that can be unlocked, kept across resets
+	newUnlocks            []Achievement // Achievements unlocked since TakeUnlocks was last called
+	achievementToasts     []string      // Names of the unlocked achievements still to be shown
+	achievementToastTimer float64       // Time the first toast has been shown
+
+	stats      Stats            // What the player did this game
+	placements PlacementHistory // The last pieces locked
+	moveCount  int              // Move and rotate key presses for the current piece
+	speed      speedMeter
+	scores     scoreHistory
+
+	lastClearWasPC    bool    // Whether the last line clear emptied the board
+	allClearStreak    int     // Perfect clears in a row, up to the last lock
+	perfectClearTimer float64 // Time left to show the perfect clear banner
+
+	// Rotation and T-spin detection
+	rotationState           int
+	lastMovementWasRotation bool
+	lastRotationPoint       Shape
+	rotationCooldown        float64
+	rotationDirection       int
+
+	// Upcoming pieces and garbage holes, which are dealt from seed
+	queue *PieceQueue
+	seed  int64
+	rng   *rand.Rand
+
+	// Replay recording
+	frame        uint32 // Number of steps played, not counting time paused
+	replayEvents []replay.ReplayEvent
+	frameHashes  []uint64 // Hash of the board every replay.HashInterval frames
+
+	// Gravity, speed and locking
+	gravityTimer   float64
+	baseSpeed      float64
+	gravitySpeed   float64
+	lockDelay      float64
+	lockDelayTimer float64
+	lockResets     int
+	maxLockResets  int
+
+	// Input handling, DAS/ARR is done by an input.InputHandler
+	prevDown              [input.NumActions]bool // Actions held in the last step
+	lastTapTime           float64
+	visualFeedbackActive  bool
+	softDropFrictionTimer float64
+	lastSoftDropTime      float64
+	softDropTimer         float64 // Time since the soft drop last moved the piece
+}
+
+// lineClearAnimation is the flash shown on full rows before they are deleted.
+// Gravity, locking and input are suspended while it runs.
+type lineClearAnimation struct {
+	rows  []int   // Rows pending deletion, highest first
+	timer float64 // Time left before the rows are deleted
+}
+
+// active reports whether rows are waiting to be deleted
+func (a lineClearAnimation) active() bool {
+	return len(a.rows) > 0
+}
+
+// lockFlashEffect is the white flash shown over a piece when it locks
+type lockFlashEffect struct {
+	active bool
+	timer  float64 // Time left to show the flash
+	shape  Shape   // Where the piece locked
+	block  Block   // Block type of the piece
+}
+
+// levelFlashEffect is the colored flash shown over the board on level up
+type levelFlashEffect struct {
+	active bool
+	timer  float64 // Time left to show the flash
+	level  int     // Level that was reached, which picks the color
+}
+
+// TrailSegment is a cell a hard dropped piece fell through. It fades out
+// until Alpha reaches 0.
+type TrailSegment struct {
+	Row, Col int
+	Alpha    float64
+}
+
+// ScorePopup is the score of a line clear that floats up from the cleared
+// rows and fades out. The position is in blocks from the bottom left corner
+// of the board.
+type ScorePopup struct {
+	Text  string
+	X, Y  float64
+	Alpha float64
+}
+
+// NewGameState creates a new game of the given mode that is ready to be
+// played with the given handling settings.
+func NewGameState(mode GameMode, settings config.Settings) *GameState {
+	return NewSeededGameState(mode, settings, time.Now().UnixNano())
+}
+
+// NewSeededGameState creates a new game like NewGameState that deals its
+// pieces from seed, so the same seed always deals the same pieces.
+func NewSeededGameState(mode GameMode, settings config.Settings, seed int64) *GameState {
+	gs := &GameState{settings: settings, rows: settings.Rows, cols: settings.Cols, seed: seed, scorer: GuidelineScorer()}
+	gs.Reset(mode)
+	return gs
+}
+
+// ApplySettings changes the handling settings of a game in progress
+func (gs *GameState) ApplySettings(settings config.Settings) {
+	gs.settings = settings
+	gs.lockDelay = settings.LockDelay
+	gs.maxLockResets = settings.MaxLockResets
+}
+
+// SetSeed sets the seed the pieces are dealt from when the game is next
+// reset
+func (gs *GameState) SetSeed(seed int64) {
+	gs.seed = seed
+}
+
+// SetStartBoard starts the game over on a copy of b, which must be the size
+// of the board including the hidden rows. Resetting the game starts it on
+// b again.
+func (gs *GameState) SetStartBoard(b Board) error {
+	if b.Rows() != gs.rows+HiddenRows || b.Cols() != gs.cols {
+		return fmt.Errorf("board is %dx%d, expected %dx%d", b.Rows(), b.Cols(), gs.rows+HiddenRows, gs.cols)
+	}
+	gs.startBoard = b.clone()
+	gs.Reset(gs.mode)
+	return nil
+}
+
+// Reset puts every piece of game state back to its initial value for a game
+// of the given mode and spawns the first piece. It is the only place a new
+// game should be set up. The handling settings, board size and the seed are
+// left as they are, so resetting a game deals the same pieces again.
+func (gs *GameState) Reset(mode GameMode) {
+	gs.mode = mode
+	if gs.startBoard != nil {
+		gs.board = gs.startBoard.clone()
+	} else {
+		gs.board = newBoard(gs.rows, gs.cols)
+	}
+	gs.score = 0
+	gs.gameOver = false
+	gs.paused = false
+	gs.modeComplete = false
+	gs.level = 1
+	gs.linesCleared = 0
+	gs.elapsedTime = 0
+	gs.sprintLinesLeft = sprintLines
+	gs.ultraTimeLeft = ultraTime
+	gs.survivalWave = 0
+	gs.nextWaveTime = firstWaveTime
+	gs.practiceUndos = nil
+	gs.combo = -1
+	gs.comboShown = 0
+	gs.comboTimer = 0
+	gs.btbActive = false
+	gs.btbCount = 0
+	gs.clearAnim = lineClearAnimation{}
+	gs.lockFlash = lockFlashEffect{}
+	gs.levelFlash = levelFlashEffect{}
+	gs.hardDropTrail = nil
+	gs.shakeTimer = 0
+	gs.shakeAmplitude = 0
+	gs.maxHeight = 0
+	gs.warningBlink = 0
+	gs.scorePopups = nil
+	gs.pendingGarbage = 0
+	gs.garbageSent = 0
+	gs.lastClear = lineClear{}
+	gs.newUnlocks = nil
+	gs.achievementToasts = nil
+	gs.achievementToastTimer = 0
+	gs.stats = Stats{}
+	gs.placements = PlacementHistory{}
+	gs.moveCount = 0
+	gs.frame = 0
+	gs.replayEvents = nil
+	gs.frameHashes = nil
+	gs.speed = speedMeter{}
+	gs.scores = scoreHistory{}
+	gs.lastClearWasPC = false
+	gs.allClearStreak = 0
+	gs.perfectClearTimer = 0
+
+	// Timers and speed
+	gs.gravityTimer = 0
+	gs.baseSpeed = levelSpeed(gs.level)
+	gs.gravitySpeed = gs.baseSpeed
+	gs.lockDelay = gs.settings.LockDelay
+	gs.lockDelayTimer = 0
+	gs.lockResets = 0
+	gs.maxLockResets = gs.settings.MaxLockResets
+
+	// Input handling
+	gs.prevDown = [input.NumActions]bool{}
+	gs.rotationCooldown = 0
+	gs.rotationDirection = 0
+	gs.lastTapTime = 0
+	gs.visualFeedbackActive = false
+	gs.softDropFrictionTimer = 0
+	gs.lastSoftDropTime = 0
+	gs.softDropTimer = 0
+
+	// Pieces
+	gs.holdPiece = NoPiece
+	gs.canHold = true
+	gs.rotationState = 0
+	gs.lastMovementWasRotation = false
+	gs.lastRotationPoint = Shape{}
+
+	// Deal the pieces from the seed
+	gs.rng = rand.New(rand.NewSource(gs.seed))
+	gs.queue = NewPieceQueue(gs.seed, NextQueueLength)
+
+	gs.addPiece() // Add initial Piece to game
+}
+
+// gameStateJSON is the form a game is exported in by ExportGameState
+type gameStateJSON struct {
+	Mode          string                 `json:"mode"`
+	Board         Board                  `json:"board"`
+	ActiveShape   [4][2]int              `json:"activeShape"` // Row and column of each block
+	CurrentPiece  Piece                  `json:"currentPiece"`
+	RotationState int                    `json:"rotationState"`
+	NextPieces    [NextQueueLength]Piece `json:"nextPieces"`
+	HoldPiece     Piece                  `json:"holdPiece"`
+	CanHold       bool                   `json:"canHold"`
+	PieceBag      []Piece                `json:"pieceBag"`
+	Score         int                    `json:"score"`
+	Level         int                    `json:"level"`
+	LinesCleared  int                    `json:"linesCleared"`
+	Combo         int                    `json:"combo"`
+	BTBActive     bool                   `json:"btbActive"`
+	Seed          int64                  `json:"seed"`
+}
+
+// ExportGameState writes the board, pieces and score of a game as JSON so
+// it can be set up again with ImportGameState.
+func ExportGameState(gs *GameState) ([]byte, error) {
+	state := gameStateJSON{
+		Mode:          gs.mode.Name(),
+		Board:         gs.board,
+		CurrentPiece:  gs.currentPiece,
+		RotationState: gs.rotationState,
+		NextPieces:    gs.NextPieces(),
+		HoldPiece:     gs.holdPiece,
+		CanHold:       gs.canHold,
+		PieceBag:      gs.queue.bag,
+		Score:         gs.score,
+		Level:         gs.level,
+		LinesCleared:  gs.linesCleared,
+		Combo:         gs.combo,
+		BTBActive:     gs.btbActive,
+		Seed:          gs.seed,
+	}
+	for i, p := range gs.activeShape {
+		state.ActiveShape[i] = [2]int{p.row, p.col}
+	}
+	return json.MarshalIndent(state, "", "\t")
+}
+
+// ImportGameState sets up a game from JSON written by ExportGameState, with
+// the default settings. Everything that isn't exported starts as it does in
+// a new game, including the random numbers, which start over from the seed.
+func ImportGameState(data []byte) (*GameState, error) {
+	var state gameStateJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	mode, err := ParseGameMode(state.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := config.DefaultSettings()
+	settings.Rows = state.Board.Rows() - HiddenRows
+	settings.Cols = state.Board.Cols()
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("board: %v", err)
+	}
+	pieces := append([]Piece{state.CurrentPiece, state.HoldPiece}, state.NextPieces[:]...)
+	for _, p := range append(pieces, state.PieceBag...) {
+		if p < NoPiece || p > ZPiece {
+			return nil, fmt.Errorf("unknown piece %d", p)
+		}
+	}
+	if state.CurrentPiece == NoPiece {
+		return nil, errors.New("no current piece")
+	}
+	if state.Level < 1 {
+		return nil, fmt.Errorf("level %d is below 1", state.Level)
+	}
+	var shape Shape
+	for i, p := range state.ActiveShape {
+		shape[i] = Point{row: p[0], col: p[1]}
+	}
+	if p := shape[0]; p.row < 0 || p.row >= state.Board.Rows() || p.col < 0 || p.col >= state.Board.Cols() || state.Board[p.row][p.col] == Empty {
+		return nil, errors.New("active shape is not on the board")
+	}
+
+	gs := NewSeededGameState(mode, settings, state.Seed)
+	gs.board = state.Board
+	gs.activeShape = shape
+	gs.currentPiece = state.CurrentPiece
+	gs.rotationState = state.RotationState % 4
+	gs.queue.preview = append([]Piece(nil), state.NextPieces[:]...)
+	gs.holdPiece = state.HoldPiece
+	gs.canHold = state.CanHold
+	gs.queue.bag = state.PieceBag
+	gs.score = state.Score
+	gs.level = state.Level
+	gs.linesCleared = state.LinesCleared
+	gs.sprintLinesLeft = maxInt(sprintLines-gs.linesCleared, 0)
+	gs.combo = state.Combo
+	gs.btbActive = state.BTBActive
+	// Let the mode bring gravity up to the level the game was saved at
+	gs.mode.OnLinesClear(gs, 0)
+	return gs, nil
+}
@@ -0,0 +1,367 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// testHaptics counts the feedback a game gives
+type testHaptics struct {
+	locks  int
+	clears []int
+}
+
+func (h *testHaptics) OnPieceLock()          { h.locks++ }
+func (h *testHaptics) OnLineClear(lines int) { h.clears = append(h.clears, lines) }
+
+// fieldValue returns field i of the struct v, which must be addressable,
+// even when the field is unexported
+func fieldValue(v reflect.Value, i int) interface{} {
+	f := v.Field(i)
+	return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Interface()
+}
+
+// resetKeeps are the fields Reset leaves as they are, which scramble
+// changes. The settings, board size, start board, seed and scorer are
+// left alone by scramble as Reset starts the new game from them.
+var resetKeeps = map[string]bool{
+	"showHeightOverlay": true,
+	"showColHeights":    true,
+	"showSurface":       true,
+	"showTSDHint":       true,
+	"showPieceCounts":   true,
+	"currentTheme":      true,
+	"showDebugOverlay":  true,
+	"showInputOverlay":  true,
+	"haptics":           true,
+	"achievements":      true,
+}
+
+// resetConfig are the fields the new game is started from
+var resetConfig = map[string]bool{
+	"settings":   true,
+	"rows":       true,
+	"cols":       true,
+	"startBoard": true,
+	"seed":       true,
+	"scorer":     true,
+}
+
+// scramble sets every field of gs but those of resetConfig to something a
+// new game doesn't start with
+func scramble(gs *GameState) {
+	gs.mode = ModeSprint
+	gs.board[0][0] = Gray
+	gs.activeShape = Shape{{row: 1, col: 1}, {row: 1, col: 2}, {row: 2, col: 1}, {row: 2, col: 2}}
+	gs.currentPiece = NoPiece
+	gs.holdPiece = TPiece
+	gs.canHold = false
+	gs.score = 1234
+	gs.gameOver = true
+	gs.paused = true
+	gs.modeComplete = true
+	gs.level = 7
+	gs.linesCleared = 63
+	gs.elapsedTime = 95.5
+	gs.sprintLinesLeft = 3
+	gs.ultraTimeLeft = 12
+	gs.survivalWave = 4
+	gs.nextWaveTime = 2
+	gs.practiceUndos = []practiceSnapshot{{currentPiece: IPiece}}
+	gs.combo = 5
+	gs.btbActive = true
+	gs.btbCount = 2
+	gs.comboShown = 5
+	gs.comboTimer = 1
+	gs.clearAnim = lineClearAnimation{rows: []int{0}, timer: 0.1}
+	gs.lockFlash = lockFlashEffect{active: true, timer: 0.05, block: Red}
+	gs.levelFlash = levelFlashEffect{active: true, timer: 0.2, level: 7}
+	gs.hardDropTrail = []TrailSegment{{Row: 3, Col: 4, Alpha: 0.5}}
+	gs.scorePopups = []ScorePopup{{Text: "100", Alpha: 1}}
+	gs.shakeTimer = 0.1
+	gs.shakeAmplitude = 4
+	gs.maxHeight = 18
+	gs.warningBlink = 0.5
+	gs.showHeightOverlay = true
+	gs.showColHeights = true
+	gs.showSurface = true
+	gs.showTSDHint = true
+	gs.showPieceCounts = true
+	gs.currentTheme = 2
+	gs.showDebugOverlay = true
+	gs.showInputOverlay = true
+	gs.pendingGarbage = 2
+	gs.garbageSent = 6
+	gs.lastClear = lineClear{lines: 4, btbActive: true}
+	gs.haptics = &testHaptics{}
+	gs.achievements = []Achievement{{ID: "test", Unlocked: true}}
+	gs.newUnlocks = []Achievement{{ID: "test", Unlocked: true}}
+	gs.achievementToasts = []string{"Test"}
+	gs.achievementToastTimer = 1
+	gs.stats = Stats{HardDrops: 9}
+	gs.placements.add(PlacedPiece{Piece: TPiece})
+	gs.moveCount = 3
+	gs.speed.record(1, 2, 3)
+	gs.scores.record(1, 1, 100, 2)
+	gs.lastClearWasPC = true
+	gs.allClearStreak = 2
+	gs.perfectClearTimer = 1
+	gs.rotationState = 3
+	gs.lastMovementWasRotation = true
+	gs.lastRotationPoint = gs.activeShape
+	gs.rotationCooldown = 0.03
+	gs.rotationDirection = -1
+	gs.queue.Next()
+	gs.rng.Int()
+	gs.frame = 1000
+	gs.replayEvents = []replay.ReplayEvent{{Frame: 1, EventType: replay.KeyDown}}
+	gs.frameHashes = []uint64{1}
+	gs.gravityTimer = 0.3
+	gs.baseSpeed = 0.1
+	gs.gravitySpeed = 0.01
+	gs.lockDelay = 2
+	gs.lockDelayTimer = 0.2
+	gs.lockResets = 4
+	gs.maxLockResets = 1
+	gs.prevDown = [input.NumActions]bool{input.ActionHardDrop: true}
+	gs.lastTapTime = 0.01
+	gs.visualFeedbackActive = true
+	gs.softDropFrictionTimer = 0.02
+	gs.lastSoftDropTime = 0.1
+	gs.softDropTimer = 0.01
+}
+
+func TestReset(t *testing.T) {
+	fresh := newTestGame(t)
+	gs := newTestGame(t)
+	scramble(gs)
+
+	// Every field has to be scrambled for the test to check it is reset
+	freshV := reflect.ValueOf(fresh).Elem()
+	gsV := reflect.ValueOf(gs).Elem()
+	kept := make(map[string]interface{})
+	for i := 0; i < gsV.NumField(); i++ {
+		name := gsV.Type().Field(i).Name
+		if resetConfig[name] {
+			continue
+		}
+		if reflect.DeepEqual(fieldValue(gsV, i), fieldValue(freshV, i)) {
+			t.Errorf("scramble doesn't change %s", name)
+		}
+		if resetKeeps[name] {
+			kept[name] = fieldValue(gsV, i)
+		}
+	}
+
+	gs.Reset(ModeMarathon)
+	for i := 0; i < gsV.NumField(); i++ {
+		name := gsV.Type().Field(i).Name
+		got := fieldValue(gsV, i)
+		want := fieldValue(freshV, i)
+		if resetKeeps[name] {
+			want = kept[name]
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("after Reset %s = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestResetStartBoard(t *testing.T) {
+	gs := newTestGame(t)
+	start := newBoard(gs.rows, gs.cols)
+	start[0][0] = Gray
+	if err := gs.SetStartBoard(start); err != nil {
+		t.Fatal(err)
+	}
+	gs.board[0][1] = Gray
+	gs.Reset(ModeMarathon)
+	if gs.board[0][0] != Gray || gs.board[0][1] != Empty {
+		t.Errorf("Reset didn't start the game on the start board:\n%v", gs.board)
+	}
+}
+
+func TestResetDealsSameSeed(t *testing.T) {
+	gs := newTestGame(t)
+	first := gs.NextPieces()
+	piece := gs.currentPiece
+	for i := 0; i < 10; i++ {
+		gs.queue.Next()
+	}
+	gs.Reset(ModeMarathon)
+	if gs.currentPiece != piece || gs.NextPieces() != first {
+		t.Errorf("Reset dealt %v %v, want %v %v from the same seed", gs.currentPiece, gs.NextPieces(), piece, first)
+	}
+
+	gs.SetSeed(2)
+	gs.Reset(ModeMarathon)
+	other := NewSeededGameState(ModeMarathon, gs.settings, 2)
+	if gs.currentPiece != other.currentPiece || gs.NextPieces() != other.NextPieces() {
+		t.Errorf("Reset after SetSeed(2) dealt %v %v, want %v %v", gs.currentPiece, gs.NextPieces(), other.currentPiece, other.NextPieces())
+	}
+}
+
+func TestGameStatesIndependent(t *testing.T) {
+	a := newTestGame(t)
+	b := newTestGame(t)
+	board := b.board.clone()
+	piece, next := b.currentPiece, b.NextPieces()
+
+	// Hard drop a few pieces in a, which locks them and deals new ones
+	for i := 0; i < 3; i++ {
+		a.Update(input.InputState{HardDrop: true}, StepLength)
+		a.Update(input.InputState{}, StepLength)
+	}
+	if a.stats.HardDrops == 0 {
+		t.Fatal("no pieces were hard dropped")
+	}
+	if !reflect.DeepEqual(b.board, board) || b.currentPiece != piece || b.NextPieces() != next || b.score != 0 || b.frame != 0 {
+		t.Error("playing one game changed another")
+	}
+}
+
+func TestPausedUpdate(t *testing.T) {
+	gs := newTestGame(t)
+	gs.Update(input.InputState{}, StepLength)
+	gs.TogglePause()
+	timer, score, shape := gs.gravityTimer, gs.score, gs.activeShape
+	for i := 0; i < 1000; i++ {
+		gs.Update(input.InputState{SoftDrop: true, HardDrop: true}, StepLength)
+	}
+	if gs.gravityTimer != timer || gs.score != score || gs.activeShape != shape {
+		t.Errorf("paused game changed: gravity timer %v, score %d, want %v and %d", gs.gravityTimer, gs.score, timer, score)
+	}
+
+	gs.TogglePause()
+	gs.Update(input.InputState{}, StepLength)
+	if gs.gravityTimer == timer {
+		t.Error("gravity timer didn't run after resuming")
+	}
+}
+
+func TestExportImportGameState(t *testing.T) {
+	gs := NewSeededGameState(ModeSprint, config.DefaultSettings(), 7)
+	gs.Update(input.InputState{HardDrop: true}, StepLength)
+	gs.Update(input.InputState{}, StepLength)
+	gs.Update(input.InputState{Hold: true}, StepLength)
+	gs.Update(input.InputState{RotateCW: true}, StepLength)
+	lockClear(t, gs, 2)
+	lockClear(t, gs, 1)
+	gs.rotatePiece(1)
+
+	data, err := ExportGameState(gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ImportGameState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Mode() != ModeSprint || !reflect.DeepEqual(got.board, gs.board) || got.activeShape != gs.activeShape ||
+		got.currentPiece != gs.currentPiece || got.rotationState != gs.rotationState || got.NextPieces() != gs.NextPieces() ||
+		got.holdPiece != gs.holdPiece || got.canHold != gs.canHold || !reflect.DeepEqual(got.queue.bag, gs.queue.bag) ||
+		got.score != gs.score || got.level != gs.level || got.linesCleared != gs.linesCleared ||
+		got.sprintLinesLeft != gs.sprintLinesLeft || got.combo != gs.combo || got.btbActive != gs.btbActive || got.seed != gs.seed {
+		t.Errorf("imported game differs from the exported one:\n%s", data)
+	}
+
+	again, err := ExportGameState(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("exporting the imported game gave\n%s\nwant\n%s", again, data)
+	}
+}
+
+func TestImportGameStateErrors(t *testing.T) {
+	data, err := ExportGameState(newTestGame(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var good map[string]interface{}
+	if err := json.Unmarshal(data, &good); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]func(m map[string]interface{}){
+		"unknown mode":     func(m map[string]interface{}) { m["mode"] = "zen" },
+		"unknown piece":    func(m map[string]interface{}) { m["holdPiece"] = 9 },
+		"no current piece": func(m map[string]interface{}) { m["currentPiece"] = -1 },
+		"level 0":          func(m map[string]interface{}) { m["level"] = 0 },
+		"shape off board":  func(m map[string]interface{}) { m["activeShape"] = [4][2]int{{30, 0}, {30, 1}, {30, 2}, {30, 3}} },
+		"narrow board":     func(m map[string]interface{}) { m["board"] = [][]int{{0, 0}} },
+	}
+	for name, change := range tests {
+		m := make(map[string]interface{})
+		for k, v := range good {
+			m[k] = v
+		}
+		change(m)
+		bad, _ := json.Marshal(m)
+		if _, err := ImportGameState(bad); err == nil {
+			t.Errorf("%s: ImportGameState() returned no error", name)
+		}
+	}
+	if _, err := ImportGameState([]byte("{")); err == nil {
+		t.Error("ImportGameState(malformed JSON) returned no error")
+	}
+}
+
+// dealt returns the first n pieces gs deals, clearing the board before
+// each so there is always room
+func dealt(gs *GameState, n int) []Piece {
+	pieces := make([]Piece, n)
+	for i := range pieces {
+		pieces[i] = gs.currentPiece
+		gs.board = newBoard(gs.rows, gs.cols)
+		gs.addPiece()
+	}
+	return pieces
+}
+
+func TestSameSeedSamePieces(t *testing.T) {
+	for _, seed := range []int64{0, 1, 42, -7, 1 << 40} {
+		a := NewSeededGameState(ModeMarathon, config.DefaultSettings(), seed)
+		b := NewSeededGameState(ModeSprint, config.DefaultSettings(), seed)
+		if a.Seed() != seed || a.NextPieces() != b.NextPieces() {
+			t.Fatalf("seed %d: previews %v and %v", seed, a.NextPieces(), b.NextPieces())
+		}
+		pa, pb := dealt(a, 50), dealt(b, 50)
+		want := deal(NewPieceQueue(seed, NextQueueLength), 50)
+		if !reflect.DeepEqual(pa, pb) || !reflect.DeepEqual(pa, want) {
+			t.Errorf("seed %d dealt\n%v\n%v\nwant the queue's\n%v", seed, pa, pb, want)
+		}
+	}
+}
+
+func TestApplySettingsKeepsGame(t *testing.T) {
+	// Switching to fullscreen and back keeps the window, so the game only
+	// sees its settings change. The board, score and timers carry on.
+	gs := newTestGame(t)
+	scramble(gs)
+	before := *gs
+	before.board = gs.board.clone()
+
+	settings := config.DefaultSettings()
+	settings.Fullscreen = true
+	settings.LockDelay = 1
+	gs.ApplySettings(settings)
+	if gs.settings != settings || gs.lockDelay != 1 {
+		t.Fatalf("ApplySettings() left settings %+v, lock delay %v", gs.settings, gs.lockDelay)
+	}
+	before.settings, before.lockDelay, before.maxLockResets = gs.settings, gs.lockDelay, gs.maxLockResets
+	if !reflect.DeepEqual(*gs, before) {
+		got, want := reflect.ValueOf(gs).Elem(), reflect.ValueOf(&before).Elem()
+		for i := 0; i < got.NumField(); i++ {
+			if g, w := fieldValue(got, i), fieldValue(want, i); !reflect.DeepEqual(g, w) {
+				t.Errorf("ApplySettings() changed %s from %v to %v", got.Type().Field(i).Name, w, g)
+			}
+		}
+	}
+}
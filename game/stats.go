@@ -0,0 +1,138 @@
+package game
+
+// Stats counts what the player did during a game
+type Stats struct {
+	PiecesPlaced [7]int // Indexed by Piece, counted as each piece comes into play
+	Rotations    int
+	Holds        int
+	LineClears   [5]int // Indexed by the number of lines cleared, 0 for locks without a clear
+	TSpins       int    // Including the minis
+	TSpinMinis   int
+	Combos       int // Clears that continued a combo
+	MaxCombo     int
+	HardDrops    int
+	MaxHeight    int // Highest the stack has been
+
+	FinesseErrors int // Pieces placed with more key presses than needed
+}
+
+// FinesseErrorRate returns the percentage of pieces placed with a finesse
+// error
+func (s Stats) FinesseErrorRate() float64 {
+	placed := s.TotalPlaced()
+	if placed == 0 {
+		return 0
+	}
+	return 100 * float64(s.FinesseErrors) / float64(placed)
+}
+
+// speedWindow is how many seconds of play the speed metrics average over
+const speedWindow = 5.0
+
+// speedRefreshTime is how often the speed metrics are recomputed
+const speedRefreshTime = 1.0
+
+// speedSample is what happened during one frame
+type speedSample struct {
+	dt           float64
+	linesCleared int
+	pieces       int
+}
+
+// speedMeter keeps the lines per minute and pieces per second over the
+// last speedWindow seconds using a ring buffer of one sample per frame
+type speedMeter struct {
+	samples      [300]speedSample
+	next         int // Index of the slot the next sample is written to
+	lastLines    int // Totals at the last sample, to find what each frame added
+	lastPieces   int
+	refreshTimer float64
+
+	linesPerMinute  float64
+	piecesPerSecond float64
+}
+
+// record adds a frame of dt seconds given the running totals of lines
+// cleared and pieces placed. The metrics are recomputed once every
+// speedRefreshTime seconds.
+func (m *speedMeter) record(dt float64, linesCleared, pieces int) {
+	m.samples[m.next] = speedSample{dt, linesCleared - m.lastLines, pieces - m.lastPieces}
+	m.next = (m.next + 1) % len(m.samples)
+	m.lastLines = linesCleared
+	m.lastPieces = pieces
+
+	m.refreshTimer += dt
+	if m.refreshTimer < speedRefreshTime {
+		return
+	}
+	m.refreshTimer = 0
+
+	// Sum the newest samples back to speedWindow seconds
+	var elapsed float64
+	var lines, placed int
+	for i := 1; i <= len(m.samples) && elapsed < speedWindow; i++ {
+		s := m.samples[(m.next-i+len(m.samples))%len(m.samples)]
+		elapsed += s.dt
+		lines += s.linesCleared
+		placed += s.pieces
+	}
+	if elapsed > 0 {
+		m.linesPerMinute = float64(lines) / elapsed * 60
+		m.piecesPerSecond = float64(placed) / elapsed
+	}
+}
+
+// scoreHistoryInterval is how often the score is recorded for the score
+// history, on top of every line clear
+const scoreHistoryInterval = 2.0
+
+// ScorePoint is the score at a point in a game
+type ScorePoint struct {
+	Time  float64 // Seconds played
+	Score int
+}
+
+// scoreHistory keeps the score every scoreHistoryInterval seconds and at
+// every line clear, in a ring buffer that keeps the newest points once it
+// is full
+type scoreHistory struct {
+	points    [300]ScorePoint
+	next      int // Index of the slot the next point is written to
+	count     int // Number of points kept
+	lastLines int // Lines cleared at the last point
+	timer     float64
+}
+
+// record adds a frame of dt seconds to the history, which ends elapsed
+// seconds into the game with the given score and lines cleared
+func (h *scoreHistory) record(dt, elapsed float64, score, linesCleared int) {
+	h.timer += dt
+	if h.timer < scoreHistoryInterval && linesCleared == h.lastLines {
+		return
+	}
+	h.timer = 0
+	h.lastLines = linesCleared
+	h.points[h.next] = ScorePoint{elapsed, score}
+	h.next = (h.next + 1) % len(h.points)
+	if h.count < len(h.points) {
+		h.count++
+	}
+}
+
+// list returns the points kept, oldest first
+func (h *scoreHistory) list() []ScorePoint {
+	points := make([]ScorePoint, h.count)
+	for i := range points {
+		points[i] = h.points[(h.next-h.count+i+len(h.points))%len(h.points)]
+	}
+	return points
+}
+
+// TotalPlaced returns the number of pieces placed
+func (s Stats) TotalPlaced() int {
+	placed := 0
+	for _, n := range s.PiecesPlaced {
+		placed += n
+	}
+	return placed
+}
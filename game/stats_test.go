@@ -0,0 +1,186 @@
+package game
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStatsCounters(t *testing.T) {
+	gs := newTestGame(t)
+	var placed [7]int
+	placed[gs.currentPiece]++ // The first piece is counted as it is dealt
+	dealt := func() { placed[gs.currentPiece]++ }
+
+	spawnPiece(gs, TPiece)
+	if !gs.rotatePiece(1) || !gs.rotatePiece(-1) || !gs.rotatePiece180() {
+		t.Fatal("T piece didn't rotate on an empty board")
+	}
+	gs.holdCurrentPiece()
+	dealt()
+	gs.holdCurrentPiece() // Can't hold again until the next piece
+	gs.instafall()
+	dealt()
+	lockClear(t, gs, 2)
+	dealt()
+	lockClear(t, gs, 1)
+	dealt()
+
+	s := gs.Stats()
+	if s.PiecesPlaced != placed {
+		t.Errorf("PiecesPlaced = %v, want %v", s.PiecesPlaced, placed)
+	}
+	if s.TotalPlaced() != 5 {
+		t.Errorf("TotalPlaced() = %d, want 5", s.TotalPlaced())
+	}
+	if s.Rotations != 3 {
+		t.Errorf("Rotations = %d, want 3", s.Rotations)
+	}
+	if s.Holds != 1 {
+		t.Errorf("Holds = %d, want 1", s.Holds)
+	}
+	if s.HardDrops != 1 {
+		t.Errorf("HardDrops = %d, want 1", s.HardDrops)
+	}
+	if want := [5]int{1, 1, 1, 0, 0}; s.LineClears != want {
+		t.Errorf("LineClears = %v, want %v", s.LineClears, want)
+	}
+	if s.Combos != 1 || s.MaxCombo != 1 {
+		t.Errorf("Combos = %d, MaxCombo = %d, want 1 and 1", s.Combos, s.MaxCombo)
+	}
+	if s.TSpins != 0 {
+		t.Errorf("TSpins = %d, want 0", s.TSpins)
+	}
+}
+
+func TestStatsTSpin(t *testing.T) {
+	gs := newTestGame(t)
+	gs.board = mustBoard(t, tsdBoard)
+	placePiece(gs, TPiece, tsdShape, 0)
+	gs.lockPiece()
+	if s := gs.Stats(); s.TSpins != 1 || s.TSpinMinis != 0 || s.LineClears[2] != 1 {
+		t.Errorf("after a T-spin double TSpins = %d, TSpinMinis = %d, doubles = %d, want 1, 0, 1", s.TSpins, s.TSpinMinis, s.LineClears[2])
+	}
+}
+
+func TestSpeedMeter(t *testing.T) {
+	// Half second frames, with a piece placed every frame and a line
+	// cleared every other frame for ten seconds, then nothing
+	var m speedMeter
+	lines, pieces := 0, 0
+	frame := func(active bool) {
+		if active {
+			pieces++
+			if pieces%2 == 0 {
+				lines++
+			}
+		}
+		m.record(0.5, lines, pieces)
+	}
+	check := func(when string, lpm, pps float64) {
+		t.Helper()
+		if math.Abs(m.linesPerMinute-lpm) > 1e-9 || math.Abs(m.piecesPerSecond-pps) > 1e-9 {
+			t.Errorf("%s: %v lines per minute, %v pieces per second, want %v and %v", when, m.linesPerMinute, m.piecesPerSecond, lpm, pps)
+		}
+	}
+
+	frame(true)
+	check("before the first refresh", 0, 0)
+	frame(true)
+	check("after one second", 60, 2)
+	for i := 0; i < 18; i++ {
+		frame(true)
+	}
+	check("after ten seconds", 60, 2)
+
+	// The window rolls over the idle frames
+	for i := 0; i < 6; i++ {
+		frame(false)
+	}
+	check("three seconds idle", 2.0/5*60, 4.0/5)
+	frame(false)
+	check("between refreshes", 2.0/5*60, 4.0/5)
+	for i := 0; i < 3; i++ {
+		frame(false)
+	}
+	check("five seconds idle", 0, 0)
+}
+
+func TestSpeedMeterFullRing(t *testing.T) {
+	// At 100 frames a second the ring only holds the last three seconds
+	var m speedMeter
+	for i := 1; i <= 1000; i++ {
+		m.record(0.01, 0, i)
+	}
+	if math.Abs(m.piecesPerSecond-100) > 1e-6 {
+		t.Errorf("%v pieces per second, want 100", m.piecesPerSecond)
+	}
+}
+
+func TestPiecesPlacedTwoBags(t *testing.T) {
+	// 14 pieces are two whole bags, each counted once as it is dealt. The
+	// first hold deals a piece, after that the held piece swaps back in
+	// without being counted again.
+	gs := newTestGame(t)
+	dealt := 1
+	for i := 0; dealt < 14; i++ {
+		gs.board = newBoard(gs.rows, gs.cols)
+		gs.board.fillShape(gs.activeShape, PieceBlock(gs.currentPiece))
+		switch i {
+		case 3:
+			gs.holdCurrentPiece()
+			dealt++
+		case 8:
+			gs.holdCurrentPiece()
+		default:
+			gs.instafall()
+			dealt++
+		}
+		if got := gs.Stats().TotalPlaced(); got != dealt {
+			t.Fatalf("step %d: TotalPlaced() = %d after %d pieces dealt", i, got, dealt)
+		}
+	}
+	if gs.stats.Holds != 2 {
+		t.Errorf("Holds = %d, want 2", gs.stats.Holds)
+	}
+	if want := [7]int{2, 2, 2, 2, 2, 2, 2}; gs.stats.PiecesPlaced != want {
+		t.Errorf("PiecesPlaced = %v, want %v", gs.stats.PiecesPlaced, want)
+	}
+}
+
+func TestScoreHistoryRecord(t *testing.T) {
+	// A point every 2 seconds, and one at every line clear in between
+	var h scoreHistory
+	for step := 1; step <= 5; step++ {
+		h.record(1, float64(step), step*10, 0)
+	}
+	h.record(0.5, 5.5, 200, 1)
+	h.record(0.5, 6, 200, 1)
+	want := []ScorePoint{{2, 20}, {4, 40}, {5.5, 200}}
+	if got := h.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("list() = %v, want %v", got, want)
+	}
+}
+
+func TestScoreHistoryWraps(t *testing.T) {
+	// A line clear every frame records every frame
+	var h scoreHistory
+	for i := 0; i < 350; i++ {
+		h.record(0, float64(i), i*100, i+1)
+		points := h.list()
+		if want := minInt(i+1, len(h.points)); len(points) != want {
+			t.Fatalf("%d points recorded, list() has %d, want %d", i+1, len(points), want)
+		}
+		if last := points[len(points)-1]; last != (ScorePoint{float64(i), i * 100}) {
+			t.Fatalf("newest point after %d recorded = %v", i+1, last)
+		}
+	}
+
+	// The oldest 50 are gone, the rest come out oldest first
+	points := h.list()
+	for j, p := range points {
+		if want := (ScorePoint{float64(j + 50), (j + 50) * 100}); p != want {
+			t.Fatalf("list()[%d] = %v, want %v", j, p, want)
+		}
+	}
+}
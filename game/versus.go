@@ -0,0 +1,95 @@
+package game
+
+import (
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// TwoPlayerMode is a marathon played against another player in a Versus,
+// where clearing lines sends the opponent the garbage Garbage gives for it
+type TwoPlayerMode struct {
+	MarathonMode
+	Garbage GarbageTable
+}
+
+// Name implements GameMode
+func (TwoPlayerMode) Name() string { return "twoplayer" }
+
+// OnLinesClear speeds gravity up to the new level and sends garbage for
+// the clear
+func (m TwoPlayerMode) OnLinesClear(gs *GameState, lines int) {
+	gs.applyLevelSpeed()
+	c := gs.lastClear
+	gs.garbageSent += m.Garbage.Calculate(lines, c.tSpinType, c.combo, c.btbActive, c.perfectClear)
+}
+
+// TakeGarbageSent returns the garbage lines this game has sent since it was
+// last called
+func (gs *GameState) TakeGarbageSent() int {
+	n := gs.garbageSent
+	gs.garbageSent = 0
+	return n
+}
+
+// AddGarbage queues lines of garbage to be pushed up from the bottom of the
+// board when the next piece locks
+func (gs *GameState) AddGarbage(lines int) {
+	gs.pendingGarbage += lines
+}
+
+// Versus is a two player game with each player on their own board. Both
+// games advance by the same steps, and the garbage each player sends rises on
+// the other's board.
+type Versus struct {
+	Players [2]*GameState
+}
+
+// NewVersus creates a two player game with the given handling settings.
+// Both players are dealt the same pieces from seed.
+func NewVersus(settings config.Settings, seed int64) *Versus {
+	return &Versus{Players: [2]*GameState{
+		NewSeededGameState(ModeTwoPlayer, settings, seed),
+		NewSeededGameState(ModeTwoPlayer, settings, seed),
+	}}
+}
+
+// Update advances both games by dt seconds with what each player does in
+// inputs, then passes on the garbage they sent. Nothing happens once the
+// game is over.
+func (v *Versus) Update(inputs [2]input.InputState, dt float64) {
+	if v.GameOver() {
+		return
+	}
+	for i, gs := range v.Players {
+		gs.Update(inputs[i], dt)
+	}
+	for i, gs := range v.Players {
+		if lines := gs.TakeGarbageSent(); lines > 0 {
+			v.Players[1-i].AddGarbage(lines)
+		}
+	}
+}
+
+// GameOver reports whether either player has topped out
+func (v *Versus) GameOver() bool {
+	return v.Players[0].GameOver() || v.Players[1].GameOver()
+}
+
+// Winner returns the number of the player who won, 1 or 2, or 0 while the
+// game goes on or when both players topped out on the same step
+func (v *Versus) Winner() int {
+	switch over0, over1 := v.Players[0].GameOver(), v.Players[1].GameOver(); {
+	case over0 && !over1:
+		return 2
+	case over1 && !over0:
+		return 1
+	}
+	return 0
+}
+
+// TogglePause pauses or unpauses both games
+func (v *Versus) TogglePause() {
+	for _, gs := range v.Players {
+		gs.TogglePause()
+	}
+}
@@ -0,0 +1,120 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// readyClear sets up the board of gs with lines full rows but for the
+// right column and a vertical I resting in it, ready to clear them
+func readyClear(gs *GameState, lines int) {
+	gs.board = newBoard(gs.rows, gs.cols)
+	for r := 0; r < lines; r++ {
+		for c := 0; c < gs.cols-1; c++ {
+			gs.board[r][c] = Gray
+		}
+	}
+	gs.board[lines][0] = Gray // Not a perfect clear
+	col := gs.cols - 1
+	placePiece(gs, IPiece, Shape{{row: 0, col: col}, {row: 1, col: col}, {row: 2, col: col}, {row: 3, col: col}}, 1)
+	gs.lastMovementWasRotation = false
+}
+
+// garbageRows returns how many rows from the bottom of b are garbage: full
+// of Gray blocks but for one hole
+func garbageRows(b Board) int {
+	rows := 0
+	for r := 0; r < b.Rows(); r++ {
+		holes, gray := 0, 0
+		for c := 0; c < b.Cols(); c++ {
+			switch b[r][c] {
+			case Empty:
+				holes++
+			case Gray:
+				gray++
+			}
+		}
+		if holes != 1 || gray != b.Cols()-1 {
+			break
+		}
+		rows++
+	}
+	return rows
+}
+
+func TestVersusTetrisSendsGarbage(t *testing.T) {
+	v := NewVersus(config.DefaultSettings(), 1)
+	readyClear(v.Players[0], 4)
+	v.Update([2]input.InputState{{HardDrop: true}, {}}, StepLength)
+	if v.Players[0].LinesCleared() != 4 {
+		t.Fatalf("player 1 cleared %d lines, want a Tetris", v.Players[0].LinesCleared())
+	}
+	if got := v.Players[1].pendingGarbage; got != 4 {
+		t.Errorf("player 2 has %d garbage lines coming after a Tetris, want 4", got)
+	}
+	if got := v.Players[0].pendingGarbage + v.Players[0].garbageSent; got != 0 {
+		t.Errorf("player 1 has %d garbage lines left over, want none", got)
+	}
+
+	// The garbage rises when player 2 next locks a piece
+	v.Update([2]input.InputState{{}, {HardDrop: true}}, StepLength)
+	if got := garbageRows(v.Players[1].Board()); got != 4 {
+		t.Errorf("player 2 has %d rows of garbage after locking, want 4:\n%s", got, v.Players[1].Board())
+	}
+	if v.Players[1].pendingGarbage != 0 {
+		t.Errorf("player 2 still has %d garbage lines coming", v.Players[1].pendingGarbage)
+	}
+	if v.GameOver() || v.Winner() != 0 {
+		t.Errorf("game over %t, winner %d, want the game to go on", v.GameOver(), v.Winner())
+	}
+}
+
+func TestVersusGarbageSent(t *testing.T) {
+	tests := []struct {
+		lines int
+		want  int
+	}{
+		{1, 0},
+		{2, 1},
+		{3, 2},
+		{4, 4},
+	}
+	for _, tt := range tests {
+		v := NewVersus(config.DefaultSettings(), 1)
+		readyClear(v.Players[1], tt.lines)
+		v.Update([2]input.InputState{{}, {HardDrop: true}}, StepLength)
+		if got := v.Players[0].pendingGarbage; got != tt.want {
+			t.Errorf("clearing %d lines sent %d garbage lines, want %d", tt.lines, got, tt.want)
+		}
+	}
+}
+
+func TestVersusWinner(t *testing.T) {
+	tests := []struct {
+		over   [2]bool
+		winner int
+	}{
+		{[2]bool{false, false}, 0},
+		{[2]bool{true, false}, 2},
+		{[2]bool{false, true}, 1},
+		{[2]bool{true, true}, 0},
+	}
+	for _, tt := range tests {
+		v := NewVersus(config.DefaultSettings(), 1)
+		v.Players[0].gameOver, v.Players[1].gameOver = tt.over[0], tt.over[1]
+		if v.GameOver() != (tt.over[0] || tt.over[1]) || v.Winner() != tt.winner {
+			t.Errorf("topped out %v: game over %t, winner %d, want winner %d", tt.over, v.GameOver(), v.Winner(), tt.winner)
+		}
+	}
+
+	// Nothing moves once the game is over
+	v := NewVersus(config.DefaultSettings(), 1)
+	v.Players[0].gameOver = true
+	frame := v.Players[1].Frame()
+	v.Update([2]input.InputState{{}, {HardDrop: true}}, StepLength)
+	if v.Players[1].Frame() != frame || v.Players[1].Stats().HardDrops != 0 {
+		t.Error("player 2 played on after player 1 topped out")
+	}
+}
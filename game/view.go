@@ -0,0 +1,278 @@
+package game
+
+import (
+	"math"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/replay"
+)
+
+// The accessors below let the renderer and the main loop see the state of a
+// game without being able to change it. Slices and boards returned by them
+// are shared with the game and must not be modified.
+
+// Board returns the board, including the hidden rows above the visible part
+func (gs *GameState) Board() Board { return gs.board }
+
+// Rows returns the number of visible rows of the board
+func (gs *GameState) Rows() int { return gs.rows }
+
+// Cols returns the width of the board
+func (gs *GameState) Cols() int { return gs.cols }
+
+// ActiveShape returns where the piece the player controls is on the board
+func (gs *GameState) ActiveShape() Shape { return gs.activeShape }
+
+// CurrentPiece returns the kind of piece the player controls
+func (gs *GameState) CurrentPiece() Piece { return gs.currentPiece }
+
+// RotationState returns how far the piece the player controls is rotated
+// clockwise from where it spawned, from 0 to 3 quarter turns
+func (gs *GameState) RotationState() int { return gs.rotationState }
+
+// Settings returns the handling settings the game is played with
+func (gs *GameState) Settings() config.Settings { return gs.settings }
+
+// Mode returns the mode the game is played in
+func (gs *GameState) Mode() GameMode { return gs.mode }
+
+// Score returns the points scored so far
+func (gs *GameState) Score() int { return gs.score }
+
+// Level returns the current level
+func (gs *GameState) Level() int { return gs.level }
+
+// GravitySpeed returns the seconds between gravity steps at the current
+// level, leaving out soft drops
+func (gs *GameState) GravitySpeed() float64 { return gs.baseSpeed }
+
+// LinesCleared returns the number of lines cleared so far
+func (gs *GameState) LinesCleared() int { return gs.linesCleared }
+
+// ElapsedTime returns the seconds played, not counting time paused
+func (gs *GameState) ElapsedTime() float64 { return gs.elapsedTime }
+
+// SprintLinesLeft returns the lines left to clear in a sprint
+func (gs *GameState) SprintLinesLeft() int { return gs.sprintLinesLeft }
+
+// UltraTimeLeft returns the seconds left in an ultra game
+func (gs *GameState) UltraTimeLeft() float64 { return gs.ultraTimeLeft }
+
+// SurvivalWave returns the number of garbage waves sent in a survival game
+func (gs *GameState) SurvivalWave() int { return gs.survivalWave }
+
+// Combo returns the combo shown in the HUD and how far it has faded, from 1
+// when it was just extended down to 0 when it should no longer be shown
+func (gs *GameState) Combo() (combo int, fade float64) {
+	return gs.comboShown, gs.comboTimer / comboFadeTime
+}
+
+// BackToBack reports whether the last line clear was a Tetris or T-spin and
+// how many back-to-back bonuses the current chain has earned
+func (gs *GameState) BackToBack() (active bool, count int) {
+	return gs.btbActive, gs.btbCount
+}
+
+// Speed returns the lines per minute and pieces per second over the last
+// few seconds of play
+func (gs *GameState) Speed() (linesPerMinute, piecesPerSecond float64) {
+	return gs.speed.linesPerMinute, gs.speed.piecesPerSecond
+}
+
+// ScoreHistory returns the score every few seconds and at every line clear
+// over the last stretch of the game, oldest first
+func (gs *GameState) ScoreHistory() []ScorePoint { return gs.scores.list() }
+
+// NextPieces returns the upcoming pieces, the next one first
+func (gs *GameState) NextPieces() [NextQueueLength]Piece {
+	var next [NextQueueLength]Piece
+	for i := range next {
+		next[i] = gs.queue.Peek(i)
+	}
+	return next
+}
+
+// LockProgress reports whether the active piece is resting on the stack and
+// how far its lock delay has run while it is, from 0 when it lands to 1 when
+// it locks
+func (gs *GameState) LockProgress() (fill float64, resting bool) {
+	if gs.gameOver || gs.clearAnim.active() || !gs.isTouchingFloor() {
+		return 0, false
+	}
+	return math.Max(0, math.Min(gs.lockDelayTimer/gs.lockDelay, 1)), true
+}
+
+// HoldPiece returns the held piece, or NoPiece when nothing is held
+func (gs *GameState) HoldPiece() Piece { return gs.holdPiece }
+
+// CanHold reports whether the player can hold the current piece
+func (gs *GameState) CanHold() bool { return gs.canHold }
+
+// Stats returns what the player did this game
+func (gs *GameState) Stats() Stats { return gs.stats }
+
+// GameOver reports whether the game has ended
+func (gs *GameState) GameOver() bool { return gs.gameOver }
+
+// ModeComplete reports whether the game ended by reaching the goal of the
+// mode rather than by topping out
+func (gs *GameState) ModeComplete() bool { return gs.modeComplete }
+
+// Paused reports whether the game is paused
+func (gs *GameState) Paused() bool { return gs.paused }
+
+// TogglePause pauses or unpauses the game. A finished game can't be paused.
+func (gs *GameState) TogglePause() {
+	if !gs.gameOver {
+		gs.paused = !gs.paused
+	}
+}
+
+// PerfectClearShown reports whether the perfect clear banner is up
+func (gs *GameState) PerfectClearShown() bool { return gs.perfectClearTimer > 0 }
+
+// AllClearStreak returns the number of perfect clears in a row, 0 when the
+// last piece locked didn't clear the board
+func (gs *GameState) AllClearStreak() int { return gs.allClearStreak }
+
+// Seed returns the seed the pieces of the game are dealt from
+func (gs *GameState) Seed() int64 { return gs.seed }
+
+// Frame returns the number of steps played, not counting time paused
+func (gs *GameState) Frame() uint32 { return gs.frame }
+
+// ReplayEvents returns the actions recorded so far
+func (gs *GameState) ReplayEvents() []replay.ReplayEvent { return gs.replayEvents }
+
+// PlacementHistory returns the history of the last pieces locked
+func (gs *GameState) PlacementHistory() *PlacementHistory { return &gs.placements }
+
+// FrameHashes returns the hashes of the board recorded so far, one every
+// replay.HashInterval frames
+func (gs *GameState) FrameHashes() []uint64 { return gs.frameHashes }
+
+// ClearingRows returns the rows flashing before they are deleted, highest
+// first, and the seconds left before they are
+func (gs *GameState) ClearingRows() ([]int, float64) {
+	return gs.clearAnim.rows, gs.clearAnim.timer
+}
+
+// TapPulse returns how strongly the active piece pulses after a tap, from 1
+// right after the tap down to 0
+func (gs *GameState) TapPulse() float64 {
+	if !gs.visualFeedbackActive {
+		return 0
+	}
+	return 1.0 - gs.lastTapTime/0.08
+}
+
+// LockFlash returns the piece that just locked while it is flashing
+func (gs *GameState) LockFlash() (shape Shape, block Block, active bool) {
+	return gs.lockFlash.shape, gs.lockFlash.block, gs.lockFlash.active
+}
+
+// LevelFlash returns the level just reached and how far the flash for it has
+// faded, from 1 at the level up down to 0 when there is no flash
+func (gs *GameState) LevelFlash() (level int, fade float64) {
+	if !gs.levelFlash.active {
+		return gs.level, 0
+	}
+	return gs.levelFlash.level, gs.levelFlash.timer / levelFlashTime
+}
+
+// HardDropTrail returns the cells the last hard dropped piece fell through
+func (gs *GameState) HardDropTrail() []TrailSegment { return gs.hardDropTrail }
+
+// ScorePopups returns the points for recent line clears rising off the board
+func (gs *GameState) ScorePopups() []ScorePopup { return gs.scorePopups }
+
+// Shake returns how far the board should be shaken right now, in pixels at
+// a UI scale of 1
+func (gs *GameState) Shake() float64 {
+	return gs.shakeAmplitude * gs.shakeTimer / shakeTime
+}
+
+// ShowTSDHint reports whether T-spin double setups are pointed out
+func (gs *GameState) ShowTSDHint() bool { return gs.showTSDHint }
+
+// ToggleTSDHint turns pointing out T-spin double setups on or off
+func (gs *GameState) ToggleTSDHint() { gs.showTSDHint = !gs.showTSDHint }
+
+// TSDHint returns where a T would go to clear the lowest T-spin double
+// setup on the board, as DetectTSDSlot finds it, leaving out the active
+// piece
+func (gs *GameState) TSDHint() (Shape, bool) {
+	b := gs.board.clone()
+	b.drawPiece(gs.activeShape, Empty)
+	row, col, _, found := DetectTSDSlot(b)
+	if !found {
+		return Shape{}, false
+	}
+	// The T of PieceShape is in the spawn state with its center at row 1,
+	// column 1
+	return moveShape(row-1, col-1, PieceShape(TPiece)), true
+}
+
+// ShowPieceCounts reports whether the pieces placed are charted next to the
+// board
+func (gs *GameState) ShowPieceCounts() bool { return gs.showPieceCounts }
+
+// TogglePieceCounts turns charting the pieces placed on or off
+func (gs *GameState) TogglePieceCounts() { gs.showPieceCounts = !gs.showPieceCounts }
+
+// CurrentTheme returns the index of the color theme the game is drawn in, 0
+// for the theme of the settings
+func (gs *GameState) CurrentTheme() int { return gs.currentTheme }
+
+// CycleTheme moves on to the next of n color themes, going back to the
+// first after the last
+func (gs *GameState) CycleTheme(n int) {
+	if n > 0 {
+		gs.currentTheme = (gs.currentTheme + 1) % n
+	}
+}
+
+// HeightWarning reports whether the warning that the stack is close to the
+// top should be shown right now, which blinks on and off while it is
+func (gs *GameState) HeightWarning() bool {
+	return gs.warningBlink > 0 && int(gs.warningBlink*2*warningBlinkRate)%2 == 0
+}
+
+// ShowHeightOverlay reports whether column heights are drawn over the board
+func (gs *GameState) ShowHeightOverlay() bool { return gs.showHeightOverlay }
+
+// ToggleHeightOverlay turns drawing column heights over the board on or off
+func (gs *GameState) ToggleHeightOverlay() { gs.showHeightOverlay = !gs.showHeightOverlay }
+
+// ShowColHeights reports whether the height of each column is written above
+// the board
+func (gs *GameState) ShowColHeights() bool { return gs.showColHeights }
+
+// ToggleColHeights turns writing column heights above the board on or off
+func (gs *GameState) ToggleColHeights() { gs.showColHeights = !gs.showColHeights }
+
+// ShowSurfaceAnalysis reports whether overhangs and holes are highlighted
+func (gs *GameState) ShowSurfaceAnalysis() bool { return gs.showSurface }
+
+// ToggleSurfaceAnalysis turns highlighting overhangs and holes on or off
+func (gs *GameState) ToggleSurfaceAnalysis() { gs.showSurface = !gs.showSurface }
+
+// ShowDebugOverlay reports whether the frame rate and memory use are shown
+func (gs *GameState) ShowDebugOverlay() bool { return gs.showDebugOverlay }
+
+// ToggleDebugOverlay turns showing the frame rate and memory use on or off
+func (gs *GameState) ToggleDebugOverlay() { gs.showDebugOverlay = !gs.showDebugOverlay }
+
+// ShowInputOverlay reports whether the actions held down are drawn
+func (gs *GameState) ShowInputOverlay() bool { return gs.showInputOverlay }
+
+// ToggleInputOverlay turns drawing the actions held down on or off
+func (gs *GameState) ToggleInputOverlay() { gs.showInputOverlay = !gs.showInputOverlay }
+
+// SurfaceAnalysis returns the overhangs and holes of the stack, as
+// FindOverhangs and FindHoles find them, leaving out the active piece
+func (gs *GameState) SurfaceAnalysis() (overhangs, holes [][2]int) {
+	b := gs.board.clone()
+	b.drawPiece(gs.activeShape, Empty)
+	return FindOverhangs(b), FindHoles(b)
+}
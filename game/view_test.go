@@ -0,0 +1,40 @@
+package game
+
+import "testing"
+
+func TestLockProgress(t *testing.T) {
+	gs := newTestGame(t)
+	if fill, resting := gs.LockProgress(); fill != 0 || resting {
+		t.Errorf("LockProgress() = %v, %t for a falling piece, want 0, false", fill, resting)
+	}
+	for gs.applyGravity() > 0 {
+	}
+
+	tests := []struct {
+		timer float64 // In lock delays
+		want  float64
+	}{
+		{-0.5, 0},
+		{0, 0},
+		{0.25, 0.25},
+		{0.5, 0.5},
+		{1, 1},
+		{3, 1},
+	}
+	for _, tt := range tests {
+		gs.lockDelayTimer = tt.timer * gs.lockDelay
+		if fill, resting := gs.LockProgress(); fill != tt.want || !resting {
+			t.Errorf("LockProgress() = %v, %t with %v of the lock delay gone, want %v, true", fill, resting, tt.timer, tt.want)
+		}
+	}
+
+	gs.clearAnim = lineClearAnimation{rows: []int{0}, timer: lineClearTime}
+	if _, resting := gs.LockProgress(); resting {
+		t.Error("LockProgress() shows a piece resting while rows are cleared")
+	}
+	gs.clearAnim = lineClearAnimation{}
+	gs.gameOver = true
+	if _, resting := gs.LockProgress(); resting {
+		t.Error("LockProgress() shows a piece resting after the game is over")
+	}
+}
@@ -1,8 +1,10 @@
 module github.com/zkry/golang-tetris
 
-go 1.14
+go 1.16
 
 require (
+	github.com/faiface/beep v1.1.0
 	github.com/faiface/pixel v0.9.0
+	github.com/veandco/go-sdl2 v0.4.40
 	golang.org/x/image v0.0.0-20200618115811-c13761719519
 )
@@ -0,0 +1,49 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/zkry/golang-tetris/controls"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// actionButtons returns the button bound to each action
+func actionButtons(k controls.Keys) [input.NumActions]pixelgl.Button {
+	return [input.NumActions]pixelgl.Button{
+		input.ActionMoveLeft:  k.MoveLeft,
+		input.ActionMoveRight: k.MoveRight,
+		input.ActionRotateCW:  k.RotateCW,
+		input.ActionRotateCCW: k.RotateCCW,
+		input.ActionRotate180: k.Rotate180,
+		input.ActionSoftDrop:  k.SoftDrop,
+		input.ActionHardDrop:  k.HardDrop,
+		input.ActionHold:      k.Hold,
+	}
+}
+
+// readActions returns which actions are held down on the window
+func readActions(win *pixelgl.Window, buttons [input.NumActions]pixelgl.Button) [input.NumActions]bool {
+	var down [input.NumActions]bool
+	for a, b := range buttons {
+		down[a] = win.Pressed(b)
+	}
+	return down
+}
+
+// releaseButton returns down with the actions bound to b released
+func releaseButton(down [input.NumActions]bool, buttons [input.NumActions]pixelgl.Button, b pixelgl.Button) [input.NumActions]bool {
+	for a := range buttons {
+		if buttons[a] == b {
+			down[a] = false
+		}
+	}
+	return down
+}
+
+// ctrlPressed reports whether either control key is held down
+func ctrlPressed(win *pixelgl.Window) bool {
+	return win.Pressed(pixelgl.KeyLeftControl) || win.Pressed(pixelgl.KeyRightControl)
+}
@@ -0,0 +1,245 @@
+// Package input turns the actions the player holds down into what they do
+// in each step of the game, repeating held moves with DAS and ARR. It knows
+// nothing about keys or windows so replays can be played back through it.
+package input
+
+import "math"
+
+// Action is something the player does with one of the bound keys. The game
+// and replays only see actions, never the keys they are bound to.
+type Action uint8
+
+// Every action the player can take
+const (
+	ActionMoveLeft Action = iota
+	ActionMoveRight
+	ActionRotateCW
+	ActionRotateCCW
+	ActionRotate180
+	ActionSoftDrop
+	ActionHardDrop
+	ActionHold
+	NumActions
+)
+
+// Input handling constants. DAS (Delayed Auto Shift) and ARR (Auto Repeat
+// Rate) are loaded from settings.json, see config.Settings.
+const (
+	ControlSensitivity = 0.05 // Longer window to detect quick taps
+	TapMovePriority    = true // Always prioritize tap movement over DAS/ARR
+	InputBufferWindow  = 0.1  // Input buffer window to capture inputs slightly early
+)
+
+// PlayerInput holds which actions are held down during one step of the
+// game and during the step before it, to tell presses and releases apart
+// from holds.
+type PlayerInput struct {
+	down     [NumActions]bool
+	prevDown [NumActions]bool
+}
+
+// Next moves on to a step with the actions in down held
+func (in *PlayerInput) Next(down [NumActions]bool) {
+	in.prevDown = in.down
+	in.down = down
+}
+
+// Pressed reports whether a is held down
+func (in *PlayerInput) Pressed(a Action) bool {
+	return in.down[a]
+}
+
+// JustPressed reports whether a was pressed this step
+func (in *PlayerInput) JustPressed(a Action) bool {
+	return in.down[a] && !in.prevDown[a]
+}
+
+// JustReleased reports whether a was released this step
+func (in *PlayerInput) JustReleased(a Action) bool {
+	return !in.down[a] && in.prevDown[a]
+}
+
+// InputState is what the player does during one step of the game
+type InputState struct {
+	MoveLeft  bool // Move the piece one column left, on a press or a repeat
+	MoveRight bool // Move the piece one column right, on a press or a repeat
+	RotateCW  bool
+	RotateCCW bool
+	Rotate180 bool
+	SoftDrop  bool // Held for as long as soft drop is held
+	HardDrop  bool
+	Hold      bool
+
+	// Every action held down this step, which is what replays record
+	Down [NumActions]bool
+}
+
+// InputHandler works out the moves of held left and right keys. A press
+// moves at once, holding for DAS seconds starts repeating the move every ARR
+// seconds and quick taps never repeat. Each game needs its own handler.
+type InputHandler struct {
+	DAS float64 // Seconds a move is held before it repeats
+	ARR float64 // Seconds between repeated moves
+
+	leftRightTimer    float64
+	ARRTimer          float64
+	lastMoveDirection int
+	keyReleaseTimer   float64
+	isTapMovement     bool
+	inputBuffer       map[Action]float64
+}
+
+// NewInputHandler creates a handler that repeats moves with the given DAS
+// and ARR in seconds
+func NewInputHandler(das, arr float64) *InputHandler {
+	return &InputHandler{DAS: das, ARR: arr, inputBuffer: make(map[Action]float64)}
+}
+
+// Update advances the handler by dt seconds, one step of the game, and
+// returns what the player does in it. The functions report which actions
+// are held down, were pressed and were released this step.
+func (h *InputHandler) Update(dt float64, pressed, justPressed, justReleased func(Action) bool) InputState {
+	var s InputState
+	for a := range s.Down {
+		s.Down[a] = pressed(Action(a))
+	}
+	move := func(direction int) {
+		if direction < 0 {
+			s.MoveLeft = true
+		} else {
+			s.MoveRight = true
+		}
+	}
+
+	// Update input buffer - clear expired inputs
+	for key, timestamp := range h.inputBuffer {
+		timestamp -= dt
+		if timestamp <= 0 {
+			delete(h.inputBuffer, key)
+		} else {
+			h.inputBuffer[key] = timestamp
+		}
+	}
+
+	// Input handling with prioritization and immediate response
+	leftPressed := pressed(ActionMoveLeft)
+	rightPressed := pressed(ActionMoveRight)
+
+	// Buffer all new key presses for responsive control
+	if justPressed(ActionMoveLeft) {
+		h.inputBuffer[ActionMoveLeft] = InputBufferWindow
+		h.keyReleaseTimer = 0
+		h.isTapMovement = true
+		move(-1)
+	}
+
+	if justPressed(ActionMoveRight) {
+		h.inputBuffer[ActionMoveRight] = InputBufferWindow
+		h.keyReleaseTimer = 0
+		h.isTapMovement = true
+		move(1)
+	}
+
+	// Process key releases with improved tap detection
+	if justReleased(ActionMoveLeft) || justReleased(ActionMoveRight) {
+		// Short taps get special treatment for precision movement
+		if h.keyReleaseTimer < ControlSensitivity {
+			h.isTapMovement = false
+
+			// Reset auto-repeat system to prevent unwanted movement
+			h.leftRightTimer = h.DAS * 1.5 // Add a small delay after taps for better control
+			h.ARRTimer = 0
+		}
+	}
+
+	// Update tap detection timer
+	if h.isTapMovement {
+		h.keyReleaseTimer += dt
+		if h.keyReleaseTimer > ControlSensitivity {
+			h.isTapMovement = false // No longer considered a tap after sensitivity threshold
+		}
+	}
+
+	// Determine movement direction with intelligent conflict resolution
+	direction := 0
+	if leftPressed && rightPressed {
+		// If both keys are pressed, use the most recently pressed one based on buffer
+		leftTime, hasLeft := h.inputBuffer[ActionMoveLeft]
+		rightTime, hasRight := h.inputBuffer[ActionMoveRight]
+
+		if hasLeft && hasRight {
+			if leftTime > rightTime {
+				direction = -1
+			} else {
+				direction = 1
+			}
+		} else if hasLeft {
+			direction = -1
+		} else if hasRight {
+			direction = 1
+		} else if h.lastMoveDirection != 0 {
+			direction = h.lastMoveDirection
+		}
+	} else if leftPressed {
+		direction = -1
+	} else if rightPressed {
+		direction = 1
+	} else {
+		// Reset DAS/ARR when no direction keys are pressed
+		h.leftRightTimer = 0
+		h.ARRTimer = 0
+		h.lastMoveDirection = 0
+	}
+
+	// Handle movement with improved DAS/ARR system
+	if direction != 0 {
+		if direction != h.lastMoveDirection {
+			// Direction change - immediate movement for responsiveness
+			h.lastMoveDirection = direction
+			h.leftRightTimer = h.DAS
+			h.ARRTimer = 0
+
+			// Only move here if we didn't already move in JustPressed
+			if !justPressed(ActionMoveLeft) && !justPressed(ActionMoveRight) {
+				move(direction)
+			}
+		} else if !h.isTapMovement {
+			// Auto-shift handling for held keys
+			h.leftRightTimer -= dt
+			if h.leftRightTimer <= 0 {
+				// DAS charged, use ARR for repeated movement
+				h.ARRTimer += dt
+				if h.ARRTimer >= h.ARR {
+					// Reset ARR immediately for more consistent repeat rate
+					h.ARRTimer = 0
+					move(direction)
+				}
+			}
+		}
+	}
+
+	s.RotateCW = justPressed(ActionRotateCW)
+	s.RotateCCW = justPressed(ActionRotateCCW)
+	s.Rotate180 = justPressed(ActionRotate180)
+	s.SoftDrop = pressed(ActionSoftDrop)
+	s.HardDrop = justPressed(ActionHardDrop)
+	s.Hold = justPressed(ActionHold)
+	return s
+}
+
+// Charge reports how far a held move is towards repeating: das and arr run
+// from 0 to 1 as the DAS delay and the time to the next repeat pass, and
+// direction is -1 or 1 for the move held, 0 when neither is.
+func (h *InputHandler) Charge() (das, arr float64, direction int) {
+	if h.lastMoveDirection == 0 {
+		return 0, 0, 0
+	}
+	das = 1
+	if h.DAS > 0 && h.leftRightTimer > 0 {
+		das = math.Max(0, 1-h.leftRightTimer/h.DAS)
+	}
+	if das == 1 && h.ARR > 0 {
+		arr = math.Min(1, h.ARRTimer/h.ARR)
+	}
+	return das, arr, h.lastMoveDirection
+}
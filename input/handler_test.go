@@ -0,0 +1,118 @@
+package input
+
+import (
+	"math"
+	"testing"
+)
+
+const step = 1.0 / 120
+
+// moveFrames runs a handler through frames steps, holding the actions of
+// down(frame), and returns the frames the piece moved on and which way
+func moveFrames(h *InputHandler, frames int, down func(frame int) [NumActions]bool) (moves []int, dirs []int) {
+	var in PlayerInput
+	for f := 0; f < frames; f++ {
+		in.Next(down(f))
+		s := h.Update(step, in.Pressed, in.JustPressed, in.JustReleased)
+		if s.MoveLeft {
+			moves, dirs = append(moves, f), append(dirs, -1)
+		}
+		if s.MoveRight {
+			moves, dirs = append(moves, f), append(dirs, 1)
+		}
+	}
+	return moves, dirs
+}
+
+// held returns a down function holding a from frame start until frame end
+func held(a Action, start, end int) func(int) [NumActions]bool {
+	return func(f int) [NumActions]bool {
+		var down [NumActions]bool
+		down[a] = f >= start && f < end
+		return down
+	}
+}
+
+func TestTapMovesOnce(t *testing.T) {
+	h := NewInputHandler(0.2, 0.05)
+	moves, dirs := moveFrames(h, 120, held(ActionMoveLeft, 10, 12))
+	if len(moves) != 1 || moves[0] != 10 || dirs[0] != -1 {
+		t.Errorf("tap moved on frames %v in directions %v, want only frame 10 to the left", moves, dirs)
+	}
+}
+
+func TestDASAndARR(t *testing.T) {
+	const das, arr = 0.2, 0.05
+	h := NewInputHandler(das, arr)
+	moves, dirs := moveFrames(h, 120, held(ActionMoveRight, 0, 120))
+	if len(moves) < 3 || moves[0] != 0 {
+		t.Fatalf("holding right moved on frames %v, want at once and then repeating", moves)
+	}
+	for _, d := range dirs {
+		if d != 1 {
+			t.Fatalf("holding right moved in directions %v", dirs)
+		}
+	}
+
+	// Nothing until DAS has charged, which starts once the press is no
+	// longer a tap
+	first := float64(moves[1]) * step
+	if first < das || first > das+ControlSensitivity+arr+step {
+		t.Errorf("first repeat %vs after the press, want between %v and %v", first, das, das+ControlSensitivity+arr)
+	}
+
+	// Then a move every ARR, to the step
+	for i := 2; i < len(moves); i++ {
+		gap := float64(moves[i]-moves[i-1]) * step
+		if math.Abs(gap-arr) > step+1e-9 {
+			t.Errorf("%vs between repeats %d and %d, want %v", gap, i-1, i, arr)
+		}
+	}
+}
+
+func TestReleaseStartsOver(t *testing.T) {
+	h := NewInputHandler(0.2, 0.05)
+	down := func(f int) [NumActions]bool {
+		var d [NumActions]bool
+		d[ActionMoveLeft] = f < 60 || f >= 70
+		return d
+	}
+	moves, _ := moveFrames(h, 100, down)
+	var after []int
+	for _, f := range moves {
+		if f >= 70 {
+			after = append(after, f)
+		}
+	}
+	// A new press moves straight away and has to charge DAS again
+	if len(after) != 1 || after[0] != 70 {
+		t.Errorf("moves after pressing again on frame 70 = %v, want only 70", after)
+	}
+}
+
+func TestCharge(t *testing.T) {
+	h := NewInputHandler(0.2, 0.05)
+	if das, arr, dir := h.Charge(); das != 0 || arr != 0 || dir != 0 {
+		t.Errorf("Charge() = %v, %v, %d with nothing held, want 0, 0, 0", das, arr, dir)
+	}
+	moveFrames(h, 60, held(ActionMoveLeft, 0, 60))
+	if das, _, dir := h.Charge(); das != 1 || dir != -1 {
+		t.Errorf("Charge() = %v, _, %d after half a second held left, want 1, -1", das, dir)
+	}
+}
+
+func TestOtherActions(t *testing.T) {
+	h := NewInputHandler(0.2, 0.05)
+	var in PlayerInput
+	for f := 0; f < 3; f++ {
+		in.Next([NumActions]bool{ActionRotateCW: true, ActionSoftDrop: true, ActionHardDrop: true, ActionHold: true})
+		s := h.Update(step, in.Pressed, in.JustPressed, in.JustReleased)
+		first := f == 0
+		if s.RotateCW != first || s.HardDrop != first || s.Hold != first || !s.SoftDrop {
+			t.Errorf("frame %d held: %+v, want presses only on the first frame and soft drop throughout", f, s)
+		}
+		if !s.Down[ActionRotateCW] || s.Down[ActionRotateCCW] {
+			t.Errorf("frame %d: Down = %v", f, s.Down)
+		}
+	}
+}
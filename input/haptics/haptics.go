@@ -0,0 +1,64 @@
+// Package haptics rumbles a gamepad when pieces lock and lines clear. The
+// rumble itself is played by a Backend, SDL for a real gamepad, so the
+// pulses don't depend on the device that plays them.
+package haptics
+
+import "time"
+
+// Pulses played by a HapticController. A line clear pulses at ClearStrength
+// for a Tetris and proportionally less for fewer lines.
+const (
+	LockStrength  = 0.3
+	LockLength    = 50 * time.Millisecond
+	ClearStrength = 0.8
+	ClearLength   = 150 * time.Millisecond
+)
+
+// maxClearLines is the number of lines cleared that pulses at ClearStrength
+const maxClearLines = 4
+
+// Backend plays rumbles on a device
+type Backend interface {
+	// Rumble rumbles at strength, from 0 to 1, for length, replacing any
+	// rumble still playing
+	Rumble(strength float64, length time.Duration) error
+	// Close releases the device
+	Close() error
+}
+
+// HapticController rumbles a gamepad for what happens in the game
+type HapticController struct {
+	backend Backend
+}
+
+// NewHapticController returns a controller playing its pulses on backend
+func NewHapticController(backend Backend) *HapticController {
+	return &HapticController{backend: backend}
+}
+
+// OnPieceLock plays a short weak pulse
+func (hc *HapticController) OnPieceLock() {
+	hc.rumble(LockStrength, LockLength)
+}
+
+// OnLineClear plays a longer pulse, stronger the more lines were cleared
+func (hc *HapticController) OnLineClear(lines int) {
+	if lines <= 0 {
+		return
+	}
+	if lines > maxClearLines {
+		lines = maxClearLines
+	}
+	hc.rumble(ClearStrength*float64(lines)/maxClearLines, ClearLength)
+}
+
+// rumble plays a pulse. Rumbling is best effort: a gamepad unplugged during
+// a game just stops rumbling.
+func (hc *HapticController) rumble(strength float64, length time.Duration) {
+	_ = hc.backend.Rumble(strength, length)
+}
+
+// Close releases the gamepad
+func (hc *HapticController) Close() error {
+	return hc.backend.Close()
+}
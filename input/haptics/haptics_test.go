@@ -0,0 +1,81 @@
+package haptics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// pulse is a rumble played by a mockBackend
+type pulse struct {
+	strength float64
+	length   time.Duration
+}
+
+// mockBackend records the rumbles played on it instead of playing them
+type mockBackend struct {
+	pulses []pulse
+	err    error // Returned by Rumble
+	closed bool
+}
+
+func (b *mockBackend) Rumble(strength float64, length time.Duration) error {
+	b.pulses = append(b.pulses, pulse{strength, length})
+	return b.err
+}
+
+func (b *mockBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestOnPieceLock(t *testing.T) {
+	var b mockBackend
+	NewHapticController(&b).OnPieceLock()
+	if want := (pulse{0.3, 50 * time.Millisecond}); len(b.pulses) != 1 || b.pulses[0] != want {
+		t.Errorf("OnPieceLock() played %v, want %v", b.pulses, want)
+	}
+}
+
+func TestOnLineClear(t *testing.T) {
+	tests := []struct {
+		lines int
+		want  []pulse
+	}{
+		{0, nil},
+		{-1, nil},
+		{1, []pulse{{0.2, 150 * time.Millisecond}}},
+		{2, []pulse{{0.4, 150 * time.Millisecond}}},
+		{3, []pulse{{0.8 * 3 / 4, 150 * time.Millisecond}}},
+		{4, []pulse{{0.8, 150 * time.Millisecond}}},
+		{5, []pulse{{0.8, 150 * time.Millisecond}}}, // No stronger than a Tetris
+	}
+	for _, tt := range tests {
+		var b mockBackend
+		NewHapticController(&b).OnLineClear(tt.lines)
+		if len(b.pulses) != len(tt.want) {
+			t.Errorf("OnLineClear(%d) played %v, want %v", tt.lines, b.pulses, tt.want)
+			continue
+		}
+		for i, p := range b.pulses {
+			if p.length != tt.want[i].length || math.Abs(p.strength-tt.want[i].strength) > 1e-9 {
+				t.Errorf("OnLineClear(%d) played %v, want %v", tt.lines, b.pulses, tt.want)
+			}
+		}
+	}
+}
+
+func TestRumbleError(t *testing.T) {
+	// A gamepad unplugged mid game is ignored, later pulses still tried
+	b := mockBackend{err: errors.New("unplugged")}
+	hc := NewHapticController(&b)
+	hc.OnPieceLock()
+	hc.OnLineClear(4)
+	if len(b.pulses) != 2 {
+		t.Errorf("%d pulses tried after the gamepad failed, want 2", len(b.pulses))
+	}
+	if err := hc.Close(); err != nil || !b.closed {
+		t.Errorf("Close() = %v, backend closed %t", err, b.closed)
+	}
+}
@@ -0,0 +1,70 @@
+package haptics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ErrNoGamepad is returned by OpenSDL when no gamepad that can rumble is
+// connected
+var ErrNoGamepad = errors.New("no gamepad that can rumble is connected")
+
+// sdlSubsystems are the parts of SDL needed to rumble a gamepad
+const sdlSubsystems = sdl.INIT_JOYSTICK | sdl.INIT_HAPTIC
+
+// sdlBackend rumbles a gamepad through SDL's simple rumble API
+type sdlBackend struct {
+	haptic *sdl.Haptic
+}
+
+// OpenSDL returns a controller for the first connected gamepad that can
+// rumble, or ErrNoGamepad when there is none
+func OpenSDL() (*HapticController, error) {
+	if err := sdl.InitSubSystem(sdlSubsystems); err != nil {
+		return nil, err
+	}
+	haptic, err := openRumble()
+	if err != nil {
+		sdl.QuitSubSystem(sdlSubsystems)
+		return nil, err
+	}
+	return NewHapticController(&sdlBackend{haptic}), nil
+}
+
+// openRumble opens the first haptic device and readies it to rumble
+func openRumble() (*sdl.Haptic, error) {
+	n, err := sdl.NumHaptics()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNoGamepad
+	}
+	haptic, err := sdl.HapticOpen(0)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := haptic.RumbleSupported(); err != nil || !ok {
+		haptic.Close()
+		return nil, errors.New("the gamepad can't rumble")
+	}
+	if err := haptic.RumbleInit(); err != nil {
+		haptic.Close()
+		return nil, err
+	}
+	return haptic, nil
+}
+
+// Rumble implements Backend
+func (b *sdlBackend) Rumble(strength float64, length time.Duration) error {
+	return b.haptic.RumblePlay(float32(strength), uint32(length/time.Millisecond))
+}
+
+// Close implements Backend
+func (b *sdlBackend) Close() error {
+	b.haptic.Close()
+	sdl.QuitSubSystem(sdlSubsystems)
+	return nil
+}
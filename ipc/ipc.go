@@ -0,0 +1,219 @@
+// Package ipc lets another program play the game over a Unix domain socket,
+// such as a bot written in another language. The protocol is newline
+// delimited JSON. The game sends the state of the game every frame:
+//
+//	{"type":"state","board":[[0,0,...],...],"piece":"T","nextPiece":"I","hold":"L","score":1234}
+//
+// The board is every row, hidden ones included, bottom row first, each an
+// array of block values. The hold piece is an empty string when nothing is
+// held. The client sends the actions to take, each tapped for one step of
+// the game in the order they are received:
+//
+//	{"action":"move_left"}
+//
+// The actions are move_left, move_right, rotate_cw, rotate_ccw, rotate_180,
+// soft_drop, hard_drop and hold.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// actionNames are the names of the actions in commands, indexed by action
+var actionNames = [input.NumActions]string{
+	input.ActionMoveLeft:  "move_left",
+	input.ActionMoveRight: "move_right",
+	input.ActionRotateCW:  "rotate_cw",
+	input.ActionRotateCCW: "rotate_ccw",
+	input.ActionRotate180: "rotate_180",
+	input.ActionSoftDrop:  "soft_drop",
+	input.ActionHardDrop:  "hard_drop",
+	input.ActionHold:      "hold",
+}
+
+// ParseAction returns the action called name in commands
+func ParseAction(name string) (input.Action, error) {
+	for a, n := range actionNames {
+		if n == name {
+			return input.Action(a), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown action %q", name)
+}
+
+// commandBuffer is the number of actions that can wait to be tapped before
+// the clients are held up
+const commandBuffer = 64
+
+// stateBuffer is the number of states that can wait to be sent to a client
+// before new ones are dropped for it
+const stateBuffer = 4
+
+// State is what the game sends to its clients every frame
+type State struct {
+	Type      string     `json:"type"` // Always "state"
+	Board     game.Board `json:"board"`
+	Piece     string     `json:"piece"`
+	NextPiece string     `json:"nextPiece"`
+	Hold      string     `json:"hold"`
+	Score     int        `json:"score"`
+}
+
+// Command is an action sent by a client
+type Command struct {
+	Action string `json:"action"`
+}
+
+// IPCServer accepts clients on a Unix domain socket, sends them the state of
+// the game and taps the actions they send
+type IPCServer struct {
+	listener net.Listener
+	commands chan input.Action // Actions received, waiting to be tapped
+
+	mu      sync.Mutex
+	clients map[net.Conn]chan []byte // Lines waiting to be sent to each client
+
+	pressed bool // Whether an action was held down for the last step
+}
+
+// NewIPCServer starts a server listening on the Unix domain socket at
+// socketPath, replacing a socket left there by a server that didn't close.
+func NewIPCServer(socketPath string) (*IPCServer, error) {
+	if info, err := os.Lstat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socketPath)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &IPCServer{
+		listener: l,
+		commands: make(chan input.Action, commandBuffer),
+		clients:  make(map[net.Conn]chan []byte),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Close stops the server and disconnects its clients. The socket file is
+// removed.
+func (s *IPCServer) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn, lines := range s.clients {
+		close(lines)
+		delete(s.clients, conn)
+	}
+	return err
+}
+
+// accept serves each client that connects until the server is closed
+func (s *IPCServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		lines := make(chan []byte, stateBuffer)
+		s.mu.Lock()
+		s.clients[conn] = lines
+		s.mu.Unlock()
+		go s.write(conn, lines)
+		go s.read(conn)
+	}
+}
+
+// write sends lines to conn until they are closed or conn fails
+func (s *IPCServer) write(conn net.Conn, lines chan []byte) {
+	defer conn.Close()
+	for line := range lines {
+		if _, err := conn.Write(line); err != nil {
+			s.drop(conn)
+			return
+		}
+	}
+}
+
+// read queues the actions conn sends until it disconnects. Lines that
+// aren't a known command are skipped with a warning.
+func (s *IPCServer) read(conn net.Conn) {
+	defer s.drop(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var c Command
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			fmt.Fprintln(os.Stderr, "ipc: reading command:", err)
+			continue
+		}
+		a, err := ParseAction(c.Action)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ipc:", err)
+			continue
+		}
+		s.commands <- a
+	}
+}
+
+// drop forgets conn, closing it once its last lines are written
+func (s *IPCServer) drop(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lines, ok := s.clients[conn]; ok {
+		close(lines)
+		delete(s.clients, conn)
+	}
+}
+
+// SendState sends the state of gs to every client. A client that hasn't
+// read the last few states misses this one rather than holding up the game.
+func (s *IPCServer) SendState(gs *game.GameState) error {
+	line, err := json.Marshal(State{
+		Type:      "state",
+		Board:     gs.Board(),
+		Piece:     game.PieceName(gs.CurrentPiece()),
+		NextPiece: game.PieceName(gs.NextPieces()[0]),
+		Hold:      game.PieceName(gs.HoldPiece()),
+		Score:     gs.Score(),
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, lines := range s.clients {
+		select {
+		case lines <- line:
+		default:
+		}
+	}
+	return nil
+}
+
+// Next returns the actions held down for the next step of the game. Each
+// action received is pressed for one step and released the next, like a
+// bot's taps.
+func (s *IPCServer) Next() [input.NumActions]bool {
+	var down [input.NumActions]bool
+	if s.pressed {
+		s.pressed = false
+		return down
+	}
+	select {
+	case a := <-s.commands:
+		down[a] = true
+		s.pressed = true
+	default:
+	}
+	return down
+}
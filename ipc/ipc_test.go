@@ -0,0 +1,143 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+)
+
+// waitFor fails the test if cond isn't true within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// startServer starts a server on a socket in a new temporary directory and
+// connects a client to it, both closed at the end of the test
+func startServer(t *testing.T) (*IPCServer, net.Conn) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "game.sock")
+	s, err := NewIPCServer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, conn
+}
+
+func TestParseAction(t *testing.T) {
+	for a := input.Action(0); a < input.NumActions; a++ {
+		if got, err := ParseAction(actionNames[a]); err != nil || got != a {
+			t.Errorf("ParseAction(%q) = %d, %v, want %d", actionNames[a], got, err, a)
+		}
+	}
+	for _, name := range []string{"", "jump", "MOVE_LEFT"} {
+		if _, err := ParseAction(name); err == nil {
+			t.Errorf("ParseAction(%q) returned no error", name)
+		}
+	}
+}
+
+func TestMoveCommand(t *testing.T) {
+	s, conn := startServer(t)
+	gs := game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), 1)
+	before := gs.ActiveShape()
+
+	// A line that isn't a command is skipped, the move after it tapped
+	if _, err := conn.Write([]byte("not json\n{\"action\":\"jump\"}\n{\"action\":\"move_left\"}\n")); err != nil {
+		t.Fatal(err)
+	}
+	var down [input.NumActions]bool
+	waitFor(t, "the move", func() bool {
+		down = s.Next()
+		return down != [input.NumActions]bool{}
+	})
+	var want [input.NumActions]bool
+	want[input.ActionMoveLeft] = true
+	if down != want {
+		t.Fatalf("Next() = %v, want only move left", down)
+	}
+	if next := s.Next(); next != ([input.NumActions]bool{}) {
+		t.Errorf("Next() after a tap = %v, want it released", next)
+	}
+
+	// Played on the game, the piece moves a column left
+	var in input.PlayerInput
+	handler := input.NewInputHandler(gs.Settings().DAS, gs.Settings().ARR)
+	in.Next(down)
+	gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+	for i, c := range gs.ActiveShape() {
+		if c.Col() != before[i].Col()-1 {
+			t.Fatalf("piece moved from %v to %v, want a column left", before, gs.ActiveShape())
+		}
+	}
+}
+
+func TestSendState(t *testing.T) {
+	s, conn := startServer(t)
+	gs := game.NewSeededGameState(game.ModeMarathon, config.DefaultSettings(), 1)
+
+	// The client is served once it has been accepted, until then states
+	// are sent to no one
+	lines := make(chan []byte, 1)
+	go func() {
+		r := bufio.NewReader(conn)
+		line, err := r.ReadBytes('\n')
+		if err == nil {
+			lines <- line
+		}
+	}()
+	var line []byte
+	waitFor(t, "a state", func() bool {
+		if err := s.SendState(gs); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case line = <-lines:
+			return true
+		default:
+			return false
+		}
+	})
+
+	var state State
+	if err := json.Unmarshal(line, &state); err != nil {
+		t.Fatalf("state %s isn't JSON: %v", line, err)
+	}
+	want := State{
+		Type:      "state",
+		Board:     gs.Board(),
+		Piece:     game.PieceName(gs.CurrentPiece()),
+		NextPiece: game.PieceName(gs.NextPieces()[0]),
+		Score:     0,
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("state = %+v, want %+v", state, want)
+	}
+}
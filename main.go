@@ -1,11 +1,14 @@
+//go:build !wasm
+// +build !wasm
+
 package main
 
 import (
+	"flag"
 	"fmt"
-	_ "image/png"
 	"math"
-	"math/rand"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/faiface/pixel"
@@ -14,140 +17,261 @@ import (
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
 
-	ss "github.com/zkry/golang-tetris/spritesheet"
+	"github.com/zkry/golang-tetris/api"
+	"github.com/zkry/golang-tetris/bot"
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/controls"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/input/haptics"
+	"github.com/zkry/golang-tetris/ipc"
+	"github.com/zkry/golang-tetris/netplay"
+	"github.com/zkry/golang-tetris/persist"
+	"github.com/zkry/golang-tetris/render"
+	"github.com/zkry/golang-tetris/replay"
+	"github.com/zkry/golang-tetris/sound"
 )
 
-// BoardRows is the height of the game board in terms of blocks
-const BoardRows = 22
-
-// BoardCols is the width of the game board in terms of blocks
-const BoardCols = 10
-
-// Point represents a coordinate on the game board with Point{row:0, col:0}
-// representing the bottom left
-type Point struct {
-	row int
-	col int
-}
-
-// Board is an array containing the entire game board pieces.
-type Board [22][10]Block
-
-// Block represents the color of the block
-type Block int
-
-// Different values a point on the grid can hold
+// Initial layout positions of the score and the panel labels, for
+// responsive scaling
 const (
-	Empty Block = iota
-	Goluboy
-	Siniy
-	Pink
-	Purple
-	Red
-	Yellow
-	Green
-	Gray
-	GoluboySpecial
-	SiniySpecial
-	PinkSpecial
-	PurpleSpecial
-	RedSpecial
-	YellowSpecial
-	GreenSpecial
-	GraySpecial
+	initialScoreX        = 500.0
+	initialScoreY        = 400.0
+	initialNextPieceTxtX = 142.0
+	initialNextPieceTxtY = 285.0
+	initialHoldPieceTxtX = 142.0
+	initialHoldPieceTxtY = 385.0
 )
 
-// Piece is a constant for a shape of piece. There are 7 classic pieces like L, and O
-type Piece int
+func main() {
+	modeFlag := flag.String("mode", "marathon", "game mode to play: marathon, sprint, ultra, survival, practice, daily or twoplayer")
+	replayFlag := flag.String("replay", "", "play back the replay file at this path, recorded in the same -mode")
+	rowsFlag := flag.Int("rows", 0, "visible height of the board, overrides rows in settings.json")
+	colsFlag := flag.Int("cols", 0, "width of the board, overrides cols in settings.json")
+	headlessFlag := flag.Bool("headless", false, "play the -replay, or let the -bot play, without a window and print how the game ended")
+	botFlag := flag.String("bot", "", "let a bot play instead of the keyboard: random, greedy or lookahead")
+	maxFramesFlag := flag.Int("max-frames", 0, "with -headless, stop after this many steps of the game, 0 for no limit")
+	boardFlag := flag.String("board", "", "with -mode=practice, start on the board set up in this text file, which also sets its size")
+	seedFlag := flag.Int64("seed", 0, "deal the pieces from this seed instead of a random one, to play a piece sequence again")
+	serverFlag := flag.String("server", "", "pair up the players that connect on this port for network games, without playing")
+	clientFlag := flag.String("client", "", "play a network game against another player through the -server at this host:port")
+	gifFlag := flag.String("gif", "", "write the -replay to this file as an animated GIF of the board instead of playing it")
+	apiPortFlag := flag.Int("api-port", 0, "serve the state of the game over HTTP on this port of localhost, 0 for none")
+	ipcFlag := flag.String("ipc", "", "let the program connected to the Unix domain socket at this path play instead of the keyboard")
+	flag.Parse()
+	// Any seed is valid, so only a seed that was given replaces a random one
+	var seed *int64
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seed = seedFlag
+		}
+	})
+	mode, err := game.ParseGameMode(*modeFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	keys, err := controls.LoadControls("resources/controls.json")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	settings, err := config.LoadSettings(settingsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *rowsFlag != 0 {
+		settings.Rows = *rowsFlag
+	}
+	if *colsFlag != 0 {
+		settings.Cols = *colsFlag
+	}
+	var startBoard game.Board
+	if *boardFlag != "" {
+		if mode != game.ModePractice {
+			fmt.Fprintln(os.Stderr, "-board can only be used with -mode=practice")
+			os.Exit(2)
+		}
+		startBoard, err = persist.LoadBoardFromFile(*boardFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		settings.Rows = startBoard.Rows() - game.HiddenRows
+		settings.Cols = startBoard.Cols()
+	}
+	if err := settings.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-// Various values that the pieces can be
-const (
-	IPiece Piece = iota
-	JPiece
-	LPiece
-	OPiece
-	SPiece
-	TPiece
-	ZPiece
-	NoPiece Piece = -1
-)
+	var player *replay.ReplayPlayer
+	if *replayFlag != "" {
+		replaySeed, events, hashes, err := replay.LoadReplay(*replayFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		player = replay.NewReplayPlayer(replaySeed, events, hashes)
+	}
+	if player != nil && seed != nil {
+		fmt.Fprintln(os.Stderr, "-seed can't be used with -replay, which has its own seed")
+		os.Exit(2)
+	}
+	if mode == game.ModeDailyChallenge && player == nil {
+		if seed != nil {
+			fmt.Fprintln(os.Stderr, "-seed can't be used with -mode=daily, which is dealt from the seed of the day")
+			os.Exit(2)
+		}
+		daily := game.DailySeed(time.Now())
+		seed = &daily
+	}
 
-// Shape is a type containing four points, which represents the four points
-// making a contiguous 'piece'.
-type Shape [4]Point
+	if *gifFlag != "" {
+		if player == nil {
+			fmt.Fprintln(os.Stderr, "-gif needs a -replay to write")
+			os.Exit(2)
+		}
+		gs := newPlaybackGameState(mode, settings, player, nil, nil)
+		f, err := os.Create(*gifFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		err = gs.EncodeGIF(f, player, 0, 0)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-const levelLength = 60.0 // Time it takes for game speed up
-const speedUpRate = 0.1  // Every new level, the amount the game speeds up by
+	if *serverFlag != "" {
+		server, err := netplay.Listen(":" + *serverFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("Pairing up players on", server.Addr())
+		if err := server.Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *clientFlag != "" {
+		if player != nil || *botFlag != "" || *ipcFlag != "" || *apiPortFlag != 0 || *headlessFlag || seed != nil || startBoard != nil {
+			fmt.Fprintln(os.Stderr, "-client is played at the keyboard, without -replay, -bot, -ipc, -api-port, -headless, -seed or -board")
+			os.Exit(2)
+		}
+		client, err := netplay.Dial(*clientFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		pixelgl.Run(func() {
+			runNetwork(keys, settings, client)
+		})
+		return
+	}
 
-// DAS (Delayed Auto Shift) and ARR (Auto Repeat Rate) constants
-const (
-	DASDelay           = 0.033 // Reduced initial delay for more responsive control
-	ARRRate            = 0.033 // Faster repeat rate for better responsiveness
-	ControlSensitivity = 0.05  // Longer window to detect quick taps
-	SoftDropSpeed      = 0.05  // Faster soft drop speed for better responsiveness
-	SoftDropFriction   = 0.1   // Less friction for smoother soft drops
-	TapMovePriority    = true  // Always prioritize tap movement over DAS/ARR
-	InputBufferWindow  = 0.1   // Input buffer window to capture inputs slightly early
-)
+	if mode == game.ModeTwoPlayer && (player != nil || *botFlag != "" || *headlessFlag) {
+		fmt.Fprintln(os.Stderr, "-mode=twoplayer is played at the keyboard, without -replay, -bot or -headless")
+		os.Exit(2)
+	}
 
-var gameBoard Board
-var activeShape Shape // The shape that the player controls
-var currentPiece Piece
-var gravityTimer float64
-var baseSpeed float64 = 0.8
-var gravitySpeed float64 = 0.8
-var lockDelay float64 = 0.25 // Slightly increased for better placement opportunity
-var lockDelayTimer float64 = 0
-var lockResets int = 0
-var maxLockResets int = 30
-var levelUpTimer float64 = levelLength
-var gameOver bool = false
-var leftRightTimer float64
-var ARRTimer float64
-var lastMoveDirection int = 0
-var keyReleaseTimer float64 = 0
-var lastKeyReleaseTime float64 = 0
-var isTapMovement bool = false
-var inputBuffer map[pixelgl.Button]float64 = make(map[pixelgl.Button]float64) // New input buffer system
-var score int
-var nextPiece Piece
-var holdPiece Piece = NoPiece
-var canHold bool = true
-var rotationState int = 0
-var pieceBag []Piece = nil
-var lastMovementWasRotation bool = false
-var lastRotationPoint Shape
-var rotationCooldown float64 = 0.0
-var rotationDirection int = 0
-var lastTapTime float64 = 0
-var visualFeedbackActive bool = false
-var softDropFrictionTimer float64 = 0
-var lastSoftDropTime float64 = 0
-var movementSmoothing bool = true // Enable movement smoothing for transitions
-
-var blockGen func(int) pixel.Picture
-var bgImgSprite pixel.Sprite
-var gameBGSprite pixel.Sprite
-var nextPieceBGSprite pixel.Sprite
-var holdPieceBGSprite pixel.Sprite
+	var botPlayer bot.Bot
+	if *botFlag != "" {
+		if player != nil {
+			fmt.Fprintln(os.Stderr, "-bot can't be used with -replay")
+			os.Exit(2)
+		}
+		difficulty, err := bot.ParseBotDifficulty(*botFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		botPlayer = bot.NewBot(difficulty)
+	}
+	var remote *ipc.IPCServer
+	if *ipcFlag != "" {
+		if player != nil || botPlayer != nil || *headlessFlag || mode == game.ModeTwoPlayer {
+			fmt.Fprintln(os.Stderr, "-ipc can't be used with -replay, -bot, -headless or -mode=twoplayer")
+			os.Exit(2)
+		}
+		remote, err = ipc.NewIPCServer(*ipcFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer remote.Close()
+	}
+	var apiServer *api.Server
+	if *apiPortFlag != 0 {
+		if *headlessFlag || mode == game.ModeTwoPlayer {
+			fmt.Fprintln(os.Stderr, "-api-port can't be used with -headless or -mode=twoplayer")
+			os.Exit(2)
+		}
+		apiServer, err = api.NewServer(fmt.Sprintf("localhost:%d", *apiPortFlag))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer apiServer.Close()
+	}
+	if *headlessFlag {
+		var gs *game.GameState
+		var desync error
+		switch {
+		case botPlayer != nil:
+			gs = newPlaybackGameState(mode, settings, nil, seed, startBoard)
+			bot.Play(gs, botPlayer, *maxFramesFlag)
+		case player != nil:
+			gs = newPlaybackGameState(mode, settings, player, nil, nil)
+			desync = gs.Simulate(player, *maxFramesFlag)
+		default:
+			fmt.Fprintln(os.Stderr, "-headless needs a -replay or -bot to play")
+			os.Exit(2)
+		}
+		fmt.Printf("Score: %d\nLines: %d\nTime: %s\nGame over: %t\n", gs.Score(), gs.LinesCleared(), render.FormatTime(gs.ElapsedTime()), gs.GameOver())
+		if desync != nil {
+			fmt.Fprintln(os.Stderr, desync)
+			os.Exit(1)
+		}
+		return
+	}
 
-func main() {
-	// Ensure random number generator is seeded properly
-	rand.Seed(time.Now().UnixNano())
-	pixelgl.Run(run)
+	pixelgl.Run(func() {
+		if mode == game.ModeTwoPlayer {
+			runTwoPlayer(settings, seed)
+			return
+		}
+		run(mode, keys, settings, player, botPlayer, remote, apiServer, seed, startBoard)
+	})
 }
 
-// run is the main code for the game. Allows pixelgl to run on main thread
-func run() {
-	// Initialize the window with minimum size constraints
-	windowWidth := 765.0
-	windowHeight := 450.0
-	minWindowWidth := 640.0  // Minimum width to keep UI elements usable
-	minWindowHeight := 400.0 // Minimum height to keep UI elements usable
+// run is the main code for the game. Allows pixelgl to run on main thread.
+// When player is not nil the game plays back its replay instead of reading
+// the keyboard, and when botPlayer or remote is not nil it plays instead.
+// When seed is not nil the games are dealt from it, and when startBoard is
+// not nil they start on it. apiServer, if not nil, is sent the game after
+// every frame and can restart it.
+func run(mode game.GameMode, keys controls.Keys, settings config.Settings, player *replay.ReplayPlayer, botPlayer bot.Bot, remote *ipc.IPCServer, apiServer *api.Server, seed *int64, startBoard game.Board) {
+	// Initialize the window where it was last closed, with minimum size
+	// constraints
+	windowWidth := config.DefaultWindowWidth
+	windowHeight := config.DefaultWindowHeight
+	minWindowWidth := config.MinWindowWidth
+	minWindowHeight := config.MinWindowHeight
+	winX, winY, winWidth, winHeight := settings.WindowPlacement(pixelgl.PrimaryMonitor().Size())
 
 	cfg := pixelgl.WindowConfig{
 		Title:  "Blockfall",
-		Bounds: pixel.R(0, 0, windowWidth, windowHeight),
+		Bounds: pixel.R(0, 0, winWidth, winHeight),
 		VSync:  true,
 		// VSync will help limit refresh rate
 		Monitor:   nil,
@@ -157,62 +281,153 @@ func run() {
 	if err != nil {
 		panic(err)
 	}
+	win.SetPos(pixel.V(winX, winY))
+	if settings.Fullscreen {
+		win.SetMonitor(pixelgl.PrimaryMonitor())
+	}
 
 	// Track initial/reference dimensions for scaling calculations
 	initialWidth := windowWidth
 	initialHeight := windowHeight
 
-	// Store initial layout positions and sizes for responsive scaling
-	const initialBoardOffsetX = 282.0
-	const initialBoardOffsetY = 25.0
-	const initialNextPieceX = 182.0
-	const initialNextPieceY = 225.0
-	const initialHoldPieceX = 182.0
-	const initialHoldPieceY = 325.0
-	const initialScoreX = 500.0
-	const initialScoreY = 400.0
-	const initialNextPieceTxtX = 142.0
-	const initialNextPieceTxtY = 285.0
-	const initialHoldPieceTxtX = 142.0
-	const initialHoldPieceTxtY = 385.0
-
 	// Track UI scale factor (will be updated based on window size)
 	uiScaleFactor := 1.0
 
-	// Load Various Resources:
-	// Matriax on opengameart.org
+	// Load Various Resources
 	pwd, err := os.Getwd()
 	if err != nil {
 		panic(err)
 	}
-	blockGen, err = ss.LoadSpriteSheet(pwd+"/resources/blocks.png", 2, 8)
-	if err != nil {
+	if err := render.Load(filepath.Join(pwd, "resources")); err != nil {
 		panic(err)
 	}
-
-	// Background image, by ansimuz on opengameart.org
-	bgPic, err := ss.LoadPicture(pwd + "/resources/parallax-mountain-bg.png")
+	render.SetAnimatedBlocks(settings.AnimatedBlock)
+	sfx := newSoundManager(settings)
+	var cues soundCues
+	music := newMusicPlayer(settings)
+	defer music.Stop()
+	// A connected gamepad rumbles on locks and clears, when it can
+	var rumble game.Haptics
+	if settings.HapticsEnabled {
+		if hc, err := haptics.OpenSDL(); err == nil {
+			defer hc.Close()
+			rumble = hc
+		} else if err != haptics.ErrNoGamepad {
+			fmt.Fprintln(os.Stderr, "the gamepad won't rumble:", err)
+		}
+	}
+	// The color themes F2 cycles through, the game can do without them
+	themes, err := render.LoadThemes(filepath.Join(pwd, "resources", "themes"))
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
 	}
-	bgImgSprite = *pixel.NewSprite(bgPic, bgPic.Bounds())
 
-	// Game Background
-	blackPic := ss.GetPlayBGPic()
-	gameBGSprite = *pixel.NewSprite(blackPic, blackPic.Bounds())
+	gs := newPlaybackGameState(mode, settings, player, seed, startBoard)
+	gs.SetHaptics(rumble)
+	var settingsMenu settingsScreen
+	var nextSeed seedField
+
+	// Input is read once per fixed step of the game
+	buttons := actionButtons(keys)
+	var in input.PlayerInput
+	handler := input.NewInputHandler(settings.DAS, settings.ARR)
+	var replayDown [input.NumActions]bool // Actions held down in the replay
+	var desync error                      // Why the replay stopped playing back as recorded, if it did
+	stepTime := 0.0                       // Time not yet simulated
+	var driver *bot.Driver                // Presses the actions botPlayer chooses, if any
+	if botPlayer != nil {
+		driver = bot.NewDriver(botPlayer)
+	}
+	atKeyboard := player == nil && botPlayer == nil && remote == nil // Whether the game is played by the player
+	// Z is kept from the game after Ctrl+Z until it is released so an undo
+	// doesn't also rotate the piece
+	undoHeld := false
+	showStats := false
+
+	// High scores are kept in the user's config directory. The game can
+	// still be played without them.
+	scoresPath, err := persist.DefaultScoresPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "high scores won't be saved:", err)
+	}
+	var highScores []persist.ScoreEntry
+	if scoresPath != "" {
+		highScores, err = persist.LoadHighScores(scoresPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "loading high scores:", err)
+		}
+	}
+	// Whether the game that just ended made the high score table
+	newHighScore := false
 
-	// Next Piece BG
-	nextPiecePic := ss.GetNextPieceBGPic()
-	nextPieceBGSprite = *pixel.NewSprite(nextPiecePic, nextPiecePic.Bounds())
+	// So are the results of the daily challenges
+	dailyPath, err := persist.DefaultDailyPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "daily challenge results won't be saved:", err)
+	}
+	dailyResults := make(map[string]persist.DailyResult)
 
-	// Hold Piece BG (using same sprite as next piece)
-	holdPieceBGSprite = *pixel.NewSprite(nextPiecePic, nextPiecePic.Bounds())
+	// And the stats of every game, for spreadsheets
+	statsPath, err := persist.DefaultStatsPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "game stats won't be saved:", err)
+	}
 
-	// Initialize the 7-bag
-	initializeBag()
+	// And the achievements unlocked, which replays and practice games can't
+	// unlock. They aren't saved when they couldn't be loaded so the file
+	// isn't overwritten.
+	achievementsPath, err := persist.DefaultAchievementsPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "achievements won't be saved:", err)
+	}
+	achievements := game.NewAchievements()
+	if achievementsPath != "" {
+		if loaded, err := persist.LoadAchievements(achievementsPath); err != nil {
+			fmt.Fprintln(os.Stderr, "loading achievements:", err)
+			achievementsPath = ""
+		} else {
+			achievements = loaded
+		}
+	}
+	if atKeyboard && mode != game.ModePractice {
+		gs.SetAchievements(achievements)
+	}
+	if dailyPath != "" {
+		if results, err := persist.LoadDailyResults(dailyPath); err != nil {
+			fmt.Fprintln(os.Stderr, "loading daily challenge results:", err)
+		} else {
+			dailyResults = results
+		}
+	}
 
-	nextPiece = getNextPiece()
-	gameBoard.addPiece() // Add initial Piece to game
+	// restart resets the game to a new one in the mode being played, dealt
+	// from the seed typed in if there is one. A replay is played back from
+	// the start and a game without a seed is dealt new pieces.
+	restart := func() {
+		if s, ok := nextSeed.seed(); ok {
+			seed = &s
+		}
+		nextSeed = seedField{}
+		switch {
+		case player != nil:
+			player.Rewind()
+		case seed != nil:
+			gs.SetSeed(*seed)
+		default:
+			gs.SetSeed(time.Now().UnixNano())
+		}
+		gs.Reset(gs.Mode())
+		in = input.PlayerInput{}
+		handler = input.NewInputHandler(settings.DAS, settings.ARR)
+		replayDown = [input.NumActions]bool{}
+		if botPlayer != nil {
+			driver = bot.NewDriver(botPlayer)
+		}
+		stepTime = 0
+		undoHeld = false
+		newHighScore = false
+		showStats = false
+	}
 
 	// Set up frame limiter for consistent timing and reduced CPU usage
 	const targetFPS = 120 // Increased FPS for smoother rendering
@@ -225,16 +440,24 @@ func run() {
 	nextPieceTxt := text.New(pixel.V(initialNextPieceTxtX, initialNextPieceTxtY), basicAtlas)
 	holdPieceTxt := text.New(pixel.V(initialHoldPieceTxtX, initialHoldPieceTxtY), basicAtlas)
 
-	// Store previous window size to detect changes
-	prevWinWidth := win.Bounds().W()
-	prevWinHeight := win.Bounds().H()
+	// Store previous window size to detect changes, starting from the size
+	// the layout is designed at so a window opened at another size is
+	// scaled
+	prevWinWidth := initialWidth
+	prevWinHeight := initialHeight
+
+	// Frame times are kept even while the debug overlay is hidden so its
+	// averages are ready as soon as it is shown
+	var frames frameTimes
 
-	for !win.Closed() && !gameOver {
+	for !win.Closed() {
 		frameStart := time.Now()
 
 		// Perform time processing events
 		dt := time.Since(last).Seconds()
 		last = time.Now()
+		frameDt := dt // Before capping, for the debug overlay
+		frames.add(frameDt)
 
 		// Don't use too small time steps
 		if dt > 0.25 {
@@ -277,295 +500,279 @@ func run() {
 			prevWinHeight = currWinHeight
 		}
 
-		// Update input buffer - clear expired inputs
-		for key, timestamp := range inputBuffer {
-			timestamp -= dt
-			if timestamp <= 0 {
-				delete(inputBuffer, key)
-			} else {
-				inputBuffer[key] = timestamp
+		if apiServer.TakeReset() {
+			// A reset asked for over HTTP starts a new game whether or not
+			// this one is over
+			restart()
+		} else if gs.GameOver() {
+			// Wait for the player to choose to restart or quit, typing the
+			// seed of the next game if they want to choose it
+			if player == nil && gs.Mode() != game.ModeDailyChallenge {
+				nextSeed.update(win)
 			}
-		}
-
-		gravityTimer += dt
-		levelUpTimer -= dt
-
-		// Update lock delay timer if piece is on ground
-		if gameBoard.isTouchingFloor() {
-			lockDelayTimer += dt
-			if lockDelayTimer >= lockDelay {
-				gameBoard.lockPiece()
-				lockDelayTimer = 0
-				lockResets = 0
+			if win.JustPressed(pixelgl.KeyR) {
+				restart()
+			} else if win.JustPressed(pixelgl.KeyQ) {
+				return
+			} else if win.JustPressed(pixelgl.KeyS) {
+				showStats = !showStats
 			}
-		} else {
-			lockDelayTimer = 0
-		}
-
-		// Time Functions:
-		// Gravity
-		if gravityTimer > gravitySpeed {
-			gravityTimer = 0 // Reset completely for more consistent timing
-			didCollide := gameBoard.applyGravity()
-			if didCollide {
-				score += 10
+		} else if settingsMenu.open {
+			// Save confirmed settings and use them straight away
+			if settingsMenu.update(win) {
+				if err := settingsMenu.values.Save(settingsPath); err != nil {
+					settingsMenu.err = err.Error()
+				} else {
+					previous := settings
+					settings = settingsMenu.values
+					gs.ApplySettings(settings)
+					handler.DAS, handler.ARR = settings.DAS, settings.ARR
+					sfx.SetVolume(settings.SFXVolume)
+					music.SetVolume(settings.MusicVolume)
+					if settings.MusicTrack != previous.MusicTrack {
+						playTrack(music, settings.MusicTrack)
+					}
+					settingsMenu.open = false
+				}
 			}
-		}
-
-		// Speed up
-		if levelUpTimer <= 0 {
-			if baseSpeed > 0.1 {
-				baseSpeed = math.Max(baseSpeed-speedUpRate, 0.1)
+		} else if win.JustPressed(pixelgl.KeyS) {
+			showStats = !showStats
+		} else if showStats {
+			// The game is held while the statistics are shown
+		} else if win.JustPressed(pixelgl.KeyTab) {
+			settingsMenu.show(settings)
+		} else if win.JustPressed(pixelgl.KeyG) {
+			// Toggle the ghost piece and remember the choice
+			settings.ShowGhost = !settings.ShowGhost
+			gs.ApplySettings(settings)
+			if err := settings.Save(settingsPath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 			}
-			levelUpTimer = levelLength
-			gravitySpeed = baseSpeed
-		}
-
-		// Input handling with prioritization and immediate response
-		leftPressed := win.Pressed(pixelgl.KeyLeft)
-		rightPressed := win.Pressed(pixelgl.KeyRight)
-
-		// Buffer all new key presses for responsive control
-		if win.JustPressed(pixelgl.KeyLeft) {
-			inputBuffer[pixelgl.KeyLeft] = InputBufferWindow
-			keyReleaseTimer = 0
-			isTapMovement = true
-
-			// Use the debounced movement system for consistent feel
-			processMoveWithBounce(win, -1)
-		}
-
-		if win.JustPressed(pixelgl.KeyRight) {
-			inputBuffer[pixelgl.KeyRight] = InputBufferWindow
-			keyReleaseTimer = 0
-			isTapMovement = true
-
-			// Use the debounced movement system for consistent feel
-			processMoveWithBounce(win, 1)
-		}
-
-		// Process key releases with improved tap detection
-		if win.JustReleased(pixelgl.KeyLeft) || win.JustReleased(pixelgl.KeyRight) {
-			lastKeyReleaseTime = 0
-
-			// Short taps get special treatment for precision movement
-			if keyReleaseTimer < ControlSensitivity {
-				isTapMovement = false
-
-				// Reset auto-repeat system to prevent unwanted movement
-				leftRightTimer = DASDelay * 1.5 // Add a small delay after taps for better control
-				ARRTimer = 0
+		} else if win.JustPressed(pixelgl.KeyB) {
+			// Toggle the board grid and remember the choice
+			settings.ShowGrid = !settings.ShowGrid
+			gs.ApplySettings(settings)
+			if err := settings.Save(settingsPath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 			}
-		}
-
-		// Update tap detection timer
-		if isTapMovement {
-			keyReleaseTimer += dt
-			if keyReleaseTimer > ControlSensitivity {
-				isTapMovement = false // No longer considered a tap after sensitivity threshold
+		} else if win.JustPressed(pixelgl.KeyF11) {
+			// Switch between fullscreen and a window and remember the
+			// choice. The window is kept, so the game carries on as it was
+			// and is rescaled to the new size like any resize.
+			settings.Fullscreen = !settings.Fullscreen
+			gs.ApplySettings(settings)
+			if settings.Fullscreen {
+				win.SetMonitor(pixelgl.PrimaryMonitor())
+			} else {
+				win.SetMonitor(nil)
 			}
-		}
-
-		// Determine movement direction with intelligent conflict resolution
-		direction := 0
-		if leftPressed && rightPressed {
-			// If both keys are pressed, use the most recently pressed one based on buffer
-			leftTime, hasLeft := inputBuffer[pixelgl.KeyLeft]
-			rightTime, hasRight := inputBuffer[pixelgl.KeyRight]
-
-			if hasLeft && hasRight {
-				if leftTime > rightTime {
-					direction = -1
-				} else {
-					direction = 1
-				}
-			} else if hasLeft {
-				direction = -1
-			} else if hasRight {
-				direction = 1
-			} else if lastMoveDirection != 0 {
-				direction = lastMoveDirection
+			if err := settings.Save(settingsPath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		} else if win.JustPressed(pixelgl.KeyK) {
+			// Toggle the patterns of colorblind mode and remember the choice
+			settings.ColorblindMode = !settings.ColorblindMode
+			gs.ApplySettings(settings)
+			if err := settings.Save(settingsPath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 			}
-		} else if leftPressed {
-			direction = -1
-		} else if rightPressed {
-			direction = 1
+		} else if win.JustPressed(pixelgl.KeyH) {
+			gs.ToggleHeightOverlay()
+		} else if win.JustPressed(pixelgl.KeyV) {
+			gs.ToggleColHeights()
+		} else if win.JustPressed(pixelgl.KeyO) {
+			gs.ToggleSurfaceAnalysis()
+		} else if win.JustPressed(pixelgl.KeyT) {
+			gs.ToggleTSDHint()
+		} else if win.JustPressed(pixelgl.KeyP) {
+			gs.TogglePieceCounts()
+		} else if win.JustPressed(pixelgl.KeyF2) {
+			gs.CycleTheme(len(themes) + 1)
+		} else if win.JustPressed(pixelgl.KeyF3) {
+			gs.ToggleDebugOverlay()
+		} else if win.JustPressed(pixelgl.KeyF4) {
+			gs.ToggleInputOverlay()
 		} else {
-			// Reset DAS/ARR when no direction keys are pressed
-			leftRightTimer = 0
-			ARRTimer = 0
-			lastMoveDirection = 0
-		}
-
-		// Handle movement with improved DAS/ARR system
-		if direction != 0 {
-			if direction != lastMoveDirection {
-				// Direction change - immediate movement for responsiveness
-				lastMoveDirection = direction
-				leftRightTimer = DASDelay
-				ARRTimer = 0
-
-				// Only move here if we didn't already move in JustPressed
-				if !win.JustPressed(pixelgl.KeyLeft) && !win.JustPressed(pixelgl.KeyRight) {
-					processMoveWithBounce(win, direction)
-				}
-			} else if !isTapMovement {
-				// Auto-shift handling for held keys
-				leftRightTimer -= dt
-				if leftRightTimer <= 0 {
-					// DAS charged, use ARR for repeated movement
-					ARRTimer += dt
-					if ARRTimer >= ARRRate {
-						// Reset ARR immediately for more consistent repeat rate
-						ARRTimer = 0
-
-						// Process movement with debouncing for smoother feel
-						processMoveWithBounce(win, direction)
-					}
-				}
+			// Pause and unpause the game
+			if win.JustPressed(pixelgl.KeyEscape) {
+				gs.TogglePause()
 			}
-		}
-
-		// Update rotation cooldown
-		if rotationCooldown > 0 {
-			rotationCooldown -= dt
-		}
-
-		// Faster, more responsive soft drop
-		if win.JustPressed(pixelgl.KeyDown) {
-			gravitySpeed = SoftDropSpeed
-			softDropFrictionTimer = 0
-			lastSoftDropTime = 0
 
-			// Immediate drop for responsiveness
-			gameBoard.applyGravity()
-		}
-
-		if win.Pressed(pixelgl.KeyDown) {
-			// More responsive soft drop system
-			if softDropFrictionTimer > 0 {
-				softDropFrictionTimer -= dt * 2 // Faster friction reduction
+			// Ctrl+Z takes back the last piece of a practice game
+			if gs.Mode() == game.ModePractice && !gs.Paused() && ctrlPressed(win) && win.JustPressed(pixelgl.KeyZ) {
+				gs.Undo()
+				undoHeld = true
+			} else if !win.Pressed(pixelgl.KeyZ) {
+				undoHeld = false
 			}
 
-			lastSoftDropTime += dt
-
-			// More aggressive friction reduction for smoother continuous drops
-			if lastSoftDropTime > 0.15 && softDropFrictionTimer > 0 {
-				softDropFrictionTimer = 0 // Just clear it completely after a short delay
+			// The game advances in fixed steps so that replays play back
+			// exactly as they were recorded
+			if gs.Paused() || desync != nil {
+				stepTime = 0
+			} else {
+				stepTime += dt
+				for stepTime >= game.StepLength && !gs.GameOver() && desync == nil {
+					stepTime -= game.StepLength
+					if player != nil {
+						for _, e := range player.NextEvents(gs.Frame()) {
+							replayDown[e.Key] = e.EventType == replay.KeyDown
+						}
+						in.Next(replayDown)
+					} else if driver != nil {
+						in.Next(driver.Next(gs))
+					} else if remote != nil {
+						in.Next(remote.Next())
+					} else {
+						down := readActions(win, buttons)
+						if undoHeld {
+							down = releaseButton(down, buttons, pixelgl.KeyZ)
+						}
+						in.Next(down)
+					}
+					gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+					if player != nil {
+						if desync = gs.CheckReplay(player); desync != nil {
+							fmt.Fprintln(os.Stderr, desync)
+						}
+					}
+				}
 			}
-
-			// Apply soft drop gravity with less friction
-			if softDropFrictionTimer <= 0 {
-				if gameBoard.applyGravity() {
-					softDropFrictionTimer = SoftDropFriction
-					lastSoftDropTime = 0
+			if remote != nil {
+				if err := remote.SendState(gs); err != nil {
+					fmt.Fprintln(os.Stderr, "sending state:", err)
 				}
 			}
-		}
-
-		if win.JustReleased(pixelgl.KeyDown) {
-			gravitySpeed = baseSpeed
-			softDropFrictionTimer = 0
-		}
-
-		// More responsive rotation with reduced cooldown
-		if win.JustPressed(pixelgl.KeyUp) {
-			if rotationCooldown <= 0 {
-				rotationSucceeded := gameBoard.rotatePiece(1) // Clockwise rotation
-				if rotationSucceeded {
-					rotationDirection = 1
-
-					// Reset lock delay if rotated and on ground
-					if gameBoard.isTouchingFloor() && lockResets < maxLockResets {
-						lockDelayTimer = 0
-						lockResets++
-					}
+			if err := apiServer.Publish(gs); err != nil {
+				fmt.Fprintln(os.Stderr, "publishing state:", err)
+			}
+			cues.play(sfx, gs)
+			// The music speeds up with the level
+			music.SetTempo(sound.LevelTempo(gs.Level()))
 
-					// Shorter rotation cooldown for more responsive feel
-					rotationCooldown = 0.03
+			if unlocks := gs.TakeUnlocks(); len(unlocks) > 0 && achievementsPath != "" {
+				if err := persist.SaveAchievements(achievementsPath, achievements); err != nil {
+					fmt.Fprintln(os.Stderr, "saving achievements:", err)
 				}
 			}
-		}
 
-		if win.JustPressed(pixelgl.KeyZ) {
-			if rotationCooldown <= 0 {
-				rotationSucceeded := gameBoard.rotatePiece(-1) // Counter-clockwise rotation
-				if rotationSucceeded {
-					rotationDirection = -1
+			// Replays that are being played back aren't saved or scored,
+			// and neither are games played by a bot or another program or
+			// practice games as undos can't be replayed
+			if gs.GameOver() && atKeyboard && gs.Mode() != game.ModePractice {
+				if err := saveReplay(gs); err != nil {
+					fmt.Fprintln(os.Stderr, "saving replay:", err)
+				}
 
-					// Reset lock delay if rotated and on ground
-					if gameBoard.isTouchingFloor() && lockResets < maxLockResets {
-						lockDelayTimer = 0
-						lockResets++
+				// Record the result as soon as the game ends. Only finished
+				// sprints have a time worth keeping.
+				if !game.IsSprint(gs.Mode()) || gs.ModeComplete() {
+					var rank int
+					highScores, rank = persist.InsertScore(highScores, scoreEntry(gs))
+					newHighScore = rank >= 0
+					if newHighScore && scoresPath != "" {
+						if err := persist.SaveHighScores(scoresPath, highScores); err != nil {
+							fmt.Fprintln(os.Stderr, "saving high scores:", err)
+						}
 					}
-
-					// Shorter rotation cooldown for more responsive feel
-					rotationCooldown = 0.03
 				}
-			}
-		}
-
-		// More responsive hard drop
-		if win.JustPressed(pixelgl.KeySpace) {
-			// Skip the visual feedback drop and go straight to hard drop for immediate response
-			preHardDropRow := activeShape[0].row
-			gameBoard.instafall()
 
-			// Scoring based on distance dropped
-			dropDistance := preHardDropRow - activeShape[0].row
-			score += 20 + dropDistance
-		}
-
-		// More responsive hold
-		if win.JustPressed(pixelgl.KeyC) && canHold {
-			gameBoard.holdPiece()
-		}
+				// Only the first daily challenge finished each day counts
+				date := dailyDate(gs.Seed())
+				if _, done := dailyResults[date]; gs.Mode() == game.ModeDailyChallenge && gs.ModeComplete() && !done {
+					dailyResults[date] = persist.DailyResult{
+						Date:           date,
+						CompletionTime: time.Duration(gs.ElapsedTime() * float64(time.Second)),
+						Score:          gs.Score(),
+					}
+					if dailyPath != "" {
+						if err := persist.SaveDailyResults(dailyPath, dailyResults); err != nil {
+							fmt.Fprintln(os.Stderr, "saving daily challenge results:", err)
+						}
+					}
+				}
 
-		// Enhanced visual feedback
-		if visualFeedbackActive {
-			lastTapTime += dt
-			if lastTapTime > 0.08 { // Shorter duration for snappier feedback
-				visualFeedbackActive = false
+				if statsPath != "" {
+					if err := persist.ExportStats(statsPath, gs); err != nil {
+						fmt.Fprintln(os.Stderr, "saving game stats:", err)
+					}
+				}
 			}
 		}
 
 		// Render at higher priority - move earlier in the frame
 		win.Clear(colornames.Black)
 
-		// Calculate center position based on current window dimensions
-		windowCenter := win.Bounds().Center()
+		// Adjust positions based on window center offset
+		xOffset := (win.Bounds().W() - initialWidth*uiScaleFactor) / 2
+		yOffset := (win.Bounds().H() - initialHeight*uiScaleFactor) / 2
 
 		// Draw backgrounds with responsive positioning
-		// Background scales to fill entire window while maintaining aspect ratio
-		bgScale := math.Max(win.Bounds().W()/bgImgSprite.Frame().W(), win.Bounds().H()/bgImgSprite.Frame().H())
-		bgImgSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, bgScale).Moved(windowCenter))
+		theme := currentTheme(gs, settings, themes)
+		render.Background(win, theme, uiScaleFactor, xOffset, yOffset)
 
-		// Game board background scales based on UI scale factor
-		gameScale := uiScaleFactor
-		gameBGPos := pixel.V(windowCenter.X, windowCenter.Y)
-		gameBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, gameScale).Moved(gameBGPos))
+		// Display text content - reuse text objects with adjusted positions
+		render.Text(win, scoreTxt, nextPieceTxt, holdPieceTxt, uiScaleFactor, gs)
 
-		// Next piece and hold piece background
-		nextPiecePos := pixel.V(initialNextPieceX*uiScaleFactor, initialNextPieceY*uiScaleFactor)
-		holdPiecePos := pixel.V(initialHoldPieceX*uiScaleFactor, initialHoldPieceY*uiScaleFactor)
+		render.HUD(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
 
-		// Adjust positions based on window center offset
-		xOffset := (win.Bounds().W() - initialWidth*uiScaleFactor) / 2
-		yOffset := (win.Bounds().H() - initialHeight*uiScaleFactor) / 2
+		// Display game elements with responsive scaling
+		render.HoldPiece(win, gs.HoldPiece(), gs.CanHold(), theme, settings.ColorblindMode, uiScaleFactor, xOffset, yOffset)
+		render.SpeedBar(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+		if gs.ShowPieceCounts() {
+			render.PieceHistogram(win, basicAtlas, gs.Stats(), uiScaleFactor, xOffset, yOffset)
+		}
+		render.RotationIndicator(win, basicAtlas, gs.RotationState(), uiScaleFactor, xOffset, yOffset)
+		render.NextPieces(win, gs.NextPieces(), theme, settings.ColorblindMode, uiScaleFactor, xOffset, yOffset)
+		render.Board(win, gs, theme)
+		render.ScorePopups(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+		if gs.ShowColHeights() {
+			render.ColumnHeights(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+		}
+
+		if gs.PerfectClearShown() {
+			boardCenter := pixel.V(382*uiScaleFactor+xOffset, 225*uiScaleFactor+yOffset)
+			banner := "PERFECT CLEAR!"
+			if streak := gs.AllClearStreak(); streak >= 2 {
+				banner = fmt.Sprintf("PERFECT CLEAR! x %d", streak)
+			}
+			render.CenteredText(win, basicAtlas, []string{banner}, 2*uiScaleFactor, boardCenter)
+		}
 
-		nextPiecePos = nextPiecePos.Add(pixel.V(xOffset, yOffset))
-		holdPiecePos = holdPiecePos.Add(pixel.V(xOffset, yOffset))
+		if name, ok := gs.AchievementToast(); ok && !gs.GameOver() {
+			render.AchievementToast(win, basicAtlas, name, uiScaleFactor)
+		}
 
-		nextPieceBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(nextPiecePos))
-		holdPieceBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(holdPiecePos))
+		if gs.GameOver() {
+			lines := gameOverLines(gs, persist.ModeScores(highScores, gs.Mode().Name()), newHighScore)
+			if _, done := dailyResults[dailyDate(gs.Seed())]; gs.Mode() == game.ModeDailyChallenge && done {
+				lines = append(lines, "", "Challenge complete for today -", "come back tomorrow!")
+			} else if player == nil && gs.Mode() != game.ModeDailyChallenge {
+				lines = append(lines, "", "Type a seed for the next game", nextSeed.line())
+			}
+			render.Overlay(win, basicAtlas, lines, uiScaleFactor, xOffset, yOffset)
+		} else if desync != nil {
+			render.Overlay(win, basicAtlas, []string{"REPLAY DESYNCED", "It no longer plays back", "as it was recorded"}, uiScaleFactor, xOffset, yOffset)
+		} else if gs.Paused() {
+			render.Overlay(win, basicAtlas, []string{"PAUSED", "Press Esc to resume"}, uiScaleFactor, xOffset, yOffset)
+		}
+		if player != nil {
+			render.ReplayIndicator(win, basicAtlas, uiScaleFactor)
+		}
 
-		// Display text content - reuse text objects with adjusted positions
-		displayText(win, scoreTxt, nextPieceTxt, holdPieceTxt, uiScaleFactor)
+		if gs.ShowInputOverlay() {
+			das, arr, direction := handler.Charge()
+			render.InputOverlay(win, basicAtlas, in.Pressed, das, arr, direction, uiScaleFactor)
+		}
+		if gs.ShowDebugOverlay() {
+			render.DebugOverlay(win, basicAtlas, debugLines(frameDt, &frames), uiScaleFactor)
+		}
 
-		// Display game elements with responsive scaling
-		displayHoldPiece(win, uiScaleFactor, xOffset, yOffset)
-		displayNextPiece(win, uiScaleFactor, xOffset, yOffset)
-		gameBoard.displayBoard(win)
+		if settingsMenu.open {
+			settingsMenu.display(win, basicAtlas, uiScaleFactor)
+		} else if showStats {
+			render.Stats(win, basicAtlas, gs.Stats(), gs.ScoreHistory(), gs.PlacementHistory(), uiScaleFactor)
+		}
 
 		win.Update()
 
@@ -579,210 +786,107 @@ func run() {
 			}
 		}
 	}
-}
-
-func displayText(win *pixelgl.Window, scoreTxt, nextPieceTxt, holdPieceTxt *text.Text, uiScaleFactor float64) {
-	// Update and draw score
-	scoreTxt.Clear()
-	fmt.Fprintf(scoreTxt, "Score: %d", score)
-	scoreTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 2*uiScaleFactor))
-
-	// Draw static text for next and hold pieces
-	nextPieceTxt.Clear()
-	fmt.Fprintf(nextPieceTxt, "Next Piece:")
-	nextPieceTxt.Draw(win, pixel.IM.Scaled(nextPieceTxt.Orig, uiScaleFactor))
-
-	holdPieceTxt.Clear()
-	fmt.Fprintf(holdPieceTxt, "Hold Piece:")
-	holdPieceTxt.Draw(win, pixel.IM.Scaled(holdPieceTxt.Orig, uiScaleFactor))
-}
-
-// Separate next piece display to its own function
-func displayNextPiece(win *pixelgl.Window, uiScaleFactor float64, xOffset, yOffset float64) {
-	baseShape := getShapeFromPiece(nextPiece)
-	pic := blockGen(block2spriteIdx(piece2Block(nextPiece)))
-	sprite := pixel.NewSprite(pic, pic.Bounds())
-	boardBlockSize := 20.0 * uiScaleFactor
-	scaleFactor := float64(boardBlockSize) / pic.Bounds().Max.Y
-	shapeWidth := getShapeWidth(baseShape) + 1
-	shapeHeight := 2
 
-	initialNextPieceX := 182.0
-	initialNextPieceY := 225.0
-
-	for i := 0; i < 4; i++ {
-		r := baseShape[i].row
-		c := baseShape[i].col
-		x := float64(c)*boardBlockSize + boardBlockSize/2
-		y := float64(r)*boardBlockSize + boardBlockSize/2
-
-		// Position calculation with scaling and offset
-		posX := x + initialNextPieceX*uiScaleFactor - (float64(shapeWidth) * 10 * uiScaleFactor) + xOffset
-		posY := y + initialNextPieceY*uiScaleFactor - (float64(shapeHeight) * 10 * uiScaleFactor) + yOffset
-
-		sprite.Draw(win, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(pixel.V(posX, posY)))
+	// Remember where the window was for the next launch. A fullscreen
+	// window keeps the windowed placement it will go back to.
+	if win.Monitor() == nil {
+		pos := win.GetPos()
+		settings.WindowX, settings.WindowY = pos.X, pos.Y
+		settings.WindowWidth = math.Max(win.Bounds().W(), minWindowWidth)
+		settings.WindowHeight = math.Max(win.Bounds().H(), minWindowHeight)
+		if err := settings.Save(settingsPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 	}
 }
 
-func displayHoldPiece(win *pixelgl.Window, uiScaleFactor float64, xOffset, yOffset float64) {
-	if holdPiece == NoPiece {
-		return
+// gameOverLines returns the text shown on the game over screen. A finished
+// sprint shows the completion time and other games the score, along with
+// the best of the mode's high scores, best first. newHighScore adds a
+// banner for a game that made the table.
+func gameOverLines(gs *game.GameState, modeScores []persist.ScoreEntry, newHighScore bool) []string {
+	var lines []string
+	if newHighScore {
+		lines = append(lines, "NEW HIGH SCORE!", "")
 	}
-
-	// Display hold piece
-	baseShape := getShapeFromPiece(holdPiece)
-	pic := blockGen(block2spriteIdx(piece2Block(holdPiece)))
-	sprite := pixel.NewSprite(pic, pic.Bounds())
-	boardBlockSize := 20.0 * uiScaleFactor
-	scaleFactor := float64(boardBlockSize) / pic.Bounds().Max.Y
-	shapeWidth := getShapeWidth(baseShape) + 1
-	shapeHeight := 2
-
-	initialHoldPieceX := 182.0
-	initialHoldPieceY := 325.0
-
-	// Draw the hold piece background with scaling
-	holdPiecePos := pixel.V(initialHoldPieceX*uiScaleFactor+xOffset, initialHoldPieceY*uiScaleFactor+yOffset)
-	holdPieceBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(holdPiecePos))
-
-	for i := 0; i < 4; i++ {
-		r := baseShape[i].row
-		c := baseShape[i].col
-		x := float64(c)*boardBlockSize + boardBlockSize/2
-		y := float64(r)*boardBlockSize + boardBlockSize/2
-
-		// Position calculation with scaling and offset
-		posX := x + initialHoldPieceX*uiScaleFactor - (float64(shapeWidth) * 10 * uiScaleFactor) + xOffset
-		posY := y + initialHoldPieceY*uiScaleFactor - (float64(shapeHeight) * 10 * uiScaleFactor) + yOffset
-
-		sprite.Draw(win, pixel.IM.Scaled(pixel.ZV, scaleFactor).Moved(pixel.V(posX, posY)))
+	if game.IsSprint(gs.Mode()) && gs.ModeComplete() {
+		lines = append(lines, "SPRINT COMPLETE", "Time: "+render.FormatTime(gs.ElapsedTime()))
+		if len(modeScores) > 0 {
+			lines = append(lines, "Best: "+render.FormatTime(modeScores[0].Time))
+		}
+	} else {
+		if gs.Mode() == game.ModeUltra && gs.ModeComplete() {
+			lines = append(lines, "TIME UP")
+		} else {
+			lines = append(lines, "GAME OVER")
+		}
+		lines = append(lines, fmt.Sprintf("Score: %d", gs.Score()))
+		if !game.IsSprint(gs.Mode()) && len(modeScores) > 0 {
+			lines = append(lines, fmt.Sprintf("Best: %d", modeScores[0].Score))
+		}
 	}
+	lines = append(lines, fmt.Sprintf("Seed: %d", gs.Seed()))
+	return append(lines, "", "Press R to restart", "or Q to quit", "S for statistics")
 }
 
-// block2spriteIdx associates a blocks color (b Block) with its index in the sprite sheet.
-func block2spriteIdx(b Block) int {
-	return int(b) - 1
-}
-
-// piece2Block associates a pieces shape (Piece) with it's color/image (Block).
-func piece2Block(p Piece) Block {
-	switch p {
-	case LPiece:
-		return Goluboy
-	case IPiece:
-		return Siniy
-	case OPiece:
-		return Pink
-	case TPiece:
-		return Purple
-	case SPiece:
-		return Red
-	case ZPiece:
-		return Yellow
-	case JPiece:
-		return Green
-	}
-	panic("piece2Block: Invalid piece passed in")
-	return GraySpecial // Return strange value value
-}
-
-// initializeBag creates a new shuffled bag of all 7 pieces
-func initializeBag() {
-	// Always create a new slice to avoid issues with empty slices
-	pieceBag = make([]Piece, 7)
-
-	// Fill the bag with one of each piece
-	for i := 0; i < 7; i++ {
-		pieceBag[i] = Piece(i)
-	}
-
-	// Shuffle the bag using Fisher-Yates algorithm
-	for i := 6; i > 0; i-- {
-		j := rand.Intn(i + 1)
-		pieceBag[i], pieceBag[j] = pieceBag[j], pieceBag[i]
+// newPlaybackGameState creates a new game like NewGameState. When player is
+// not nil the game is started with the replay's seed and the replay is
+// rewound so it can be played back. Otherwise the game is dealt from seed
+// when it is not nil. The game starts on startBoard when it is not nil,
+// which must fit the board size in settings.
+func newPlaybackGameState(mode game.GameMode, settings config.Settings, player *replay.ReplayPlayer, seed *int64, startBoard game.Board) *game.GameState {
+	var gs *game.GameState
+	if player != nil {
+		player.Rewind()
+		gs = game.NewSeededGameState(mode, settings, player.Seed())
+	} else if seed != nil {
+		gs = game.NewSeededGameState(mode, settings, *seed)
+	} else {
+		gs = game.NewGameState(mode, settings)
 	}
-}
-
-// getNextPiece returns the next piece from the 7-bag
-func getNextPiece() Piece {
-	// If bag is empty or nil, create a new one
-	if pieceBag == nil || len(pieceBag) == 0 {
-		initializeBag()
-		// Double check that bag was properly initialized
-		if len(pieceBag) == 0 {
-			// Emergency fallback - use a random piece if bag is still empty
-			return Piece(rand.Intn(7))
+	if startBoard != nil {
+		if err := gs.SetStartBoard(startBoard); err != nil {
+			panic(err)
 		}
 	}
-
-	// Take the first piece from the bag
-	nextPiece := pieceBag[0]
-
-	// Remove the first piece from the bag
-	if len(pieceBag) > 1 {
-		pieceBag = pieceBag[1:]
-	} else {
-		// If this was the last piece, immediately refill the bag
-		initializeBag()
-	}
-
-	return nextPiece
+	return gs
 }
 
-// Check if a T-spin was performed for scoring
-func isTSpin(board Board) bool {
-	// Only check for T-spins with T pieces
-	if currentPiece != TPiece || !lastMovementWasRotation {
-		return false
+// saveReplay writes the seed and inputs of the game to the replays
+// directory, named after the time it ended
+func saveReplay(gs *game.GameState) error {
+	if err := os.MkdirAll("replays", 0755); err != nil {
+		return err
 	}
+	path := filepath.Join("replays", time.Now().Format("20060102_150405")+".rep")
+	return replay.SaveReplay(path, gs.Seed(), gs.ReplayEvents(), gs.FrameHashes())
+}
 
-	// For a T-spin, at least 3 of the 4 corners around the T's center must be blocked
-	centerRow := activeShape[1].row
-	centerCol := activeShape[1].col
-
-	// Check each of the 4 corners around the T's center
-	corners := [][2]int{
-		{centerRow + 1, centerCol + 1}, // top-right
-		{centerRow + 1, centerCol - 1}, // top-left
-		{centerRow - 1, centerCol + 1}, // bottom-right
-		{centerRow - 1, centerCol - 1}, // bottom-left
+// scoreEntry returns the result of the game for the high score table
+func scoreEntry(gs *game.GameState) persist.ScoreEntry {
+	e := persist.ScoreEntry{
+		Score:        gs.Score(),
+		Mode:         gs.Mode().Name(),
+		Date:         time.Now(),
+		LinesCleared: gs.LinesCleared(),
 	}
-
-	blockedCorners := 0
-	for _, corner := range corners {
-		r, c := corner[0], corner[1]
-		// Check if corner is blocked (either by wall or another block)
-		if r < 0 || r >= BoardRows || c < 0 || c >= BoardCols || board[r][c] != Empty {
-			blockedCorners++
-		}
+	if game.IsSprint(gs.Mode()) {
+		e.Time = gs.ElapsedTime()
 	}
-
-	// Require at least 3 corners to be blocked for a T-spin
-	return blockedCorners >= 3
-}
-
-// isInputBuffered checks if a specific input is in the buffer and active
-func isInputBuffered(key pixelgl.Button) bool {
-	val, exists := inputBuffer[key]
-	return exists && val > 0
+	return e
 }
 
-// processMoveWithBounce processes directional movement with debouncing to prevent input stuttering
-func processMoveWithBounce(win *pixelgl.Window, direction int) bool {
-	// Always move at least once for snappy feel
-	moveSucceeded := gameBoard.movePiece(direction)
-
-	if moveSucceeded {
-		lastTapTime = 0
-		visualFeedbackActive = true
-
-		// Reset lock delay if moved and on ground
-		if gameBoard.isTouchingFloor() && lockResets < maxLockResets {
-			lockDelayTimer = 0
-			lockResets++
-		}
-		return true
+// currentTheme returns the theme the game is drawn in: the theme of the
+// settings at first, then each of themes in turn
+func currentTheme(gs *game.GameState, settings config.Settings, themes []*render.FileTheme) render.Theme {
+	if i := gs.CurrentTheme(); i > 0 && i <= len(themes) {
+		return themes[i-1]
 	}
+	return render.ThemeFor(settings)
+}
 
-	return false
+// dailyDate returns the date, as yyyy-mm-dd, of the daily challenge dealt
+// from seed, see game.DailySeed
+func dailyDate(seed int64) string {
+	return fmt.Sprintf("%04d-%02d-%02d", seed/10000, seed/100%100, seed%100)
 }
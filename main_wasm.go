@@ -0,0 +1,153 @@
+//go:build wasm
+// +build wasm
+
+package main
+
+import (
+	"math"
+	"syscall/js"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/render"
+)
+
+// canvasID is the id of the <canvas> element the game is drawn on
+const canvasID = "tetris"
+
+// maxFrameTime is the most time a frame simulates, so a game left in a
+// hidden tab doesn't race to catch up when it is shown again
+const maxFrameTime = 0.25
+
+// browserKeys are the KeyboardEvent codes of the keys bound to each action,
+// the same keys as the default resources/controls.json
+var browserKeys = [input.NumActions]string{
+	input.ActionMoveLeft:  "ArrowLeft",
+	input.ActionMoveRight: "ArrowRight",
+	input.ActionRotateCW:  "ArrowUp",
+	input.ActionRotateCCW: "KeyZ",
+	input.ActionRotate180: "KeyA",
+	input.ActionSoftDrop:  "ArrowDown",
+	input.ActionHardDrop:  "Space",
+	input.ActionHold:      "KeyC",
+}
+
+// keyboard keeps which keys are held down on the page, by KeyboardEvent
+// code, from the event listeners added by listen. The browser calls the
+// listeners between frames, so no locking is needed.
+type keyboard struct {
+	down    map[string]bool
+	pressed map[string]bool // Pressed since the last frame, without repeats
+}
+
+// listen adds the key event listeners to the page. The keys of the game
+// don't scroll the page.
+func (k *keyboard) listen() {
+	k.down = make(map[string]bool)
+	k.pressed = make(map[string]bool)
+	gameKeys := map[string]bool{"Escape": true, "KeyR": true}
+	for _, code := range browserKeys {
+		gameKeys[code] = true
+	}
+	document := js.Global().Get("document")
+	document.Call("addEventListener", "keydown", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		e := args[0]
+		code := e.Get("code").String()
+		if gameKeys[code] {
+			e.Call("preventDefault")
+		}
+		if !e.Get("repeat").Bool() {
+			k.pressed[code] = true
+		}
+		k.down[code] = true
+		return nil
+	}))
+	document.Call("addEventListener", "keyup", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		delete(k.down, args[0].Get("code").String())
+		return nil
+	}))
+}
+
+// actions returns which actions are held down
+func (k *keyboard) actions() [input.NumActions]bool {
+	var down [input.NumActions]bool
+	for a, code := range browserKeys {
+		down[a] = k.down[code]
+	}
+	return down
+}
+
+// justPressed reports whether the key with code was pressed since the last
+// call to endFrame
+func (k *keyboard) justPressed(code string) bool { return k.pressed[code] }
+
+// endFrame forgets the keys pressed during the frame
+func (k *keyboard) endFrame() {
+	for code := range k.pressed {
+		delete(k.pressed, code)
+	}
+}
+
+// main runs a marathon game in the browser on the page's canvas, with the
+// default settings as the browser can't read settings.json
+func main() {
+	canvas, err := render.NewCanvas(canvasID)
+	if err != nil {
+		js.Global().Get("console").Call("error", err.Error())
+		return
+	}
+	settings := config.DefaultSettings()
+	theme := render.ThemeFor(settings)
+
+	var keys keyboard
+	keys.listen()
+	gs := game.NewGameState(game.ModeMarathon, settings)
+	var in input.PlayerInput
+	handler := input.NewInputHandler(settings.DAS, settings.ARR)
+	stepTime := 0.0 // Time not yet simulated
+	last := math.NaN()
+
+	var frame js.Func
+	frame = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		now := args[0].Float() / 1000
+		dt := 0.0
+		if !math.IsNaN(last) {
+			dt = math.Min(now-last, maxFrameTime)
+		}
+		last = now
+
+		switch {
+		case gs.GameOver():
+			if keys.justPressed("KeyR") {
+				gs = game.NewGameState(game.ModeMarathon, settings)
+				in = input.PlayerInput{}
+				handler = input.NewInputHandler(settings.DAS, settings.ARR)
+				stepTime = 0
+			}
+		case keys.justPressed("Escape"):
+			gs.TogglePause()
+		}
+
+		// The game advances in the same fixed steps as on the desktop
+		if gs.Paused() {
+			stepTime = 0
+		} else {
+			stepTime += dt
+			for stepTime >= game.StepLength && !gs.GameOver() {
+				stepTime -= game.StepLength
+				in.Next(keys.actions())
+				gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+			}
+		}
+		keys.endFrame()
+
+		render.DrawGame(canvas, gs, theme, settings.ShowGhost)
+		js.Global().Call("requestAnimationFrame", frame)
+		return nil
+	})
+	js.Global().Call("requestAnimationFrame", frame)
+
+	// The game runs in the browser's animation frames from here on
+	select {}
+}
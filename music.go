@@ -0,0 +1,52 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/sound"
+)
+
+// musicFiles holds the music tracks, built into the game like the sound
+// effects
+//
+//go:embed resources/music
+var musicFiles embed.FS
+
+// musicDir is the root of musicFiles, holding the tracks
+var musicDir = func() fs.FS {
+	dir, err := fs.Sub(musicFiles, "resources/music")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}()
+
+// musicTracks are the names of the tracks that can be chosen on the
+// settings screen
+var musicTracks = sound.Tracks(musicDir)
+
+// newMusicPlayer starts the music track in settings at its volume. The game
+// plays on without music when the track can't be played.
+func newMusicPlayer(settings config.Settings) *sound.MusicPlayer {
+	music := sound.NewMusicPlayer(musicDir, settings.MusicVolume)
+	playTrack(music, settings.MusicTrack)
+	return music
+}
+
+// playTrack loops track on music, or stops the music when track is empty
+func playTrack(music *sound.MusicPlayer, track string) {
+	if track == "" {
+		music.Stop()
+		return
+	}
+	if err := music.Play(track); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
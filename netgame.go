@@ -0,0 +1,175 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/controls"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/netplay"
+	"github.com/zkry/golang-tetris/render"
+)
+
+// runNetwork plays a two player game against the opponent client is paired
+// with. The game runs here and only garbage and the end of the game are
+// sent over the network.
+func runNetwork(keys controls.Keys, settings config.Settings, client *netplay.Client) {
+	defer client.Close()
+
+	layoutWidth := config.DefaultWindowWidth
+	layoutHeight := config.DefaultWindowHeight
+	cfg := pixelgl.WindowConfig{
+		Title:     "Blockfall",
+		Bounds:    pixel.R(0, 0, layoutWidth, layoutHeight),
+		VSync:     true,
+		Resizable: true,
+	}
+	win, err := pixelgl.NewWindow(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	if err := render.Load(filepath.Join(pwd, "resources")); err != nil {
+		panic(err)
+	}
+	render.SetAnimatedBlocks(settings.AnimatedBlock)
+	theme := render.ThemeFor(settings)
+	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
+
+	// The window stays responsive while waiting for an opponent
+	seeds := make(chan int64, 1)
+	errs := make(chan error, 1)
+	go func() {
+		seed, err := client.Start()
+		if err != nil {
+			errs <- err
+			return
+		}
+		seeds <- seed
+	}()
+
+	var gs *game.GameState
+	buttons := actionButtons(keys)
+	var in input.PlayerInput
+	handler := input.NewInputHandler(settings.DAS, settings.ARR)
+	var netErr error      // Why the connection was lost, if it was
+	sentGameOver := false // Whether the opponent has been told the game was lost
+
+	const targetFPS = 120
+	frameDuration := time.Second / targetFPS
+	last := time.Now()
+	stepTime := 0.0 // Time not yet simulated
+
+	for !win.Closed() {
+		frameStart := time.Now()
+		dt := math.Min(time.Since(last).Seconds(), 0.25)
+		last = time.Now()
+
+		if gs == nil {
+			select {
+			case seed := <-seeds:
+				gs = game.NewSeededGameState(game.ModeTwoPlayer, settings, seed)
+			case netErr = <-errs:
+			default:
+			}
+		}
+
+		// The game stops when either player tops out or the opponent can't
+		// be reached
+		over := gs == nil || gs.GameOver() || client.OpponentLost() || netErr != nil
+		if over && win.JustPressed(pixelgl.KeyQ) {
+			return
+		}
+		if !over {
+			// The game can't be paused as the opponent's game goes on
+			stepTime += dt
+			for stepTime >= game.StepLength && !gs.GameOver() {
+				stepTime -= game.StepLength
+				in.Next(readActions(win, buttons))
+				gs.Update(handler.Update(game.StepLength, in.Pressed, in.JustPressed, in.JustReleased), game.StepLength)
+			}
+
+			if lines := gs.TakeGarbageSent(); lines > 0 {
+				if err := client.SendGarbage(lines); err != nil {
+					netErr = err
+				}
+			}
+			if lines := client.TakeGarbage(); lines > 0 {
+				gs.AddGarbage(lines)
+			}
+			if err := client.Err(); err != nil && netErr == nil {
+				netErr = err
+			}
+		}
+		if gs != nil && gs.GameOver() && !sentGameOver {
+			sentGameOver = true
+			if err := client.SendGameOver(); err != nil {
+				fmt.Fprintln(os.Stderr, "sending game over:", err)
+			}
+		}
+
+		win.Clear(colornames.Black)
+		uiScaleFactor := math.Min(win.Bounds().W()/layoutWidth, win.Bounds().H()/layoutHeight)
+		xOffset := (win.Bounds().W() - layoutWidth*uiScaleFactor) / 2
+		yOffset := (win.Bounds().H() - layoutHeight*uiScaleFactor) / 2
+		render.Background(win, theme, uiScaleFactor, xOffset, yOffset)
+		center := win.Bounds().Center()
+		if gs == nil {
+			lines := []string{"Waiting for an opponent..."}
+			if netErr != nil {
+				lines = []string{"Couldn't join a game:", netErr.Error(), "", "Press Q to quit"}
+			}
+			render.CenteredText(win, basicAtlas, lines, 2*uiScaleFactor, center)
+		} else {
+			at := func(x, y float64) pixel.Vec {
+				return pixel.V(x*uiScaleFactor+xOffset, y*uiScaleFactor+yOffset)
+			}
+			scoreTxt := text.New(at(initialScoreX, initialScoreY), basicAtlas)
+			nextPieceTxt := text.New(at(initialNextPieceTxtX, initialNextPieceTxtY), basicAtlas)
+			holdPieceTxt := text.New(at(initialHoldPieceTxtX, initialHoldPieceTxtY), basicAtlas)
+			render.Text(win, scoreTxt, nextPieceTxt, holdPieceTxt, uiScaleFactor, gs)
+			render.HUD(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+			render.HoldPiece(win, gs.HoldPiece(), gs.CanHold(), theme, settings.ColorblindMode, uiScaleFactor, xOffset, yOffset)
+			render.NextPieces(win, gs.NextPieces(), theme, settings.ColorblindMode, uiScaleFactor, xOffset, yOffset)
+			render.BoardAt(win, gs, theme, uiScaleFactor, xOffset, yOffset)
+			render.ScorePopups(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+
+			var result string
+			switch {
+			case gs.GameOver():
+				result = "You Lose!"
+			case client.OpponentLost():
+				result = "You Win!"
+			case netErr != nil:
+				result = "Connection lost"
+			}
+			if result != "" {
+				render.Overlay(win, basicAtlas, []string{result, "", "Press Q to quit"}, uiScaleFactor, xOffset, yOffset)
+			}
+		}
+
+		win.Update()
+
+		if elapsed := time.Since(frameStart); elapsed < frameDuration {
+			time.Sleep(frameDuration - elapsed)
+		}
+	}
+}
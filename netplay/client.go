@@ -0,0 +1,126 @@
+package netplay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// maxGarbageMessage is the most garbage lines one message can carry
+const maxGarbageMessage = 255
+
+// Client is a player's connection to a server
+type Client struct {
+	conn io.ReadWriteCloser
+	r    *bufio.Reader
+
+	mu           sync.Mutex
+	garbage      int   // Garbage lines received and not yet taken
+	opponentLost bool  // Whether the opponent has topped out
+	err          error // Why the connection was lost, nil while it is up
+}
+
+// Dial connects to the server at the TCP address addr, such as
+// "localhost:7777"
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient returns a client that talks to a server over conn
+func NewClient(conn io.ReadWriteCloser) *Client {
+	return &Client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// Start tells the server the player is ready and waits for the opponent,
+// returning the seed both games are dealt from. Messages from the opponent
+// are then received in the background.
+func (c *Client) Start() (seed int64, err error) {
+	if err := WriteMessage(c.conn, Message{Type: MsgPlayerReady}); err != nil {
+		return 0, err
+	}
+	m, err := ReadMessage(c.r)
+	if err != nil {
+		return 0, err
+	}
+	if m.Type != MsgGameStart {
+		return 0, fmt.Errorf("server sent message %d instead of starting the game", m.Type)
+	}
+	go c.receive()
+	return m.Seed, nil
+}
+
+// receive queues up the garbage the opponent sends until they top out or
+// the connection is lost
+func (c *Client) receive() {
+	for {
+		m, err := ReadMessage(c.r)
+		c.mu.Lock()
+		switch {
+		case err != nil:
+			c.err = err
+		case m.Type == MsgGarbage:
+			c.garbage += int(m.Lines)
+		case m.Type == MsgGameOver:
+			c.opponentLost = true
+		}
+		stop := err != nil || c.opponentLost
+		c.mu.Unlock()
+		if stop {
+			return
+		}
+	}
+}
+
+// SendGarbage sends lines of garbage to the opponent
+func (c *Client) SendGarbage(lines int) error {
+	for lines > 0 {
+		n := lines
+		if n > maxGarbageMessage {
+			n = maxGarbageMessage
+		}
+		if err := WriteMessage(c.conn, Message{Type: MsgGarbage, Lines: uint8(n)}); err != nil {
+			return err
+		}
+		lines -= n
+	}
+	return nil
+}
+
+// SendGameOver tells the opponent the player has topped out
+func (c *Client) SendGameOver() error {
+	return WriteMessage(c.conn, Message{Type: MsgGameOver})
+}
+
+// TakeGarbage returns the garbage lines the opponent has sent since it was
+// last called
+func (c *Client) TakeGarbage() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.garbage
+	c.garbage = 0
+	return n
+}
+
+// OpponentLost reports whether the opponent has topped out
+func (c *Client) OpponentLost() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opponentLost
+}
+
+// Err returns why the connection to the server was lost, nil while it is up
+// or after the opponent has topped out
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close disconnects from the server
+func (c *Client) Close() error { return c.conn.Close() }
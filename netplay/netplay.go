@@ -0,0 +1,79 @@
+// Package netplay plays two player games over TCP. A Server pairs up the
+// players that connect to it and deals them the same pieces. Each game runs
+// on its player's machine and only the garbage sent and the end of the game
+// go over the network.
+//
+// Every message is a type byte followed by its payload, little endian:
+//
+//	MsgPlayerReady  no payload, the client is ready to play
+//	MsgGameStart    seed int64, the game starts dealt from seed
+//	MsgGarbage      lines uint8, lines of garbage sent to the opponent
+//	MsgGameOver     no payload, the sender topped out
+package netplay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MsgType is the kind of a message, its first byte
+type MsgType uint8
+
+// Message types
+const (
+	MsgPlayerReady MsgType = iota + 1
+	MsgGameStart
+	MsgGarbage
+	MsgGameOver
+)
+
+// Message is a message of any type. Only the payload of its type is sent.
+type Message struct {
+	Type  MsgType
+	Seed  int64 // Payload of MsgGameStart
+	Lines uint8 // Payload of MsgGarbage
+}
+
+// WriteMessage writes m to w
+func WriteMessage(w io.Writer, m Message) error {
+	buf := []byte{byte(m.Type)}
+	switch m.Type {
+	case MsgGameStart:
+		buf = append(buf, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(buf[1:], uint64(m.Seed))
+	case MsgGarbage:
+		buf = append(buf, m.Lines)
+	case MsgPlayerReady, MsgGameOver:
+	default:
+		return fmt.Errorf("unknown message type %d", m.Type)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadMessage reads the next message from r
+func ReadMessage(r *bufio.Reader) (Message, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return Message{}, err
+	}
+	m := Message{Type: MsgType(t)}
+	switch m.Type {
+	case MsgGameStart:
+		var seed [8]byte
+		if _, err := io.ReadFull(r, seed[:]); err != nil {
+			return Message{}, err
+		}
+		m.Seed = int64(binary.LittleEndian.Uint64(seed[:]))
+	case MsgGarbage:
+		if m.Lines, err = r.ReadByte(); err != nil {
+			return Message{}, err
+		}
+	case MsgPlayerReady, MsgGameOver:
+	default:
+		return Message{}, fmt.Errorf("unknown message type %d", t)
+	}
+	return m, nil
+}
@@ -0,0 +1,201 @@
+package netplay
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		m    Message
+		want []byte
+	}{
+		{Message{Type: MsgPlayerReady}, []byte{1}},
+		{Message{Type: MsgGameStart, Seed: 0x0102030405060708}, []byte{2, 8, 7, 6, 5, 4, 3, 2, 1}},
+		{Message{Type: MsgGameStart, Seed: -1}, []byte{2, 255, 255, 255, 255, 255, 255, 255, 255}},
+		{Message{Type: MsgGarbage, Lines: 4}, []byte{3, 4}},
+		{Message{Type: MsgGameOver}, []byte{4}},
+	}
+	var buf bytes.Buffer
+	for _, tt := range tests {
+		buf.Reset()
+		if err := WriteMessage(&buf, tt.m); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), tt.want) {
+			t.Errorf("WriteMessage(%+v) wrote %v, want %v", tt.m, buf.Bytes(), tt.want)
+		}
+		got, err := ReadMessage(bufio.NewReader(&buf))
+		if err != nil || got != tt.m {
+			t.Errorf("ReadMessage() = %+v, %v, want %+v", got, err, tt.m)
+		}
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	if err := WriteMessage(&bytes.Buffer{}, Message{Type: 9}); err == nil {
+		t.Error("WriteMessage() wrote a message of unknown type 9")
+	}
+	for _, data := range [][]byte{{}, {9}, {2, 1, 2, 3}, {3}} {
+		if m, err := ReadMessage(bufio.NewReader(bytes.NewReader(data))); err == nil {
+			t.Errorf("ReadMessage(%v) = %+v, want an error", data, m)
+		}
+	}
+}
+
+// waitFor fails the test if cond isn't true within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// pipeMatch starts a match played with seed between two clients, each
+// talking to the server over a net.Pipe. The match's result is sent on the
+// channel returned when it ends.
+func pipeMatch(t *testing.T, seed int64) (a, b *Client, result <-chan error) {
+	t.Helper()
+	aClient, aServer := net.Pipe()
+	bClient, bServer := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- PlayMatch(aServer, bServer, seed) }()
+	t.Cleanup(func() {
+		for _, c := range []net.Conn{aClient, aServer, bClient, bServer} {
+			c.Close()
+		}
+	})
+
+	a, b = NewClient(aClient), NewClient(bClient)
+	seeds := make(chan int64, 2)
+	errs := make(chan error, 2)
+	for _, c := range []*Client{a, b} {
+		go func(c *Client) {
+			seed, err := c.Start()
+			seeds <- seed
+			errs <- err
+		}(c)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		if got := <-seeds; got != seed {
+			t.Errorf("client started with seed %d, want %d", got, seed)
+		}
+	}
+	return a, b, done
+}
+
+func TestPlayMatch(t *testing.T) {
+	a, b, result := pipeMatch(t, 42)
+
+	// Garbage goes each way, more than a message holds split over several
+	if err := a.SendGarbage(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SendGarbage(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SendGarbage(300); err != nil {
+		t.Fatal(err)
+	}
+	var toA, toB int
+	waitFor(t, "the garbage", func() bool {
+		toA += a.TakeGarbage()
+		toB += b.TakeGarbage()
+		return toA == 2 && toB == 304
+	})
+
+	// A topping out ends the match for both
+	if err := b.SendGameOver(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "player 2 to lose", a.OpponentLost)
+	if b.OpponentLost() {
+		t.Error("player 2 sees player 1 as having lost")
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("PlayMatch() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PlayMatch() didn't return after the game was over")
+	}
+	if err := a.Err(); err != nil {
+		t.Errorf("player 1 connection error %v after winning", err)
+	}
+}
+
+func TestPlayMatchNotReady(t *testing.T) {
+	aClient, aServer := net.Pipe()
+	bClient, bServer := net.Pipe()
+	defer aClient.Close()
+	defer bClient.Close()
+	done := make(chan error, 1)
+	go func() { done <- PlayMatch(aServer, bServer, 1) }()
+
+	if err := WriteMessage(aClient, Message{Type: MsgGarbage, Lines: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err == nil {
+		t.Error("PlayMatch() started a game with a player who wasn't ready")
+	}
+}
+
+func TestPlayMatchDisconnect(t *testing.T) {
+	a, b, result := pipeMatch(t, 7)
+	b.Close()
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Error("PlayMatch() = nil after a player disconnected, want an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PlayMatch() didn't return after a player disconnected")
+	}
+	a.Close()
+	waitFor(t, "player 1 to see the connection drop", func() bool { return a.Err() != nil })
+}
+
+func TestServerOverTCP(t *testing.T) {
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	go s.Serve()
+
+	var clients [2]*Client
+	for i := range clients {
+		if clients[i], err = Dial(s.Addr().String()); err != nil {
+			t.Fatal(err)
+		}
+		defer clients[i].Close()
+	}
+	seeds := make(chan int64, 2)
+	for _, c := range clients {
+		go func(c *Client) {
+			seed, err := c.Start()
+			if err != nil {
+				t.Error(err)
+			}
+			seeds <- seed
+		}(c)
+	}
+	if a, b := <-seeds, <-seeds; a != b {
+		t.Errorf("players started with seeds %d and %d, want the same", a, b)
+	}
+	if err := clients[0].SendGarbage(3); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the garbage", func() bool { return clients[1].TakeGarbage() == 3 })
+}
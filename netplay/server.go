@@ -0,0 +1,97 @@
+package netplay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Server pairs up the clients that connect to it into games
+type Server struct {
+	listener net.Listener
+}
+
+// Listen starts a server listening for clients on the TCP address addr,
+// such as ":7777"
+func Listen(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{listener: l}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr { return s.listener.Addr() }
+
+// Close stops the server listening. Games in progress carry on.
+func (s *Server) Close() error { return s.listener.Close() }
+
+// Serve accepts clients two at a time and plays each pair's game in the
+// background with a seed from the clock, until the server is closed
+func (s *Server) Serve() error {
+	for {
+		a, err := s.listener.Accept()
+		if err != nil {
+			return err
+		}
+		b, err := s.listener.Accept()
+		if err != nil {
+			a.Close()
+			return err
+		}
+		go func() {
+			defer a.Close()
+			defer b.Close()
+			PlayMatch(a, b, time.Now().UnixNano())
+		}()
+	}
+}
+
+// PlayMatch runs the game between the clients at the other ends of a and b.
+// Once both are ready it starts their games dealt from seed, then passes the
+// garbage each sends on to the other until one of them tops out, which is
+// passed on too, or disconnects.
+func PlayMatch(a, b io.ReadWriter, seed int64) error {
+	players := [2]io.ReadWriter{a, b}
+	readers := [2]*bufio.Reader{bufio.NewReader(a), bufio.NewReader(b)}
+	for i, r := range readers {
+		m, err := ReadMessage(r)
+		if err != nil {
+			return err
+		}
+		if m.Type != MsgPlayerReady {
+			return fmt.Errorf("player %d sent message %d before being ready", i+1, m.Type)
+		}
+	}
+	for _, p := range players {
+		if err := WriteMessage(p, Message{Type: MsgGameStart, Seed: seed}); err != nil {
+			return err
+		}
+	}
+
+	// Each player's messages are relayed to the other until the game ends
+	done := make(chan error, 2)
+	for i := range players {
+		go func(from *bufio.Reader, to io.Writer) {
+			for {
+				m, err := ReadMessage(from)
+				if err != nil {
+					done <- err
+					return
+				}
+				if err := WriteMessage(to, m); err != nil {
+					done <- err
+					return
+				}
+				if m.Type == MsgGameOver {
+					done <- nil
+					return
+				}
+			}
+		}(readers[i], players[1-i])
+	}
+	return <-done
+}
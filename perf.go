@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// frameWindow is how many of the latest frames the debug overlay averages
+const frameWindow = 60
+
+// frameTimes keeps the length in seconds of the latest frames in a ring
+// buffer
+type frameTimes struct {
+	times [frameWindow]float64
+	next  int // Where the next frame time goes
+	count int // Frame times kept, up to frameWindow
+}
+
+// add records a frame that took dt seconds, replacing the oldest once the
+// buffer is full
+func (f *frameTimes) add(dt float64) {
+	f.times[f.next] = dt
+	f.next = (f.next + 1) % frameWindow
+	if f.count < frameWindow {
+		f.count++
+	}
+}
+
+// averageFPS returns the frame rate over the frames kept, 0 when there are
+// none
+func (f *frameTimes) averageFPS() float64 {
+	total := 0.0
+	for _, dt := range f.times[:f.count] {
+		total += dt
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(f.count) / total
+}
+
+// minMax returns the shortest and longest of the frames kept, 0 for both
+// when there are none
+func (f *frameTimes) minMax() (min, max float64) {
+	for i, dt := range f.times[:f.count] {
+		if i == 0 || dt < min {
+			min = dt
+		}
+		if dt > max {
+			max = dt
+		}
+	}
+	return min, max
+}
+
+// debugLines returns the lines of the debug overlay for a frame that took dt
+// seconds
+func debugLines(dt float64, frames *frameTimes) []string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fps := 0.0
+	if dt > 0 {
+		fps = 1 / dt
+	}
+	min, max := frames.minMax()
+	return []string{
+		fmt.Sprintf("FPS: %.0f", fps),
+		fmt.Sprintf("Avg FPS: %.1f", frames.averageFPS()),
+		fmt.Sprintf("Frame: %.1f-%.1f ms", min*1000, max*1000),
+		fmt.Sprintf("Allocated: %.1f MB", float64(mem.TotalAlloc)/(1<<20)),
+	}
+}
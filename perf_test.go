@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestFrameTimesWraps(t *testing.T) {
+	var f frameTimes
+	if fps := f.averageFPS(); fps != 0 {
+		t.Errorf("averageFPS() = %v with no frames, want 0", fps)
+	}
+	if min, max := f.minMax(); min != 0 || max != 0 {
+		t.Errorf("minMax() = %v, %v with no frames, want 0, 0", min, max)
+	}
+
+	// A slow frame, then a window of frames at 60 FPS pushes it out
+	f.add(0.5)
+	if min, max := f.minMax(); min != 0.5 || max != 0.5 || f.averageFPS() != 2 {
+		t.Errorf("after one frame of 0.5s: minMax() = %v, %v, averageFPS() = %v", min, max, f.averageFPS())
+	}
+	for i := 0; i < frameWindow-1; i++ {
+		f.add(1.0 / 60)
+	}
+	if _, max := f.minMax(); max != 0.5 || f.count != frameWindow {
+		t.Errorf("with the window full: max %v, %d frames, want 0.5 and %d", max, f.count, frameWindow)
+	}
+	f.add(1.0 / 60)
+	if min, max := f.minMax(); min != 1.0/60 || max != 1.0/60 || f.count != frameWindow {
+		t.Errorf("after the slow frame went: minMax() = %v, %v with %d frames", min, max, f.count)
+	}
+	if fps := f.averageFPS(); fps < 59.999 || fps > 60.001 {
+		t.Errorf("averageFPS() = %v, want 60", fps)
+	}
+}
+
+func TestFrameTimesMinMax(t *testing.T) {
+	var f frameTimes
+	times := []float64{0.02, 0.01, 0.05, 0.03, 0.016, 0.04}
+	for _, dt := range times {
+		f.add(dt)
+	}
+	if min, max := f.minMax(); min != 0.01 || max != 0.05 {
+		t.Errorf("minMax() = %v, %v, want 0.01, 0.05", min, max)
+	}
+
+	// The oldest go first as the window fills, taking the extremes with
+	// them once only new frames are left
+	for i := 0; i < frameWindow-len(times)+2; i++ {
+		f.add(0.025)
+	}
+	if min, max := f.minMax(); min != 0.016 || max != 0.05 {
+		t.Errorf("minMax() = %v, %v with the first 2 frames gone, want 0.016, 0.05", min, max)
+	}
+	for i := 0; i < len(times); i++ {
+		f.add(0.025)
+	}
+	if min, max := f.minMax(); min != 0.025 || max != 0.025 {
+		t.Errorf("minMax() = %v, %v with only new frames, want 0.025, 0.025", min, max)
+	}
+}
+
+func TestDebugLines(t *testing.T) {
+	var f frameTimes
+	f.add(0.02)
+	f.add(0.03)
+	lines := debugLines(0.02, &f)
+	if len(lines) != 4 {
+		t.Fatalf("debugLines() = %q, want 4 lines", lines)
+	}
+	for i, want := range []string{"FPS: 50", "Avg FPS: 40.0", "Frame: 20.0-30.0 ms"} {
+		if lines[i] != want {
+			t.Errorf("debugLines()[%d] = %q, want %q", i, lines[i], want)
+		}
+	}
+	if lines := debugLines(0, &frameTimes{}); lines[0] != "FPS: 0" {
+		t.Errorf("debugLines(0) starts %q, want FPS: 0", lines[0])
+	}
+}
@@ -0,0 +1,69 @@
+package persist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// unlockedAchievement is how an unlocked achievement is saved, its name and
+// description coming from game.NewAchievements
+type unlockedAchievement struct {
+	ID         string    `json:"id"`
+	UnlockedAt time.Time `json:"unlockedAt"`
+}
+
+// DefaultAchievementsPath returns where the unlocked achievements are kept,
+// next to the high scores.
+func DefaultAchievementsPath() (string, error) {
+	return configFile("achievements.json")
+}
+
+// LoadAchievements returns every achievement, unlocked as saved at path. A
+// missing file has nothing unlocked, and saved achievements that no longer
+// exist are dropped.
+func LoadAchievements(path string) ([]game.Achievement, error) {
+	achievements := game.NewAchievements()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return achievements, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var unlocked []unlockedAchievement
+	if err := json.Unmarshal(data, &unlocked); err != nil {
+		return nil, err
+	}
+	for _, u := range unlocked {
+		for i := range achievements {
+			if achievements[i].ID == u.ID {
+				achievements[i].Unlocked = true
+				achievements[i].UnlockedAt = u.UnlockedAt
+			}
+		}
+	}
+	return achievements, nil
+}
+
+// SaveAchievements writes the unlocked achievements to path, creating its
+// directory if needed.
+func SaveAchievements(path string, achievements []game.Achievement) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	unlocked := []unlockedAchievement{}
+	for _, a := range achievements {
+		if a.Unlocked {
+			unlocked = append(unlocked, unlockedAchievement{ID: a.ID, UnlockedAt: a.UnlockedAt})
+		}
+	}
+	data, err := json.MarshalIndent(unlocked, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,63 @@
+package persist
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAchievementsRoundTrip(t *testing.T) {
+	path := filepath.Join(tempDir(t), "golang-tetris", "achievements.json")
+	achievements, err := LoadAchievements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range achievements {
+		if a.Unlocked {
+			t.Fatalf("%s unlocked with no file", a.ID)
+		}
+	}
+
+	date := time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)
+	achievements[1].Unlocked, achievements[1].UnlockedAt = true, date
+	if err := SaveAchievements(path, achievements); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadAchievements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range got {
+		if a.Unlocked != (i == 1) {
+			t.Errorf("%s unlocked = %t", a.ID, a.Unlocked)
+		}
+	}
+	if !got[1].UnlockedAt.Equal(date) {
+		t.Errorf("%s unlocked at %v, want %v", got[1].ID, got[1].UnlockedAt, date)
+	}
+}
+
+func TestLoadAchievementsDropsUnknown(t *testing.T) {
+	path := filepath.Join(tempDir(t), "achievements.json")
+	data := `[{"id": "retired", "unlockedAt": "2024-03-14T15:09:26Z"}]`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	achievements, err := LoadAchievements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range achievements {
+		if a.Unlocked {
+			t.Errorf("%s unlocked by a saved achievement that no longer exists", a.ID)
+		}
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`[{"id": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAchievements(path); err == nil {
+		t.Error("LoadAchievements(malformed) returned no error")
+	}
+}
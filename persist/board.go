@@ -0,0 +1,24 @@
+package persist
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// LoadBoardFromFile reads a board set up in a text file. Each line is a row,
+// the top row first and the hidden rows included, written with the
+// characters of Board.String: '.' for empty cells and a letter for each
+// color of block. A 20x10 board is 22 lines of 10 characters.
+func LoadBoardFromFile(path string) (game.Board, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %v", path, err)
+	}
+	b, err := game.BoardFromString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %v", path, err)
+	}
+	return b, nil
+}
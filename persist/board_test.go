@@ -0,0 +1,77 @@
+package persist
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// tsdSetup is a T-spin double setup on a 20x10 board, hidden rows included
+const tsdSetup = `..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+..........
+XX........
+XXX...XXXX
+XXXX.XXSGG
+`
+
+func TestLoadBoardFromFile(t *testing.T) {
+	path := filepath.Join(tempDir(t), "tsd.txt")
+	if err := ioutil.WriteFile(path, []byte(tsdSetup), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBoardFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Rows() != 22 || b.Cols() != 10 {
+		t.Fatalf("board is %dx%d, want 22x10", b.Rows(), b.Cols())
+	}
+
+	// The last line is the bottom row
+	want := map[[2]int]game.Block{
+		{0, 0}: game.Gray, {0, 4}: game.Empty, {0, 7}: game.Siniy, {0, 8}: game.Goluboy, {0, 9}: game.Goluboy,
+		{1, 2}: game.Gray, {1, 3}: game.Empty, {1, 5}: game.Empty, {1, 6}: game.Gray,
+		{2, 1}: game.Gray, {2, 2}: game.Empty, {21, 0}: game.Empty,
+	}
+	for cell, block := range want {
+		if got := b.At(cell[0], cell[1]); got != block {
+			t.Errorf("At(%d, %d) = %v, want %v", cell[0], cell[1], got, block)
+		}
+	}
+	if b.String() != tsdSetup {
+		t.Errorf("String() of the loaded board =\n%s\nwant the file", b.String())
+	}
+}
+
+func TestLoadBoardFromFileErrors(t *testing.T) {
+	dir := tempDir(t)
+	bad := filepath.Join(dir, "bad.txt")
+	if err := ioutil.WriteFile(bad, []byte("....\n..Q.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{bad, filepath.Join(dir, "missing.txt")} {
+		if _, err := LoadBoardFromFile(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("LoadBoardFromFile(%s) error = %v, want one naming the file", path, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package persist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DailyResult is the first finished daily challenge of a day
+type DailyResult struct {
+	Date           string        `json:"date"` // UTC day of the challenge as yyyy-mm-dd
+	CompletionTime time.Duration `json:"completionTime"`
+	Score          int           `json:"score"`
+}
+
+// DefaultDailyPath returns where the daily challenge results are kept, next
+// to the high scores.
+func DefaultDailyPath() (string, error) {
+	return configFile("daily.json")
+}
+
+// LoadDailyResults reads the daily challenge results at path, keyed by their
+// Date. A missing file has no results.
+func LoadDailyResults(path string) (map[string]DailyResult, error) {
+	results := make(map[string]DailyResult)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return results, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SaveDailyResults writes results to path, creating its directory if needed.
+func SaveDailyResults(path string, results map[string]DailyResult) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(results, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
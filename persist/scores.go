@@ -0,0 +1,119 @@
+// Package persist stores game results on disk between runs.
+package persist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MaxHighScores is the number of scores kept for each game mode
+const MaxHighScores = 10
+
+// ScoreEntry is a single finished game on the high score table
+type ScoreEntry struct {
+	Score        int       `json:"score"`
+	Mode         string    `json:"mode"`
+	Date         time.Time `json:"date"`
+	LinesCleared int       `json:"linesCleared"`
+	Time         float64   `json:"time,omitempty"` // Seconds taken, set for timed modes such as sprint
+}
+
+// beats reports whether e ranks above o. Entries with a Time are ranked by
+// the shortest time, others by the highest score.
+func (e ScoreEntry) beats(o ScoreEntry) bool {
+	if e.Time > 0 || o.Time > 0 {
+		return e.Time < o.Time
+	}
+	return e.Score > o.Score
+}
+
+// DefaultScoresPath returns where the high scores are kept, in the user's
+// config directory.
+func DefaultScoresPath() (string, error) {
+	return configFile("scores.json")
+}
+
+// configFile returns the path of the file name in the game's directory of
+// the user's config directory
+func configFile(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "golang-tetris", name), nil
+}
+
+// LoadHighScores reads the high scores at path. A missing file is an empty
+// table.
+func LoadHighScores(path string) ([]ScoreEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var scores []ScoreEntry
+	if err := json.Unmarshal(data, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// SaveHighScores writes scores to path, creating its directory if needed.
+func SaveHighScores(path string, scores []ScoreEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(scores, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// InsertScore adds e to scores if it ranks in the top MaxHighScores of its
+// mode. Returns the new table and the rank of e within its mode starting at
+// 0, or scores unchanged and -1 when e doesn't make the table.
+func InsertScore(scores []ScoreEntry, e ScoreEntry) ([]ScoreEntry, int) {
+	modeScores := ModeScores(scores, e.Mode)
+	rank := sort.Search(len(modeScores), func(i int) bool {
+		return e.beats(modeScores[i])
+	})
+	if rank >= MaxHighScores {
+		return scores, -1
+	}
+
+	modeScores = append(modeScores, ScoreEntry{})
+	copy(modeScores[rank+1:], modeScores[rank:])
+	modeScores[rank] = e
+	if len(modeScores) > MaxHighScores {
+		modeScores = modeScores[:MaxHighScores]
+	}
+
+	// Keep the other modes as they were
+	var table []ScoreEntry
+	for _, s := range scores {
+		if s.Mode != e.Mode {
+			table = append(table, s)
+		}
+	}
+	return append(table, modeScores...), rank
+}
+
+// ModeScores returns the entries of scores for mode, best first.
+func ModeScores(scores []ScoreEntry, mode string) []ScoreEntry {
+	var modeScores []ScoreEntry
+	for _, s := range scores {
+		if s.Mode == mode {
+			modeScores = append(modeScores, s)
+		}
+	}
+	sort.SliceStable(modeScores, func(i, j int) bool {
+		return modeScores[i].beats(modeScores[j])
+	})
+	return modeScores
+}
@@ -0,0 +1,110 @@
+package persist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tempDir returns a new temporary directory removed at the end of the test
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "persist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestHighScoresRoundTrip(t *testing.T) {
+	path := filepath.Join(tempDir(t), "golang-tetris", "scores.json")
+	scores, err := LoadHighScores(path)
+	if err != nil || scores != nil {
+		t.Fatalf("LoadHighScores(missing) = %v, %v, want an empty table", scores, err)
+	}
+
+	date := time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)
+	want := []ScoreEntry{
+		{Score: 12000, Mode: "marathon", Date: date, LinesCleared: 54},
+		{Score: 3000, Mode: "sprint", Date: date, LinesCleared: 40, Time: 61.25},
+	}
+	if err := SaveHighScores(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadHighScores(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadHighScores() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadHighScoresMalformed(t *testing.T) {
+	path := filepath.Join(tempDir(t), "scores.json")
+	if err := ioutil.WriteFile(path, []byte(`[{"score": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHighScores(path); err == nil {
+		t.Error("LoadHighScores(malformed) returned no error")
+	}
+}
+
+// scoresOf returns the scores of entries in order
+func scoresOf(entries []ScoreEntry) []int {
+	var scores []int
+	for _, e := range entries {
+		scores = append(scores, e.Score)
+	}
+	return scores
+}
+
+func TestInsertScore(t *testing.T) {
+	var table []ScoreEntry
+	table, rank := InsertScore(table, ScoreEntry{Score: 500, Mode: "marathon"})
+	if rank != 0 || len(table) != 1 {
+		t.Fatalf("InsertScore(empty) = %v, %d, want the score at rank 0", table, rank)
+	}
+	for score := 100; score <= 1000; score += 100 {
+		if score != 500 {
+			table, _ = InsertScore(table, ScoreEntry{Score: score, Mode: "marathon"})
+		}
+	}
+	table, _ = InsertScore(table, ScoreEntry{Score: 7, Mode: "ultra"})
+
+	// Too low to make a full table
+	if got, rank := InsertScore(table, ScoreEntry{Score: 50, Mode: "marathon"}); rank != -1 || !reflect.DeepEqual(got, table) {
+		t.Errorf("InsertScore(50) = rank %d, want -1 and the table unchanged", rank)
+	}
+
+	// Pushes the lowest score off the table
+	table, rank = InsertScore(table, ScoreEntry{Score: 550, Mode: "marathon"})
+	if rank != 5 {
+		t.Errorf("InsertScore(550) rank = %d, want 5", rank)
+	}
+	want := []int{1000, 900, 800, 700, 600, 550, 500, 400, 300, 200}
+	if got := scoresOf(ModeScores(table, "marathon")); !reflect.DeepEqual(got, want) {
+		t.Errorf("marathon scores = %v, want %v", got, want)
+	}
+	if got := scoresOf(ModeScores(table, "ultra")); !reflect.DeepEqual(got, []int{7}) {
+		t.Errorf("ultra scores = %v, want [7] kept", got)
+	}
+}
+
+func TestInsertScoreByTime(t *testing.T) {
+	// Timed modes rank the fastest first, whatever the score
+	var table []ScoreEntry
+	table, _ = InsertScore(table, ScoreEntry{Score: 900, Mode: "sprint", Time: 90})
+	table, _ = InsertScore(table, ScoreEntry{Score: 100, Mode: "sprint", Time: 60})
+	table, rank := InsertScore(table, ScoreEntry{Score: 500, Mode: "sprint", Time: 75})
+	if rank != 1 {
+		t.Errorf("InsertScore(75s) rank = %d, want 1", rank)
+	}
+	if got, want := scoresOf(ModeScores(table, "sprint")), []int{100, 500, 900}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sprint scores = %v, want %v", got, want)
+	}
+}
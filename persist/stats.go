@@ -0,0 +1,203 @@
+package persist
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// statsHeader is the first line of a stats file
+var statsHeader = []string{
+	"Piece", "Count",
+	"LinesCleared1", "LinesCleared2", "LinesCleared3", "LinesCleared4",
+	"TSpins", "TSpinMinis", "Combos", "MaxCombo", "HardDrops", "Holds",
+	"FinesseErrors", "ElapsedSeconds", "Score", "Mode",
+}
+
+// SessionStats is one game's row of a stats file
+type SessionStats struct {
+	Pieces         [7]int // Placed of each piece, indexed by game.Piece
+	LineClears     [4]int // Clears of 1 to 4 lines
+	TSpins         int    // Including the minis
+	TSpinMinis     int
+	Combos         int
+	MaxCombo       int
+	HardDrops      int
+	Holds          int
+	FinesseErrors  int
+	ElapsedSeconds float64
+	Score          int
+	Mode           string
+}
+
+// Count returns the number of pieces placed
+func (s SessionStats) Count() int {
+	n := 0
+	for _, c := range s.Pieces {
+		n += c
+	}
+	return n
+}
+
+// NewSessionStats takes the stats of the game gs
+func NewSessionStats(gs *game.GameState) SessionStats {
+	stats := gs.Stats()
+	s := SessionStats{
+		Pieces:         stats.PiecesPlaced,
+		TSpins:         stats.TSpins,
+		TSpinMinis:     stats.TSpinMinis,
+		Combos:         stats.Combos,
+		MaxCombo:       stats.MaxCombo,
+		HardDrops:      stats.HardDrops,
+		Holds:          stats.Holds,
+		FinesseErrors:  stats.FinesseErrors,
+		ElapsedSeconds: gs.ElapsedTime(),
+		Score:          gs.Score(),
+		Mode:           gs.Mode().Name(),
+	}
+	copy(s.LineClears[:], stats.LineClears[1:])
+	return s
+}
+
+// DefaultStatsPath returns where the stats of each game are kept, next to
+// the high scores.
+func DefaultStatsPath() (string, error) {
+	return configFile("stats.csv")
+}
+
+// ExportStats adds the stats of the game gs as a row of the CSV file at
+// path, creating the file with its header if needed. The Piece column has
+// the number placed of each piece separated by spaces, in the order
+// I J L O S T Z, and Count their total.
+func ExportStats(path string, gs *game.GameState) error {
+	return appendStats(path, NewSessionStats(gs))
+}
+
+// appendStats adds s as a row of the stats file at path
+func appendStats(path string, s SessionStats) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		w.Write(statsHeader)
+	}
+	w.Write(s.record())
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ImportStats reads every game of the stats file at path, oldest first.
+func ImportStats(path string) ([]SessionStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(statsHeader)
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %v", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var sessions []SessionStats
+	for i, record := range records[1:] {
+		s, err := parseSessionStats(record)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: row %d: %v", path, i+2, err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// record returns the columns of s in the order of statsHeader
+func (s SessionStats) record() []string {
+	pieces := make([]string, len(s.Pieces))
+	for i, n := range s.Pieces {
+		pieces[i] = strconv.Itoa(n)
+	}
+	return []string{
+		strings.Join(pieces, " "),
+		strconv.Itoa(s.Count()),
+		strconv.Itoa(s.LineClears[0]),
+		strconv.Itoa(s.LineClears[1]),
+		strconv.Itoa(s.LineClears[2]),
+		strconv.Itoa(s.LineClears[3]),
+		strconv.Itoa(s.TSpins),
+		strconv.Itoa(s.TSpinMinis),
+		strconv.Itoa(s.Combos),
+		strconv.Itoa(s.MaxCombo),
+		strconv.Itoa(s.HardDrops),
+		strconv.Itoa(s.Holds),
+		strconv.Itoa(s.FinesseErrors),
+		strconv.FormatFloat(s.ElapsedSeconds, 'g', -1, 64),
+		strconv.Itoa(s.Score),
+		s.Mode,
+	}
+}
+
+// parseSessionStats reads a row written by SessionStats.record. The Count
+// column is worked out from the Piece column so it isn't read.
+func parseSessionStats(record []string) (SessionStats, error) {
+	var s SessionStats
+	pieces := strings.Fields(record[0])
+	if len(pieces) != len(s.Pieces) {
+		return s, fmt.Errorf("Piece has %d counts, want %d", len(pieces), len(s.Pieces))
+	}
+	for i, p := range pieces {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return s, fmt.Errorf("Piece: %v", err)
+		}
+		s.Pieces[i] = n
+	}
+
+	ints := []*int{
+		&s.LineClears[0], &s.LineClears[1], &s.LineClears[2], &s.LineClears[3],
+		&s.TSpins, &s.TSpinMinis, &s.Combos, &s.MaxCombo, &s.HardDrops, &s.Holds,
+		&s.FinesseErrors,
+	}
+	for i, dst := range ints {
+		n, err := strconv.Atoi(record[2+i])
+		if err != nil {
+			return s, fmt.Errorf("%s: %v", statsHeader[2+i], err)
+		}
+		*dst = n
+	}
+
+	elapsed, err := strconv.ParseFloat(record[13], 64)
+	if err != nil {
+		return s, fmt.Errorf("ElapsedSeconds: %v", err)
+	}
+	s.ElapsedSeconds = elapsed
+	if s.Score, err = strconv.Atoi(record[14]); err != nil {
+		return s, fmt.Errorf("Score: %v", err)
+	}
+	s.Mode = record[15]
+	return s, nil
+}
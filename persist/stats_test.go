@@ -0,0 +1,111 @@
+package persist
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+)
+
+func TestStatsRoundTrip(t *testing.T) {
+	path := filepath.Join(tempDir(t), "golang-tetris", "stats.csv")
+	want := []SessionStats{
+		{
+			Pieces:         [7]int{12, 9, 10, 11, 8, 13, 7},
+			LineClears:     [4]int{10, 5, 2, 3},
+			TSpins:         4,
+			TSpinMinis:     1,
+			Combos:         6,
+			MaxCombo:       3,
+			HardDrops:      68,
+			Holds:          15,
+			FinesseErrors:  2,
+			ElapsedSeconds: 93.125,
+			Score:          18450,
+			Mode:           "marathon",
+		},
+		{
+			Pieces:         [7]int{1, 0, 0, 0, 0, 0, 0},
+			ElapsedSeconds: 1.0 / 3,
+			Mode:           "sprint",
+		},
+	}
+	for _, s := range want {
+		if err := appendStats(path, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ImportStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportStats() = %+v, want %+v", got, want)
+	}
+
+	// The header is only written once, the rows after it
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(statsHeader, ",") {
+		t.Errorf("stats file is\n%s\nwant the header and 2 rows", data)
+	}
+	if !strings.HasPrefix(lines[1], "12 9 10 11 8 13 7,70,") {
+		t.Errorf("first row %q doesn't start with the pieces and their count", lines[1])
+	}
+}
+
+func TestExportStats(t *testing.T) {
+	path := filepath.Join(tempDir(t), "stats.csv")
+	gs := game.NewSeededGameState(game.ModeSprint, config.DefaultSettings(), 1)
+	if err := ExportStats(path, gs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ImportStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []SessionStats{NewSessionStats(gs)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportStats() = %+v, want %+v", got, want)
+	}
+	if got[0].Count() != 1 || got[0].Mode != "sprint" {
+		t.Errorf("exported %d pieces in %q, want 1 in sprint", got[0].Count(), got[0].Mode)
+	}
+}
+
+func TestImportStatsErrors(t *testing.T) {
+	header := strings.Join(statsHeader, ",") + "\n"
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"short row", header + "1 1 1 1 1 1 1,7,0\n"},
+		{"too few pieces", header + "1 1 1,3,0,0,0,0,0,0,0,0,0,0,0,1.5,0,marathon\n"},
+		{"bad piece", header + "1 1 1 1 1 1 x,7,0,0,0,0,0,0,0,0,0,0,0,1.5,0,marathon\n"},
+		{"bad count", header + "1 1 1 1 1 1 1,7,0,0,0,0,-,0,0,0,0,0,0,1.5,0,marathon\n"},
+		{"bad time", header + "1 1 1 1 1 1 1,7,0,0,0,0,0,0,0,0,0,0,0,soon,0,marathon\n"},
+		{"bad score", header + "1 1 1 1 1 1 1,7,0,0,0,0,0,0,0,0,0,0,0,1.5,lots,marathon\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir(t), "stats.csv")
+			if err := ioutil.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ImportStats(path); err == nil {
+				t.Error("ImportStats() returned no error")
+			}
+		})
+	}
+
+	if _, err := ImportStats(filepath.Join(tempDir(t), "missing.csv")); err == nil {
+		t.Error("ImportStats(missing) returned no error")
+	}
+}
@@ -0,0 +1,521 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// heightColor returns the color of a column of the given height on a board
+// with rows visible rows. Low columns are green, turning yellow towards the
+// middle of the board and red towards the top.
+func heightColor(height, rows int) pixel.RGBA {
+	green := pixel.RGB(0, 1, 0)
+	yellow := pixel.RGB(1, 1, 0)
+	red := pixel.RGB(1, 0, 0)
+
+	t := float64(height) / float64(rows)
+	switch {
+	case t <= 0.25:
+		return green
+	case t <= 0.6:
+		f := (t - 0.25) / 0.35
+		return green.Scaled(1 - f).Add(yellow.Scaled(f))
+	default:
+		f := math.Min((t-0.6)/0.35, 1)
+		return yellow.Scaled(1 - f).Add(red.Scaled(f))
+	}
+}
+
+// blockSize returns the size of a block at a UI scale of 1 on a board with
+// the given number of visible rows and columns. Blocks are sized so the whole
+// board fits in the 200x400 playing field, which makes them 20 wide on a
+// standard board.
+func blockSize(rows, cols int) float64 {
+	return math.Min(200/float64(cols), 400/float64(rows))
+}
+
+// boardOrigin returns the bottom left corner of a board with the given number
+// of visible rows and columns at a UI scale of 1. Boards that don't fill the
+// playing field are centered in it.
+func boardOrigin(rows, cols int) pixel.Vec {
+	size := blockSize(rows, cols)
+	return pixel.V(282+(200-size*float64(cols))/2, 25+(400-size*float64(rows))/2)
+}
+
+// Board displays a particular game board with all of its pieces
+// onto a given window, win with support for responsive scaling, in the
+// colors of theme
+func Board(win *pixelgl.Window, gs *game.GameState, theme Theme) {
+	// Get UI scale factor and offsets from the window's current size
+	// Base scale is 1.0 at the initial window size of 765x450
+	widthRatio := win.Bounds().W() / layoutWidth
+	heightRatio := win.Bounds().H() / layoutHeight
+	uiScaleFactor := math.Min(widthRatio, heightRatio)
+
+	// Calculate center offsets
+	xOffset := (win.Bounds().W() - layoutWidth*uiScaleFactor) / 2
+	yOffset := (win.Bounds().H() - layoutHeight*uiScaleFactor) / 2
+	BoardAt(win, gs, theme, uiScaleFactor, xOffset, yOffset)
+}
+
+// BoardAt draws the board of gs like Board does, in a layout scaled by
+// uiScaleFactor whose bottom left corner is at xOffset and yOffset
+func BoardAt(win *pixelgl.Window, gs *game.GameState, theme Theme, uiScaleFactor, xOffset, yOffset float64) {
+	board := gs.Board()
+	rows, cols := gs.Rows(), gs.Cols()
+	activeShape := gs.ActiveShape()
+	settings := gs.Settings()
+
+	// Scale the board block size based on UI scale
+	boardBlockSize := blockSize(rows, cols) * uiScaleFactor
+
+	// Use consistent offsets for proper grid alignment, scaled for window size
+	origin := boardOrigin(rows, cols)
+	boardOffsetX := origin.X*uiScaleFactor + xOffset
+	boardOffsetY := origin.Y*uiScaleFactor + yOffset
+
+	// Shake the board after a hard drop, less and less as the shake ends.
+	// The shake doesn't use the game's random numbers so replays stay the
+	// same.
+	if shake := gs.Shake(); shake > 0 {
+		a := shake * uiScaleFactor
+		boardOffsetX += rand.Float64()*2*a - a
+		boardOffsetY += rand.Float64()*2*a - a
+	}
+	boardWidth := float64(cols) * boardBlockSize
+
+	// Themes can color over the see-through board background
+	if bg := boardColor(theme); bg.A > 0 {
+		imd := imdraw.New(nil)
+		imd.Color = bg
+		imd.Push(pixel.V(boardOffsetX, boardOffsetY), pixel.V(boardOffsetX+boardWidth, boardOffsetY+float64(rows)*boardBlockSize))
+		imd.Rectangle(0)
+		imd.Draw(win)
+	}
+
+	// Create a map to cache sprites for each block type, with the mask
+	// the theme draws them with
+	spriteCache := make(map[game.Block]*pixel.Sprite, 16)
+	maskCache := make(map[game.Block]pixel.RGBA, 16)
+
+	// First get the active shape and ghost shape
+	pieceType := board.At(activeShape[0].Row(), activeShape[0].Col())
+	clearingRows, clearTimer := gs.ClearingRows()
+	var clearPreview map[int]game.ClearType
+	if len(clearingRows) == 0 {
+		clearPreview = gs.RowClearPreview()
+	}
+
+	// Draw board pieces directly
+	solidBlocks := imdraw.New(nil)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if block := board.At(r, c); block != game.Empty {
+				// Get or create cached sprite
+				sprite, exists := spriteCache[block]
+				if !exists {
+					blockPic, mask := blockPicture(theme, block)
+					sprite = pixel.NewSprite(blockPic, blockPic.Bounds())
+					spriteCache[block] = sprite
+					maskCache[block] = mask
+				}
+
+				// Calculate position using consistent offsets
+				x := float64(c)*boardBlockSize + boardBlockSize/2
+				y := float64(r)*boardBlockSize + boardBlockSize/2
+
+				// Apply visual feedback for active piece
+				size := boardBlockSize
+				if pulse := gs.TapPulse(); pulse > 0 && gs.IsPartOfActiveShape(r, c) {
+					// Subtle scale pulse effect for tactile feedback
+					pulseIntensity := 0.1 * pulse
+					size = boardBlockSize * (1.0 + pulseIntensity)
+				}
+
+				if theme.Solid() {
+					pushSolidBlock(solidBlocks, theme, block, pixel.V(x+boardOffsetX, y+boardOffsetY), size)
+					continue
+				}
+				sprite.DrawColorMask(win, blockMatrix(sprite.Frame(), size).Moved(pixel.V(x+boardOffsetX, y+boardOffsetY)), maskCache[block])
+				if settings.ColorblindMode {
+					drawPattern(win, block, pixel.V(x+boardOffsetX, y+boardOffsetY), size)
+				}
+			}
+		}
+	}
+	solidBlocks.Draw(win)
+
+	// Patterns go over the solid blocks once they are all drawn
+	if theme.Solid() && settings.ColorblindMode {
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				if block := board.At(r, c); block != game.Empty {
+					pos := pixel.V(float64(c)*boardBlockSize+boardBlockSize/2+boardOffsetX, float64(r)*boardBlockSize+boardBlockSize/2+boardOffsetY)
+					drawPattern(win, block, pos, boardBlockSize)
+				}
+			}
+		}
+	}
+
+	// Tint the whole board on level up, fading out as the flash ends
+	if level, fade := gs.LevelFlash(); fade > 0 {
+		imd := imdraw.New(nil)
+		imd.Color = levelFlashColors[(level-2)%len(levelFlashColors)].Mul(pixel.Alpha(0.5 * fade))
+		imd.Push(pixel.V(boardOffsetX, boardOffsetY), pixel.V(boardOffsetX+boardWidth, boardOffsetY+float64(rows)*boardBlockSize))
+		imd.Rectangle(0)
+		imd.Draw(win)
+	}
+
+	// Blinking red border while the stack is close to the top
+	if gs.HeightWarning() {
+		imd := imdraw.New(nil)
+		imd.Color = pixel.RGB(1, 0, 0)
+		imd.Push(pixel.V(boardOffsetX, boardOffsetY), pixel.V(boardOffsetX+boardWidth, boardOffsetY+float64(rows)*boardBlockSize))
+		imd.Rectangle(3 * uiScaleFactor)
+		imd.Draw(win)
+	}
+
+	// Bars up to the height of each column, colored by how high it is
+	if gs.ShowHeightOverlay() {
+		imd := imdraw.New(nil)
+		for c := 0; c < cols; c++ {
+			h := board.ColHeight(c)
+			if h == 0 {
+				continue
+			}
+			imd.Color = heightColor(h, rows).Mul(pixel.Alpha(0.5))
+			x := boardOffsetX + (float64(c)+0.35)*boardBlockSize
+			imd.Push(pixel.V(x, boardOffsetY), pixel.V(x+0.3*boardBlockSize, boardOffsetY+float64(h)*boardBlockSize))
+			imd.Rectangle(0)
+		}
+		imd.Draw(win)
+	}
+
+	// Overhangs in red and the holes under them in blue
+	if gs.ShowSurfaceAnalysis() {
+		overhangs, holes := gs.SurfaceAnalysis()
+		imd := imdraw.New(nil)
+		highlight := func(cells [][2]int, color pixel.RGBA) {
+			imd.Color = color.Mul(pixel.Alpha(0.45))
+			for _, cell := range cells {
+				if cell[0] >= rows {
+					continue
+				}
+				x := boardOffsetX + float64(cell[1])*boardBlockSize
+				y := boardOffsetY + float64(cell[0])*boardBlockSize
+				imd.Push(pixel.V(x, y), pixel.V(x+boardBlockSize, y+boardBlockSize))
+				imd.Rectangle(0)
+			}
+		}
+		highlight(overhangs, pixel.RGB(1, 0, 0))
+		highlight(holes, pixel.RGB(0, 0.3, 1))
+		imd.Draw(win)
+	}
+
+	// Outline where a T would go to clear a T-spin double setup
+	if gs.ShowTSDHint() {
+		if shape, found := gs.TSDHint(); found {
+			imd := imdraw.New(nil)
+			for _, p := range shape {
+				if p.Row() >= rows {
+					continue
+				}
+				x := boardOffsetX + float64(p.Col())*boardBlockSize
+				y := boardOffsetY + float64(p.Row())*boardBlockSize
+				imd.Color = pixel.RGB(0.6, 0.2, 0.8).Mul(pixel.Alpha(0.3))
+				imd.Push(pixel.V(x, y), pixel.V(x+boardBlockSize, y+boardBlockSize))
+				imd.Rectangle(0)
+				imd.Color = pixel.RGB(0.8, 0.5, 1).Mul(pixel.Alpha(0.6))
+				imd.Push(pixel.V(x, y), pixel.V(x+boardBlockSize, y+boardBlockSize))
+				imd.Rectangle(1)
+			}
+			imd.Draw(win)
+		}
+	}
+
+	// Flash the piece that just locked in white over its blocks
+	if shape, block, active := gs.LockFlash(); active {
+		flashPic := blockGen(block2spriteIdx(block))
+		flashSprite := pixel.NewSprite(flashPic, flashPic.Bounds())
+		for _, p := range shape {
+			if p.Row() >= rows {
+				continue
+			}
+			x := float64(p.Col())*boardBlockSize + boardBlockSize/2
+			y := float64(p.Row())*boardBlockSize + boardBlockSize/2
+			flashSprite.DrawColorMask(win,
+				blockMatrix(flashSprite.Frame(), boardBlockSize).Moved(pixel.V(x+boardOffsetX, y+boardOffsetY)),
+				pixel.RGBA{R: 2, G: 2, B: 2, A: 1})
+		}
+	}
+
+	// Fading trail left behind by a hard drop
+	if trail := gs.HardDropTrail(); len(trail) > 0 {
+		imd := imdraw.New(nil)
+		for _, seg := range trail {
+			// Don't cover the piece that landed
+			if seg.Row >= rows || board.At(seg.Row, seg.Col) != game.Empty {
+				continue
+			}
+			imd.Color = pixel.RGB(1, 1, 1).Mul(pixel.Alpha(seg.Alpha))
+			x := boardOffsetX + float64(seg.Col)*boardBlockSize
+			y := boardOffsetY + float64(seg.Row)*boardBlockSize
+			imd.Push(pixel.V(x, y), pixel.V(x+boardBlockSize, y+boardBlockSize))
+			imd.Rectangle(0)
+		}
+		imd.Draw(win)
+	}
+
+	// Grid lines between the cells of the playing field
+	if settings.ShowGrid {
+		imd := imdraw.New(nil)
+		imd.Color = pixel.RGB(1, 1, 1).Mul(pixel.Alpha(0.15))
+		for _, line := range gridLines(pixel.V(boardOffsetX, boardOffsetY), boardBlockSize, rows, cols) {
+			imd.Push(line[0], line[1])
+			imd.Line(1)
+		}
+		imd.Draw(win)
+	}
+
+	// Highlight the rows the piece would clear if dropped now
+	if len(clearPreview) > 0 {
+		imd := imdraw.New(nil)
+		for r, clearType := range clearPreview {
+			if r >= rows {
+				continue
+			}
+			switch clearType {
+			case game.ClearTypeTSpin:
+				imd.Color = pixel.RGB(0.6, 0.2, 0.8).Mul(pixel.Alpha(0.35))
+			case game.ClearTypeTetris:
+				imd.Color = pixel.RGB(1, 1, 1).Mul(pixel.Alpha(0.35))
+			default:
+				imd.Color = pixel.RGB(1, 1, 0).Mul(pixel.Alpha(0.35))
+			}
+			y := float64(r)*boardBlockSize + boardOffsetY
+			imd.Push(pixel.V(boardOffsetX, y), pixel.V(boardOffsetX+boardWidth, y+boardBlockSize))
+			imd.Rectangle(0)
+		}
+		imd.Draw(win)
+	}
+
+	// Flash the rows that are being cleared between white and black
+	if len(clearingRows) > 0 {
+		imd := imdraw.New(nil)
+		imd.Color = pixel.RGB(1, 1, 1)
+		if int(clearTimer/lineClearFlashTime)%2 == 1 {
+			imd.Color = pixel.RGB(0, 0, 0)
+		}
+		for _, r := range clearingRows {
+			if r >= rows {
+				continue
+			}
+			y := float64(r)*boardBlockSize + boardOffsetY
+			imd.Push(pixel.V(boardOffsetX, y), pixel.V(boardOffsetX+boardWidth, y+boardBlockSize))
+			imd.Rectangle(0)
+		}
+		imd.Draw(win)
+	}
+
+	// Draw ghost piece with transparency, or outlined in a solid theme,
+	// unless the player turned it off
+	if cells := ghostCells(gs); len(cells) > 0 {
+		ghostBlockPic, ghostMask := blockPicture(theme, pieceType)
+		ghostSprite := pixel.NewSprite(ghostBlockPic, ghostBlockPic.Bounds())
+		outline := imdraw.New(nil)
+		outline.Color = theme.GhostColor()
+
+		for _, p := range cells {
+			x := float64(p.Col())*boardBlockSize + boardBlockSize/2
+			y := float64(p.Row())*boardBlockSize + boardBlockSize/2
+
+			if theme.Solid() {
+				inset := 2 * uiScaleFactor
+				outline.Push(
+					pixel.V(x-boardBlockSize/2+inset+boardOffsetX, y-boardBlockSize/2+inset+boardOffsetY),
+					pixel.V(x+boardBlockSize/2-inset+boardOffsetX, y+boardBlockSize/2-inset+boardOffsetY))
+				outline.Rectangle(2 * uiScaleFactor)
+				continue
+			}
+			ghostSprite.DrawColorMask(win,
+				blockMatrix(ghostSprite.Frame(), boardBlockSize).Moved(pixel.V(x+boardOffsetX, y+boardOffsetY)),
+				ghostMask.Mul(theme.GhostColor()))
+		}
+		outline.Draw(win)
+	}
+
+	// Draw the active piece with emphasis
+	for i := 0; i < 4; i++ {
+		r := activeShape[i].Row()
+		c := activeShape[i].Col()
+
+		if r < rows { // Only draw visible parts
+			x := float64(c)*boardBlockSize + boardBlockSize/2
+			y := float64(r)*boardBlockSize + boardBlockSize/2
+
+			activePic, activeMask := blockPicture(theme, pieceType)
+			activeSprite := pixel.NewSprite(activePic, activePic.Bounds())
+
+			// Apply visual emphasis for active piece
+			size := boardBlockSize
+			if pulse := gs.TapPulse(); pulse > 0 {
+				// Enhanced effect for active piece
+				pulseIntensity := 0.15 * pulse
+				size = boardBlockSize * (1.0 + pulseIntensity)
+			}
+
+			if theme.Solid() {
+				imd := imdraw.New(nil)
+				pushSolidBlock(imd, theme, pieceType, pixel.V(x+boardOffsetX, y+boardOffsetY), size)
+				imd.Draw(win)
+			} else {
+				activeSprite.DrawColorMask(win, blockMatrix(activeSprite.Frame(), size).Moved(pixel.V(x+boardOffsetX, y+boardOffsetY)), activeMask)
+			}
+			if settings.ColorblindMode {
+				drawPattern(win, pieceType, pixel.V(x+boardOffsetX, y+boardOffsetY), size)
+			}
+		}
+	}
+
+	// Bar along the bottom of a resting piece filling up as its lock delay
+	// runs out, turning red just before it locks
+	if fill, resting := gs.LockProgress(); resting {
+		bottom, left, right := activeShape[0].Row(), activeShape[0].Col(), activeShape[0].Col()
+		for _, p := range activeShape[1:] {
+			bottom = minInt(bottom, p.Row())
+			left = minInt(left, p.Col())
+			right = maxInt(right, p.Col())
+		}
+		if bottom < rows {
+			min := pixel.V(boardOffsetX+float64(left)*boardBlockSize, boardOffsetY+float64(bottom)*boardBlockSize)
+			max := min.Add(pixel.V(float64(right-left+1)*boardBlockSize, 0.2*boardBlockSize))
+
+			imd := imdraw.New(nil)
+			imd.Color = pixel.RGB(1, 1, 0)
+			if fill > 0.75 {
+				imd.Color = pixel.RGB(1, 0, 0)
+			}
+			imd.Push(min, pixel.V(min.X+fill*(max.X-min.X), max.Y))
+			imd.Rectangle(0)
+			imd.Color = pixel.RGB(1, 1, 1)
+			imd.Push(min, max)
+			imd.Rectangle(1)
+			imd.Draw(win)
+		}
+	}
+}
+
+// gridLines returns the lines between the cells of a board of rows by cols
+// blocks size wide with its bottom left corner at origin. The lines stay
+// inside the board, so there are none along its edges.
+func gridLines(origin pixel.Vec, size float64, rows, cols int) [][2]pixel.Vec {
+	top := origin.Y + float64(rows)*size
+	right := origin.X + float64(cols)*size
+	var lines [][2]pixel.Vec
+	for c := 1; c < cols; c++ {
+		x := origin.X + float64(c)*size
+		lines = append(lines, [2]pixel.Vec{pixel.V(x, origin.Y), pixel.V(x, top)})
+	}
+	for r := 1; r < rows; r++ {
+		y := origin.Y + float64(r)*size
+		lines = append(lines, [2]pixel.Vec{pixel.V(origin.X, y), pixel.V(right, y)})
+	}
+	return lines
+}
+
+// ghostCells returns the cells of the ghost piece of gs that are drawn: the
+// visible ones not covered by the active piece, or none when the player
+// turned the ghost off
+func ghostCells(gs *game.GameState) []game.Point {
+	if !gs.Settings().ShowGhost {
+		return nil
+	}
+	var cells []game.Point
+	for _, p := range gs.GhostPiece() {
+		if p.Row() < gs.Rows() && !gs.IsPartOfActiveShape(p.Row(), p.Col()) {
+			cells = append(cells, p)
+		}
+	}
+	return cells
+}
+
+// ColumnHeights writes the height of each column above it, green for low
+// columns turning red as they near the top
+func ColumnHeights(win *pixelgl.Window, atlas *text.Atlas, gs *game.GameState, uiScaleFactor, xOffset, yOffset float64) {
+	rows, cols := gs.Rows(), gs.Cols()
+	boardBlockSize := blockSize(rows, cols) * uiScaleFactor
+	origin := boardOrigin(rows, cols).Scaled(uiScaleFactor).Add(pixel.V(xOffset, yOffset))
+
+	txt := text.New(pixel.ZV, atlas)
+	for c, h := range game.ColumnHeights(gs.Board()) {
+		txt.Clear()
+		txt.Color = colHeightColor(h, rows)
+		fmt.Fprint(txt, h)
+		pos := pixel.V(
+			origin.X+float64(c)*boardBlockSize+boardBlockSize/2-txt.Bounds().W()*uiScaleFactor/2,
+			origin.Y+float64(rows)*boardBlockSize+5*uiScaleFactor,
+		)
+		txt.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(pos))
+	}
+}
+
+// colHeightColor returns the color of the height of a column on a board
+// with rows visible rows: green up to 30% of the way up, red from 70% and
+// in between the two. On a standard board those are heights 6 and 14.
+func colHeightColor(height, rows int) pixel.RGBA {
+	f := math.Max(0, math.Min((float64(height)/float64(rows)-0.3)/0.4, 1))
+	return pixel.RGB(0, 1, 0).Scaled(1 - f).Add(pixel.RGB(1, 0, 0).Scaled(f))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// blockPicture returns the picture block b is drawn with in theme and the
+// mask to draw it with. A FileTheme tints the grey block of each piece, and
+// animated blocks tint the frame of the pulsing block showing now.
+func blockPicture(theme Theme, b game.Block) (pixel.Picture, pixel.RGBA) {
+	if _, ok := blockPiece(b); ok {
+		if animatedBlocks {
+			return animatedBlockGen(time.Since(animationStart).Seconds()), blockColor(theme, b)
+		}
+		if _, ok := theme.(*FileTheme); ok {
+			return blockGen(block2spriteIdx(game.Gray)), blockColor(theme, b)
+		}
+	}
+	return blockGen(block2spriteIdx(b)), pixel.RGB(1, 1, 1)
+}
+
+// boardColor returns the color drawn over the board background in theme,
+// transparent to leave the background as it is
+func boardColor(theme Theme) pixel.RGBA {
+	if theme.Solid() {
+		return pixel.RGB(0, 0, 0)
+	}
+	if t, ok := theme.(*FileTheme); ok {
+		return t.boardColor
+	}
+	return pixel.RGBA{}
+}
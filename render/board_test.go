@@ -0,0 +1,70 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+)
+
+func TestGhostCells(t *testing.T) {
+	settings := config.DefaultSettings()
+	settings.ShowGhost = false
+	gs := game.NewSeededGameState(game.ModeMarathon, settings, 1)
+	if cells := ghostCells(gs); len(cells) != 0 {
+		t.Errorf("ghostCells() = %v with the ghost turned off, want none", cells)
+	}
+
+	// A new piece is high above its ghost on the floor
+	settings.ShowGhost = true
+	gs.ApplySettings(settings)
+	cells := ghostCells(gs)
+	if len(cells) != 4 {
+		t.Fatalf("ghostCells() = %v, want 4 cells", cells)
+	}
+	for _, p := range cells {
+		if p.Row() > 1 {
+			t.Errorf("ghost cell %v isn't on the floor", p)
+		}
+	}
+}
+
+func TestGridLines(t *testing.T) {
+	origin := pixel.V(282, 25)
+	lines := gridLines(origin, 20, 20, 10)
+	if len(lines) != 9+19 {
+		t.Fatalf("gridLines() returned %d lines, want %d", len(lines), 9+19)
+	}
+
+	// Every line is inside the playing field, vertical or horizontal, and
+	// crosses the whole of it
+	field := pixel.R(282, 25, 482, 425)
+	for _, line := range lines {
+		a, b := line[0], line[1]
+		if !field.Contains(a) || !field.Contains(b) {
+			t.Errorf("line %v-%v leaves the playing field %v", a, b, field)
+		}
+		switch {
+		case a.X == b.X:
+			if a.Y != field.Min.Y || b.Y != field.Max.Y || a.X == field.Min.X || a.X == field.Max.X {
+				t.Errorf("vertical line %v-%v doesn't cross the field between its edges", a, b)
+			}
+		case a.Y == b.Y:
+			if a.X != field.Min.X || b.X != field.Max.X || a.Y == field.Min.Y || a.Y == field.Max.Y {
+				t.Errorf("horizontal line %v-%v doesn't cross the field between its edges", a, b)
+			}
+		default:
+			t.Errorf("line %v-%v is diagonal", a, b)
+		}
+	}
+
+	// The lines are a block apart
+	if got := lines[1][0].X - lines[0][0].X; got != 20 {
+		t.Errorf("columns are %v apart, want 20", got)
+	}
+}
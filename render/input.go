@@ -0,0 +1,121 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+
+	"github.com/zkry/golang-tetris/input"
+)
+
+// inputKey is a key of the input overlay: the action it shows and where it
+// sits, in keys from the left and rows from the bottom
+type inputKey struct {
+	action   input.Action
+	label    string
+	col, row int
+}
+
+// inputKeys are laid out like the arrow keys, the moves and drops on the
+// bottom row and hold and the rotations above them
+var inputKeys = []inputKey{
+	{input.ActionHold, "HOLD", 0, 1},
+	{input.ActionRotateCCW, "CCW", 1, 1},
+	{input.ActionRotate180, "180", 2, 1},
+	{input.ActionRotateCW, "CW", 3, 1},
+	{input.ActionMoveLeft, "LEFT", 0, 0},
+	{input.ActionSoftDrop, "SOFT", 1, 0},
+	{input.ActionMoveRight, "RIGHT", 2, 0},
+	{input.ActionHardDrop, "HARD", 3, 0},
+}
+
+// Sizes of the input overlay in pixels at a UI scale of 1
+const (
+	inputKeyWidth  = 46.0
+	inputKeyHeight = 22.0
+	inputKeyGap    = 4.0
+	inputBarHeight = 3.0
+)
+
+// coloredRect is a rectangle of the input overlay and the color it is
+// filled with
+type coloredRect struct {
+	pixel.Rect
+	Color pixel.RGBA
+}
+
+// inputKeyRect returns where key k of the input overlay goes. The bottom
+// row is raised to leave room for the bars under it.
+func inputKeyRect(k inputKey, uiScaleFactor float64) pixel.Rect {
+	keySize := pixel.V(inputKeyWidth, inputKeyHeight).Scaled(uiScaleFactor)
+	gap := inputKeyGap * uiScaleFactor
+	origin := pixel.V(10, 10+2*(inputBarHeight+inputKeyGap)).Scaled(uiScaleFactor)
+	min := origin.Add(pixel.V(float64(k.col)*(keySize.X+gap), float64(k.row)*(keySize.Y+gap)))
+	return pixel.Rect{Min: min, Max: min.Add(keySize)}
+}
+
+// inputOverlayRects returns the rectangles of the input overlay in the
+// order they are drawn: each key, followed by its DAS and ARR bars and
+// their fill for the left and right keys
+func inputOverlayRects(pressed func(input.Action) bool, das, arr float64, direction int, uiScaleFactor float64) []coloredRect {
+	gap := inputKeyGap * uiScaleFactor
+	barHeight := inputBarHeight * uiScaleFactor
+	barColors := [2]pixel.RGBA{pixel.ToRGBA(colornames.Orange), pixel.ToRGBA(colornames.Lime)}
+
+	var rects []coloredRect
+	for _, k := range inputKeys {
+		key := inputKeyRect(k, uiScaleFactor)
+		c := pixel.ToRGBA(colornames.Grey)
+		if pressed(k.action) {
+			c = pixel.ToRGBA(colornames.Yellow)
+		}
+		rects = append(rects, coloredRect{key, c})
+
+		if k.action != input.ActionMoveLeft && k.action != input.ActionMoveRight {
+			continue
+		}
+		var fill [2]float64
+		if (k.action == input.ActionMoveLeft && direction < 0) || (k.action == input.ActionMoveRight && direction > 0) {
+			fill = [2]float64{das, arr}
+		}
+		for i, f := range fill {
+			barMin := key.Min.Sub(pixel.V(0, float64(i+1)*(barHeight+gap)))
+			rects = append(rects, coloredRect{pixel.Rect{Min: barMin, Max: barMin.Add(pixel.V(key.W(), barHeight))}, pixel.ToRGBA(colornames.Dimgray)})
+			if f > 0 {
+				rects = append(rects, coloredRect{pixel.Rect{Min: barMin, Max: barMin.Add(pixel.V(key.W()*f, barHeight))}, barColors[i]})
+			}
+		}
+	}
+	return rects
+}
+
+// InputOverlay draws the actions in the bottom left corner of the window,
+// yellow while pressed reports them held down. Two bars under each of the
+// left and right keys fill up as the move held charges DAS and then ARR,
+// with das, arr and direction as input.InputHandler.Charge reports them.
+func InputOverlay(win *pixelgl.Window, atlas *text.Atlas, pressed func(input.Action) bool, das, arr float64, direction int, uiScaleFactor float64) {
+	imd := imdraw.New(nil)
+	for _, r := range inputOverlayRects(pressed, das, arr, direction, uiScaleFactor) {
+		imd.Color = r.Color
+		imd.Push(r.Min, r.Max)
+		imd.Rectangle(0)
+	}
+	imd.Draw(win)
+
+	// Labels are centered on their key
+	for _, k := range inputKeys {
+		txt := text.New(pixel.ZV, atlas)
+		txt.Color = colornames.Black
+		fmt.Fprint(txt, k.label)
+		center := inputKeyRect(k, uiScaleFactor).Center()
+		offset := pixel.V(-txt.Bounds().W()/2, -atlas.Ascent()/2)
+		txt.Draw(win, pixel.IM.Moved(offset).Scaled(pixel.ZV, uiScaleFactor).Moved(center))
+	}
+}
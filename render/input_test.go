@@ -0,0 +1,124 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+	"golang.org/x/image/colornames"
+
+	"github.com/zkry/golang-tetris/input"
+)
+
+func TestInputOverlayKeys(t *testing.T) {
+	yellow, grey := pixel.ToRGBA(colornames.Yellow), pixel.ToRGBA(colornames.Grey)
+	tests := []struct {
+		name string
+		held []input.Action
+	}{
+		{"nothing", nil},
+		{"left", []input.Action{input.ActionMoveLeft}},
+		{"right and soft drop", []input.Action{input.ActionMoveRight, input.ActionSoftDrop}},
+		{"rotations", []input.Action{input.ActionRotateCW, input.ActionRotateCCW, input.ActionRotate180}},
+		{"hold and hard drop", []input.Action{input.ActionHold, input.ActionHardDrop}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state input.InputState
+			for _, a := range tt.held {
+				state.Down[a] = true
+			}
+			pressed := func(a input.Action) bool { return state.Down[a] }
+
+			// Each key is the first rectangle drawn where it goes
+			rects := inputOverlayRects(pressed, 0, 0, 0, 1)
+			for _, k := range inputKeys {
+				want := grey
+				if state.Down[k.action] {
+					want = yellow
+				}
+				key := inputKeyRect(k, 1)
+				found := false
+				for _, r := range rects {
+					if r.Rect == key {
+						if r.Color != want {
+							t.Errorf("%s key is %v, want %v", k.label, r.Color, want)
+						}
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("%s key isn't drawn", k.label)
+				}
+			}
+		})
+	}
+}
+
+func TestInputOverlayKeysApart(t *testing.T) {
+	for i, a := range inputKeys {
+		ra := inputKeyRect(a, 1.5)
+		if ra.W() != inputKeyWidth*1.5 || ra.H() != inputKeyHeight*1.5 {
+			t.Errorf("%s key is %vx%v at a scale of 1.5", a.label, ra.W(), ra.H())
+		}
+		for _, b := range inputKeys[i+1:] {
+			if ra.Intersects(inputKeyRect(b, 1.5)) {
+				t.Errorf("%s and %s keys overlap", a.label, b.label)
+			}
+		}
+	}
+}
+
+func TestInputOverlayBars(t *testing.T) {
+	dimgray, orange, lime := pixel.ToRGBA(colornames.Dimgray), pixel.ToRGBA(colornames.Orange), pixel.ToRGBA(colornames.Lime)
+	keyOf := func(a input.Action) pixel.Rect {
+		for _, k := range inputKeys {
+			if k.action == a {
+				return inputKeyRect(k, 1)
+			}
+		}
+		t.Fatalf("no key for action %d", a)
+		return pixel.Rect{}
+	}
+	// fills returns the widths of the bars filled in under the key of a
+	fills := func(rects []coloredRect, a input.Action) []float64 {
+		key := keyOf(a)
+		var widths []float64
+		for _, r := range rects {
+			if r.Max.Y <= key.Min.Y && r.Min.X == key.Min.X && (r.Color == orange || r.Color == lime) {
+				widths = append(widths, r.W())
+			}
+		}
+		return widths
+	}
+	held := func(input.Action) bool { return true }
+
+	// Held right, DAS half charged
+	rects := inputOverlayRects(held, 0.5, 0, 1, 1)
+	if got := fills(rects, input.ActionMoveRight); len(got) != 1 || got[0] != inputKeyWidth/2 {
+		t.Errorf("right bars filled %v, want DAS half full", got)
+	}
+	if got := fills(rects, input.ActionMoveLeft); len(got) != 0 {
+		t.Errorf("left bars filled %v while right is held, want none", got)
+	}
+
+	// Held left, DAS charged and ARR a quarter of the way
+	rects = inputOverlayRects(held, 1, 0.25, -1, 1)
+	if got := fills(rects, input.ActionMoveLeft); len(got) != 2 || got[0] != inputKeyWidth || got[1] != inputKeyWidth/4 {
+		t.Errorf("left bars filled %v, want DAS full and ARR a quarter", got)
+	}
+
+	// The empty bars are always under both keys
+	empty := 0
+	for _, r := range inputOverlayRects(held, 0, 0, 0, 1) {
+		if r.Color == dimgray {
+			empty++
+		}
+	}
+	if empty != 4 {
+		t.Errorf("%d empty bars, want 2 under each of left and right", empty)
+	}
+}
@@ -0,0 +1,127 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// patternSize is the width and height of a pattern in pixels, the size of a
+// block on the usual board
+const patternSize = 20.0
+
+// patternColor darkens the block under the lines of a pattern, leaving its
+// color showing through
+var patternColor = pixel.RGBA{A: 0.45}
+
+// patternFunc draws a pattern filling a patternSize square from the origin
+type patternFunc func(imd *imdraw.IMDraw)
+
+// piecePatterns are the patterns drawn over the blocks of each piece in
+// colorblind mode, indexed by Piece, so the pieces can be told apart without
+// their color
+var piecePatterns = [7]patternFunc{
+	game.IPiece: horizontalStripes,
+	game.JPiece: verticalStripes,
+	game.LPiece: dots,
+	game.OPiece: solid,
+	game.SPiece: diagonalStripes,
+	game.TPiece: crosshatch,
+	game.ZPiece: circles,
+}
+
+// patternSprites are the patterns drawn once, by the first call to
+// patternSprite as drawing needs the window to exist
+var patternSprites [7]*pixel.Sprite
+
+func horizontalStripes(imd *imdraw.IMDraw) {
+	for y := 3.0; y < patternSize; y += 5 {
+		imd.Push(pixel.V(0, y), pixel.V(patternSize, y))
+		imd.Line(2)
+	}
+}
+
+func verticalStripes(imd *imdraw.IMDraw) {
+	for x := 3.0; x < patternSize; x += 5 {
+		imd.Push(pixel.V(x, 0), pixel.V(x, patternSize))
+		imd.Line(2)
+	}
+}
+
+func dots(imd *imdraw.IMDraw) {
+	for y := 4.0; y < patternSize; y += 6 {
+		for x := 4.0; x < patternSize; x += 6 {
+			imd.Push(pixel.V(x, y))
+		}
+	}
+	imd.Circle(1.5, 0)
+}
+
+// solid leaves the block as it is, telling its piece apart by having no
+// pattern
+func solid(imd *imdraw.IMDraw) {}
+
+func diagonalStripes(imd *imdraw.IMDraw) {
+	for d := -patternSize; d < patternSize; d += 6 {
+		imd.Push(pixel.V(d, 0), pixel.V(d+patternSize, patternSize))
+		imd.Line(2)
+	}
+}
+
+func crosshatch(imd *imdraw.IMDraw) {
+	for d := -patternSize; d < patternSize; d += 7 {
+		imd.Push(pixel.V(d, 0), pixel.V(d+patternSize, patternSize))
+		imd.Line(1.5)
+		imd.Push(pixel.V(d, patternSize), pixel.V(d+patternSize, 0))
+		imd.Line(1.5)
+	}
+}
+
+func circles(imd *imdraw.IMDraw) {
+	center := pixel.V(patternSize/2, patternSize/2)
+	for r := 3.0; r < patternSize/2; r += 4 {
+		imd.Push(center)
+		imd.Circle(r, 1.5)
+	}
+}
+
+// patternSprite returns the sprite of the pattern of piece p, drawing it
+// with an IMDraw onto a canvas and keeping its pixels the first time
+func patternSprite(p game.Piece) *pixel.Sprite {
+	if patternSprites[p] != nil {
+		return patternSprites[p]
+	}
+	bounds := pixel.R(0, 0, patternSize, patternSize)
+	canvas := pixelgl.NewCanvas(bounds)
+	imd := imdraw.New(nil)
+	imd.Color = patternColor
+	piecePatterns[p](imd)
+	imd.Draw(canvas)
+
+	pic := pixel.MakePictureData(bounds)
+	pixels := canvas.Pixels()
+	for i := range pic.Pix {
+		pic.Pix[i].R = pixels[4*i]
+		pic.Pix[i].G = pixels[4*i+1]
+		pic.Pix[i].B = pixels[4*i+2]
+		pic.Pix[i].A = pixels[4*i+3]
+	}
+	patternSprites[p] = pixel.NewSprite(pic, bounds)
+	return patternSprites[p]
+}
+
+// drawPattern draws the pattern of the piece of block b over a block of
+// size pixels centered on pos. Blocks that aren't of a piece are left as
+// they are.
+func drawPattern(win *pixelgl.Window, b game.Block, pos pixel.Vec, size float64) {
+	p, ok := blockPiece(b)
+	if !ok {
+		return
+	}
+	patternSprite(p).Draw(win, pixel.IM.Scaled(pixel.ZV, size/patternSize).Moved(pos))
+}
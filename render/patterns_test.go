@@ -0,0 +1,94 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// recordTarget is a pixel.Target that keeps the triangles drawn onto it
+// instead of drawing them, so shapes can be checked without a window
+type recordTarget struct {
+	tris *pixel.TrianglesData
+}
+
+// recordedTriangles are triangles made by a recordTarget
+type recordedTriangles struct {
+	*pixel.TrianglesData
+}
+
+// Draw does nothing, the triangles being kept already
+func (recordedTriangles) Draw() {}
+
+func (r *recordTarget) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	r.tris = pixel.MakeTrianglesData(t.Len())
+	r.tris.Update(t)
+	return recordedTriangles{r.tris}
+}
+
+func (r *recordTarget) MakePicture(p pixel.Picture) pixel.TargetPicture { return nil }
+
+// patternPositions returns the corners of the triangles of the pattern of
+// piece p, in the order they are drawn
+func patternPositions(p game.Piece) []pixel.Vec {
+	imd := imdraw.New(nil)
+	imd.Color = patternColor
+	piecePatterns[p](imd)
+	var target recordTarget
+	imd.Draw(&target)
+	if target.tris == nil {
+		return nil
+	}
+	positions := make([]pixel.Vec, target.tris.Len())
+	for i := range positions {
+		positions[i] = target.tris.Position(i)
+	}
+	return positions
+}
+
+func TestPiecePatternsDistinct(t *testing.T) {
+	funcs := make(map[uintptr]game.Piece)
+	drawn := make(map[string]game.Piece)
+	for p := game.IPiece; p <= game.ZPiece; p++ {
+		if piecePatterns[p] == nil {
+			t.Fatalf("piece %d has no pattern", p)
+		}
+		f := reflect.ValueOf(piecePatterns[p]).Pointer()
+		if other, ok := funcs[f]; ok {
+			t.Errorf("pieces %d and %d have the same pattern", other, p)
+		}
+		funcs[f] = p
+
+		// What the patterns draw differs too, the solid one drawing nothing
+		positions := patternPositions(p)
+		if (len(positions) == 0) != (p == game.OPiece) {
+			t.Errorf("pattern of piece %d draws %d triangle corners", p, len(positions))
+		}
+		key := fmt.Sprint(positions)
+		if other, ok := drawn[key]; ok {
+			t.Errorf("patterns of pieces %d and %d draw the same", other, p)
+		}
+		drawn[key] = p
+	}
+}
+
+func TestBlockPiece(t *testing.T) {
+	for p := game.IPiece; p <= game.ZPiece; p++ {
+		if got, ok := blockPiece(game.PieceBlock(p)); !ok || got != p {
+			t.Errorf("blockPiece(PieceBlock(%d)) = %d, %t", p, got, ok)
+		}
+	}
+	for _, b := range []game.Block{game.Empty, game.Gray} {
+		if p, ok := blockPiece(b); ok {
+			t.Errorf("blockPiece(%d) = %d, want no piece", b, p)
+		}
+	}
+}
@@ -0,0 +1,581 @@
+//go:build !wasm
+// +build !wasm
+
+// Package render draws a game and its surroundings onto a pixelgl window,
+// or onto a canvas in the browser when built for WebAssembly.
+package render
+
+import (
+	"fmt"
+	_ "image/png"
+	"math"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+
+	"github.com/zkry/golang-tetris/game"
+	ss "github.com/zkry/golang-tetris/spritesheet"
+)
+
+// holdLockedMask greys out the hold piece while holding is unavailable
+var holdLockedMask = pixel.RGBA{R: 0.4, G: 0.4, B: 0.4, A: 1.0}
+
+const lineClearFlashTime = 0.05 // How long each white or black flash lasts
+
+// levelFlashColors are the colors the board flashes on level up, taken in
+// turn for each level
+var levelFlashColors = []pixel.RGBA{
+	pixel.RGB(1, 1, 1),
+	pixel.RGB(0.3, 0.8, 1),
+	pixel.RGB(0.4, 1, 0.4),
+	pixel.RGB(1, 0.9, 0.2),
+	pixel.RGB(1, 0.5, 0.1),
+	pixel.RGB(1, 0.3, 0.6),
+	pixel.RGB(0.7, 0.4, 1),
+}
+
+// Layout of the panels next to the board at a UI scale of 1
+// layoutWidth and layoutHeight are the size of the window the layout is
+// designed at, with a UI scale of 1
+const (
+	layoutWidth  = 765.0
+	layoutHeight = 450.0
+)
+
+const (
+	nextPieceX = 182.0
+	nextPieceY = 150.0
+	holdPieceX = 182.0
+	holdPieceY = 325.0
+)
+
+var blockGen func(int) pixel.Picture
+
+// animatedBlockFPS is the frame rate of the pulsing block
+const animatedBlockFPS = 12
+
+// animatedBlockGen gives the frame of the pulsing grey block t seconds into
+// its animation
+var animatedBlockGen func(float64) pixel.Picture
+
+// animatedBlocks is whether the blocks of the pieces are drawn pulsing, see
+// SetAnimatedBlocks
+var animatedBlocks bool
+
+// animationStart is when the blocks started pulsing
+var animationStart = time.Now()
+
+var bgImgSprite pixel.Sprite
+
+var gameBGSprite pixel.Sprite
+
+var nextPieceBGSprite pixel.Sprite
+
+var holdPieceBGSprite pixel.Sprite
+
+// Load loads the block sprites and background images from the resources
+// directory dir. It must be called before anything is drawn.
+func Load(dir string) error {
+	// Matriax on opengameart.org
+	var err error
+	blockGen, err = ss.LoadSpriteSheet(filepath.Join(dir, "blocks.png"), 2, 8)
+	if err != nil {
+		return err
+	}
+	animatedBlockGen, err = ss.LoadAnimatedSpriteSheet(filepath.Join(dir, "blocks_animated.png"), 1, 8, animatedBlockFPS)
+	if err != nil {
+		return err
+	}
+
+	// Background image, by ansimuz on opengameart.org
+	bgPic, err := ss.LoadPicture(filepath.Join(dir, "parallax-mountain-bg.png"))
+	if err != nil {
+		return err
+	}
+	bgImgSprite = *pixel.NewSprite(bgPic, bgPic.Bounds())
+
+	// Game Background
+	blackPic := ss.GetPlayBGPic()
+	gameBGSprite = *pixel.NewSprite(blackPic, blackPic.Bounds())
+
+	// Next Piece BG
+	nextPiecePic := ss.GetNextPieceBGPic()
+	nextPieceBGSprite = *pixel.NewSprite(nextPiecePic, nextPiecePic.Bounds())
+
+	// Hold Piece BG (using same sprite as next piece)
+	holdPieceBGSprite = *pixel.NewSprite(nextPiecePic, nextPiecePic.Bounds())
+	return nil
+}
+
+// SetAnimatedBlocks chooses whether the blocks of the pieces are drawn with
+// the pulsing grey block tinted in their color rather than their own sprite
+func SetAnimatedBlocks(on bool) {
+	animatedBlocks = on
+}
+
+// Background draws the background image of theme filling the window along
+// with the backgrounds of the board and the next and hold piece panels
+func Background(win *pixelgl.Window, theme Theme, uiScaleFactor, xOffset, yOffset float64) {
+	BackgroundImage(win, theme)
+	Panels(win, theme, uiScaleFactor, xOffset, yOffset)
+}
+
+// BackgroundImage draws the background image of theme filling the window
+func BackgroundImage(win *pixelgl.Window, theme Theme) {
+	// A solid theme is drawn on the jet black the window is cleared to
+	if theme.Solid() {
+		return
+	}
+
+	// Background scales to fill entire window while maintaining aspect ratio
+	bg := &bgImgSprite
+	if t, ok := theme.(*FileTheme); ok && t.background != nil {
+		bg = t.background
+	}
+	bgScale := math.Max(win.Bounds().W()/bg.Frame().W(), win.Bounds().H()/bg.Frame().H())
+	bg.Draw(win, pixel.IM.Scaled(pixel.ZV, bgScale).Moved(win.Bounds().Center()))
+}
+
+// Panels draws the backgrounds of the board and the next and hold piece
+// panels of a game laid out at xOffset and yOffset
+func Panels(win *pixelgl.Window, theme Theme, uiScaleFactor, xOffset, yOffset float64) {
+	if theme.Solid() {
+		return
+	}
+
+	// Game board background scales based on UI scale factor, centered in
+	// the layout
+	layoutCenter := pixel.V(layoutWidth/2*uiScaleFactor+xOffset, layoutHeight/2*uiScaleFactor+yOffset)
+	gameBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(layoutCenter))
+
+	// Next piece and hold piece background
+	nextPiecePos := pixel.V(nextPieceX*uiScaleFactor+xOffset, nextPieceY*uiScaleFactor+yOffset)
+	holdPiecePos := pixel.V(holdPieceX*uiScaleFactor+xOffset, holdPieceY*uiScaleFactor+yOffset)
+
+	// The next piece panel is stretched to fit the whole queue
+	nextPieceBGSprite.Draw(win, pixel.IM.ScaledXY(pixel.ZV, pixel.V(uiScaleFactor, 2.5*uiScaleFactor)).Moved(nextPiecePos))
+	holdPieceBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(holdPiecePos))
+}
+
+// Text draws the score and the labels of the next and hold piece panels
+func Text(win *pixelgl.Window, scoreTxt, nextPieceTxt, holdPieceTxt *text.Text, uiScaleFactor float64, gs *game.GameState) {
+	// Update and draw score, or the lines left when playing a sprint, along
+	// with the level, lines cleared, time played and speed
+	scoreTxt.Clear()
+	if game.IsSprint(gs.Mode()) {
+		fmt.Fprintf(scoreTxt, "Lines remaining: %d\n", gs.SprintLinesLeft())
+	} else {
+		fmt.Fprintf(scoreTxt, "Score: %d\n", gs.Score())
+	}
+	fmt.Fprintf(scoreTxt, "Level: %d\nLines: %d", gs.Level(), gs.LinesCleared())
+
+	// Sprints are timed to the millisecond, ultra games show their countdown
+	// in the HUD instead
+	switch gs.Mode() {
+	case game.ModeMarathon, game.ModePractice:
+		fmt.Fprintf(scoreTxt, "\nTime: %s", formatStopwatch(gs.ElapsedTime()))
+	case game.ModeSprint, game.ModeDailyChallenge:
+		fmt.Fprintf(scoreTxt, "\nTime: %s", FormatTime(gs.ElapsedTime()))
+	}
+	linesPerMinute, piecesPerSecond := gs.Speed()
+	fmt.Fprintf(scoreTxt, "\nLPM %.1f  PPS %.1f", linesPerMinute, piecesPerSecond)
+	scoreTxt.Draw(win, pixel.IM.Scaled(scoreTxt.Orig, 2*uiScaleFactor))
+
+	// Draw static text for next and hold pieces
+	nextPieceTxt.Clear()
+	fmt.Fprintf(nextPieceTxt, "Next Piece:")
+	nextPieceTxt.Draw(win, pixel.IM.Scaled(nextPieceTxt.Orig, uiScaleFactor))
+
+	holdPieceTxt.Clear()
+	fmt.Fprintf(holdPieceTxt, "Hold Piece:")
+	if gs.CanHold() {
+		holdPieceTxt.Draw(win, pixel.IM.Scaled(holdPieceTxt.Orig, uiScaleFactor))
+	} else {
+		holdPieceTxt.DrawColorMask(win, pixel.IM.Scaled(holdPieceTxt.Orig, uiScaleFactor), holdLockedMask)
+	}
+}
+
+// ReplayIndicator marks the top right corner of the window while a
+// replay is being played back
+func ReplayIndicator(win *pixelgl.Window, atlas *text.Atlas, uiScaleFactor float64) {
+	topRight := win.Bounds().Max.Sub(pixel.V(20*uiScaleFactor, 20*uiScaleFactor))
+
+	txt := text.New(pixel.ZV, atlas)
+	txt.Color = colornames.Red
+	fmt.Fprint(txt, "REPLAY")
+	width := txt.Bounds().W() * 1.5 * uiScaleFactor
+	txt.Draw(win, pixel.IM.Scaled(pixel.ZV, 1.5*uiScaleFactor).Moved(topRight.Sub(pixel.V(width, 6*uiScaleFactor))))
+
+	// Recording dot to the left of the text
+	imd := imdraw.New(nil)
+	imd.Color = colornames.Red
+	imd.Push(topRight.Sub(pixel.V(width+10*uiScaleFactor, 0)))
+	imd.Circle(5*uiScaleFactor, 0)
+	imd.Draw(win)
+}
+
+// AchievementToast tells the player in the top right corner of the window
+// that the achievement called name was just unlocked
+func AchievementToast(win *pixelgl.Window, atlas *text.Atlas, name string, uiScaleFactor float64) {
+	topRight := win.Bounds().Max.Sub(pixel.V(10*uiScaleFactor, 10*uiScaleFactor))
+
+	txt := text.New(pixel.ZV, atlas)
+	txt.Color = colornames.Gold
+	fmt.Fprintln(txt, "ACHIEVEMENT UNLOCKED")
+	txt.Color = colornames.White
+	fmt.Fprint(txt, name)
+	size := txt.Bounds().Size().Scaled(1.2 * uiScaleFactor)
+	padding := 6 * uiScaleFactor
+
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGBA{A: 0.7}
+	imd.Push(topRight.Sub(size).Sub(pixel.V(2*padding, 2*padding)), topRight)
+	imd.Rectangle(0)
+	imd.Color = colornames.Gold
+	imd.Push(topRight.Sub(size).Sub(pixel.V(2*padding, 2*padding)), topRight)
+	imd.Rectangle(uiScaleFactor)
+	imd.Draw(win)
+
+	// The text is drawn from the top left of the box, down from the
+	// baseline of its first line
+	origin := pixel.V(topRight.X-size.X-padding, topRight.Y-padding-atlas.Ascent()*1.2*uiScaleFactor)
+	txt.Draw(win, pixel.IM.Scaled(pixel.ZV, 1.2*uiScaleFactor).Moved(origin))
+}
+
+// DebugOverlay writes lines in the top left corner of the window over a
+// dark box so they can be read over anything
+func DebugOverlay(win *pixelgl.Window, atlas *text.Atlas, lines []string, uiScaleFactor float64) {
+	topLeft := pixel.V(10*uiScaleFactor, win.Bounds().H()-10*uiScaleFactor)
+
+	txt := text.New(pixel.ZV, atlas)
+	txt.Color = colornames.Lime
+	for _, line := range lines {
+		fmt.Fprintln(txt, line)
+	}
+	size := txt.Bounds().Size().Scaled(uiScaleFactor)
+	padding := 4 * uiScaleFactor
+
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGBA{A: 0.7}
+	imd.Push(topLeft, topLeft.Add(pixel.V(size.X+2*padding, -size.Y-2*padding)))
+	imd.Rectangle(0)
+	imd.Draw(win)
+
+	origin := topLeft.Add(pixel.V(padding, -padding-atlas.Ascent()*uiScaleFactor))
+	txt.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(origin))
+}
+
+// FormatTime formats a time in seconds as mm:ss.mmm
+func FormatTime(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	secs := d / time.Second
+	d -= secs * time.Second
+	return fmt.Sprintf("%02d:%02d.%03d", minutes, secs, d/time.Millisecond)
+}
+
+// formatStopwatch formats a time in seconds as mm:ss, dropping the fraction
+// of a second
+func formatStopwatch(seconds float64) string {
+	secs := int(seconds)
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
+
+// HUD shows information about the current game to the right of the
+// board, below the score.
+func HUD(win *pixelgl.Window, atlas *text.Atlas, gs *game.GameState, uiScaleFactor, xOffset, yOffset float64) {
+	hudY := 250.0
+
+	// Whatever the mode shows comes first, with the rest of the HUD below it
+	for _, item := range gs.Mode().HUDExtras(gs) {
+		itemTxt := text.New(pixel.V(item.X*uiScaleFactor+xOffset, item.Y*uiScaleFactor+yOffset), atlas)
+		itemTxt.Color = item.Color
+		fmt.Fprint(itemTxt, item.Text)
+		itemTxt.Draw(win, pixel.IM.Scaled(itemTxt.Orig, item.Scale*uiScaleFactor))
+		hudY = math.Min(hudY, item.Y-16*item.Scale)
+	}
+
+	// Combos of 2 or more fade out after the last clear that extended them
+	// and grow with the combo
+	if combo, fade := gs.Combo(); fade > 0 {
+		comboTxt := text.New(pixel.V(500*uiScaleFactor+xOffset, hudY*uiScaleFactor+yOffset), atlas)
+		fmt.Fprintf(comboTxt, "COMBO x %d", combo)
+		scale := 1.5 * math.Min(1+float64(combo)*0.05, 2)
+		comboTxt.DrawColorMask(win, pixel.IM.Scaled(comboTxt.Orig, scale*uiScaleFactor), pixel.ToRGBA(colornames.Orange).Mul(pixel.Alpha(fade)))
+		hudY -= comboTxt.LineHeight * scale
+	}
+
+	if btbActive, btbCount := gs.BackToBack(); btbActive {
+		btbTxt := text.New(pixel.V(500*uiScaleFactor+xOffset, hudY*uiScaleFactor+yOffset), atlas)
+		btbTxt.Color = colornames.Gold
+		if btbCount > 0 {
+			fmt.Fprintf(btbTxt, "B2B x %d", btbCount)
+		} else {
+			fmt.Fprint(btbTxt, "B2B")
+		}
+		btbTxt.Draw(win, pixel.IM.Scaled(btbTxt.Orig, 1.5*uiScaleFactor))
+	}
+
+	// The seed sits at the bottom so players can share the piece sequence
+	seedTxt := text.New(pixel.V(500*uiScaleFactor+xOffset, 30*uiScaleFactor+yOffset), atlas)
+	seedTxt.Color = colornames.Lightgray
+	fmt.Fprintf(seedTxt, "Seed: %s", shortSeed(gs.Seed()))
+	seedTxt.Draw(win, pixel.IM.Scaled(seedTxt.Orig, uiScaleFactor))
+}
+
+// shortSeed returns the last 6 digits of seed, which is enough to tell
+// games apart at a glance
+func shortSeed(seed int64) string {
+	digits := strconv.FormatInt(seed, 10)
+	if len(digits) > 6 {
+		digits = digits[len(digits)-6:]
+	}
+	return digits
+}
+
+// ScorePopups draws the scores of recent line clears centered on their
+// position over the board.
+func ScorePopups(win *pixelgl.Window, atlas *text.Atlas, gs *game.GameState, uiScaleFactor, xOffset, yOffset float64) {
+	size := blockSize(gs.Rows(), gs.Cols())
+	origin := boardOrigin(gs.Rows(), gs.Cols())
+	for _, p := range gs.ScorePopups() {
+		txt := text.New(pixel.ZV, atlas)
+		txt.Dot.X -= txt.BoundsOf(p.Text).W() / 2
+		fmt.Fprint(txt, p.Text)
+		pos := origin.Add(pixel.V(p.X, p.Y).Scaled(size)).Scaled(uiScaleFactor).Add(pixel.V(xOffset, yOffset))
+		txt.DrawColorMask(win, pixel.IM.Scaled(pixel.ZV, 1.5*uiScaleFactor).Moved(pos), pixel.Alpha(p.Alpha))
+	}
+}
+
+// Overlay darkens the game board and writes lines of text centered on
+// top of it. Used for the pause and game over screens.
+func Overlay(win *pixelgl.Window, atlas *text.Atlas, lines []string, uiScaleFactor, xOffset, yOffset float64) {
+	boardMin := pixel.V(282.0*uiScaleFactor+xOffset, 25.0*uiScaleFactor+yOffset)
+	boardMax := boardMin.Add(pixel.V(200*uiScaleFactor, 400*uiScaleFactor))
+
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGBA{A: 0.7}
+	imd.Push(boardMin, boardMax)
+	imd.Rectangle(0)
+	imd.Draw(win)
+
+	center := boardMin.Add(boardMax).Scaled(0.5)
+	CenteredText(win, atlas, lines, 1.5*uiScaleFactor, center)
+}
+
+// CenteredText writes lines of text centered on the point center
+func CenteredText(win *pixelgl.Window, atlas *text.Atlas, lines []string, scale float64, center pixel.Vec) {
+	txt := text.New(pixel.ZV, atlas)
+	for _, line := range lines {
+		txt.Dot.X -= txt.BoundsOf(line).W() / 2
+		fmt.Fprintln(txt, line)
+	}
+	center.Y += txt.Bounds().H() / 2 * scale
+	txt.Draw(win, pixel.IM.Scaled(pixel.ZV, scale).Moved(center))
+}
+
+// NextPieces shows the upcoming pieces in a vertical stack, the
+// piece that comes next at the top, in the colors of theme. In colorblind
+// mode the blocks have the pattern of their piece.
+func NextPieces(win *pixelgl.Window, nextPieces [game.NextQueueLength]game.Piece, theme Theme, colorblind bool, uiScaleFactor float64, xOffset, yOffset float64) {
+	blockSize := 15.0 * uiScaleFactor
+
+	initialFirstSlotY := 248.0
+	const slotHeight = 48.0
+
+	for slot, piece := range nextPieces {
+		baseShape := game.PieceShape(piece)
+		pic, mask := blockPicture(theme, game.PieceBlock(piece))
+		sprite := pixel.NewSprite(pic, pic.Bounds())
+
+		// Center the piece in its slot using its bounding box
+		minRow, maxRow, minCol, maxCol := baseShape[0].Row(), baseShape[0].Row(), baseShape[0].Col(), baseShape[0].Col()
+		for i := 1; i < 4; i++ {
+			r, c := baseShape[i].Row(), baseShape[i].Col()
+			if r < minRow {
+				minRow = r
+			}
+			if r > maxRow {
+				maxRow = r
+			}
+			if c < minCol {
+				minCol = c
+			}
+			if c > maxCol {
+				maxCol = c
+			}
+		}
+		centerRow := float64(minRow+maxRow) / 2
+		centerCol := float64(minCol+maxCol) / 2
+		slotCenter := pixel.V(nextPieceX*uiScaleFactor+xOffset, (initialFirstSlotY-float64(slot)*slotHeight)*uiScaleFactor+yOffset)
+
+		for i := 0; i < 4; i++ {
+			x := (float64(baseShape[i].Col()) - centerCol) * blockSize
+			y := (float64(baseShape[i].Row()) - centerRow) * blockSize
+			if theme.Solid() {
+				imd := imdraw.New(nil)
+				pushSolidBlock(imd, theme, game.PieceBlock(piece), slotCenter.Add(pixel.V(x, y)), blockSize)
+				imd.Draw(win)
+			} else {
+				sprite.DrawColorMask(win, blockMatrix(sprite.Frame(), blockSize).Moved(slotCenter.Add(pixel.V(x, y))), mask)
+			}
+			if colorblind {
+				drawPattern(win, game.PieceBlock(piece), slotCenter.Add(pixel.V(x, y)), blockSize)
+			}
+		}
+	}
+}
+
+// HoldPiece shows the held piece, greyed out when canHold is false to
+// show that it can't be swapped until the next piece, in the colors of
+// theme. In colorblind mode the blocks have the pattern of the piece.
+func HoldPiece(win *pixelgl.Window, holdPiece game.Piece, canHold bool, theme Theme, colorblind bool, uiScaleFactor float64, xOffset, yOffset float64) {
+	if holdPiece == game.NoPiece {
+		return
+	}
+
+	// Display hold piece
+	baseShape := game.PieceShape(holdPiece)
+	pic, mask := blockPicture(theme, game.PieceBlock(holdPiece))
+	sprite := pixel.NewSprite(pic, pic.Bounds())
+	boardBlockSize := 20.0 * uiScaleFactor
+	shapeWidth := game.ShapeWidth(baseShape) + 1
+	shapeHeight := 2
+
+	// Draw the hold piece background with scaling
+	holdPiecePos := pixel.V(holdPieceX*uiScaleFactor+xOffset, holdPieceY*uiScaleFactor+yOffset)
+	if !theme.Solid() {
+		holdPieceBGSprite.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(holdPiecePos))
+	}
+
+	for i := 0; i < 4; i++ {
+		r := baseShape[i].Row()
+		c := baseShape[i].Col()
+		x := float64(c)*boardBlockSize + boardBlockSize/2
+		y := float64(r)*boardBlockSize + boardBlockSize/2
+
+		// Position calculation with scaling and offset
+		posX := x + holdPieceX*uiScaleFactor - (float64(shapeWidth) * 10 * uiScaleFactor) + xOffset
+		posY := y + holdPieceY*uiScaleFactor - (float64(shapeHeight) * 10 * uiScaleFactor) + yOffset
+
+		matrix := blockMatrix(sprite.Frame(), boardBlockSize).Moved(pixel.V(posX, posY))
+		if theme.Solid() {
+			imd := imdraw.New(nil)
+			pushSolidBlock(imd, theme, game.PieceBlock(holdPiece), pixel.V(posX, posY), boardBlockSize)
+			imd.SetColorMask(holdMask(pixel.Alpha(1), canHold))
+			imd.Draw(win)
+		} else {
+			sprite.DrawColorMask(win, matrix, holdMask(mask, canHold))
+		}
+		if colorblind {
+			drawPattern(win, game.PieceBlock(holdPiece), pixel.V(posX, posY), boardBlockSize)
+		}
+	}
+}
+
+// holdMask returns the color mask the held piece is drawn with, the mask of
+// its blocks, greyed out by holdLockedMask when canHold is false
+func holdMask(mask pixel.RGBA, canHold bool) pixel.RGBA {
+	if canHold {
+		return mask
+	}
+	return mask.Mul(holdLockedMask)
+}
+
+// Layout of the speed bar left of the panels at a UI scale of 1
+const (
+	speedBarX      = 55.0
+	speedBarY      = 120.0
+	speedBarWidth  = 14.0
+	speedBarHeight = 200.0
+)
+
+// The gravity of the speed bar when it is full and empty, in seconds between
+// gravity steps
+const (
+	slowGravity = 0.8
+	fastGravity = 0.1
+)
+
+// speedFill returns how full the speed bar is for gravity steps the given
+// seconds apart, from 1 at slowGravity down to 0 at fastGravity and beyond
+func speedFill(gravity float64) float64 {
+	return math.Max(0, math.Min((gravity-fastGravity)/(slowGravity-fastGravity), 1))
+}
+
+// SpeedBar draws the speed of gravity as a bar that empties and turns from
+// green to red as the game speeds up, with the level below it
+func SpeedBar(win *pixelgl.Window, atlas *text.Atlas, gs *game.GameState, uiScaleFactor, xOffset, yOffset float64) {
+	fill := speedFill(gs.GravitySpeed())
+	min := pixel.V(speedBarX*uiScaleFactor+xOffset, speedBarY*uiScaleFactor+yOffset)
+	max := min.Add(pixel.V(speedBarWidth, speedBarHeight).Scaled(uiScaleFactor))
+
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGBA{A: 0.6}
+	imd.Push(min, max)
+	imd.Rectangle(0)
+	imd.Color = pixel.RGB(1, 0, 0).Scaled(1 - fill).Add(pixel.RGB(0, 1, 0).Scaled(fill))
+	imd.Push(min, pixel.V(max.X, min.Y+fill*(max.Y-min.Y)))
+	imd.Rectangle(0)
+	imd.Color = colornames.White
+	imd.Push(min, max)
+	imd.Rectangle(1)
+	imd.Draw(win)
+
+	txt := text.New(pixel.ZV, atlas)
+	fmt.Fprintf(txt, "Spd: %d", gs.Level())
+	pos := pixel.V((min.X+max.X)/2-txt.Bounds().W()*uiScaleFactor/2, min.Y-20*uiScaleFactor)
+	txt.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(pos))
+}
+
+// Where the rotation indicator is drawn at a UI scale of 1, in the bottom
+// right corner of the hold piece panel
+const (
+	rotationIndicatorX = 218.0
+	rotationIndicatorY = 288.0
+)
+
+// blockMatrix scales a block sprite cut from frame to size pixels square.
+// Blocks are drawn square even when their tiles in the sprite sheet aren't.
+func blockMatrix(frame pixel.Rect, size float64) pixel.Matrix {
+	return pixel.IM.ScaledXY(pixel.ZV, pixel.V(size/frame.W(), size/frame.H()))
+}
+
+// RotationIndicator draws the rotation state of the active piece as its
+// number and an arrow pointing up for the spawn state and turning a quarter
+// clockwise for each state after it
+func RotationIndicator(win *pixelgl.Window, atlas *text.Atlas, rotationState int, uiScaleFactor, xOffset, yOffset float64) {
+	center := pixel.V(rotationIndicatorX*uiScaleFactor+xOffset, rotationIndicatorY*uiScaleFactor+yOffset)
+
+	txt := text.New(center.Sub(pixel.V(20*uiScaleFactor, 4*uiScaleFactor)), atlas)
+	fmt.Fprint(txt, rotationState)
+	txt.Draw(win, pixel.IM.Scaled(txt.Orig, uiScaleFactor))
+
+	dir := rotationArrow(rotationState, 7*uiScaleFactor)
+	side := dir.Normal().Scaled(0.6)
+	imd := imdraw.New(nil)
+	imd.Color = colornames.White
+	imd.Push(center.Sub(dir), center.Add(dir.Scaled(0.2)))
+	imd.Line(2 * uiScaleFactor)
+	imd.Push(center.Add(dir), center.Add(dir.Scaled(0.2)).Add(side), center.Add(dir.Scaled(0.2)).Sub(side))
+	imd.Polygon(0)
+	imd.Draw(win)
+}
+
+// rotationArrow returns the arrow of the rotation indicator for
+// rotationState, from its center to its tip, which is length long
+func rotationArrow(rotationState int, length float64) pixel.Vec {
+	return pixel.V(0, length).Rotated(-math.Pi / 2 * float64(rotationState))
+}
+
+// block2spriteIdx associates a blocks color (b Block) with its index in the sprite sheet.
+func block2spriteIdx(b game.Block) int {
+	return int(b) - 1
+}
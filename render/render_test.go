@@ -0,0 +1,90 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestFormatTime(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00.000"},
+		{0.0004, "00:00.000"},
+		{1.5, "00:01.500"},
+		{59.999, "00:59.999"},
+		{59.9996, "01:00.000"},
+		{60.0, "01:00.000"},
+		{61.25, "01:01.250"},
+		{3599.999, "59:59.999"},
+	}
+	for _, tt := range tests {
+		if got := FormatTime(tt.seconds); got != tt.want {
+			t.Errorf("FormatTime(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestFormatStopwatch(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00"},
+		{59.999, "00:59"},
+		{60.0, "01:00"},
+		{125.7, "02:05"},
+	}
+	for _, tt := range tests {
+		if got := formatStopwatch(tt.seconds); got != tt.want {
+			t.Errorf("formatStopwatch(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestHoldMask(t *testing.T) {
+	mask := pixel.RGB(1, 0.5, 0)
+	if got := holdMask(mask, true); got != mask {
+		t.Errorf("holdMask(%v, true) = %v, want the mask unchanged", mask, got)
+	}
+	if got, want := holdMask(mask, false), (pixel.RGBA{R: 0.4, G: 0.2, B: 0, A: 1}); got != want {
+		t.Errorf("holdMask(%v, false) = %v, want %v", mask, got, want)
+	}
+	if got := holdMask(pixel.Alpha(1), false); got != holdLockedMask {
+		t.Errorf("holdMask(white, false) = %v, want %v", got, holdLockedMask)
+	}
+}
+
+func TestRotationArrow(t *testing.T) {
+	// Up for the spawn state, then a quarter turn clockwise for each state
+	want := [4]pixel.Vec{pixel.V(0, 7), pixel.V(7, 0), pixel.V(0, -7), pixel.V(-7, 0)}
+	for state, w := range want {
+		if got := rotationArrow(state, 7); got.Sub(w).Len() > 1e-9 {
+			t.Errorf("rotationArrow(%d, 7) = %v, want %v", state, got, w)
+		}
+	}
+}
+
+func TestSpeedFill(t *testing.T) {
+	tests := []struct {
+		gravity float64
+		want    float64
+	}{
+		{slowGravity, 1},
+		{1.5, 1},
+		{0.45, 0.5},
+		{fastGravity, 0},
+		{0.01, 0},
+	}
+	for _, tt := range tests {
+		if got := speedFill(tt.gravity); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("speedFill(%v) = %v, want %v", tt.gravity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+//go:build !wasm
+// +build !wasm
+
+package render
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// rotationNames are the guideline names of the rotation states, indexed by
+// rotation state
+var rotationNames = [4]string{"0", "R", "2", "L"}
+
+// moveHistoryLength is the number of pieces the move history shows
+const moveHistoryLength = 10
+
+// Layout of the piece histogram in the top left corner at a UI scale of 1
+const (
+	histogramX         = 15.0  // Left edge of the first bar
+	histogramY         = 360.0 // Bottom of the bars
+	histogramBarWidth  = 12.0
+	histogramBarGap    = 3.0
+	histogramBarHeight = 50.0 // Height of the tallest bar
+)
+
+// PieceHistogram draws a small bar chart of the pieces placed in the top
+// left corner of the window, each bar in the color of its piece with its
+// letter below and its count above
+func PieceHistogram(win *pixelgl.Window, atlas *text.Atlas, stats game.Stats, uiScaleFactor, xOffset, yOffset float64) {
+	maxPlaced := 1
+	for _, n := range stats.PiecesPlaced {
+		if n > maxPlaced {
+			maxPlaced = n
+		}
+	}
+
+	// Labels are laid out at a scale of 1 and scaled with the UI
+	offset := pixel.V(xOffset, yOffset).Scaled(1 / uiScaleFactor)
+	imd := imdraw.New(nil)
+	labels := text.New(pixel.ZV, atlas)
+	for p, n := range stats.PiecesPlaced {
+		x := histogramX + float64(p)*(histogramBarWidth+histogramBarGap)
+		height := histogramBarHeight * float64(n) / float64(maxPlaced)
+		min := pixel.V(x*uiScaleFactor+xOffset, histogramY*uiScaleFactor+yOffset)
+		imd.Color = pieceColors[p]
+		imd.Push(min, min.Add(pixel.V(histogramBarWidth, height).Scaled(uiScaleFactor)))
+		imd.Rectangle(0)
+
+		center := x + histogramBarWidth/2
+		count := fmt.Sprint(n)
+		labels.Dot = pixel.V(center-labels.BoundsOf(pieceNames[p]).W()/2, histogramY-12).Add(offset)
+		fmt.Fprint(labels, pieceNames[p])
+		labels.Dot = pixel.V(center-labels.BoundsOf(count).W()/2, histogramY+height+3).Add(offset)
+		fmt.Fprint(labels, count)
+	}
+	imd.Draw(win)
+	labels.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor))
+}
+
+// Size of the score graph at a UI scale of 1
+const (
+	scoreGraphWidth  = 200.0
+	scoreGraphHeight = 80.0
+)
+
+// Stats darkens the whole window and shows the statistics of the
+// game, with a bar chart of the pieces placed, a graph of the score
+// over time and the last pieces placed.
+func Stats(win *pixelgl.Window, atlas *text.Atlas, stats game.Stats, scores []game.ScorePoint, placements *game.PlacementHistory, uiScaleFactor float64) {
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGBA{A: 0.85}
+	imd.Push(win.Bounds().Min, win.Bounds().Max)
+	imd.Rectangle(0)
+	imd.Draw(win)
+
+	center := win.Bounds().Center()
+	CenteredText(win, atlas, []string{"STATISTICS"}, 2*uiScaleFactor, center.Add(pixel.V(0, 170*uiScaleFactor)))
+
+	// Bar chart of the pieces placed, scaled to the most placed piece
+	maxPlaced := 1
+	for _, n := range stats.PiecesPlaced {
+		if n > maxPlaced {
+			maxPlaced = n
+		}
+	}
+	const barMaxWidth = 150.0
+	const barHeight = 16.0
+	chartOrigin := center.Add(pixel.V(-260*uiScaleFactor, 110*uiScaleFactor))
+	bars := imdraw.New(nil)
+	labels := text.New(pixel.ZV, atlas)
+	for p, n := range stats.PiecesPlaced {
+		y := chartOrigin.Y - float64(p)*(barHeight+6)*uiScaleFactor
+
+		pic := blockGen(block2spriteIdx(game.PieceBlock(game.Piece(p))))
+		sprite := pixel.NewSprite(pic, pic.Bounds())
+		blockPos := pixel.V(chartOrigin.X, y+barHeight/2*uiScaleFactor)
+		sprite.Draw(win, blockMatrix(sprite.Frame(), barHeight*uiScaleFactor).Moved(blockPos))
+
+		barMin := pixel.V(chartOrigin.X+15*uiScaleFactor, y)
+		width := barMaxWidth * float64(n) / float64(maxPlaced) * uiScaleFactor
+		bars.Color = pixel.RGB(0.8, 0.8, 0.8)
+		bars.Push(barMin, barMin.Add(pixel.V(width, barHeight*uiScaleFactor)))
+		bars.Rectangle(0)
+
+		labels.Dot = pixel.V(barMin.X+width+5*uiScaleFactor, y+3*uiScaleFactor).Scaled(1 / uiScaleFactor)
+		fmt.Fprintf(labels, "%s %d", pieceNames[p], n)
+	}
+	bars.Draw(win)
+	labels.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor))
+
+	// Counters next to the chart
+	counters := text.New(center.Add(pixel.V(40*uiScaleFactor, 110*uiScaleFactor)), atlas)
+	fmt.Fprintf(counters, "Singles:    %d\n", stats.LineClears[1])
+	fmt.Fprintf(counters, "Doubles:    %d\n", stats.LineClears[2])
+	fmt.Fprintf(counters, "Triples:    %d\n", stats.LineClears[3])
+	fmt.Fprintf(counters, "Tetrises:   %d\n", stats.LineClears[4])
+	fmt.Fprintf(counters, "T-spins:    %d (%d mini)\n", stats.TSpins, stats.TSpinMinis)
+	fmt.Fprintf(counters, "Combos:     %d\n", stats.Combos)
+	fmt.Fprintf(counters, "Max combo:  %d\n", stats.MaxCombo)
+	fmt.Fprintf(counters, "Rotations:  %d\n", stats.Rotations)
+	fmt.Fprintf(counters, "Holds:      %d\n", stats.Holds)
+	fmt.Fprintf(counters, "Hard drops: %d\n", stats.HardDrops)
+	fmt.Fprintf(counters, "Max height: %d\n", stats.MaxHeight)
+	fmt.Fprintf(counters, "Finesse:    %d (%.1f%%)\n", stats.FinesseErrors, stats.FinesseErrorRate())
+	fmt.Fprintf(counters, "Efficiency: %.2f (last 10)\n", placements.Efficiency())
+	counters.Draw(win, pixel.IM.Scaled(counters.Orig, 1.3*uiScaleFactor))
+
+	// The last pieces placed, newest first, with their rotation, column
+	// and the lines they cleared
+	history := text.New(center.Add(pixel.V(290*uiScaleFactor, 110*uiScaleFactor)), atlas)
+	fmt.Fprintln(history, "Last moves")
+	last := placements.Last(moveHistoryLength)
+	for i := len(last) - 1; i >= 0; i-- {
+		p := last[i]
+		fmt.Fprintf(history, "%s %s c%-2d", pieceNames[p.Piece], rotationNames[p.RotationState], p.Col)
+		if p.LinesCleared > 0 {
+			fmt.Fprintf(history, " +%d", p.LinesCleared)
+		}
+		fmt.Fprintln(history)
+	}
+	history.Draw(win, pixel.IM.Scaled(history.Orig, uiScaleFactor))
+
+	scoreGraph(win, atlas, scores, center.Add(pixel.V(-260*uiScaleFactor, -140*uiScaleFactor)), uiScaleFactor)
+
+	CenteredText(win, atlas, []string{"Press S to close"}, uiScaleFactor, center.Sub(pixel.V(0, 170*uiScaleFactor)))
+}
+
+// scoreGraph draws the score over time as a cyan line in a box with its
+// bottom left corner at origin, labelled with the highest score. Time runs
+// from the first point to the last across the box.
+func scoreGraph(win *pixelgl.Window, atlas *text.Atlas, scores []game.ScorePoint, origin pixel.Vec, uiScaleFactor float64) {
+	size := pixel.V(scoreGraphWidth, scoreGraphHeight).Scaled(uiScaleFactor)
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGB(0.5, 0.5, 0.5)
+	imd.Push(origin, origin.Add(size))
+	imd.Rectangle(1)
+
+	maxScore := 0
+	for _, p := range scores {
+		if p.Score > maxScore {
+			maxScore = p.Score
+		}
+	}
+	if len(scores) >= 2 {
+		start, end := scores[0].Time, scores[len(scores)-1].Time
+		imd.Color = pixel.RGB(0, 1, 1)
+		for _, p := range scores {
+			x, y := 0.0, 0.0
+			if end > start {
+				x = (p.Time - start) / (end - start)
+			}
+			if maxScore > 0 {
+				y = float64(p.Score) / float64(maxScore)
+			}
+			imd.Push(origin.Add(pixel.V(x*size.X, y*size.Y)))
+		}
+		imd.Line(2 * uiScaleFactor)
+	}
+	imd.Draw(win)
+
+	label := text.New(pixel.ZV, atlas)
+	fmt.Fprint(label, maxScore)
+	pos := origin.Add(pixel.V(-label.Bounds().W()*uiScaleFactor-4*uiScaleFactor, size.Y-10*uiScaleFactor))
+	label.Draw(win, pixel.IM.Scaled(pixel.ZV, uiScaleFactor).Moved(pos))
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+)
+
+// Theme chooses the colors the pieces are drawn in
+type Theme interface {
+	// BlockColor returns the color of the blocks of piece p
+	BlockColor(p game.Piece) pixel.RGBA
+	// GhostColor returns the color the ghost piece is drawn in
+	GhostColor() pixel.RGBA
+	// Solid reports whether blocks are drawn as squares of their BlockColor,
+	// the ghost as an outline and the board on opaque black, rather than
+	// with the block sprites on the background images
+	Solid() bool
+}
+
+// DefaultTheme draws the pieces with the block sprites and a see-through
+// ghost piece
+type DefaultTheme struct {
+	GhostOpacity float64 // Opacity of the ghost piece from 0 to 1
+}
+
+// BlockColor returns the color most of a block sprite of piece p is
+func (DefaultTheme) BlockColor(p game.Piece) pixel.RGBA { return pieceColors[p] }
+
+// GhostColor masks the ghost piece to GhostOpacity
+func (t DefaultTheme) GhostColor() pixel.RGBA {
+	return pixel.RGBA{R: 1, G: 1, B: 1, A: t.GhostOpacity}
+}
+
+// Solid implements Theme
+func (DefaultTheme) Solid() bool { return false }
+
+// HighContrastTheme draws each piece in a primary color on jet black, with
+// the ghost piece outlined in white
+type HighContrastTheme struct{}
+
+// highContrastColors are the colors of the pieces in HighContrastTheme,
+// indexed by Piece
+var highContrastColors = [7]pixel.RGBA{
+	game.IPiece: pixel.RGB(0, 1, 1),
+	game.JPiece: pixel.RGB(0, 0, 1),
+	game.LPiece: pixel.RGB(1, 0.5, 0),
+	game.OPiece: pixel.RGB(1, 1, 0),
+	game.SPiece: pixel.RGB(0, 1, 0),
+	game.TPiece: pixel.RGB(1, 0, 1),
+	game.ZPiece: pixel.RGB(1, 0, 0),
+}
+
+// BlockColor implements Theme
+func (HighContrastTheme) BlockColor(p game.Piece) pixel.RGBA { return highContrastColors[p] }
+
+// GhostColor implements Theme
+func (HighContrastTheme) GhostColor() pixel.RGBA { return pixel.RGB(1, 1, 1) }
+
+// Solid implements Theme
+func (HighContrastTheme) Solid() bool { return true }
+
+// ThemeFor returns the theme chosen in settings
+func ThemeFor(settings config.Settings) Theme {
+	if settings.Theme == config.ThemeHighContrast {
+		return HighContrastTheme{}
+	}
+	return DefaultTheme{GhostOpacity: settings.GhostOpacity}
+}
+
+// pieceNames are the letters the pieces are known by, indexed by Piece
+var pieceNames = [7]string{"I", "J", "L", "O", "S", "T", "Z"}
+
+// pieceColors are the colors of the blocks of each piece, indexed by Piece
+var pieceColors = [7]pixel.RGBA{
+	game.IPiece: pixel.RGB(0.2, 0.3, 1),
+	game.JPiece: pixel.RGB(0.3, 0.9, 0.3),
+	game.LPiece: pixel.RGB(0.4, 0.8, 1),
+	game.OPiece: pixel.RGB(1, 0.5, 0.8),
+	game.SPiece: pixel.RGB(1, 0.2, 0.2),
+	game.TPiece: pixel.RGB(0.6, 0.3, 0.9),
+	game.ZPiece: pixel.RGB(1, 0.9, 0.2),
+}
+
+// blockPiece returns the piece whose blocks are b, ok being false for
+// blocks that aren't of a piece, such as garbage
+func blockPiece(b game.Block) (p game.Piece, ok bool) {
+	for p := game.IPiece; p <= game.ZPiece; p++ {
+		if game.PieceBlock(p) == b {
+			return p, true
+		}
+	}
+	return game.NoPiece, false
+}
+
+// blockColor returns the color of block b in theme, white for blocks that
+// aren't of a piece such as garbage
+func blockColor(theme Theme, b game.Block) pixel.RGBA {
+	if p, ok := blockPiece(b); ok {
+		return theme.BlockColor(p)
+	}
+	return pixel.RGB(1, 1, 1)
+}
+
+// pushSolidBlock adds a square of size pixels centered on center in the
+// color of block b to imd, outlined in black so neighbouring blocks of the
+// same color can still be told apart
+func pushSolidBlock(imd *imdraw.IMDraw, theme Theme, b game.Block, center pixel.Vec, size float64) {
+	half := pixel.V(size/2, size/2)
+	imd.Color = blockColor(theme, b)
+	imd.Push(center.Sub(half), center.Add(half))
+	imd.Rectangle(0)
+	imd.Color = pixel.RGB(0, 0, 0)
+	imd.Push(center.Sub(half), center.Add(half))
+	imd.Rectangle(1)
+}
@@ -0,0 +1,51 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+)
+
+func TestGhostOpacity(t *testing.T) {
+	for _, opacity := range []float64{0, 0.25, 0.4, 1} {
+		settings := config.DefaultSettings()
+		settings.GhostOpacity = opacity
+		if got := ThemeFor(settings).GhostColor(); got.A != opacity || got.R != 1 || got.G != 1 || got.B != 1 {
+			t.Errorf("ghost color with opacity %v = %v, want white at that opacity", opacity, got)
+		}
+	}
+}
+
+func TestHighContrastColors(t *testing.T) {
+	// Every piece in its own opaque color, none of them the black of the
+	// board
+	var theme HighContrastTheme
+	seen := make(map[pixel.RGBA]game.Piece)
+	for p := game.IPiece; p <= game.ZPiece; p++ {
+		c := theme.BlockColor(p)
+		if other, ok := seen[c]; ok {
+			t.Errorf("pieces %s and %s are both %v", pieceNames[other], pieceNames[p], c)
+		}
+		seen[c] = p
+		if c.A != 1 || c == pixel.RGB(0, 0, 0) {
+			t.Errorf("piece %s is %v, want an opaque color that isn't black", pieceNames[p], c)
+		}
+	}
+	if got := theme.GhostColor(); got != pixel.RGB(1, 1, 1) {
+		t.Errorf("ghost color = %v, want opaque white", got)
+	}
+}
+
+func TestThemeFor(t *testing.T) {
+	settings := config.DefaultSettings()
+	if theme := ThemeFor(settings); theme.Solid() {
+		t.Errorf("ThemeFor(default settings) = %T, want sprites", theme)
+	}
+	settings.Theme = config.ThemeHighContrast
+	if theme, ok := ThemeFor(settings).(HighContrastTheme); !ok || !theme.Solid() {
+		t.Errorf("ThemeFor(high contrast) = %T, want HighContrastTheme", ThemeFor(settings))
+	}
+}
@@ -0,0 +1,127 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+
+	"github.com/zkry/golang-tetris/game"
+	ss "github.com/zkry/golang-tetris/spritesheet"
+)
+
+// themeFile is a theme as written in its JSON file
+type themeFile struct {
+	Name            string    `json:"name"`
+	BlockColors     [7]string `json:"blockColors"`     // Hex colors of each piece, indexed by Piece
+	BoardBgColor    string    `json:"boardBgColor"`    // Hex color drawn over the board background, empty for none
+	GhostAlpha      float64   `json:"ghostAlpha"`      // Opacity of the ghost piece from 0 to 1
+	BackgroundImage string    `json:"backgroundImage"` // Image behind the game, relative to the theme file, empty for the mountains
+}
+
+// FileTheme is a theme loaded from a JSON file. Its blocks are the grey
+// block sprite tinted with the color of each piece.
+type FileTheme struct {
+	Name        string
+	blockColors [7]pixel.RGBA
+	boardColor  pixel.RGBA
+	ghostAlpha  float64
+	imagePath   string        // Path of the background image, empty for none
+	background  *pixel.Sprite // Loaded from imagePath by LoadThemes
+}
+
+// ParseTheme reads a theme from the contents of its JSON file. Colors are
+// written #rrggbb or #rrggbbaa.
+func ParseTheme(data []byte) (*FileTheme, error) {
+	var f themeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Name == "" {
+		return nil, fmt.Errorf("theme has no name")
+	}
+	if f.GhostAlpha < 0 || f.GhostAlpha > 1 {
+		return nil, fmt.Errorf("ghostAlpha must be between 0 and 1")
+	}
+	t := &FileTheme{Name: f.Name, ghostAlpha: f.GhostAlpha, imagePath: f.BackgroundImage}
+	for p, hex := range f.BlockColors {
+		c, err := parseHexColor(hex)
+		if err != nil {
+			return nil, fmt.Errorf("color of %s piece: %v", pieceNames[p], err)
+		}
+		t.blockColors[p] = c
+	}
+	if f.BoardBgColor != "" {
+		c, err := parseHexColor(f.BoardBgColor)
+		if err != nil {
+			return nil, fmt.Errorf("boardBgColor: %v", err)
+		}
+		t.boardColor = c
+	}
+	return t, nil
+}
+
+// parseHexColor reads a color written #rrggbb or #rrggbbaa, returning it
+// alpha premultiplied as pixel expects
+func parseHexColor(s string) (pixel.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == len(s) || (len(hex) != 6 && len(hex) != 8) {
+		return pixel.RGBA{}, fmt.Errorf("%q is not a color written #rrggbb or #rrggbbaa", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return pixel.RGBA{}, fmt.Errorf("%q is not a color written #rrggbb or #rrggbbaa", s)
+	}
+	channel := func(shift uint) float64 { return float64(v>>shift&0xff) / 255 }
+	return pixel.RGB(channel(24), channel(16), channel(8)).Mul(pixel.Alpha(channel(0))), nil
+}
+
+// LoadThemes loads every theme in the JSON files of dir, in the order of
+// their file names, along with their background images.
+func LoadThemes(dir string) ([]*FileTheme, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var themes []*FileTheme
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %v", path, err)
+		}
+		t, err := ParseTheme(data)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %v", path, err)
+		}
+		if t.imagePath != "" {
+			pic, err := ss.LoadPicture(filepath.Join(dir, t.imagePath))
+			if err != nil {
+				return nil, fmt.Errorf("loading %s: %v", path, err)
+			}
+			t.background = pixel.NewSprite(pic, pic.Bounds())
+		}
+		themes = append(themes, t)
+	}
+	return themes, nil
+}
+
+// BlockColor implements Theme
+func (t *FileTheme) BlockColor(p game.Piece) pixel.RGBA { return t.blockColors[p] }
+
+// GhostColor masks the ghost piece to the theme's ghost alpha
+func (t *FileTheme) GhostColor() pixel.RGBA {
+	return pixel.RGBA{R: 1, G: 1, B: 1, A: t.ghostAlpha}
+}
+
+// Solid implements Theme
+func (t *FileTheme) Solid() bool { return false }
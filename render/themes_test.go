@@ -0,0 +1,148 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		s    string
+		want pixel.RGBA
+	}{
+		{"#000000", pixel.RGB(0, 0, 0)},
+		{"#ffffff", pixel.RGB(1, 1, 1)},
+		{"#FF0000", pixel.RGB(1, 0, 0)},
+		{"#00ff00ff", pixel.RGB(0, 1, 0)},
+		{"#0000ff00", pixel.RGBA{}},
+		{"#ffffff80", pixel.RGBA{R: 128.0 / 255, G: 128.0 / 255, B: 128.0 / 255, A: 128.0 / 255}}, // Premultiplied
+		{"#336699", pixel.RGB(0x33/255.0, 0x66/255.0, 0x99/255.0)},
+	}
+	for _, tt := range tests {
+		got, err := parseHexColor(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("parseHexColor(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseHexColorMalformed(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"#",
+		"ffffff",   // No #
+		"#fff",     // Too short
+		"#fffffff", // Odd length
+		"#ffffffff0",
+		"#gggggg",
+		"#12 456",
+		"#-12345",
+		"#+1234567",
+		"##fffff",
+	} {
+		if c, err := parseHexColor(s); err == nil {
+			t.Errorf("parseHexColor(%q) = %v, want an error", s, c)
+		}
+	}
+}
+
+// themeJSON is a theme file with colors for fields that can be replaced
+const themeJSON = `{
+	"name": "Test",
+	"blockColors": ["#ff0000", "#00ff00", "#0000ff", "#ffff00", "#00ffff", "#ff00ff", "#ffffff"],
+	"boardBgColor": "#10101080",
+	"ghostAlpha": 0.25,
+	"backgroundImage": ""
+}`
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme([]byte(themeJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Name != "Test" || theme.Solid() || theme.imagePath != "" {
+		t.Errorf("ParseTheme() = %+v", theme)
+	}
+	if got := theme.BlockColor(game.JPiece); got != pixel.RGB(0, 1, 0) {
+		t.Errorf("BlockColor(J) = %v, want green", got)
+	}
+	if got := theme.GhostColor(); got != (pixel.RGBA{R: 1, G: 1, B: 1, A: 0.25}) {
+		t.Errorf("GhostColor() = %v, want white at 0.25", got)
+	}
+	if theme.boardColor.A != 128.0/255 {
+		t.Errorf("board color %v, want half opaque", theme.boardColor)
+	}
+}
+
+func TestParseThemeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string // Replaced in themeJSON
+		new  string
+		err  string // Part of the error
+	}{
+		{"malformed block color", `"#0000ff"`, `"#00zzff"`, "color of L piece"},
+		{"short block color", `"#ffffff"]`, `"#fff"]`, "color of Z piece"},
+		{"missing block color", `, "#ffffff"]`, `]`, "color of Z piece"},
+		{"malformed board color", `"#10101080"`, `"10101080"`, "boardBgColor"},
+		{"no name", `"Test"`, `""`, "no name"},
+		{"ghost alpha", `0.25`, `1.5`, "ghostAlpha"},
+		{"negative ghost alpha", `0.25`, `-0.1`, "ghostAlpha"},
+		{"bad JSON", `}`, ``, ""},
+		{"block color not a string", `"#ff0000"`, `16711680`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(themeJSON, tt.old, tt.new, 1)
+			_, err := ParseTheme([]byte(data))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseTheme() error = %v, want one about %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadThemes(t *testing.T) {
+	// The built in themes, in the order of their file names
+	themes, err := LoadThemes(filepath.Join("..", "resources", "themes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, theme := range themes {
+		names = append(names, theme.Name)
+	}
+	if got := strings.Join(names, " "); got != "Classic Monochrome Pastel" {
+		t.Errorf("LoadThemes() loaded %s, want Classic Monochrome Pastel", got)
+	}
+
+	// Classic is the colors of the sprites, to the nearest 8 bits
+	for p := game.IPiece; p <= game.ZPiece; p++ {
+		got, want := themes[0].BlockColor(p), pieceColors[p]
+		if d := got.Sub(want); d.R*d.R+d.G*d.G+d.B*d.B > 3*0.002*0.002 || got.A != 1 {
+			t.Errorf("Classic %s is %v, want %v", pieceNames[p], got, want)
+		}
+	}
+}
+
+func TestLoadThemesError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "themes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "broken.json")
+	if err := ioutil.WriteFile(path, []byte(strings.Replace(themeJSON, "#ff0000", "#red", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadThemes(dir); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("LoadThemes() error = %v, want one naming broken.json", err)
+	}
+}
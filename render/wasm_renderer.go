@@ -0,0 +1,195 @@
+//go:build wasm
+// +build wasm
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"syscall/js"
+
+	"github.com/faiface/pixel"
+
+	"github.com/zkry/golang-tetris/game"
+)
+
+// Canvas draws onto a <canvas> element of the page through its 2D context.
+// It stands in for the pixelgl window in the browser, where blocks are
+// drawn as colored rectangles instead of sprites.
+type Canvas struct {
+	el  js.Value
+	ctx js.Value
+}
+
+// NewCanvas returns the <canvas> element of the page with id
+func NewCanvas(id string) (*Canvas, error) {
+	el := js.Global().Get("document").Call("getElementById", id)
+	if el.IsNull() {
+		return nil, fmt.Errorf("no canvas with id %q on the page", id)
+	}
+	return &Canvas{el: el, ctx: el.Call("getContext", "2d")}, nil
+}
+
+// Width returns the width of the canvas in pixels
+func (c *Canvas) Width() float64 { return c.el.Get("width").Float() }
+
+// Height returns the height of the canvas in pixels
+func (c *Canvas) Height() float64 { return c.el.Get("height").Float() }
+
+// FillRect fills the rectangle w wide and h high with its top left corner
+// at (x, y) in color, a CSS color such as "#ff0000"
+func (c *Canvas) FillRect(x, y, w, h float64, color string) {
+	c.ctx.Set("fillStyle", color)
+	c.ctx.Call("fillRect", x, y, w, h)
+}
+
+// FillText writes s in color with the left of its baseline at (x, y), in a
+// monospace font size pixels high
+func (c *Canvas) FillText(s string, x, y, size float64, color string) {
+	c.ctx.Set("font", fmt.Sprintf("%.0fpx monospace", size))
+	c.ctx.Set("fillStyle", color)
+	c.ctx.Call("fillText", s, x, y)
+}
+
+// cssColor returns c, not alpha-premultiplied, as a CSS color
+func cssColor(c pixel.RGBA) string {
+	return fmt.Sprintf("rgba(%.0f, %.0f, %.0f, %.3g)", c.R*255, c.G*255, c.B*255, c.A)
+}
+
+// Layout of the canvas in blocks of the board. The hold piece and the
+// score are to the left of the board, the next pieces to the right.
+const (
+	canvasSideWidth = 5.0 // Width of each side of the board
+	previewScale    = 0.7 // Size of the blocks of the hold and next pieces
+	previewSlot     = 2.5 // Height of the space of each next piece
+)
+
+// Canvas colors
+const (
+	canvasBackground = "#000000"
+	canvasBoard      = "#141414"
+	canvasText       = "#ffffff"
+	canvasOutline    = "#000000"
+)
+
+// DrawGame draws gs onto c in theme, scaled to fit the canvas: the board
+// with the ghost piece when showGhost is set, the held and next pieces, the
+// score, level and lines, and what to press when the game is paused or over
+func DrawGame(c *Canvas, gs *game.GameState, theme Theme, showGhost bool) {
+	rows, cols := gs.Rows(), gs.Cols()
+	size := math.Floor(math.Min(c.Height()/float64(rows), c.Width()/(float64(cols)+2*canvasSideWidth)))
+	boardX := canvasSideWidth * size
+	c.FillRect(0, 0, c.Width(), c.Height(), canvasBackground)
+	c.FillRect(boardX, 0, float64(cols)*size, float64(rows)*size, canvasBoard)
+
+	// Row 0 is the bottom of the board while y grows down the canvas
+	cell := func(r, col int) (x, y float64) {
+		return boardX + float64(col)*size, float64(rows-1-r) * size
+	}
+	board := gs.Board()
+	for r := 0; r < rows; r++ {
+		for col := 0; col < cols; col++ {
+			if b := board.At(r, col); b != game.Empty {
+				x, y := cell(r, col)
+				drawBlock(c, blockColor(theme, b), x, y, size)
+			}
+		}
+	}
+	if showGhost && !gs.GameOver() {
+		// The ghost is the color of the piece, see-through
+		color := blockColor(theme, game.PieceBlock(gs.CurrentPiece()))
+		color.A = theme.GhostColor().A
+		for _, p := range gs.GhostPiece() {
+			if p.Row() >= rows || gs.IsPartOfActiveShape(p.Row(), p.Col()) {
+				continue
+			}
+			x, y := cell(p.Row(), p.Col())
+			if theme.Solid() {
+				drawOutline(c, cssColor(theme.GhostColor()), x, y, size)
+			} else {
+				c.FillRect(x, y, size, size, cssColor(color))
+			}
+		}
+	}
+
+	// The held piece and the score to the left of the board
+	c.FillText("HOLD", size/2, size, size*0.6, canvasText)
+	if p := gs.HoldPiece(); p != game.NoPiece {
+		color := blockColor(theme, game.PieceBlock(p))
+		if !gs.CanHold() {
+			color = color.Scaled(0.4)
+		}
+		drawPreview(c, p, color, canvasSideWidth*size/2, 2.5*size, size*previewScale)
+	}
+	for i, line := range []string{
+		"SCORE", fmt.Sprint(gs.Score()),
+		"LEVEL", fmt.Sprint(gs.Level()),
+		"LINES", fmt.Sprint(gs.LinesCleared()),
+	} {
+		c.FillText(line, size/2, (5+float64(i))*size, size*0.6, canvasText)
+	}
+
+	// The next pieces to the right of the board
+	rightX := boardX + float64(cols)*size
+	c.FillText("NEXT", rightX+size/2, size, size*0.6, canvasText)
+	for i, p := range gs.NextPieces() {
+		drawPreview(c, p, blockColor(theme, game.PieceBlock(p)), rightX+canvasSideWidth*size/2, (2.5+float64(i)*previewSlot)*size, size*previewScale)
+	}
+
+	var message []string
+	switch {
+	case gs.GameOver():
+		message = []string{"GAME OVER", "R to restart"}
+	case gs.Paused():
+		message = []string{"PAUSED", "Esc to resume"}
+	}
+	for i, line := range message {
+		c.FillText(line, boardX+size/2, (float64(rows)/2+float64(i))*size, size*0.6, canvasText)
+	}
+}
+
+// drawBlock draws a block size pixels square with its top left corner at
+// (x, y), outlined so neighbouring blocks of the same color can still be
+// told apart
+func drawBlock(c *Canvas, color pixel.RGBA, x, y, size float64) {
+	c.FillRect(x, y, size, size, canvasOutline)
+	c.FillRect(x+1, y+1, size-2, size-2, cssColor(color))
+}
+
+// drawOutline draws the outline of a block size pixels square with its
+// top left corner at (x, y)
+func drawOutline(c *Canvas, color string, x, y, size float64) {
+	const width = 2.0
+	c.FillRect(x, y, size, width, color)
+	c.FillRect(x, y+size-width, size, width, color)
+	c.FillRect(x, y, width, size, color)
+	c.FillRect(x+size-width, y, width, size, color)
+}
+
+// drawPreview draws piece p out of blocks size pixels square in color,
+// centered on (x, y)
+func drawPreview(c *Canvas, p game.Piece, color pixel.RGBA, x, y, size float64) {
+	shape := game.PieceShape(p)
+	minRow, maxRow, minCol, maxCol := shape[0].Row(), shape[0].Row(), shape[0].Col(), shape[0].Col()
+	for _, pt := range shape[1:] {
+		if pt.Row() < minRow {
+			minRow = pt.Row()
+		}
+		if pt.Row() > maxRow {
+			maxRow = pt.Row()
+		}
+		if pt.Col() < minCol {
+			minCol = pt.Col()
+		}
+		if pt.Col() > maxCol {
+			maxCol = pt.Col()
+		}
+	}
+	centerRow := float64(minRow+maxRow) / 2
+	centerCol := float64(minCol+maxCol) / 2
+	for _, pt := range shape {
+		bx := x + (float64(pt.Col())-centerCol-0.5)*size
+		by := y - (float64(pt.Row())-centerRow+0.5)*size
+		drawBlock(c, color, bx, by, size)
+	}
+}
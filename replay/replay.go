@@ -0,0 +1,201 @@
+// Package replay records the inputs of a game so it can be played back.
+//
+// A replay file is little endian binary:
+//
+//	magic      [4]byte  "TREP"
+//	version    uint16   currently 2
+//	seed       int64    seed of the game's random number generator
+//	count      uint32   number of events
+//	events     count × {frame uint32, type uint8, key uint8}
+//	hashCount  uint32   number of board hashes
+//	hashes     hashCount × uint64
+//
+// Frames count the fixed time steps of the game from 0. Keys are the index
+// of an action rather than a keyboard key, so replays don't depend on the
+// key bindings. The hashes are of the board every HashInterval frames, so
+// a replay that plays back differently than it was recorded can be caught.
+// Version 1 files have no hashes and are still read.
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// Event types
+const (
+	KeyDown uint8 = iota
+	KeyUp
+)
+
+// ReplayEvent is a key being pressed or released during a frame
+type ReplayEvent struct {
+	Frame     uint32
+	EventType uint8
+	Key       uint8
+}
+
+var magic = [4]byte{'T', 'R', 'E', 'P'}
+
+const version = 2
+
+// HashInterval is the number of frames between the board hashes of a replay
+const HashInterval = 60
+
+// header starts every replay file
+type header struct {
+	Magic   [4]byte
+	Version uint16
+	Seed    int64
+	Count   uint32
+}
+
+// SaveReplay writes the seed, events and board hashes of a game to path.
+func SaveReplay(path string, seed int64, events []ReplayEvent, hashes []uint64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	h := header{magic, version, seed, uint32(len(events))}
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		f.Close()
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, events); err != nil {
+		f.Close()
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(hashes))); err != nil {
+		f.Close()
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, hashes); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadReplay reads the seed, events and board hashes of a replay written by
+// SaveReplay. Replays of version 1 have no hashes.
+func LoadReplay(path string) (int64, []ReplayEvent, []uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var h header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return 0, nil, nil, fmt.Errorf("%s: reading header: %v", path, err)
+	}
+	if h.Magic != magic {
+		return 0, nil, nil, fmt.Errorf("%s: not a replay file", path)
+	}
+	if h.Version != 1 && h.Version != version {
+		return 0, nil, nil, fmt.Errorf("%s: unsupported replay version %d", path, h.Version)
+	}
+
+	// Check the size before allocating so a corrupt count can't ask for
+	// more memory than the file could hold
+	info, err := f.Stat()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	eventSize := int64(binary.Size(ReplayEvent{}))
+	eventsEnd := int64(binary.Size(h)) + int64(h.Count)*eventSize
+	if eventsEnd > info.Size() {
+		return 0, nil, nil, fmt.Errorf("%s: replay is truncated", path)
+	}
+
+	events := make([]ReplayEvent, h.Count)
+	if err := binary.Read(r, binary.LittleEndian, events); err != nil {
+		return 0, nil, nil, err
+	}
+	if h.Version == 1 {
+		return h.Seed, events, nil, nil
+	}
+
+	var hashCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &hashCount); err != nil {
+		return 0, nil, nil, fmt.Errorf("%s: reading hashes: %v", path, err)
+	}
+	if eventsEnd+4+int64(hashCount)*8 > info.Size() {
+		return 0, nil, nil, fmt.Errorf("%s: replay is truncated", path)
+	}
+	hashes := make([]uint64, hashCount)
+	if err := binary.Read(r, binary.LittleEndian, hashes); err != nil {
+		return 0, nil, nil, err
+	}
+	return h.Seed, events, hashes, nil
+}
+
+// ReplayPlayer hands back the events of a replay frame by frame
+type ReplayPlayer struct {
+	seed   int64
+	events []ReplayEvent
+	hashes []uint64 // Hashes of the board every HashInterval frames
+	next   int      // Index of the first event not yet returned
+}
+
+// NewReplayPlayer returns a player for a replay with the given seed, events
+// and board hashes, as returned by LoadReplay. Events must be in frame
+// order. Hashes may be nil when the replay has none.
+func NewReplayPlayer(seed int64, events []ReplayEvent, hashes []uint64) *ReplayPlayer {
+	return &ReplayPlayer{seed: seed, events: events, hashes: hashes}
+}
+
+// Seed returns the seed the game must be started with for the replay to
+// play back as it was recorded.
+func (p *ReplayPlayer) Seed() int64 {
+	return p.seed
+}
+
+// NextEvents returns the events of frame. Frames must be asked for in
+// increasing order; events of frames that were skipped are dropped.
+func (p *ReplayPlayer) NextEvents(frame uint32) []ReplayEvent {
+	for p.next < len(p.events) && p.events[p.next].Frame < frame {
+		p.next++
+	}
+	start := p.next
+	for p.next < len(p.events) && p.events[p.next].Frame == frame {
+		p.next++
+	}
+	return p.events[start:p.next]
+}
+
+// CheckHash compares hash, the hash of the board at frame, to the one
+// recorded for that frame, if any. It returns an error when they differ,
+// which means the replay no longer plays back as it was recorded.
+func (p *ReplayPlayer) CheckHash(frame uint32, hash uint64) error {
+	if frame == 0 || frame%HashInterval != 0 {
+		return nil
+	}
+	i := int(frame/HashInterval) - 1
+	if i >= len(p.hashes) || p.hashes[i] == hash {
+		return nil
+	}
+	return fmt.Errorf("replay desynced at frame %d: board hash %016x, recorded %016x", frame, hash, p.hashes[i])
+}
+
+// LastFrame returns the frame of the last event of the replay, or 0 when it
+// has none
+func (p *ReplayPlayer) LastFrame() uint32 {
+	if len(p.events) == 0 {
+		return 0
+	}
+	return p.events[len(p.events)-1].Frame
+}
+
+// Rewind starts the replay over from the first frame
+func (p *ReplayPlayer) Rewind() {
+	p.next = 0
+}
@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	dir := tempDir(t)
+	seed := int64(-0x123456789abcdef)
+	events := []ReplayEvent{
+		{0, KeyDown, 3},
+		{0, KeyDown, 7},
+		{12, KeyUp, 3},
+		{1 << 31, KeyUp, 255},
+	}
+	hashes := []uint64{0, 1, ^uint64(0), 0xdeadbeefcafef00d}
+
+	path := filepath.Join(dir, "a.trep")
+	if err := SaveReplay(path, seed, events, hashes); err != nil {
+		t.Fatal(err)
+	}
+	gotSeed, gotEvents, gotHashes, err := LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotSeed != seed || !reflect.DeepEqual(gotEvents, events) || !reflect.DeepEqual(gotHashes, hashes) {
+		t.Errorf("LoadReplay() = %d, %v, %v, want %d, %v, %v", gotSeed, gotEvents, gotHashes, seed, events, hashes)
+	}
+
+	// Saving what was loaded writes the same bytes
+	again := filepath.Join(dir, "b.trep")
+	if err := SaveReplay(again, gotSeed, gotEvents, gotHashes); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := ioutil.ReadFile(path)
+	b, _ := ioutil.ReadFile(again)
+	if !bytes.Equal(a, b) {
+		t.Errorf("replay saved after loading differs:\n%x\n%x", a, b)
+	}
+	if want := 18 + 6*len(events) + 4 + 8*len(hashes); len(a) != want {
+		t.Errorf("replay is %d bytes, want %d", len(a), want)
+	}
+}
+
+func TestLoadReplayVersion1(t *testing.T) {
+	var buf bytes.Buffer
+	events := []ReplayEvent{{5, KeyDown, 1}, {9, KeyUp, 1}}
+	binary.Write(&buf, binary.LittleEndian, header{magic, 1, 42, uint32(len(events))})
+	binary.Write(&buf, binary.LittleEndian, events)
+	path := filepath.Join(tempDir(t), "v1.trep")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	seed, got, hashes, err := LoadReplay(path)
+	if err != nil || seed != 42 || !reflect.DeepEqual(got, events) || hashes != nil {
+		t.Errorf("LoadReplay(version 1) = %d, %v, %v, %v", seed, got, hashes, err)
+	}
+}
+
+func TestLoadReplayBad(t *testing.T) {
+	dir := tempDir(t)
+	good := filepath.Join(dir, "good.trep")
+	if err := SaveReplay(good, 1, []ReplayEvent{{1, KeyDown, 0}}, []uint64{7}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(good)
+
+	huge := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(huge[14:], 1<<31)
+	tests := map[string][]byte{
+		"empty":           nil,
+		"bad magic":       append([]byte("XREP"), data[4:]...),
+		"unknown version": append(append([]byte(nil), data[:4]...), append([]byte{9, 0}, data[6:]...)...),
+		"truncated":       data[:len(data)-1],
+		"huge count":      huge,
+	}
+	for name, data := range tests {
+		path := filepath.Join(dir, "bad.trep")
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := LoadReplay(path); err == nil {
+			t.Errorf("%s: LoadReplay() returned no error", name)
+		}
+	}
+}
+
+func TestReplayPlayer(t *testing.T) {
+	events := []ReplayEvent{{0, KeyDown, 1}, {3, KeyDown, 2}, {3, KeyUp, 1}, {8, KeyUp, 2}}
+	p := NewReplayPlayer(5, events, []uint64{10, 20})
+	if got := p.NextEvents(0); !reflect.DeepEqual(got, events[:1]) {
+		t.Errorf("NextEvents(0) = %v, want %v", got, events[:1])
+	}
+	if got := p.NextEvents(1); len(got) != 0 {
+		t.Errorf("NextEvents(1) = %v, want none", got)
+	}
+	if got := p.NextEvents(3); !reflect.DeepEqual(got, events[1:3]) {
+		t.Errorf("NextEvents(3) = %v, want %v", got, events[1:3])
+	}
+	if got := p.NextEvents(9); len(got) != 0 {
+		t.Errorf("NextEvents(9) = %v, want the skipped frame 8 dropped", got)
+	}
+	if p.LastFrame() != 8 {
+		t.Errorf("LastFrame() = %d, want 8", p.LastFrame())
+	}
+	p.Rewind()
+	if got := p.NextEvents(0); !reflect.DeepEqual(got, events[:1]) {
+		t.Errorf("NextEvents(0) after Rewind = %v, want %v", got, events[:1])
+	}
+
+	if err := p.CheckHash(HashInterval, 10); err != nil {
+		t.Error(err)
+	}
+	if err := p.CheckHash(2*HashInterval, 21); err == nil {
+		t.Error("CheckHash() of a different hash returned no error")
+	}
+	if err := p.CheckHash(3*HashInterval, 1); err != nil {
+		t.Errorf("CheckHash() past the recorded hashes = %v", err)
+	}
+	if err := p.CheckHash(HashInterval+1, 1); err != nil {
+		t.Errorf("CheckHash() between hashes = %v", err)
+	}
+}
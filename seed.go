@@ -0,0 +1,44 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// maxSeedDigits is the most digits an int64 seed can have
+const maxSeedDigits = 19
+
+// seedField is the text field on the game over screen where the player can
+// type the seed of the next game
+type seedField struct {
+	text string
+}
+
+// update adds the digits typed this frame to the field, backspace removing
+// the last one
+func (f *seedField) update(win *pixelgl.Window) {
+	for _, r := range win.Typed() {
+		if r >= '0' && r <= '9' && len(f.text) < maxSeedDigits {
+			f.text += string(r)
+		}
+	}
+	if (win.JustPressed(pixelgl.KeyBackspace) || win.Repeated(pixelgl.KeyBackspace)) && f.text != "" {
+		f.text = f.text[:len(f.text)-1]
+	}
+}
+
+// seed returns the seed typed into the field. ok is false when nothing or
+// a number too large for a seed was typed.
+func (f *seedField) seed() (seed int64, ok bool) {
+	seed, err := strconv.ParseInt(f.text, 10, 64)
+	return seed, err == nil
+}
+
+// line returns how the field is shown on the game over screen
+func (f *seedField) line() string {
+	return "Seed: " + f.text + "_"
+}
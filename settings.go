@@ -0,0 +1,195 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/render"
+)
+
+// settingsPath is where the handling settings are loaded from and saved to
+const settingsPath = "resources/settings.json"
+
+// settingsSlider is a single adjustable line of the settings screen
+type settingsSlider struct {
+	label    string
+	min, max float64
+	step     float64
+	show     func(v float64) string // Text of the value
+	get      func(s *config.Settings) float64
+	set      func(s *config.Settings, v float64)
+}
+
+// printValue returns a settingsSlider show function printing the value
+// with format
+func printValue(format string) func(v float64) string {
+	return func(v float64) string { return fmt.Sprintf(format, v) }
+}
+
+// settingsSliders lists every setting shown on the settings screen in order
+var settingsSliders = []settingsSlider{
+	{"DAS", 0, 0.3, 0.001, printValue("%.3fs"),
+		func(s *config.Settings) float64 { return s.DAS },
+		func(s *config.Settings, v float64) { s.DAS = v }},
+	{"ARR", 0, 0.1, 0.001, printValue("%.3fs"),
+		func(s *config.Settings) float64 { return s.ARR },
+		func(s *config.Settings, v float64) { s.ARR = v }},
+	{"Soft drop", config.MinSoftDropSpeed, 0.2, 0.01, printValue("%.2fs"),
+		func(s *config.Settings) float64 { return s.SoftDropSpeed },
+		func(s *config.Settings, v float64) { s.SoftDropSpeed = v }},
+	{"Friction", 0, 0.5, 0.01, printValue("%.2fs"),
+		func(s *config.Settings) float64 { return s.SoftDropFriction },
+		func(s *config.Settings, v float64) { s.SoftDropFriction = v }},
+	{"Lock delay", config.MinLockDelay, 1, 0.05, printValue("%.2fs"),
+		func(s *config.Settings) float64 { return s.LockDelay },
+		func(s *config.Settings, v float64) { s.LockDelay = v }},
+	{"Lock resets", 0, 60, 1, printValue("%.0f"),
+		func(s *config.Settings) float64 { return float64(s.MaxLockResets) },
+		func(s *config.Settings, v float64) { s.MaxLockResets = int(v) }},
+	{"Ghost", 0, 100, 5, printValue("%.0f%%"),
+		func(s *config.Settings) float64 { return s.GhostOpacity * 100 },
+		func(s *config.Settings, v float64) { s.GhostOpacity = v / 100 }},
+	{"Sound", 0, 100, 10, printValue("%.0f%%"),
+		func(s *config.Settings) float64 { return s.SFXVolume * 100 },
+		func(s *config.Settings, v float64) { s.SFXVolume = v / 100 }},
+	{"Music", 0, 100, 10, printValue("%.0f%%"),
+		func(s *config.Settings) float64 { return s.MusicVolume * 100 },
+		func(s *config.Settings, v float64) { s.MusicVolume = v / 100 }},
+	// The track is its place in musicTracks counted from 1, 0 for none
+	{"Track", 0, float64(len(musicTracks)), 1,
+		func(v float64) string {
+			if v < 1 {
+				return "Off"
+			}
+			return musicTracks[int(v)-1]
+		},
+		func(s *config.Settings) float64 {
+			for i, track := range musicTracks {
+				if track == s.MusicTrack {
+					return float64(i + 1)
+				}
+			}
+			return 0
+		},
+		func(s *config.Settings, v float64) {
+			s.MusicTrack = ""
+			if v >= 1 {
+				s.MusicTrack = musicTracks[int(v)-1]
+			}
+		}},
+}
+
+// settingsSliderWidth is the number of characters in a slider's bar
+const settingsSliderWidth = 10
+
+// settingsScreen lets the player change the handling settings while the
+// game is running. Changes are only kept once confirmed.
+type settingsScreen struct {
+	open     bool
+	selected int
+	values   config.Settings // The values being edited
+	err      string          // Why the last save failed
+}
+
+// show opens the settings screen to edit a copy of current
+func (sc *settingsScreen) show(current config.Settings) {
+	sc.open = true
+	sc.selected = 0
+	sc.values = current
+	sc.err = ""
+}
+
+// update handles the input of the settings screen. Up and down choose a
+// setting and left and right change it. Returns true when the player
+// confirms the changes with enter. Escape or tab closes the screen without
+// keeping them.
+func (sc *settingsScreen) update(win *pixelgl.Window) bool {
+	if win.JustPressed(pixelgl.KeyEscape) || win.JustPressed(pixelgl.KeyTab) {
+		sc.open = false
+		return false
+	}
+	if win.JustPressed(pixelgl.KeyEnter) {
+		return true
+	}
+
+	if win.JustPressed(pixelgl.KeyUp) || win.Repeated(pixelgl.KeyUp) {
+		sc.selected = (sc.selected + len(settingsSliders) - 1) % len(settingsSliders)
+	}
+	if win.JustPressed(pixelgl.KeyDown) || win.Repeated(pixelgl.KeyDown) {
+		sc.selected = (sc.selected + 1) % len(settingsSliders)
+	}
+
+	steps := 0.0
+	if win.JustPressed(pixelgl.KeyLeft) || win.Repeated(pixelgl.KeyLeft) {
+		steps--
+	}
+	if win.JustPressed(pixelgl.KeyRight) || win.Repeated(pixelgl.KeyRight) {
+		steps++
+	}
+	if steps != 0 {
+		slider := settingsSliders[sc.selected]
+		v := slider.get(&sc.values) + steps*slider.step
+		// Round to the step so repeated changes don't drift
+		v = math.Round(v/slider.step) * slider.step
+		slider.set(&sc.values, math.Max(slider.min, math.Min(slider.max, v)))
+	}
+	return false
+}
+
+// display darkens the whole window and draws every setting with a slider
+// showing where its value sits in its range. The selected setting is
+// highlighted.
+func (sc *settingsScreen) display(win *pixelgl.Window, atlas *text.Atlas, uiScaleFactor float64) {
+	imd := imdraw.New(nil)
+	imd.Color = pixel.RGBA{A: 0.85}
+	imd.Push(win.Bounds().Min, win.Bounds().Max)
+	imd.Rectangle(0)
+	imd.Draw(win)
+
+	center := win.Bounds().Center()
+	scale := 1.5 * uiScaleFactor
+	render.CenteredText(win, atlas, []string{"SETTINGS"}, 2*uiScaleFactor, center.Add(pixel.V(0, 150*uiScaleFactor)))
+
+	txt := text.New(pixel.ZV, atlas)
+	for i, slider := range settingsSliders {
+		v := slider.get(&sc.values)
+		filled := 0
+		if slider.max > slider.min {
+			filled = int(math.Round((v - slider.min) / (slider.max - slider.min) * settingsSliderWidth))
+		}
+		// Settings loaded from the file can be outside of the slider's range
+		if filled < 0 {
+			filled = 0
+		} else if filled > settingsSliderWidth {
+			filled = settingsSliderWidth
+		}
+		bar := strings.Repeat("=", filled) + strings.Repeat("-", settingsSliderWidth-filled)
+
+		prefix := "  "
+		txt.Color = colornames.White
+		if i == sc.selected {
+			prefix = "> "
+			txt.Color = colornames.Yellow
+		}
+		fmt.Fprintf(txt, "%s%-11s [%s] %s\n", prefix, slider.label, bar, slider.show(v))
+	}
+	origin := center.Sub(txt.Bounds().Center().Scaled(scale))
+	txt.Draw(win, pixel.IM.Scaled(pixel.ZV, scale).Moved(origin))
+
+	footer := []string{"Up/Down select, Left/Right adjust", "Enter to save, Esc to cancel"}
+	if sc.err != "" {
+		footer = append(footer, "Could not save: "+sc.err)
+	}
+	render.CenteredText(win, atlas, footer, uiScaleFactor, center.Sub(pixel.V(0, 130*uiScaleFactor)))
+}
@@ -0,0 +1,76 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/sound"
+)
+
+// soundFiles holds the sound effects so the game doesn't need the
+// resources directory to play them
+//
+//go:embed resources/sounds
+var soundFiles embed.FS
+
+// newSoundManager loads the embedded sound effects at the volume in settings
+func newSoundManager(settings config.Settings) *sound.SoundManager {
+	sounds, err := fs.Sub(soundFiles, "resources/sounds")
+	if err != nil {
+		panic(err)
+	}
+	return sound.NewSoundManager(sounds, settings.SFXVolume)
+}
+
+// soundCues plays the sound effects of what happened in a game since it
+// was last checked. It tells what happened from the game's counters, so
+// the game itself knows nothing of sound.
+type soundCues struct {
+	stats    game.Stats
+	level    int
+	lines    int
+	gameOver bool
+}
+
+// locks returns the number of pieces locked in a game with stats s, each
+// lock being counted once in LineClears
+func locks(s game.Stats) int {
+	n := 0
+	for _, c := range s.LineClears {
+		n += c
+	}
+	return n
+}
+
+// play plays the sounds of what changed in gs since the last call. A lock
+// only plays one sound, the line clear or hard drop when it was one. When
+// a new game has started the counters are reset without a sound.
+func (c *soundCues) play(sfx *sound.SoundManager, gs *game.GameState) {
+	stats := gs.Stats()
+	if stats.Rotations > c.stats.Rotations {
+		sfx.PlayRotate()
+	}
+	if stats.Holds > c.stats.Holds {
+		sfx.PlayHold()
+	}
+	switch lines := gs.LinesCleared() - c.lines; {
+	case lines > 0:
+		sfx.PlayLineClear(lines)
+	case stats.HardDrops > c.stats.HardDrops:
+		sfx.PlayHardDrop()
+	case locks(stats) > locks(c.stats):
+		sfx.PlayPieceLock()
+	}
+	if gs.Level() > c.level && c.level > 0 {
+		sfx.PlayLevelUp()
+	}
+	if gs.GameOver() && !c.gameOver {
+		sfx.PlayGameOver()
+	}
+	*c = soundCues{stats: stats, level: gs.Level(), lines: gs.LinesCleared(), gameOver: gs.GameOver()}
+}
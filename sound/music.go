@@ -0,0 +1,201 @@
+package sound
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/speaker"
+	"github.com/faiface/beep/vorbis"
+)
+
+// Tempos of the music in beats per minute. The tracks are recorded at
+// BaseTempo and played faster as the level goes up, up to MaxTempo.
+const (
+	BaseTempo     = 120.0
+	MaxTempo      = 200.0
+	tempoPerLevel = 5.0
+)
+
+// LevelTempo returns the tempo the music is played at on level
+func LevelTempo(level int) float64 {
+	return math.Min(BaseTempo+float64(level)*tempoPerLevel, MaxTempo)
+}
+
+// decoders decode the music tracks by file extension, in the order the
+// extensions are tried
+var decoders = []struct {
+	ext    string
+	decode func(f fs.File) (beep.StreamSeekCloser, beep.Format, error)
+}{
+	{".mp3", func(f fs.File) (beep.StreamSeekCloser, beep.Format, error) { return mp3.Decode(f) }},
+	{".ogg", func(f fs.File) (beep.StreamSeekCloser, beep.Format, error) { return vorbis.Decode(f) }},
+}
+
+// isTrack returns whether name is the file name of a music track
+func isTrack(name string) bool {
+	for _, d := range decoders {
+		if path.Ext(name) == d.ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Tracks returns the names of the music tracks at the root of fsys, the
+// file names without their extension, in order
+func Tracks(fsys fs.FS) []string {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil
+	}
+	var tracks []string
+	for _, e := range entries {
+		if isTrack(e.Name()) && !e.IsDir() {
+			tracks = append(tracks, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+		}
+	}
+	sort.Strings(tracks)
+	return tracks
+}
+
+// stopper streams s until it is stopped, then ends so the speaker drops it
+type stopper struct {
+	s       beep.Streamer
+	stopped bool
+}
+
+// Stream implements beep.Streamer
+func (st *stopper) Stream(samples [][2]float64) (int, bool) {
+	if st.stopped {
+		return 0, false
+	}
+	return st.s.Stream(samples)
+}
+
+// Err implements beep.Streamer
+func (st *stopper) Err() error {
+	return st.s.Err()
+}
+
+// MusicPlayer loops a music track in the background at a tempo that can
+// change while it plays. Changing the tempo resamples the track, so it
+// plays higher as well as faster. A nil MusicPlayer plays nothing.
+type MusicPlayer struct {
+	fsys   fs.FS
+	volume float64
+	tempo  float64
+
+	// The track playing, nil when none is
+	track     beep.StreamSeekCloser
+	stopper   *stopper
+	resampler *beep.Resampler
+	gain      *effects.Volume
+	rateRatio float64 // Ratio of the track's sample rate to SampleRate
+}
+
+// NewMusicPlayer returns a player of the tracks at the root of fsys at
+// volume, from 0 to 1, and BaseTempo. The speaker is opened by the first
+// track played.
+func NewMusicPlayer(fsys fs.FS, volume float64) *MusicPlayer {
+	return &MusicPlayer{fsys: fsys, volume: volume, tempo: BaseTempo}
+}
+
+// Play stops the track playing, if any, and loops track, one of Tracks.
+// Returns an error, with nothing playing, when the track is missing, can't
+// be decoded or the speaker can't be opened.
+func (mp *MusicPlayer) Play(track string) error {
+	if mp == nil {
+		return nil
+	}
+	mp.Stop()
+
+	var f fs.File
+	var decode func(fs.File) (beep.StreamSeekCloser, beep.Format, error)
+	for _, d := range decoders {
+		var err error
+		if f, err = mp.fsys.Open(track + d.ext); err == nil {
+			decode = d.decode
+			break
+		}
+	}
+	if f == nil {
+		return fmt.Errorf("music track %q not found", track)
+	}
+	stream, format, err := decode(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("loading music track %q: %v", track, err)
+	}
+	if err := openSpeaker(); err != nil {
+		stream.Close()
+		return err
+	}
+
+	mp.track = stream
+	mp.rateRatio = float64(format.SampleRate) / float64(SampleRate)
+	mp.resampler = beep.ResampleRatio(4, mp.ratio(), beep.Loop(-1, stream))
+	mp.gain = &effects.Volume{Streamer: mp.resampler, Base: 2}
+	setVolume(mp.gain, mp.volume)
+	mp.stopper = &stopper{s: mp.gain}
+	speaker.Play(mp.stopper)
+	return nil
+}
+
+// Stop stops the track playing, if any
+func (mp *MusicPlayer) Stop() {
+	if mp == nil || mp.track == nil {
+		return
+	}
+	speaker.Lock()
+	mp.stopper.stopped = true
+	speaker.Unlock()
+	mp.track.Close()
+	mp.track, mp.stopper, mp.resampler, mp.gain = nil, nil, nil, nil
+}
+
+// ratio returns the resampling ratio that plays the track at SampleRate
+// and the tempo
+func (mp *MusicPlayer) ratio() float64 {
+	return mp.rateRatio * mp.tempo / BaseTempo
+}
+
+// SetTempo changes the tempo the music is played at, in beats per minute
+func (mp *MusicPlayer) SetTempo(bpm float64) {
+	if mp == nil || bpm == mp.tempo {
+		return
+	}
+	mp.tempo = bpm
+	if mp.resampler != nil {
+		speaker.Lock()
+		mp.resampler.SetRatio(mp.ratio())
+		speaker.Unlock()
+	}
+}
+
+// SetVolume changes the volume of the music, from 0 for silent to 1 for
+// full volume
+func (mp *MusicPlayer) SetVolume(volume float64) {
+	if mp == nil {
+		return
+	}
+	mp.volume = volume
+	if mp.gain != nil {
+		speaker.Lock()
+		setVolume(mp.gain, volume)
+		speaker.Unlock()
+	}
+}
+
+// setVolume sets v to scale by volume, from 0 to 1. beep's volumes are
+// exponents, so volume is turned into the power of 2 that scales by it.
+func setVolume(v *effects.Volume, volume float64) {
+	v.Volume = math.Log2(volume)
+	v.Silent = volume <= 0
+}
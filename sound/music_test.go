@@ -0,0 +1,98 @@
+package sound
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/faiface/beep"
+)
+
+func TestLevelTempo(t *testing.T) {
+	tests := []struct {
+		level int
+		want  float64
+	}{
+		{0, 120},
+		{1, 125},
+		{10, 170},
+		{16, 200},
+		{17, 200}, // Capped at MaxTempo
+		{30, 200},
+	}
+	for _, tt := range tests {
+		if got := LevelTempo(tt.level); got != tt.want {
+			t.Errorf("LevelTempo(%d) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSetTempoRatio(t *testing.T) {
+	// A track recorded at 48000 Hz, as Play would have set it up without
+	// the speaker
+	mp := NewMusicPlayer(fstest.MapFS{}, 1)
+	mp.rateRatio = 48000.0 / float64(SampleRate)
+	mp.resampler = beep.ResampleRatio(4, mp.ratio(), beep.Silence(-1))
+	if got, want := mp.resampler.Ratio(), mp.rateRatio; got != want {
+		t.Errorf("ratio at BaseTempo = %v, want %v", got, want)
+	}
+
+	for _, bpm := range []float64{150, LevelTempo(16), BaseTempo, 90} {
+		mp.SetTempo(bpm)
+		if got, want := mp.resampler.Ratio(), mp.rateRatio*bpm/BaseTempo; math.Abs(got-want) > 1e-12 {
+			t.Errorf("ratio at %v bpm = %v, want %v", bpm, got, want)
+		}
+	}
+
+	// The tempo set with nothing playing is kept for the next track
+	mp.resampler = nil
+	mp.SetTempo(180)
+	if got, want := mp.ratio(), mp.rateRatio*1.5; math.Abs(got-want) > 1e-12 {
+		t.Errorf("ratio at 180 bpm = %v, want %v", got, want)
+	}
+}
+
+func TestPlayMissingTrack(t *testing.T) {
+	mp := NewMusicPlayer(fstest.MapFS{
+		"broken.mp3": {Data: []byte("not music")},
+		"notes.txt":  {Data: []byte("not music either")},
+	}, 1)
+	for _, track := range []string{"missing", "notes", "broken"} {
+		if err := mp.Play(track); err == nil || !strings.Contains(err.Error(), track) {
+			t.Errorf("Play(%q) error = %v, want one naming the track", track, err)
+		}
+		if mp.track != nil || mp.resampler != nil {
+			t.Errorf("Play(%q) left a track playing", track)
+		}
+	}
+
+	// Stopping and changing nothing playing does nothing
+	mp.Stop()
+	mp.SetTempo(150)
+	mp.SetVolume(0.5)
+}
+
+func TestTracks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"b.ogg":      {},
+		"a.mp3":      {},
+		"cover.png":  {},
+		"dir.mp3/x":  {},
+		"readme.txt": {},
+	}
+	got := strings.Join(Tracks(fsys), " ")
+	if got != "a b" {
+		t.Errorf("Tracks() = %s, want a b", got)
+	}
+}
+
+func TestMusicPlayerNil(t *testing.T) {
+	var mp *MusicPlayer
+	if err := mp.Play("any"); err != nil {
+		t.Errorf("Play() on a nil MusicPlayer = %v", err)
+	}
+	mp.SetTempo(150)
+	mp.SetVolume(0.5)
+	mp.Stop()
+}
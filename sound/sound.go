@@ -0,0 +1,159 @@
+// Package sound plays the sound effects of the game through the speaker.
+// Sounds that are missing and a speaker that can't be opened are skipped,
+// so the game plays the same without them.
+package sound
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/speaker"
+	"github.com/faiface/beep/wav"
+)
+
+// SampleRate is the rate the speaker plays at. Sounds recorded at other
+// rates are resampled when they are loaded.
+const SampleRate beep.SampleRate = 44100
+
+// Names of the sound effects, loaded from files of the same name with a
+// .wav extension
+const (
+	soundLock      = "lock"
+	soundRotate    = "rotate"
+	soundHardDrop  = "hard_drop"
+	soundHold      = "hold"
+	soundLineClear = "line_clear" // 1 to 3 lines
+	soundTetris    = "tetris"     // 4 lines
+	soundLevelUp   = "level_up"
+	soundGameOver  = "game_over"
+)
+
+var soundNames = []string{soundLock, soundRotate, soundHardDrop, soundHold, soundLineClear, soundTetris, soundLevelUp, soundGameOver}
+
+// The speaker is opened once, by the first sound that needs it
+var (
+	speakerOnce sync.Once
+	speakerErr  error
+)
+
+// openSpeaker opens the speaker if it hasn't been yet and returns why it
+// couldn't be, if it couldn't
+func openSpeaker() error {
+	speakerOnce.Do(func() {
+		speakerErr = speaker.Init(SampleRate, SampleRate.N(time.Second/30))
+	})
+	return speakerErr
+}
+
+// SoundManager plays the sound effects of the game. The zero value and a
+// nil SoundManager play nothing.
+type SoundManager struct {
+	sounds map[string]*beep.Buffer // Decoded sounds by name, missing ones left out
+	volume float64                 // From 0 for silent to 1 for full volume
+}
+
+// NewSoundManager loads the sound effects from the root of fsys and opens
+// the speaker to play them at volume, from 0 to 1. Missing sounds are left
+// out silently, sounds that can't be decoded are left out with a warning.
+// When no sound loads or the speaker can't be opened, the SoundManager
+// plays nothing.
+func NewSoundManager(fsys fs.FS, volume float64) *SoundManager {
+	sm := &SoundManager{sounds: make(map[string]*beep.Buffer), volume: volume}
+	for _, name := range soundNames {
+		path := name + ".wav"
+		buf, err := loadWAV(fsys, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "loading sound %s: %v\n", path, err)
+			continue
+		}
+		sm.sounds[name] = buf
+	}
+	if len(sm.sounds) == 0 {
+		return sm
+	}
+	if err := openSpeaker(); err != nil {
+		fmt.Fprintln(os.Stderr, "sound effects won't play:", err)
+		sm.sounds = nil
+	}
+	return sm
+}
+
+// loadWAV decodes the WAV file at path in fsys into a buffer at SampleRate
+func loadWAV(fsys fs.FS, path string) (*beep.Buffer, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	streamer, format, err := wav.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	buf := beep.NewBuffer(beep.Format{SampleRate: SampleRate, NumChannels: 2, Precision: 2})
+	buf.Append(beep.Resample(4, format.SampleRate, SampleRate, streamer))
+	return buf, streamer.Err()
+}
+
+// volumeStreamer plays s at volume, from 0 to 1
+func volumeStreamer(s beep.Streamer, volume float64) beep.Streamer {
+	v := &effects.Volume{Streamer: s, Base: 2}
+	setVolume(v, volume)
+	return v
+}
+
+// SetVolume changes the volume of the sounds played from now on, from 0
+// for silent to 1 for full volume
+func (sm *SoundManager) SetVolume(volume float64) {
+	if sm != nil {
+		sm.volume = volume
+	}
+}
+
+// play starts the sound called name over whatever is playing, if it loaded
+func (sm *SoundManager) play(name string) {
+	if sm == nil || sm.volume <= 0 {
+		return
+	}
+	buf, ok := sm.sounds[name]
+	if !ok {
+		return
+	}
+	speaker.Play(volumeStreamer(buf.Streamer(0, buf.Len()), sm.volume))
+}
+
+// PlayLineClear plays the sound of a lock that cleared lines lines, a
+// bigger one for a Tetris
+func (sm *SoundManager) PlayLineClear(lines int) {
+	switch {
+	case lines >= 4:
+		sm.play(soundTetris)
+	case lines > 0:
+		sm.play(soundLineClear)
+	}
+}
+
+// PlayPieceLock plays the sound of a piece locking without a clear
+func (sm *SoundManager) PlayPieceLock() { sm.play(soundLock) }
+
+// PlayRotate plays the sound of a piece rotating
+func (sm *SoundManager) PlayRotate() { sm.play(soundRotate) }
+
+// PlayHardDrop plays the sound of a piece hard dropping
+func (sm *SoundManager) PlayHardDrop() { sm.play(soundHardDrop) }
+
+// PlayHold plays the sound of a piece being held
+func (sm *SoundManager) PlayHold() { sm.play(soundHold) }
+
+// PlayLevelUp plays the sound of the level going up
+func (sm *SoundManager) PlayLevelUp() { sm.play(soundLevelUp) }
+
+// PlayGameOver plays the sound of the game ending
+func (sm *SoundManager) PlayGameOver() { sm.play(soundGameOver) }
@@ -0,0 +1,51 @@
+package sound
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+// playAll plays every sound effect of sm
+func playAll(sm *SoundManager) {
+	for lines := 0; lines <= 4; lines++ {
+		sm.PlayLineClear(lines)
+	}
+	sm.PlayPieceLock()
+	sm.PlayRotate()
+	sm.PlayHardDrop()
+	sm.PlayHold()
+	sm.PlayLevelUp()
+	sm.PlayGameOver()
+	sm.SetVolume(0.5)
+}
+
+func TestSoundManagerNoFiles(t *testing.T) {
+	// Without sounds the speaker is never opened and nothing plays
+	sm := NewSoundManager(fstest.MapFS{}, 1)
+	if sm == nil {
+		t.Fatal("NewSoundManager() = nil")
+	}
+	if len(sm.sounds) != 0 {
+		t.Errorf("%d sounds loaded from an empty directory", len(sm.sounds))
+	}
+	playAll(sm)
+}
+
+func TestSoundManagerBadFiles(t *testing.T) {
+	// Files that aren't sounds are left out, as if they were missing
+	fsys := fstest.MapFS{
+		soundLock + ".wav":   {Data: []byte("not a sound")},
+		soundTetris + ".wav": {Data: nil},
+	}
+	sm := NewSoundManager(fsys, 1)
+	if len(sm.sounds) != 0 {
+		t.Errorf("%d sounds loaded from files that aren't sounds", len(sm.sounds))
+	}
+	playAll(sm)
+}
+
+func TestSoundManagerNil(t *testing.T) {
+	var sm *SoundManager
+	playAll(sm)
+	playAll(&SoundManager{})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"io/fs"
 	"os"
 	"sync"
 
@@ -13,15 +14,78 @@ import (
 // Cache for storing sprites to avoid recreating them
 var (
 	spriteMutex  sync.RWMutex
-	spriteCache  = make(map[int]pixel.Picture)
+	sheetCache   = make(map[sheetKey][]pixel.Picture) // Tiles of each sprite sheet loaded
 	pictureCache = make(map[string]pixel.Picture)
 )
 
+// sheetKey is a sprite sheet as it was loaded: its path and the rows and
+// columns it was divided into, as the same file can be cut up differently
+type sheetKey struct {
+	path       string
+	rows, cols int
+}
+
+// openFile opens the files loaded from the operating system, a variable so
+// loading can be watched
+var openFile = os.Open
+
+// osFS opens files from the operating system through openFile. Unlike
+// os.DirFS it takes absolute paths as well as relative ones.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) { return openFile(name) }
+
 // LoadSpriteSheet takes a path to a resource and how it should be divided and returns
-// a funciton to optain the sprite at that index
+// a funciton to optain the sprite at that index. Tiles don't need to be square.
+// Every tile is cut out on the first load of a sheet, and loading it again
+// with the same division reuses them without reading the file.
 func LoadSpriteSheet(path string, row, col int) (func(int) pixel.Picture, error) {
+	key := sheetKey{path, row, col}
+	spriteMutex.RLock()
+	tiles, exists := sheetCache[key]
+	spriteMutex.RUnlock()
+
+	if !exists {
+		var err error
+		tiles, err = loadTiles(osFS{}, path, row, col)
+		if err != nil {
+			return nil, err
+		}
+
+		// Store in cache for future loads
+		spriteMutex.Lock()
+		sheetCache[key] = tiles
+		spriteMutex.Unlock()
+	}
+	return tileGen(tiles), nil
+}
+
+// LoadSpriteSheetFromFS is LoadSpriteSheet for a sheet at path in fsys, such
+// as an embed.FS holding the resources. The tiles aren't cached as the same
+// path can hold different sheets in different file systems.
+func LoadSpriteSheetFromFS(fsys fs.FS, path string, rows, cols int) (func(int) pixel.Picture, error) {
+	tiles, err := loadTiles(fsys, path, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	return tileGen(tiles), nil
+}
+
+// tileGen returns a function to obtain the tile at an index of tiles
+func tileGen(tiles []pixel.Picture) func(int) pixel.Picture {
+	return func(i int) pixel.Picture {
+		if i < 0 || i >= len(tiles) {
+			panic("Index out of bounds for sprite sheet")
+		}
+		return tiles[i]
+	}
+}
+
+// loadTiles reads the sprite sheet at path in fsys and cuts it into row by
+// col tiles, left to right then top to bottom
+func loadTiles(fsys fs.FS, path string, row, col int) ([]pixel.Picture, error) {
 	// Open file
-	file, err := os.Open(path)
+	file, err := fsys.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -33,48 +97,50 @@ func LoadSpriteSheet(path string, row, col int) (func(int) pixel.Picture, error)
 		return nil, err
 	}
 
-	// Check if tile is square
+	// Check the sheet divides into tiles
 	b := img.Bounds()
-	if b.Max.X/col != b.Max.Y/row {
-		fmt.Println("width/col = ", b.Max.X, ", height/row = ", b.Max.Y)
-		return nil, fmt.Errorf(fmt.Sprintf("Invalid dimensions (%d, %d) for sprite sheet %s\n", row, col, path))
+	tileWidth := b.Max.X / col
+	tileHeight := b.Max.Y / row
+	if tileWidth == 0 || tileHeight == 0 {
+		return nil, fmt.Errorf("invalid dimensions (%d, %d) for sprite sheet %s", row, col, path)
 	}
 
-	tileSize := b.Max.X / col
-
-	return func(i int) pixel.Picture {
-		if i < 0 || i >= row*col {
-			panic("Index out of bounds for sprite sheet")
+	tiles := make([]pixel.Picture, 0, row*col)
+	for r := 0; r < row; r++ {
+		for c := 0; c < col; c++ {
+			subImage := img.(interface {
+				SubImage(r image.Rectangle) image.Image
+			}).SubImage(image.Rect(c*tileWidth, r*tileHeight, (c+1)*tileWidth, (r+1)*tileHeight))
+			tiles = append(tiles, pixel.PictureDataFromImage(subImage))
 		}
+	}
+	return tiles, nil
+}
 
-		// Check if this sprite is already in the cache
-		spriteMutex.RLock()
-		cachedSprite, exists := spriteCache[i]
-		spriteMutex.RUnlock()
-
-		if exists {
-			return cachedSprite
+// LoadAnimatedSpriteSheet loads a sprite sheet like LoadSpriteSheet whose
+// tiles are the frames of an animation, left to right then top to bottom,
+// played at fps frames a second. The function returned gives the frame
+// showing t seconds into the animation, which loops.
+func LoadAnimatedSpriteSheet(path string, rows, cols, fps int) (func(t float64) pixel.Picture, error) {
+	if fps <= 0 {
+		return nil, fmt.Errorf("invalid frame rate %d for animated sprite sheet %s", fps, path)
+	}
+	frame, err := LoadSpriteSheet(path, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	frames := rows * cols
+	return func(t float64) pixel.Picture {
+		i := int(t*float64(fps)) % frames
+		if i < 0 {
+			i += frames
 		}
-
-		// If not in cache, create it
-		r := i / col
-		c := i % col
-
-		subImage := img.(interface {
-			SubImage(r image.Rectangle) image.Image
-		}).SubImage(image.Rect(c*tileSize, r*tileSize, (c+1)*tileSize, (r+1)*tileSize))
-
-		picData := pixel.PictureDataFromImage(subImage)
-
-		// Store in cache for future use
-		spriteMutex.Lock()
-		spriteCache[i] = picData
-		spriteMutex.Unlock()
-
-		return picData
+		return frame(i)
 	}, nil
 }
 
+// LoadPicture loads the image at path, keeping it for later loads of the
+// same path
 func LoadPicture(path string) (pixel.Picture, error) {
 	// Check if the picture is already cached
 	spriteMutex.RLock()
@@ -86,17 +152,10 @@ func LoadPicture(path string) (pixel.Picture, error) {
 	}
 
 	// If not in cache, load it
-	file, err := os.Open(path)
+	pic, err := LoadPictureFromFS(osFS{}, path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-
-	pic := pixel.PictureDataFromImage(img)
 
 	// Store in cache
 	spriteMutex.Lock()
@@ -106,6 +165,21 @@ func LoadPicture(path string) (pixel.Picture, error) {
 	return pic, nil
 }
 
+// LoadPictureFromFS loads the image at path in fsys, such as an embed.FS
+// holding the resources. Unlike LoadPicture it isn't cached.
+func LoadPictureFromFS(fsys fs.FS, path string) (pixel.Picture, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return pixel.PictureDataFromImage(img), nil
+}
+
 // Background image caching
 var (
 	playBGPic      pixel.Picture
@@ -0,0 +1,272 @@
+package spritesheet
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/faiface/pixel"
+)
+
+// tileColors are the colors of the tiles of sheetPNG, left to right
+var tileColors = []color.RGBA{
+	{R: 255, A: 255},
+	{G: 255, A: 255},
+	{B: 255, A: 255},
+	{R: 255, G: 255, A: 255},
+}
+
+// sheetPNG returns a 160x40 PNG of 4 square tiles in a row, each filled
+// with its color of tileColors
+func sheetPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 160, 40))
+	for x := 0; x < 160; x++ {
+		for y := 0; y < 40; y++ {
+			img.SetRGBA(x, y, tileColors[x/40])
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeSheet writes sheetPNG to a new temporary directory, which is removed
+// at the end of the test, and returns its path
+func writeSheet(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "spritesheet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "sheet.png")
+	if err := ioutil.WriteFile(path, sheetPNG(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// countOpens has openFile count the files it opens until the end of the
+// test
+func countOpens(t *testing.T) *int {
+	opens := 0
+	t.Cleanup(func() { openFile = os.Open })
+	openFile = func(name string) (*os.File, error) {
+		opens++
+		return os.Open(name)
+	}
+	return &opens
+}
+
+// colorAt returns the color of pic at the center of its bounds
+func colorAt(pic pixel.Picture) pixel.RGBA {
+	return pic.(*pixel.PictureData).Color(pic.Bounds().Center())
+}
+
+func TestLoadSpriteSheetTiles(t *testing.T) {
+	path := writeSheet(t)
+	tests := []struct {
+		rows, cols int
+		w, h       float64
+	}{
+		{1, 4, 40, 40},
+		{1, 2, 80, 40},
+		{4, 1, 160, 10}, // Not square
+		{2, 8, 20, 20},
+	}
+	for _, tt := range tests {
+		tile, err := LoadSpriteSheet(path, tt.rows, tt.cols)
+		if err != nil {
+			t.Fatalf("LoadSpriteSheet(%d, %d): %v", tt.rows, tt.cols, err)
+		}
+		for i := 0; i < tt.rows*tt.cols; i++ {
+			b := tile(i).Bounds()
+			if b.W() != tt.w || b.H() != tt.h {
+				t.Errorf("%dx%d tile %d is %vx%v, want %vx%v", tt.rows, tt.cols, i, b.W(), b.H(), tt.w, tt.h)
+			}
+		}
+	}
+}
+
+func TestLoadSpriteSheetOrder(t *testing.T) {
+	tile, err := LoadSpriteSheet(writeSheet(t), 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range tileColors {
+		if got, want := colorAt(tile(i)), pixel.ToRGBA(c); got != want {
+			t.Errorf("tile %d is %v, want %v", i, got, want)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("tile(4) of 4 didn't panic")
+		}
+	}()
+	tile(4)
+}
+
+func TestLoadSpriteSheetErrors(t *testing.T) {
+	path := writeSheet(t)
+	if _, err := LoadSpriteSheet(path, 1, 200); err == nil {
+		t.Error("LoadSpriteSheet() cut a 160 pixel wide sheet into 200 columns")
+	}
+	if _, err := LoadSpriteSheet(filepath.Join(filepath.Dir(path), "missing.png"), 1, 4); err == nil {
+		t.Error("LoadSpriteSheet(missing) returned no error")
+	}
+	notPNG := filepath.Join(filepath.Dir(path), "sheet.txt")
+	if err := ioutil.WriteFile(notPNG, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSpriteSheet(notPNG, 1, 4); err == nil {
+		t.Error("LoadSpriteSheet(not an image) returned no error")
+	}
+}
+
+func TestLoadAnimatedSpriteSheet(t *testing.T) {
+	const fps = 8
+	frame, err := LoadAnimatedSpriteSheet(writeSheet(t), 1, 4, fps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame(0) == frame(1.0/fps) || colorAt(frame(0)) == colorAt(frame(1.0/fps)) {
+		t.Error("frames at 0 and 1/fps seconds are the same")
+	}
+
+	tests := []struct {
+		t    float64
+		want int // Tile shown
+	}{
+		{0, 0},
+		{0.5 / fps, 0},
+		{1.0 / fps, 1},
+		{3.5 / fps, 3},
+		{4.0 / fps, 0}, // Loops back to the first
+		{9.0 / fps, 1},
+		{-1.0 / fps, 3},
+	}
+	for _, tt := range tests {
+		if got, want := colorAt(frame(tt.t)), pixel.ToRGBA(tileColors[tt.want]); got != want {
+			t.Errorf("frame at %vs is %v, want tile %d", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestLoadAnimatedSpriteSheetErrors(t *testing.T) {
+	path := writeSheet(t)
+	for _, fps := range []int{0, -1} {
+		if _, err := LoadAnimatedSpriteSheet(path, 1, 4, fps); err == nil {
+			t.Errorf("LoadAnimatedSpriteSheet() at %d fps returned no error", fps)
+		}
+	}
+	if _, err := LoadAnimatedSpriteSheet(filepath.Join(filepath.Dir(path), "missing.png"), 1, 4, 8); err == nil {
+		t.Error("LoadAnimatedSpriteSheet(missing) returned no error")
+	}
+}
+
+func TestLoadSpriteSheetCached(t *testing.T) {
+	path := writeSheet(t)
+	opens := countOpens(t)
+	first, err := LoadSpriteSheet(path, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadSpriteSheet(path, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *opens != 1 {
+		t.Errorf("loading a sheet twice opened it %d times, want 1", *opens)
+	}
+	for i := 0; i < 4; i++ {
+		if first(i) != second(i) {
+			t.Errorf("tile %d isn't the same picture on the second load", i)
+		}
+	}
+
+	// Cut up differently it is loaded again, the first division staying
+	// cached
+	if _, err := LoadSpriteSheet(path, 4, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSpriteSheet(path, 1, 4); err != nil {
+		t.Fatal(err)
+	}
+	if *opens != 2 {
+		t.Errorf("loading a sheet in 2 divisions opened it %d times, want 2", *opens)
+	}
+}
+
+func TestLoadPictureCached(t *testing.T) {
+	path := writeSheet(t)
+	opens := countOpens(t)
+	first, err := LoadPicture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadPicture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *opens != 1 || first != second {
+		t.Errorf("loading a picture twice opened it %d times, same picture %t, want 1 and true", *opens, first == second)
+	}
+	if b := first.Bounds(); b.W() != 160 || b.H() != 40 {
+		t.Errorf("picture is %vx%v, want 160x40", b.W(), b.H())
+	}
+}
+
+func TestLoadSpriteSheetFromFS(t *testing.T) {
+	fsys := fstest.MapFS{"resources/sheet.png": {Data: sheetPNG(t)}}
+	opens := countOpens(t)
+	tile, err := LoadSpriteSheetFromFS(fsys, "resources/sheet.png", 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range tileColors {
+		if got, want := colorAt(tile(i)), pixel.ToRGBA(c); got != want {
+			t.Errorf("tile %d is %v, want %v", i, got, want)
+		}
+		if b := tile(i).Bounds(); b.W() != 40 || b.H() != 40 {
+			t.Errorf("tile %d is %vx%v, want 40x40", i, b.W(), b.H())
+		}
+	}
+	if *opens != 0 {
+		t.Errorf("loading from a file system opened %d files of the operating system", *opens)
+	}
+
+	if _, err := LoadSpriteSheetFromFS(fsys, "resources/missing.png", 1, 4); err == nil {
+		t.Error("LoadSpriteSheetFromFS(missing) returned no error")
+	}
+}
+
+func TestLoadPictureFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sheet.png": {Data: sheetPNG(t)},
+		"bad.png":   {Data: []byte("not an image")},
+	}
+	pic, err := LoadPictureFromFS(fsys, "sheet.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := pic.Bounds(); b.W() != 160 || b.H() != 40 {
+		t.Errorf("picture is %vx%v, want 160x40", b.W(), b.H())
+	}
+	if got, want := colorAt(pic), pixel.ToRGBA(tileColors[2]); got != want {
+		t.Errorf("center of the picture is %v, want %v", got, want)
+	}
+	for _, path := range []string{"bad.png", "missing.png"} {
+		if _, err := LoadPictureFromFS(fsys, path); err == nil {
+			t.Errorf("LoadPictureFromFS(%s) returned no error", path)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+//go:build !wasm
+// +build !wasm
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
+
+	"github.com/zkry/golang-tetris/config"
+	"github.com/zkry/golang-tetris/controls"
+	"github.com/zkry/golang-tetris/game"
+	"github.com/zkry/golang-tetris/input"
+	"github.com/zkry/golang-tetris/render"
+)
+
+// runTwoPlayer plays two player games side by side in one window, the left
+// player on the keys around WASD and the right player on the arrow keys.
+// When seed is not nil the games are dealt from it.
+func runTwoPlayer(settings config.Settings, seed *int64) {
+	// Each player gets a half as wide as the one player window
+	layoutWidth := config.DefaultWindowWidth
+	layoutHeight := config.DefaultWindowHeight
+	cfg := pixelgl.WindowConfig{
+		Title:     "Blockfall",
+		Bounds:    pixel.R(0, 0, 2*layoutWidth, layoutHeight),
+		VSync:     true,
+		Resizable: true,
+	}
+	win, err := pixelgl.NewWindow(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	if err := render.Load(filepath.Join(pwd, "resources")); err != nil {
+		panic(err)
+	}
+	render.SetAnimatedBlocks(settings.AnimatedBlock)
+	theme := render.ThemeFor(settings)
+	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
+
+	var buttons [2][input.NumActions]pixelgl.Button
+	for i, c := range controls.TwoPlayerControls() {
+		keys, err := c.Keys()
+		if err != nil {
+			panic(err)
+		}
+		buttons[i] = actionButtons(keys)
+	}
+
+	// Both games are dealt the same pieces
+	newVersus := func() *game.Versus {
+		s := time.Now().UnixNano()
+		if seed != nil {
+			s = *seed
+		}
+		return game.NewVersus(settings, s)
+	}
+	v := newVersus()
+	var ins [2]input.PlayerInput
+	handlers := [2]*input.InputHandler{
+		input.NewInputHandler(settings.DAS, settings.ARR),
+		input.NewInputHandler(settings.DAS, settings.ARR),
+	}
+
+	const targetFPS = 120
+	frameDuration := time.Second / targetFPS
+	last := time.Now()
+	stepTime := 0.0 // Time not yet simulated
+
+	for !win.Closed() {
+		frameStart := time.Now()
+		dt := math.Min(time.Since(last).Seconds(), 0.25)
+		last = time.Now()
+
+		if v.GameOver() {
+			if win.JustPressed(pixelgl.KeyR) {
+				v = newVersus()
+				ins = [2]input.PlayerInput{}
+				for i := range handlers {
+					handlers[i] = input.NewInputHandler(settings.DAS, settings.ARR)
+				}
+				stepTime = 0
+			} else if win.JustPressed(pixelgl.KeyQ) {
+				return
+			}
+		} else {
+			if win.JustPressed(pixelgl.KeyEscape) {
+				v.TogglePause()
+			}
+
+			// Both games advance by the same fixed steps so neither player
+			// gets more time than the other
+			if v.Players[0].Paused() {
+				stepTime = 0
+			} else {
+				stepTime += dt
+				for stepTime >= game.StepLength && !v.GameOver() {
+					stepTime -= game.StepLength
+					var states [2]input.InputState
+					for i := range states {
+						ins[i].Next(readActions(win, buttons[i]))
+						states[i] = handlers[i].Update(game.StepLength, ins[i].Pressed, ins[i].JustPressed, ins[i].JustReleased)
+					}
+					v.Update(states, game.StepLength)
+				}
+			}
+		}
+
+		win.Clear(colornames.Black)
+		render.BackgroundImage(win, theme)
+
+		// Each half of the window is laid out like the one player window
+		half := win.Bounds().W() / 2
+		uiScaleFactor := math.Min(half/layoutWidth, win.Bounds().H()/layoutHeight)
+		yOffset := (win.Bounds().H() - layoutHeight*uiScaleFactor) / 2
+		for i, gs := range v.Players {
+			xOffset := float64(i)*half + (half-layoutWidth*uiScaleFactor)/2
+			at := func(x, y float64) pixel.Vec {
+				return pixel.V(x*uiScaleFactor+xOffset, y*uiScaleFactor+yOffset)
+			}
+
+			render.Panels(win, theme, uiScaleFactor, xOffset, yOffset)
+			scoreTxt := text.New(at(initialScoreX, initialScoreY), basicAtlas)
+			nextPieceTxt := text.New(at(initialNextPieceTxtX, initialNextPieceTxtY), basicAtlas)
+			holdPieceTxt := text.New(at(initialHoldPieceTxtX, initialHoldPieceTxtY), basicAtlas)
+			render.Text(win, scoreTxt, nextPieceTxt, holdPieceTxt, uiScaleFactor, gs)
+			render.HUD(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+			render.HoldPiece(win, gs.HoldPiece(), gs.CanHold(), theme, settings.ColorblindMode, uiScaleFactor, xOffset, yOffset)
+			render.NextPieces(win, gs.NextPieces(), theme, settings.ColorblindMode, uiScaleFactor, xOffset, yOffset)
+			render.BoardAt(win, gs, theme, uiScaleFactor, xOffset, yOffset)
+			render.ScorePopups(win, basicAtlas, gs, uiScaleFactor, xOffset, yOffset)
+
+			label := text.New(at(282, 430), basicAtlas)
+			fmt.Fprintf(label, "Player %d", i+1)
+			label.Draw(win, pixel.IM.Scaled(label.Orig, 1.5*uiScaleFactor))
+		}
+
+		center := win.Bounds().Center()
+		if v.GameOver() {
+			result := "Draw!"
+			if winner := v.Winner(); winner != 0 {
+				result = fmt.Sprintf("Player %d Wins!", winner)
+			}
+			render.CenteredText(win, basicAtlas, []string{result, "", "Press R to play again or Q to quit"}, 2*uiScaleFactor, center)
+		} else if v.Players[0].Paused() {
+			render.CenteredText(win, basicAtlas, []string{"PAUSED", "Press Esc to resume"}, 2*uiScaleFactor, center)
+		}
+
+		win.Update()
+
+		if elapsed := time.Since(frameStart); elapsed < frameDuration {
+			time.Sleep(frameDuration - elapsed)
+		}
+	}
+}